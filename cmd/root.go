@@ -11,6 +11,7 @@ package cmd
 /*****************************************************************************************************************/
 
 import (
+	"github.com/observerly/skysolve/internal/profiles"
 	"github.com/observerly/skysolve/internal/solver"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +28,7 @@ var rootCommand = &cobra.Command{
 
 func init() {
 	rootCommand.AddCommand(solver.AstrometryCommand)
+	rootCommand.AddCommand(profiles.ProfilesCommand)
 }
 
 /*****************************************************************************************************************/
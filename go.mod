@@ -8,6 +8,7 @@ require (
 	github.com/observerly/sidera v0.7.0
 	github.com/oklog/ulid v1.3.1
 	github.com/spf13/cobra v1.8.1
+	golang.org/x/image v0.23.0
 	golang.org/x/sync v0.10.0
 	gonum.org/v1/gonum v0.15.1
 	gorm.io/driver/sqlite v1.5.7
@@ -22,6 +23,5 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.22 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/exp v0.0.0-20241108190413-2d47ceb2692f // indirect
-	golang.org/x/image v0.23.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 )
@@ -0,0 +1,160 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package profiles
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+
+	"github.com/observerly/skysolve/pkg/fov"
+	"github.com/spf13/cobra"
+)
+
+/*****************************************************************************************************************/
+
+var (
+	ProfilesLocation string
+	Name             string
+	Telescope        string
+	Camera           string
+	FocalLength      float64
+	PixelSizeX       float64
+	PixelSizeY       float64
+)
+
+/*****************************************************************************************************************/
+
+var ProfilesCommand = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage the named equipment (telescope and camera) profiles the pixel scale is derived from",
+	Long:  "Manage the named equipment (telescope and camera) profiles the pixel scale is derived from",
+}
+
+/*****************************************************************************************************************/
+
+var ListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List the equipment profiles",
+	Long:  "List the equipment profiles, and the unbinned pixel scale of each",
+	Run: func(cmd *cobra.Command, args []string) {
+		location, profiles, err := readProfiles()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Profiles: %s\n", location)
+
+		for _, profile := range profiles.Profiles {
+			scale, err := profile.GetPixelScale(1, 1)
+			if err != nil {
+				fmt.Printf("%s: %v\n", profile.Name, err)
+				continue
+			}
+
+			fmt.Printf(
+				"%s: %s, %s (%v mm, %v µm, %.3f\"/pixel)\n",
+				profile.Name, profile.Telescope, profile.Camera, profile.FocalLength, profile.PixelSizeX, scale.X*3600,
+			)
+		}
+	},
+}
+
+/*****************************************************************************************************************/
+
+var SetCommand = &cobra.Command{
+	Use:   "set",
+	Short: "Add or replace an equipment profile",
+	Long:  "Add an equipment profile, or replace the existing profile of the same name",
+	Run: func(cmd *cobra.Command, args []string) {
+		location, profiles, err := readProfiles()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if err := profiles.Set(fov.Profile{
+			Name:        Name,
+			Telescope:   Telescope,
+			Camera:      Camera,
+			FocalLength: FocalLength,
+			PixelSizeX:  PixelSizeX,
+			PixelSizeY:  PixelSizeY,
+		}); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			cmd.Usage()
+			return
+		}
+
+		if err := fov.WriteProfiles(location, profiles); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		fmt.Printf("Profile %s written to: %s\n", Name, location)
+	},
+}
+
+/*****************************************************************************************************************/
+
+func init() {
+	// Add the profiles flag to the profiles command for setting the location of the equipment profiles file:
+	// example usage: --profiles ./profiles.json
+	ProfilesCommand.PersistentFlags().StringVarP(
+		&ProfilesLocation,
+		"profiles",
+		"",
+		"",
+		"The equipment profiles file location on the filesystem (default the user configuration directory)",
+	)
+
+	// Add the equipment flags to the set command for describing the telescope and camera of the profile:
+	// example usage: --name redcat51-asi2600 --focal-length 250 --pixel-size-x 3.76
+	SetCommand.Flags().StringVarP(&Name, "name", "", "", "The unique name of the profile")
+
+	SetCommand.Flags().StringVarP(&Telescope, "telescope", "", "", "The description of the telescope")
+
+	SetCommand.Flags().StringVarP(&Camera, "camera", "", "", "The description of the camera")
+
+	SetCommand.Flags().Float64VarP(&FocalLength, "focal-length", "", 0, "The focal length of the telescope (in mm)")
+
+	SetCommand.Flags().Float64VarP(&PixelSizeX, "pixel-size-x", "", 0, "The unbinned pixel size in the x-axis (in µm)")
+
+	SetCommand.Flags().Float64VarP(&PixelSizeY, "pixel-size-y", "", 0, "The unbinned pixel size in the y-axis (in µm)")
+
+	ProfilesCommand.AddCommand(ListCommand)
+
+	ProfilesCommand.AddCommand(SetCommand)
+}
+
+/*****************************************************************************************************************/
+
+// readProfiles reads the equipment profiles file of the profiles flag, or the default profiles file of the user.
+func readProfiles() (string, *fov.Profiles, error) {
+	location := ProfilesLocation
+
+	if location == "" {
+		var err error
+
+		location, err = fov.GetDefaultProfilesLocation()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	profiles, err := fov.ReadProfiles(location)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return location, profiles, nil
+}
+
+/*****************************************************************************************************************/
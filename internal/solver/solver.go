@@ -17,13 +17,22 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
 	"github.com/observerly/skysolve/internal/utils"
 	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/bayer"
 	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/extract"
+	"github.com/observerly/skysolve/pkg/fitsio"
 	"github.com/observerly/skysolve/pkg/fov"
+	"github.com/observerly/skysolve/pkg/imageio"
+	"github.com/observerly/skysolve/pkg/reject"
 	"github.com/observerly/skysolve/pkg/solve"
+	"github.com/observerly/skysolve/pkg/wcs"
+	"github.com/observerly/skysolve/pkg/xylist"
 	"github.com/spf13/cobra"
 )
 
@@ -31,12 +40,40 @@ import (
 
 var (
 	InputFileLocation          string
-	RA                         float32
-	Dec                        float32
+	XYListFileLocation         string
+	Width                      int
+	Height                     int
+	RA                         string
+	Dec                        string
 	PixelScaleX                float64
 	PixelScaleY                float64
 	QuadTolerance              float64
 	EuclidianDistanceTolerance float64
+	Strategies                 []string
+	Extractor                  string
+	PSF                        string
+	MinimumFWHM                float64
+	MaximumSharpness           float64
+	Reject                     bool
+	EdgeMargin                 float64
+	Saturation                 float64
+	BadPixelMaskLocation       string
+	Debayer                    string
+	Frames                     string
+	Downsample                 int
+	Projection                 string
+	CoordinateFrame            string
+	SiteLatitude               float64
+	SiteLongitude              float64
+	SiteElevation              float64
+	DateObs                    string
+	Refraction                 bool
+	Temperature                float64
+	Pressure                   float64
+	ProfileName                string
+	ProfilesLocation           string
+	Target                     string
+	TargetSIMBAD               bool
 )
 
 /*****************************************************************************************************************/
@@ -47,10 +84,14 @@ func getFilePathStem(file *os.File) string {
 	directory := filepath.Dir(path)
 	// Get the full filename (e.g. "astrometry.fits")
 	base := filepath.Base(path)
-	// Extract the extension (e.g. ".fits")
+	// Extract the extension (e.g. ".fits", ".xisf" or ".png")
 	extension := filepath.Ext(base)
 	// Remove the extension from the filename (e.g. "astrometry"):
 	name := strings.TrimSuffix(base, extension)
+	// Remove the inner extension of a tile-compressed file (e.g. "astrometry" from "astrometry.fits.fz"):
+	if extension == ".fz" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
 	// Return the filepath stem (e.g. "./samples/astrometry")
 	return filepath.Join(directory, name)
 }
@@ -62,27 +103,123 @@ var AstrometryCommand = &cobra.Command{
 	Short: "astrometry",
 	Long:  "astrometry",
 	Run: func(cmd *cobra.Command, args []string) {
-		// Attempt to open the file from the given filepath and validate it exists:
-		inputFile, err := os.Open(InputFileLocation)
+		if InputFileLocation == "" && XYListFileLocation == "" {
+			fmt.Println("either an input file or an xylist file is required")
+			cmd.Usage()
+			return
+		}
+
+		var inputFile, xylistFile *os.File
+
+		var err error
+
+		if XYListFileLocation != "" {
+			// Attempt to open the xylist file from the given filepath and validate it exists:
+			xylistFile, err = os.Open(XYListFileLocation)
+			if err != nil {
+				fmt.Println("failed to open xylist file:", err)
+				cmd.Usage()
+				return
+			}
+
+			fmt.Println("XYList File Location:", XYListFileLocation)
+
+			// Defer closing the xylist file:
+			defer xylistFile.Close()
+		} else {
+			// Attempt to open the file from the given filepath and validate it exists:
+			inputFile, err = os.Open(InputFileLocation)
+			if err != nil {
+				fmt.Println("failed to open input file:", err)
+				cmd.Usage()
+				return
+			}
+
+			fmt.Println("Input File Location:", InputFileLocation)
+
+			// Defer closing the input file:
+			defer inputFile.Close()
+		}
+
+		// Parse the right ascension and declination hints, in either decimal or sexagesimal notation:
+		ra, err := utils.ParseRAFlag(RA)
+		if err != nil {
+			fmt.Println("failed to parse the ra flag:", err)
+			cmd.Usage()
+			return
+		}
+
+		dec, err := utils.ParseDecFlag(Dec)
 		if err != nil {
-			fmt.Println("failed to open input file:", err)
+			fmt.Println("failed to parse the dec flag:", err)
 			cmd.Usage()
 			return
 		}
 
-		fmt.Println("Input File Location:", InputFileLocation)
+		// Resolve the named target, if any, to the right ascension and declination hints it has not been given:
+		if Target != "" {
+			target, err := catalog.NewTargetResolver(catalog.TargetResolverParams{SIMBAD: TargetSIMBAD}).Resolve(Target)
+			if err != nil {
+				fmt.Println("failed to resolve the target:", err)
+				cmd.Usage()
+				return
+			}
+
+			fmt.Printf("Target: %s (%s)\n", target.Name, target.Source)
+
+			if math.IsNaN(float64(ra)) {
+				ra = float32(target.RA)
+			}
 
-		// Defer closing the input file:
-		defer inputFile.Close()
+			if math.IsNaN(float64(dec)) {
+				dec = float32(target.Dec)
+			}
+		}
+
+		// Resolve the equipment profile, if any, that the pixel scale follows from:
+		profile, err := getProfile(ProfileName, ProfilesLocation)
+		if err != nil {
+			fmt.Println("failed to resolve the equipment profile:", err)
+			cmd.Usage()
+			return
+		}
 
 		params := RunSolverParams{
 			InputFile:                    inputFile,
-			RA:                           RA,
-			Dec:                          Dec,
+			XYListFile:                   xylistFile,
+			Width:                        Width,
+			Height:                       Height,
+			RA:                           ra,
+			Dec:                          dec,
 			PixelScaleX:                  PixelScaleX,
 			PixelScaleY:                  PixelScaleY,
 			QuadTolerance:                QuadTolerance,
 			EuclidianceDistanceTolerance: EuclidianDistanceTolerance,
+			Strategies:                   Strategies,
+			Extractor:                    Extractor,
+			PSF:                          PSF,
+			Reject:                       Reject,
+			Rejection: reject.Params{
+				MinimumFWHM:      MinimumFWHM,
+				MaximumSharpness: MaximumSharpness,
+				EdgeMargin:       EdgeMargin,
+				Saturation:       Saturation,
+			},
+			BadPixelMaskLocation: BadPixelMaskLocation,
+			Debayer:              Debayer,
+			Frames:               Frames,
+			Downsample:           Downsample,
+			Projection:           Projection,
+			CoordinateFrame:      CoordinateFrame,
+			SiteLatitude:         SiteLatitude,
+			SiteLongitude:        SiteLongitude,
+			SiteElevation:        SiteElevation,
+			DateObs:              DateObs,
+			Refraction:           Refraction,
+			Temperature:          Temperature,
+			Pressure:             Pressure,
+			Profile:              profile,
+			TargetSIMBAD:         TargetSIMBAD,
 		}
 
 		// Attempt to run the solver with the given parameters:
@@ -98,34 +235,63 @@ var AstrometryCommand = &cobra.Command{
 
 func init() {
 	// Add the input flag to the astrometry command for reading the file from some input location:
-	// example usage: --input ./astrometry.fits or -i ./astrometry.fits
+	// example usage: --input ./astrometry.fits or -i ./astrometry.xisf
 	AstrometryCommand.Flags().StringVarP(
 		&InputFileLocation,
 		"input",
 		"i",
 		"",
-		"The input file location on the filesystem",
+		"The input image location on the filesystem (FITS, XISF, TIFF or PNG)",
+	)
+
+	// Add the xylist flag to the astrometry command for solving from a pre-extracted list of stars:
+	// example usage: --xylist ./stars.csv (CSV, JSON or a FITS binary table of x, y and flux)
+	AstrometryCommand.Flags().StringVarP(
+		&XYListFileLocation,
+		"xylist",
+		"",
+		"",
+		"The pre-extracted star list (xylist) location on the filesystem, which skips star extraction",
+	)
+
+	// Add the width flag to the astrometry command for setting the width of the image an xylist was extracted from:
+	// example usage: --width 4096
+	AstrometryCommand.Flags().IntVarP(
+		&Width,
+		"width",
+		"",
+		0,
+		"The width of the image (in pixels) the xylist was extracted from",
+	)
+
+	// Add the height flag to the astrometry command for setting the height of the image an xylist was extracted from:
+	// example usage: --height 4096
+	AstrometryCommand.Flags().IntVarP(
+		&Height,
+		"height",
+		"",
+		0,
+		"The height of the image (in pixels) the xylist was extracted from",
 	)
-	AstrometryCommand.MarkFlagRequired("input")
 
 	// Add the approximated point equatorial coordinate RA to the astrometry command for setting the approximate RA:
-	// example usage: --ra 98.6
-	AstrometryCommand.Flags().Float32VarP(
+	// example usage: --ra 98.6, --ra "06 34 24" or --ra 6.573h
+	AstrometryCommand.Flags().StringVarP(
 		&RA,
 		"ra",
 		"",
-		float32(math.NaN()),
-		"The approximate right ascension of the central image point (in degrees)",
+		"",
+		"The approximate right ascension of the central image point (in degrees, sexagesimal hours, or hours as \"6.573h\")",
 	)
 
 	// Add the approximated point equatorial coordinate dec to the astrometry command for setting the approximate dec:
-	// example usage: --dec 2.5
-	AstrometryCommand.Flags().Float32VarP(
+	// example usage: --dec 2.5 or --dec "+02:30:00"
+	AstrometryCommand.Flags().StringVarP(
 		&Dec,
 		"dec",
 		"",
-		float32(math.NaN()),
-		"The approximate declination of the central image point (in degrees)",
+		"",
+		"The approximate declination of the central image point (in decimal or sexagesimal degrees)",
 	)
 
 	// Add the pixel scale X flag to the astrometry command for setting the pixel scale in the x-axis:
@@ -167,249 +333,1253 @@ func init() {
 		10.0,
 		"The euclidian distance (in pixels) tolerance for the solver",
 	)
-}
 
-/*****************************************************************************************************************/
+	// Add the strategies flag to the astrometry command for setting the ordered fallback chain of solver strategies:
+	// example usage: --strategies tracking,quad,triangle
+	AstrometryCommand.Flags().StringSliceVarP(
+		&Strategies,
+		"strategies",
+		"",
+		[]string{"quad", "triangle"},
+		"The ordered solver strategies to attempt until one passes verification, of \"quad\", \"triangle\", \"blind\" (the catalog sources without the hint) or \"tracking\" (the previous frame)",
+	)
 
-type RunSolverParams struct {
-	InputFile                    *os.File `json:"inputFile"`
-	RA                           float32  `json:"ra"`
-	Dec                          float32  `json:"dec"`
-	PixelScaleX                  float64  `json:"pixelScaleX"`
-	PixelScaleY                  float64  `json:"pixelScaleY"`
-	QuadTolerance                float64  `json:"quadTolerance"`
-	EuclidianceDistanceTolerance float64  `json:"euclidianDistanceTolerance"`
+	// Add the extractor flag to the astrometry command for choosing the star extraction implementation:
+	// example usage: --extractor native
+	AstrometryCommand.Flags().StringVarP(
+		&Extractor,
+		"extractor",
+		"",
+		"iris",
+		"The star extractor, either \"iris\" or \"native\" (mesh background, deblending and windowed centroids)",
+	)
+
+	// Add the PSF flag to the astrometry command for fitting a PSF model to each star of the native extractor:
+	// example usage: --psf moffat
+	AstrometryCommand.Flags().StringVarP(
+		&PSF,
+		"psf",
+		"",
+		"none",
+		"The PSF model fitted by the native extractor, either \"none\", \"gaussian\" or \"moffat\"",
+	)
+
+	// Add the rejection flags to the astrometry command for removing spurious stars before quad generation:
+	// example usage: --reject --min-fwhm 1.5 --max-sharpness 0.9 --edge-margin 8 --saturation 60000
+	AstrometryCommand.Flags().BoolVarP(
+		&Reject,
+		"reject",
+		"",
+		false,
+		"Reject spurious stars (hot pixels, cosmic rays, edge and saturated stars) before quad generation",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&MinimumFWHM,
+		"min-fwhm",
+		"",
+		reject.DefaultParams.MinimumFWHM,
+		"The minimum FWHM (in pixels) of a star, rejecting hot pixels (0 disables)",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&MaximumSharpness,
+		"max-sharpness",
+		"",
+		reject.DefaultParams.MaximumSharpness,
+		"The maximum sharpness of a star, rejecting cosmic ray hits (0 disables)",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&EdgeMargin,
+		"edge-margin",
+		"",
+		reject.DefaultParams.EdgeMargin,
+		"The margin (in pixels) from the edge of the image within which stars are rejected (0 disables)",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&Saturation,
+		"saturation",
+		"",
+		0,
+		"The saturation level (in ADU) at which stars are rejected (0 uses DATAMAX or SATURATE, if present)",
+	)
+
+	// Add the debayer flag to the astrometry command for extracting stars from one-shot-colour (OSC) images:
+	// example usage: --debayer superpixel
+	AstrometryCommand.Flags().StringVarP(
+		&Debayer,
+		"debayer",
+		"",
+		"auto",
+		"The debayering of OSC images, either \"auto\" (luminance if BAYERPAT exists), \"none\", \"luminance\" or \"superpixel\"",
+	)
+
+	// Add the frames flag to the astrometry command for solving multi-extension FITS files and data cubes:
+	// example usage: --frames shared
+	AstrometryCommand.Flags().StringVarP(
+		&Frames,
+		"frames",
+		"",
+		"independent",
+		"How the planes of every image HDU are solved, either \"independent\" (each plane) or \"shared\" (stacked cubes, each solved against one shared catalog query)",
+	)
+
+	// Add the downsample flag to the astrometry command for extracting stars from a binned image of large sensors:
+	// example usage: --downsample 4
+	AstrometryCommand.Flags().IntVarP(
+		&Downsample,
+		"downsample",
+		"",
+		1,
+		"The binning factor before star extraction, where centroids are refined at full resolution (1 disables it, -1 selects it automatically)",
+	)
+
+	// Add the projection flag to the astrometry command for fitting wide-field and all-sky cameras:
+	// example usage: --projection zea
+	AstrometryCommand.Flags().StringVarP(
+		&Projection,
+		"projection",
+		"",
+		"tan",
+		"The projection of the solution, either \"tan\" (gnomonic), \"sin\", \"arc\", \"zea\", \"stg\" or \"car\"",
+	)
+
+	// Add the coordinate frame flag to the astrometry command for reporting the solution in galactic coordinates:
+	// example usage: --coordinate-frame galactic
+	AstrometryCommand.Flags().StringVarP(
+		&CoordinateFrame,
+		"coordinate-frame",
+		"",
+		"icrs",
+		"The celestial frame the centre and corners of the solution are reported in, either \"icrs\", \"galactic\" or \"ecliptic\"",
+	)
+
+	// Add the bad pixel mask flag to the astrometry command, e.g., a FITS image or a text list of bad pixels:
+	// example usage: --bad-pixel-mask ./samples/mask.fits
+	AstrometryCommand.Flags().StringVarP(
+		&BadPixelMaskLocation,
+		"bad-pixel-mask",
+		"",
+		"",
+		"The bad pixel mask, either a FITS image (non-zero is bad) or a text file of \"x y\", \"column x\" or \"row y\" lines",
+	)
+
+	// Add the site flags to the astrometry command for the observing site, in place of the SITELAT, SITELONG and
+	// SITEELEV headers:
+	// example usage: --site-latitude 52.21 --site-longitude -0.12 --site-elevation 25
+	AstrometryCommand.Flags().Float64VarP(
+		&SiteLatitude,
+		"site-latitude",
+		"",
+		math.NaN(),
+		"The latitude of the observing site (in degrees), in place of the SITELAT header",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&SiteLongitude,
+		"site-longitude",
+		"",
+		math.NaN(),
+		"The east positive longitude of the observing site (in degrees), in place of the SITELONG header",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&SiteElevation,
+		"site-elevation",
+		"",
+		math.NaN(),
+		"The elevation of the observing site (in metres), in place of the SITEELEV header",
+	)
+
+	// Add the date flag to the astrometry command for the time of the observation, in place of the DATE-OBS header:
+	// example usage: --date-obs 2025-03-20T21:30:00
+	AstrometryCommand.Flags().StringVarP(
+		&DateObs,
+		"date-obs",
+		"",
+		"",
+		"The (UTC) time of the middle of the exposure, in place of the DATE-OBS and EXPTIME headers",
+	)
+
+	// Add the refraction flags to the astrometry command for fitting against the observed (refracted) catalog places:
+	// example usage: --refraction --temperature 5 --pressure 1000
+	AstrometryCommand.Flags().BoolVarP(
+		&Refraction,
+		"refraction",
+		"",
+		false,
+		"Fit the solution against the observed places of the catalog sources, as refracted by the atmosphere",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&Temperature,
+		"temperature",
+		"",
+		math.NaN(),
+		"The ambient temperature (in °C) of the refraction, in place of the AMBTEMP header (default 10°C)",
+	)
+
+	AstrometryCommand.Flags().Float64VarP(
+		&Pressure,
+		"pressure",
+		"",
+		math.NaN(),
+		"The atmospheric pressure (in hPa) of the refraction, in place of the PRESSURE header (default from the elevation)",
+	)
+
+	// Add the profile flags to the astrometry command for deriving the pixel scale from a named equipment profile:
+	// example usage: --profile redcat51-asi2600 --profiles ./profiles.json
+	AstrometryCommand.Flags().StringVarP(
+		&ProfileName,
+		"profile",
+		"",
+		"",
+		"The name of the equipment profile (telescope and camera) the pixel scale is derived from",
+	)
+
+	AstrometryCommand.Flags().StringVarP(
+		&ProfilesLocation,
+		"profiles",
+		"",
+		"",
+		"The equipment profiles file location on the filesystem (default the user configuration directory)",
+	)
+
+	// Add the target flags to the astrometry command for resolving the pointing hint from the name of the target:
+	// example usage: --target "NGC 7000" --target-simbad
+	AstrometryCommand.Flags().StringVarP(
+		&Target,
+		"target",
+		"",
+		"",
+		"The name of the target, e.g., \"M42\" or \"NGC 7000\", resolved to the ra and dec hints (default the OBJECT header or filename)",
+	)
+
+	AstrometryCommand.Flags().BoolVarP(
+		&TargetSIMBAD,
+		"target-simbad",
+		"",
+		false,
+		"Look up target names missing from the bundled catalog in SIMBAD",
+	)
 }
 
 /*****************************************************************************************************************/
 
-func RunSolver(params RunSolverParams) error {
-	// Assume an image of 2x2 pixels with 16-bit depth, and no offset:
-	fit := fits.NewFITSImage(2, 0, 0, 65535)
+// getProfile reads the named equipment profile from the profiles file, or from the default profiles file of the user
+// when no location is given, returning nil when no profile is named.
+func getProfile(name, location string) (*fov.Profile, error) {
+	if name == "" {
+		return nil, nil
+	}
 
-	// Read in our exposure data into the image:
-	err := fit.Read(params.InputFile)
-	if err != nil {
-		return fmt.Errorf("failed to read file: %v", err)
+	if location == "" {
+		var err error
+
+		location, err = fov.GetDefaultProfilesLocation()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Attempt to get the RA header from the FITS file, or resolve the user's input:
-	ra, err := utils.ResolveOrExtractRAFromHeaders(params.RA, fit.Header)
+	profiles, err := fov.ReadProfiles(location)
 	if err != nil {
-		return fmt.Errorf("failed to resolve or extract RA from headers: %v", err)
+		return nil, err
 	}
 
-	fmt.Printf("Right Ascension: %v°\n", ra)
-
-	// Attempt to get the Dec header from the FITS file, or resolve the user's input:
-	dec, err := utils.ResolveOrExtractDecFromHeaders(params.Dec, fit.Header)
+	profile, err := profiles.Get(name)
 	if err != nil {
-		return fmt.Errorf("failed to resolve or extract Dec from headers: %v", err)
+		return nil, err
 	}
 
-	fmt.Printf("Declination: %v°\n", dec)
+	return &profile, nil
+}
 
-	// Attempt to extract the height from the FITS file headers:
-	height, err := utils.ExtractImageHeightFromHeaders(fit.Header)
-	if err != nil {
-		return fmt.Errorf("failed to extract height from headers: %v", err)
-	}
+/*****************************************************************************************************************/
 
-	fmt.Printf("Height: %v pixels\n", height)
+type RunSolverParams struct {
+	InputFile                    *os.File      `json:"inputFile"`
+	XYListFile                   *os.File      `json:"xylistFile"`
+	Width                        int           `json:"width"`
+	Height                       int           `json:"height"`
+	RA                           float32       `json:"ra"`
+	Dec                          float32       `json:"dec"`
+	PixelScaleX                  float64       `json:"pixelScaleX"`
+	PixelScaleY                  float64       `json:"pixelScaleY"`
+	QuadTolerance                float64       `json:"quadTolerance"`
+	EuclidianceDistanceTolerance float64       `json:"euclidianDistanceTolerance"`
+	Strategies                   []string      `json:"strategies"`
+	Extractor                    string        `json:"extractor"`
+	PSF                          string        `json:"psf"`
+	Reject                       bool          `json:"reject"`
+	Rejection                    reject.Params `json:"rejection"`
+	BadPixelMaskLocation         string        `json:"badPixelMaskLocation"`
+	Debayer                      string        `json:"debayer"`
+	Frames                       string        `json:"frames"`
+	Downsample                   int           `json:"downsample"`
+	Projection                   string        `json:"projection"`
+	CoordinateFrame              string        `json:"coordinateFrame"`
+	Profile                      *fov.Profile  `json:"profile"`
+	SiteLatitude                 float64       `json:"siteLatitude"`
+	SiteLongitude                float64       `json:"siteLongitude"`
+	SiteElevation                float64       `json:"siteElevation"`
+	DateObs                      string        `json:"dateObs"`
+	Refraction                   bool          `json:"refraction"`
+	Temperature                  float64       `json:"temperature"`
+	Pressure                     float64       `json:"pressure"`
+	TargetSIMBAD                 bool          `json:"targetSimbad"`
+}
 
-	// Attempt to extract the width from the FITS file header:                                                                                                                                     Attempt to extract the width from the FITS file headers:
-	width, err := utils.ExtractImageWidthFromHeaders(fit.Header)
-	if err != nil {
-		return fmt.Errorf("failed to extract width from headers: %v", err)
-	}
+/*****************************************************************************************************************/
 
-	fmt.Printf("Width: %v pixels\n", width)
+// frame is a single image to plate solve, either a plane of the pixel data of the input file, or the stars of a
+// pre-extracted xylist (without pixel data):
+type frame struct {
+	Header fits.FITSHeader
+	Data   []float32
+	Stars  []photometry.Star
+	Width  int32
+	Height int32
+	ADU    int32
+}
 
-	pixelScaleX := params.PixelScaleX
+/*****************************************************************************************************************/
 
-	// Validate the pixel scale in the x-axis:
-	if pixelScaleX == math.Inf(-1) {
-		return fmt.Errorf("pixel scale x is required")
-	}
+// frameSolution is the WCS solution of a single plane of a multi-extension FITS file or data cube.
+type frameSolution struct {
+	HDU         int                     `json:"hdu"`
+	Plane       int                     `json:"plane"`
+	Planes      int                     `json:"planes"`
+	Extname     string                  `json:"extname,omitempty"`
+	WCS         *wcs.WCS                `json:"wcs"`
+	Observation *astrometry.Observation `json:"observation,omitempty"`
+}
 
-	if pixelScaleX == 0 {
-		return fmt.Errorf("pixel scale x must be non-zero")
-	}
+/*****************************************************************************************************************/
+
+// catalogQuery shares the catalog sources between the frames of a file, e.g., the CCDs of a mosaic camera, such that
+// a single radial search, centred on the union of the pointing hints of the frames and covering the field of view of
+// every frame, is performed rather than one per frame. Each frame is still solved independently against the sources.
+type catalogQuery struct {
+	Center  astrometry.ICRSEquatorialCoordinate
+	Radius  float64
+	Frames  int
+	Sources []catalog.Source
+}
+
+/*****************************************************************************************************************/
 
-	if pixelScaleX < 0 {
-		pixelScaleX = math.Abs(pixelScaleX)
+// newCatalogQuery returns the catalog query shared by the frames, centred on the mean of the pointing hints of the
+// frames, with a radius reaching the furthest edge of the field of view of any frame. Frames without a pointing hint
+// or pixel scale are left out of the query, and fail to solve in turn.
+func newCatalogQuery(params RunSolverParams, frames []fitsio.Frame) (*catalogQuery, error) {
+	type field struct {
+		ra, dec, radius float64
 	}
 
-	fmt.Printf("Pixel Scale X: %v\n", pixelScaleX)
+	fields := []field{}
 
-	pixelScaleY := params.PixelScaleY
+	var x, y, z float64
 
-	// Validate the pixel scale in the y-axis:
-	if pixelScaleY == math.Inf(-1) {
-		return fmt.Errorf("pixel scale y is required")
-	}
+	for _, f := range frames {
+		header := utils.NewFITSHeaderFromHeader(f.Header)
+
+		ra, dec, err := getPointing(params, header)
+		if err != nil {
+			continue
+		}
+
+		scale, err := getPixelScale(params, header)
+		if err != nil {
+			continue
+		}
 
-	if pixelScaleY == 0 {
-		return fmt.Errorf("pixel scale y must be non-zero")
+		fields = append(fields, field{
+			ra:     float64(ra),
+			dec:    float64(dec),
+			radius: fov.GetRadialExtent(float64(f.Width), float64(f.Height), scale),
+		})
+
+		// Sum the unit vectors of the hints, such that hints either side of 0h do not average to 12h:
+		alpha, delta := float64(ra)*math.Pi/180, float64(dec)*math.Pi/180
+
+		x += math.Cos(delta) * math.Cos(alpha)
+		y += math.Cos(delta) * math.Sin(alpha)
+		z += math.Sin(delta)
 	}
 
-	if pixelScaleY < 0 {
-		pixelScaleY = math.Abs(pixelScaleY)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no pointing hint found for any of the %d frames", len(frames))
 	}
 
-	fmt.Printf("Pixel Scale Y: %v\n", pixelScaleY)
+	ra := math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
 
-	// Get our approximate radial extent for the field of view of the image (in degrees):
-	radius := fov.GetRadialExtent(
-		float64(fit.Header.Naxis1),
-		float64(fit.Header.Naxis2),
-		fov.PixelScale{
-			X: params.PixelScaleX,
-			Y: params.PixelScaleY,
-		})
+	dec := math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
 
-	fmt.Printf("Search Radius: %v°\n", radius)
+	radius := 0.0
 
-	// Create a new SIMBAD service client:
-	service := catalog.NewCatalogService(catalog.GAIA, catalog.Params{
-		Limit:     100, // Limit the number of records to 100
-		Threshold: 16,  // Limiting Magntiude, filter out any stars that are magnitude 16 or above (fainter)
-	})
+	// Extend the radius to the edge of the field of view of every frame, relative to the centre of the query:
+	for _, f := range fields {
+		cos := math.Sin(dec*math.Pi/180)*math.Sin(f.dec*math.Pi/180) +
+			math.Cos(dec*math.Pi/180)*math.Cos(f.dec*math.Pi/180)*math.Cos((ra-f.ra)*math.Pi/180)
 
-	// Attempt to create a new PlateSolver:
-	solver, err := solve.NewPlateSolver(solve.Params{
-		Data:                fit.Data,           // The exposure data from the fits image
-		Width:               int(width),         // The width of the image
-		Height:              int(height),        // The height of the image
-		PixelScaleX:         params.PixelScaleX, // The pixel scale in the x-axis
-		PixelScaleY:         params.PixelScaleY, // The pixel scale in the y-axis
-		ADU:                 fit.ADU,            // The analog-to-digital unit of the image
-		ExtractionThreshold: 16,                 // Extract a minimum of 16 of the brightest stars
-		Radius:              16,                 // 16 pixels radius for the star extraction
-		Sigma:               2.5,                // 8 pixels sigma for the Gaussian kernel
-	})
-	if err != nil {
-		fmt.Printf("there was an error while creating the plate solver: %v", err)
-		return err
-	}
+		separation := math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
 
-	// Whilst we have no matches, and whilst we are within 1 degree of the initial { ra, dec } guess, keep solving:
-	eq := astrometry.ICRSEquatorialCoordinate{
-		RA:  float64(ra),
-		Dec: float64(dec),
+		radius = math.Max(radius, separation+f.radius)
 	}
 
-	// Perform a radial search with the given center and radius, for all sources with a magnitude less than 10:
-	sources, err := service.PerformRadialSearch(eq, radius)
-	if err != nil {
-		fmt.Printf("there was an error while performing the SIMBAD radial search: %v", err)
-		return err
-	}
+	return &catalogQuery{
+		Center: astrometry.ICRSEquatorialCoordinate{RA: ra, Dec: dec},
+		Radius: radius,
+		Frames: len(frames),
+	}, nil
+}
 
-	// Append the sources to the solver:
-	solver.Sources = append(solver.Sources, sources...)
+/*****************************************************************************************************************/
 
-	// Define the tolerances for the solver, we can adjust these as needed:
-	tolerance := solve.ToleranceParams{
-		QuadTolerance:           params.QuadTolerance,
-		EuclidianPixelTolerance: params.EuclidianceDistanceTolerance,
-	}
+func RunSolver(params RunSolverParams) error {
+	if params.XYListFile != nil {
+		// Read in the pre-extracted stars, skipping both the pixel data and the star extraction:
+		list, err := xylist.ReadFile(params.XYListFile)
+		if err != nil {
+			return fmt.Errorf("failed to read xylist: %v", err)
+		}
 
-	wcs, _, err := solver.Solve(tolerance, 3)
+		stars := list.Stars()
 
-	if err != nil {
-		fmt.Println("an error occured while plate solving:", err)
-		return err
-	}
+		fmt.Printf("Stars: %d\n", len(stars))
 
-	if wcs == nil {
-		fmt.Println("no WCS solution found")
-		return fmt.Errorf("no WCS solution found")
-	}
+		// The user's input takes precedence over any dimensions recorded in the xylist:
+		width, height := int32(list.Width), int32(list.Height)
 
-	// Print fields from wcaxes through cd2_2
-	fmt.Printf("WCAXES: %d\n", wcs.WCAXES)
-	fmt.Printf("CRPIX1: %.6f\n", wcs.CRPIX1)
-	fmt.Printf("CRPIX2: %.6f\n", wcs.CRPIX2)
-	fmt.Printf("CRVAL1: %.6f\n", wcs.CRVAL1)
-	fmt.Printf("CRVAL2: %.6f\n", wcs.CRVAL2)
-	fmt.Printf("CTYPE1: %s\n", wcs.CTYPE1)
-	fmt.Printf("CTYPE2: %s\n", wcs.CTYPE2)
-	fmt.Printf("CDELT1: %.6f\n", wcs.CDELT1)
-	fmt.Printf("CDELT2: %.6f\n", wcs.CDELT2)
-	fmt.Printf("CUNIT1: %s\n", wcs.CUNIT1)
-	fmt.Printf("CUNIT2: %s\n", wcs.CUNIT2)
-	fmt.Printf("CD1_1:  %.6f\n", wcs.CD1_1)
-	fmt.Printf("CD1_2:  %.6f\n", wcs.CD1_2)
-	fmt.Printf("CD2_1:  %.6f\n", wcs.CD2_1)
-	fmt.Printf("CD2_2:  %.6f\n", wcs.CD2_2)
+		if params.Width > 0 {
+			width = int32(params.Width)
+		}
 
-	// Attempt to write the WCS solution to the output file:
-	fit.Header.Set("WCSAXES", wcs.WCAXES, "Number of World Coordinate System axes")
-	fit.Header.Set("CRPIX1", wcs.CRPIX1, "X Pixel coordinate of reference point")
-	fit.Header.Set("CRPIX2", wcs.CRPIX2, "Y Pixel coordinate of reference point")
-	fit.Header.Set("CDELT1", wcs.CDELT1, "Coordinate increment at reference point")
-	fit.Header.Set("CDELT2", wcs.CDELT2, "Coordinate increment at reference point")
-	fit.Header.Set("CUNIT1", wcs.CUNIT1, "Units of coordinate increment and value")
-	fit.Header.Set("CUNIT2", wcs.CUNIT2, "Units of coordinate increment and value")
-	fit.Header.Set("CTYPE1", wcs.CTYPE1, "Coordinate type code")
-	fit.Header.Set("CTYPE2", wcs.CTYPE2, "Coordinate type code")
-	fit.Header.Set("CRVAL1", wcs.CRVAL1, "Coordinate value at reference point")
-	fit.Header.Set("CRVAL2", wcs.CRVAL2, "Coordinate value at reference point")
-	fit.Header.Set("CD1_1", wcs.CD1_1, "Coordinate transformation matrix element")
-	fit.Header.Set("CD1_2", wcs.CD1_2, "Coordinate transformation matrix element")
-	fit.Header.Set("CD2_1", wcs.CD2_1, "Coordinate transformation matrix element")
-	fit.Header.Set("CD2_2", wcs.CD2_2, "Coordinate transformation matrix element")
-
-	// Attempt to write the WCS solution to the FITS file:
-	buf, err := fit.WriteToBuffer()
-	if err != nil {
-		fmt.Println("failed to write to buffer:", err)
-		return err
-	}
+		if params.Height > 0 {
+			height = int32(params.Height)
+		}
 
-	// Get the filepath stem where the file is located (e.g. "./samples/astrometry" from
-	// "./samples/astrometry.fits"):
-	wcsOutputFileStem := getFilePathStem(params.InputFile)
+		if width <= 0 || height <= 0 {
+			return fmt.Errorf("the image width and height are required when solving from an xylist")
+		}
 
-	// Join directory with the new filename and extension for the FITS output file:
-	outputFile, err := os.Create(fmt.Sprintf("%s.wcs.fits", wcsOutputFileStem))
+		solution, err := solveFrame(params, frame{
+			Header: fits.NewFITSHeader(2, width, height),
+			Stars:  stars,
+			Width:  width,
+			Height: height,
+			ADU:    65535,
+		}, nil, nil)
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		fmt.Println("failed to create output file:", err)
-		return err
+		// Without pixel data, there is no FITS image to write the WCS solution into, so only the JSON is written:
+		return writeJSONSolution(solution.WCS, getFilePathStem(params.XYListFile))
 	}
 
-	// Attempt to write the buffer to the output file:
-	_, err = buf.WriteTo(outputFile)
+	// Read in all of the header and data units, e.g., the CCDs of a mosaic camera written as IMAGE extensions, where
+	// XISF, TIFF and PNG images are converted to a primary HDU with their metadata mapped onto FITS keywords:
+	hdus, err := imageio.ReadFile(params.InputFile)
 	if err != nil {
-		fmt.Println("failed to write to output file:", err)
-		return err
+		return fmt.Errorf("failed to read file: %v", err)
 	}
 
-	// Join directory with the new filename and extension for the JSON output file:
-	wcsOutputFile, err := os.Create(fmt.Sprintf("%s.wcs.json", wcsOutputFileStem))
+	// Every two dimensional plane of every image HDU, e.g., each frame of a data cube:
+	frames, err := fitsio.GetFrames(hdus)
 	if err != nil {
-		fmt.Println("failed to create output file:", err)
-		return err
+		return fmt.Errorf("failed to read file: %v", err)
 	}
 
-	// Defer closing the output file:
+	var query *catalogQuery
+
+	switch params.Frames {
+	case "", "independent":
+	case "shared":
+		// Stack the planes of each data cube into a single frame, solved once for every plane of the cube:
+		frames, err = fitsio.StackFramesByHDU(frames)
+		if err != nil {
+			return err
+		}
+
+		query, err = newCatalogQuery(params, frames)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown frames mode: %s", params.Frames)
+	}
+
+	solutions := []frameSolution{}
+
+	// The solution of the most recently solved frame, from which the tracking strategy predicts the next frame:
+	var previous *wcs.WCS
+
+	for _, f := range frames {
+		if len(frames) > 1 {
+			fmt.Printf("Frame: %s\n", f)
+		}
+
+		header := utils.NewFITSHeaderFromHeader(f.Header)
+
+		solution, err := solveFrame(params, frame{
+			Header: header,
+			Data:   f.Data,
+			Width:  int32(f.Width),
+			Height: int32(f.Height),
+			ADU:    utils.ExtractADUFromHeaders(header, f.Data),
+		}, query, previous)
+
+		if err != nil {
+			// A single frame that fails to solve, e.g., a CCD with too few stars, does not abort the other frames:
+			if len(frames) > 1 {
+				fmt.Printf("Error: %s: %v\n", f, err)
+				continue
+			}
+
+			return err
+		}
+
+		previous = solution.WCS
+
+		solutions = append(solutions, frameSolution{
+			HDU:         f.HDU,
+			Plane:       f.Plane,
+			Planes:      f.Planes,
+			Extname:     f.Name,
+			WCS:         solution.WCS,
+			Observation: getObservation(params, header, solution.WCS, f.Width, f.Height),
+		})
+	}
+
+	if len(solutions) == 0 {
+		return fmt.Errorf("no WCS solution found for any of the %d frames", len(frames))
+	}
+
+	// Get the filepath stem where the file is located (e.g. "./samples/astrometry" from
+	// "./samples/astrometry.fits"):
+	wcsOutputFileStem := getFilePathStem(params.InputFile)
+
+	// Attempt to write the WCS solutions into the headers of a copy of the FITS file:
+	outputFileName, err := writeFITSSolution(hdus, solutions, wcsOutputFileStem)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Solution written to: %s\n", outputFileName)
+
+	// A single image retains the single WCS solution of the JSON output:
+	if len(frames) == 1 {
+		return writeJSONSolution(solutions[0].WCS, wcsOutputFileStem)
+	}
+
+	return writeJSONSolution(solutions, wcsOutputFileStem)
+}
+
+/*****************************************************************************************************************/
+
+// solveFrame plate solves a single frame, returning the verified solution. When the catalog query is shared, the
+// catalog sources of the first frame are reused by every subsequent frame, and the previous solution, if any, is
+// the starting point of the tracking strategy.
+func solveFrame(params RunSolverParams, f frame, query *catalogQuery, previous *wcs.WCS) (*solve.Solution, error) {
+	width, height := f.Width, f.Height
+
+	// Attempt to get the RA and Dec from the user's input, the headers, or the target the frame is named after:
+	ra, dec, err := getPointing(params, f.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Right Ascension: %v°\n", ra)
+
+	fmt.Printf("Declination: %v°\n", dec)
+
+	fmt.Printf("Height: %v pixels\n", height)
+
+	fmt.Printf("Width: %v pixels\n", width)
+
+	// Resolve the pixel scale from the flags, the equipment profile, or the equipment headers, in that order:
+	scale, err := getPixelScale(params, f.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Pixel Scale X: %v\n", scale.X)
+
+	fmt.Printf("Pixel Scale Y: %v\n", scale.Y)
+
+	// Get our approximate radial extent for the field of view of the image (in degrees):
+	radius := fov.GetRadialExtent(float64(width), float64(height), scale)
+
+	fmt.Printf("Search Radius: %v°\n", radius)
+
+	var extraction *extract.Params
+
+	// Resolve the star extraction implementation:
+	switch params.Extractor {
+	case "", "iris":
+	case "native":
+		psf, err := extract.ParsePSFModel(params.PSF)
+		if err != nil {
+			return nil, err
+		}
+
+		extraction = &extract.Params{
+			Saturation: float64(f.ADU),
+			PSF:        psf,
+		}
+	default:
+		return nil, fmt.Errorf("unknown star extractor: %s", params.Extractor)
+	}
+
+	// Resolve the projection the solution is fitted with, where the default is the gnomonic (TAN) projection:
+	projection := wcs.RADEC_TAN
+
+	if params.Projection != "" {
+		projection, err = wcs.ParseProjection(params.Projection)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve the celestial frame the solution is reported in, where the default is the ICRS:
+	frame, err := astrometry.ParseCoordinateFrame(params.CoordinateFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	var debayer *bayer.Params
+
+	// Resolve the debayering of one-shot-colour images, which is only possible with the pixel data:
+	if len(f.Stars) == 0 {
+		debayer, err = getDebayerParams(params.Debayer, f.Header)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if debayer != nil {
+		fmt.Printf("Debayering: %s (%s)\n", debayer.Mode, debayer.Pattern)
+	}
+
+	var rejection *reject.Params
+
+	// Reject spurious stars only when requested, where a bad pixel mask on its own only rejects the masked stars:
+	switch {
+	case params.Reject:
+		criteria := params.Rejection
+
+		// Derive the saturation level from the DATAMAX or SATURATE headers, unless it has been provided:
+		if criteria.Saturation == 0 && len(f.Stars) == 0 {
+			criteria.Saturation = float64(utils.ExtractSaturationFromHeaders(f.Header))
+		}
+
+		rejection = &criteria
+	case params.BadPixelMaskLocation != "":
+		rejection = &reject.Params{}
+	}
+
+	if params.BadPixelMaskLocation != "" {
+		mask, err := reject.ReadMask(params.BadPixelMaskLocation, int(width), int(height))
+		if err != nil {
+			return nil, err
+		}
+
+		if mask.Width != int(width) || mask.Height != int(height) {
+			return nil, fmt.Errorf("bad pixel mask dimensions %dx%d do not match the image %dx%d", mask.Width, mask.Height, width, height)
+		}
+
+		rejection.Mask = mask
+	}
+
+	// Attempt to create a new PlateSolver:
+	solver, err := solve.NewPlateSolver(solve.Params{
+		Data:                f.Data,            // The exposure data of the frame
+		Stars:               f.Stars,           // The pre-extracted stars, if solving from an xylist
+		Extraction:          extraction,        // The native extraction parameters, if not using the iris extractor
+		Rejection:           rejection,         // The criteria for rejecting spurious stars, if requested
+		Bayer:               debayer,           // The debayering of one-shot-colour images, if any
+		Downsample:          params.Downsample, // The binning factor before star extraction (-1 is automatic)
+		Projection:          projection,        // The projection of the solution, e.g., ZEA for all-sky cameras
+		RA:                  float64(ra),       // The approximate right ascension of the center of the image
+		Dec:                 float64(dec),      // The approximate declination of the center of the image
+		HasHint:             true,              // The right ascension and declination are a pointing hint
+		Width:               int(width),        // The width of the image
+		Height:              int(height),       // The height of the image
+		PixelScaleX:         scale.X,           // The pixel scale in the x-axis
+		PixelScaleY:         scale.Y,           // The pixel scale in the y-axis
+		ADU:                 f.ADU,             // The analog-to-digital unit of the image
+		ExtractionThreshold: 16,                // Extract a minimum of 16 of the brightest stars
+		Radius:              16,                // 16 pixels radius for the star extraction
+		Sigma:               2.5,               // 8 pixels sigma for the Gaussian kernel
+	})
+	if err != nil {
+		fmt.Printf("there was an error while creating the plate solver: %v", err)
+		return nil, err
+	}
+
+	if rejected := solver.Rejections; rejected.Total() > 0 {
+		fmt.Printf(
+			"Rejected Stars: %d (edge: %d, masked: %d, saturated: %d, narrow: %d, sharp: %d)\n",
+			rejected.Total(), rejected.Edge, rejected.Masked, rejected.Saturated, rejected.Narrow, rejected.Sharp,
+		)
+	}
+
+	var sources []catalog.Source
+
+	if query != nil && query.Sources != nil {
+		// Reuse the catalog sources shared between the frames:
+		sources = query.Sources
+	} else {
+		limit := 100
+
+		// Whilst we have no matches, and whilst we are within 1 degree of the initial { ra, dec } guess, keep solving:
+		eq := astrometry.ICRSEquatorialCoordinate{
+			RA:  float64(ra),
+			Dec: float64(dec),
+		}
+
+		// A shared query searches an area covering all of the frames, e.g., every CCD of a mosaic camera:
+		if query != nil {
+			eq, radius = query.Center, query.Radius
+			limit *= query.Frames
+
+			fmt.Printf("Shared Search: %v°, %v° (radius %v°)\n", eq.RA, eq.Dec, radius)
+		}
+
+		// Create a new SIMBAD service client:
+		service := catalog.NewCatalogService(catalog.GAIA, catalog.Params{
+			Limit:     limit, // Limit the number of records to 100 (per frame)
+			Threshold: 16,    // Limiting Magntiude, filter out any stars that are magnitude 16 or above (fainter)
+		})
+
+		// Perform a radial search with the given center and radius, for all sources with a magnitude less than 10:
+		sources, err = service.PerformRadialSearch(eq, radius)
+		if err != nil {
+			fmt.Printf("there was an error while performing the SIMBAD radial search: %v", err)
+			return nil, err
+		}
+
+		if query != nil {
+			query.Sources = sources
+		}
+	}
+
+	var refractor *astrometry.Refractor
+
+	// Fit against the observed places of the catalog sources, such that the distortion of the solution is optical:
+	if params.Refraction {
+		refractor, err = getRefractor(params, f.Header)
+		if err != nil {
+			return nil, err
+		}
+
+		eq := astrometry.ICRSEquatorialCoordinate{RA: float64(ra), Dec: float64(dec)}
+
+		fmt.Printf("Refraction: %.4f' at an altitude of %.4f°\n", refractor.GetRefraction(eq)*60, refractor.GetAltitude(eq))
+
+		sources = solve.RefractSources(sources, refractor)
+	}
+
+	// Append the sources to the solver:
+	solver.Sources = append(solver.Sources, sources...)
+
+	// Define the tolerances for the solver, we can adjust these as needed:
+	tolerance := solve.ToleranceParams{
+		QuadTolerance:           params.QuadTolerance,
+		EuclidianPixelTolerance: params.EuclidianceDistanceTolerance,
+	}
+
+	// Resolve the ordered fallback chain of solver strategies:
+	strategies, err := solve.GetStrategiesByName(params.Strategies, solve.StrategyOptions{
+		Index:    solver.Sources,
+		Previous: previous,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Attempt each strategy in turn until one produces a verified solution:
+	solution, err := solver.SolveWithStrategies(strategies, tolerance, 3, solve.VerificationParams{
+		MinimumMatches:    solve.DefaultVerificationParams.MinimumMatches,
+		MinimumMatchRatio: solve.DefaultVerificationParams.MinimumMatchRatio,
+		PixelTolerance:    params.EuclidianceDistanceTolerance,
+	})
+	if err != nil {
+		fmt.Println("an error occured while plate solving:", err)
+		return nil, err
+	}
+
+	// Refit the solution, fitted against the observed places, to the astrometric places across the image:
+	if refractor != nil {
+		if err := solve.RemoveRefraction(solution.WCS, refractor, solver.Width, solver.Height); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record the time of the observation alongside the solution, e.g., for the epoch of the coordinates:
+	setObservationDate(solution.WCS, f.Header)
+
+	wcs := solution.WCS
+
+	if wcs == nil {
+		fmt.Println("no WCS solution found")
+		return nil, fmt.Errorf("no WCS solution found")
+	}
+
+	fmt.Printf("Strategy: %s (%d of %d stars verified, RMS %.3f pixels)\n", solution.Strategy, solution.Verification.Matched, solution.Verification.Total, solution.Verification.RMS)
+
+	// Print fields from wcaxes through cd2_2
+	fmt.Printf("WCAXES: %d\n", wcs.WCAXES)
+	fmt.Printf("CRPIX1: %.6f\n", wcs.CRPIX1)
+	fmt.Printf("CRPIX2: %.6f\n", wcs.CRPIX2)
+	fmt.Printf("CRVAL1: %.6f\n", wcs.CRVAL1)
+	fmt.Printf("CRVAL2: %.6f\n", wcs.CRVAL2)
+	fmt.Printf("CTYPE1: %s\n", wcs.CTYPE1)
+	fmt.Printf("CTYPE2: %s\n", wcs.CTYPE2)
+	fmt.Printf("CDELT1: %.6f\n", wcs.CDELT1)
+	fmt.Printf("CDELT2: %.6f\n", wcs.CDELT2)
+	fmt.Printf("CUNIT1: %s\n", wcs.CUNIT1)
+	fmt.Printf("CUNIT2: %s\n", wcs.CUNIT2)
+	fmt.Printf("CD1_1:  %.6f\n", wcs.CD1_1)
+	fmt.Printf("CD1_2:  %.6f\n", wcs.CD1_2)
+	fmt.Printf("CD2_1:  %.6f\n", wcs.CD2_1)
+	fmt.Printf("CD2_2:  %.6f\n", wcs.CD2_2)
+
+	printFootprint(wcs, frame, int(width), int(height))
+
+	printApparentCentre(params, f.Header, wcs, int(width), int(height))
+
+	return solution, nil
+}
+
+/*****************************************************************************************************************/
+
+// getPointing resolves the right ascension and declination hints, in degrees, from the user's input or the headers,
+// falling back, for a hint that is neither given nor in the headers, to the target named by the OBJECT header or by
+// the filename, e.g., "M42_Ha_300s.fits".
+func getPointing(params RunSolverParams, header fits.FITSHeader) (float32, float32, error) {
+	ra, raErr := utils.ResolveOrExtractRAFromHeaders(params.RA, header)
+
+	dec, decErr := utils.ResolveOrExtractDecFromHeaders(params.Dec, header)
+
+	// Only a hint that is neither given by the user nor in the headers falls back to that of the target:
+	missingRA := raErr != nil && math.IsNaN(float64(params.RA))
+
+	missingDec := decErr != nil && math.IsNaN(float64(params.Dec))
+
+	if missingRA || missingDec {
+		if target, err := findTarget(params, header); err == nil {
+			fmt.Printf("Target: %s (%s)\n", target.Name, target.Source)
+
+			if missingRA {
+				ra, raErr = float32(target.RA), nil
+			}
+
+			if missingDec {
+				dec, decErr = float32(target.Dec), nil
+			}
+		}
+	}
+
+	if raErr != nil {
+		return ra, dec, fmt.Errorf("failed to resolve or extract RA from headers: %v", raErr)
+	}
+
+	if decErr != nil {
+		return ra, dec, fmt.Errorf("failed to resolve or extract Dec from headers: %v", decErr)
+	}
+
+	return ra, dec, nil
+}
+
+/*****************************************************************************************************************/
+
+// findTarget finds the target named by the OBJECT header, or otherwise by the filename of the input.
+func findTarget(params RunSolverParams, header fits.FITSHeader) (catalog.Target, error) {
+	resolver := catalog.NewTargetResolver(catalog.TargetResolverParams{SIMBAD: params.TargetSIMBAD})
+
+	if object, exists := header.Strings["OBJECT"]; exists && strings.TrimSpace(object.Value) != "" {
+		if target, err := resolver.Find(object.Value); err == nil {
+			return target, nil
+		}
+	}
+
+	file := params.InputFile
+	if file == nil {
+		file = params.XYListFile
+	}
+
+	if file == nil {
+		return catalog.Target{}, fmt.Errorf("no target named by the OBJECT header or filename")
+	}
+
+	// The filename is only matched against the bundled catalog, rather than sent to SIMBAD as a whole:
+	return catalog.NewTargetResolver(catalog.TargetResolverParams{}).Find(filepath.Base(getFilePathStem(file)))
+}
+
+/*****************************************************************************************************************/
+
+// getPixelScale resolves the pixel scale (in degrees) of the frame from the pixel scale flags, or otherwise from the
+// equipment profile at the XBINNING and YBINNING of the frame, or otherwise from the FOCALLEN and XPIXSZ headers.
+func getPixelScale(params RunSolverParams, header fits.FITSHeader) (fov.PixelScale, error) {
+	x, y := params.PixelScaleX, params.PixelScaleY
+
+	if !math.IsInf(x, -1) || !math.IsInf(y, -1) {
+		// Validate the pixel scale in the x-axis:
+		if math.IsInf(x, -1) {
+			return fov.PixelScale{}, fmt.Errorf("pixel scale x is required")
+		}
+
+		if x == 0 {
+			return fov.PixelScale{}, fmt.Errorf("pixel scale x must be non-zero")
+		}
+
+		// Validate the pixel scale in the y-axis:
+		if math.IsInf(y, -1) {
+			return fov.PixelScale{}, fmt.Errorf("pixel scale y is required")
+		}
+
+		if y == 0 {
+			return fov.PixelScale{}, fmt.Errorf("pixel scale y must be non-zero")
+		}
+
+		return fov.PixelScale{X: math.Abs(x), Y: math.Abs(y)}, nil
+	}
+
+	if params.Profile != nil {
+		binningX, binningY := utils.ExtractBinningFromHeaders(header)
+
+		fmt.Printf("Profile: %s (%dx%d binning)\n", params.Profile.Name, binningX, binningY)
+
+		return params.Profile.GetPixelScale(binningX, binningY)
+	}
+
+	equipment, err := utils.ExtractEquipmentFromHeaders(header)
+	if err != nil {
+		return fov.PixelScale{}, fmt.Errorf("the pixel scale, an equipment profile, or the equipment headers are required: %v", err)
+	}
+
+	fmt.Printf("Focal Length: %v mm, Pixel Size: %v µm\n", equipment.FocalLength, equipment.PixelSizeX)
+
+	return fov.NewPixelScaleFromEquipment(equipment)
+}
+
+/*****************************************************************************************************************/
+
+// The names of the longitude and latitude of each celestial coordinate frame:
+var frameCoordinateNames = map[astrometry.CoordinateFrame][2]string{
+	astrometry.FRAME_ICRS:     {"RA", "Dec"},
+	astrometry.FRAME_GALACTIC: {"l", "b"},
+	astrometry.FRAME_ECLIPTIC: {"λ", "β"},
+}
+
+/*****************************************************************************************************************/
+
+// printFootprint reports the centre and the corners of the solution in the celestial coordinate frame.
+func printFootprint(w *wcs.WCS, frame astrometry.CoordinateFrame, width, height int) {
+	names := frameCoordinateNames[frame]
+
+	centre := w.PixelToEquatorialCoordinate(float64(width)/2, float64(height)/2)
+
+	lon, lat := centre.ToFrame(frame)
+
+	fmt.Printf("Centre (%s): %s %.6f°, %s %.6f°\n", frame, names[0], lon, names[1], lat)
+
+	for i, corner := range w.GetCorners(width, height) {
+		lon, lat := corner.ToFrame(frame)
+
+		fmt.Printf("Corner %d (%s): %s %.6f°, %s %.6f°\n", i+1, frame, names[0], lon, names[1], lat)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// printApparentCentre reports the centre of the solution at J2000 (ICRS), and at its apparent place of the time of the
+// observation (JNow), i.e., as used to sync the mount, where the apparent place is unavailable for a frame without a
+// DATE-OBS header or the date flag, rather than assuming the frame was observed now.
+func printApparentCentre(params RunSolverParams, header fits.FITSHeader, w *wcs.WCS, width, height int) {
+	centre := w.PixelToEquatorialCoordinate(float64(width)/2, float64(height)/2)
+
+	fmt.Printf("Centre (J2000): RA %.6f°, Dec %.6f°\n", centre.RA, centre.Dec)
+
+	datetime, err := getObservationTime(params, header)
+	if err != nil {
+		fmt.Printf("Centre (JNow): unavailable: %v (set the DATE-OBS header or --date-obs)\n", err)
+		return
+	}
+
+	apparent := centre.ToApparent(astrometry.ApparentParams{Datetime: datetime, Deflection: true})
+
+	fmt.Printf("Centre (JNow): RA %.6f°, Dec %.6f° (%s)\n", apparent.RA, apparent.Dec, datetime.Format(time.RFC3339))
+}
+
+/*****************************************************************************************************************/
+
+// getObservation reports the altitude, azimuth, hour angle, airmass and parallactic angle of the centre of the
+// solution, as seen from the observing site at the middle of the exposure, returning nil when neither the flags nor
+// the headers of the frame record the site or the time of the observation.
+func getObservation(params RunSolverParams, header fits.FITSHeader, w *wcs.WCS, width, height int) *astrometry.Observation {
+	observer, err := getObserver(params, header)
+	if err != nil {
+		fmt.Printf("Observation: unavailable: %v\n", err)
+		return nil
+	}
+
+	datetime, err := getObservationTime(params, header)
+	if err != nil {
+		fmt.Printf("Observation: unavailable: %v\n", err)
+		return nil
+	}
+
+	centre := w.PixelToEquatorialCoordinate(float64(width)/2, float64(height)/2)
+
+	o := centre.Observe(observer, datetime)
+
+	fmt.Printf("Observer: Latitude %.6f°, Longitude %.6f°, Elevation %.1f m\n", observer.Latitude, observer.Longitude, observer.Elevation)
+	fmt.Printf("Observed: %s\n", o.Datetime.Format(time.RFC3339Nano))
+	fmt.Printf("Altitude: %.4f°\n", o.Altitude)
+	fmt.Printf("Azimuth: %.4f°\n", o.Azimuth)
+	fmt.Printf("Hour Angle: %.4f° (%.4fh)\n", o.HourAngle, o.HourAngle/15)
+	fmt.Printf("Airmass: %.4f\n", o.Airmass)
+	fmt.Printf("Parallactic Angle: %.4f°\n", o.ParallacticAngle)
+
+	return &o
+}
+
+/*****************************************************************************************************************/
+
+// getObserver resolves the observing site from the SITELAT, SITELONG and SITEELEV headers of the frame, where the
+// site flags take precedence over the headers.
+func getObserver(params RunSolverParams, header fits.FITSHeader) (astrometry.Observer, error) {
+	observer, err := utils.ExtractObserverFromHeaders(header)
+
+	// The headers are only required for the coordinates of the site which the flags do not provide:
+	if err != nil && (math.IsNaN(params.SiteLatitude) || math.IsNaN(params.SiteLongitude)) {
+		return astrometry.Observer{}, err
+	}
+
+	if !math.IsNaN(params.SiteLatitude) {
+		if params.SiteLatitude < -90 || params.SiteLatitude > 90 {
+			return astrometry.Observer{}, fmt.Errorf("site latitude value is out of range: %v", params.SiteLatitude)
+		}
+
+		observer.Latitude = params.SiteLatitude
+	}
+
+	if !math.IsNaN(params.SiteLongitude) {
+		observer.Longitude = math.Mod(params.SiteLongitude+540, 360) - 180
+	}
+
+	if !math.IsNaN(params.SiteElevation) {
+		observer.Elevation = params.SiteElevation
+	}
+
+	return observer, nil
+}
+
+/*****************************************************************************************************************/
+
+// getObservationTime resolves the (UTC) time of the middle of the exposure from the DATE-OBS and EXPTIME headers of
+// the frame, where the date flag takes precedence over the headers.
+func getObservationTime(params RunSolverParams, header fits.FITSHeader) (time.Time, error) {
+	if params.DateObs != "" {
+		return wcs.ParseDateObs(params.DateObs)
+	}
+
+	return utils.ExtractObservationTimeFromHeaders(header)
+}
+
+/*****************************************************************************************************************/
+
+// getRefractor resolves the atmospheric refraction of the frame from its observing site, time, temperature and
+// pressure, where the flags take precedence over the headers, the temperature defaults to 10°C, and the pressure
+// defaults to the standard pressure at the elevation of the site.
+func getRefractor(params RunSolverParams, header fits.FITSHeader) (*astrometry.Refractor, error) {
+	observer, err := getObserver(params, header)
+	if err != nil {
+		return nil, fmt.Errorf("refraction requires the observing site: %v", err)
+	}
+
+	datetime, err := getObservationTime(params, header)
+	if err != nil {
+		return nil, fmt.Errorf("refraction requires the time of the observation: %v", err)
+	}
+
+	temperature, pressure, err := utils.ExtractAtmosphereFromHeaders(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if !math.IsNaN(params.Temperature) {
+		temperature = params.Temperature
+	}
+
+	if math.IsNaN(temperature) {
+		temperature = astrometry.STANDARD_TEMPERATURE
+	}
+
+	if !math.IsNaN(params.Pressure) {
+		pressure = params.Pressure
+	}
+
+	// A zero pressure is the standard pressure at the elevation of the site:
+	if math.IsNaN(pressure) {
+		pressure = 0
+	}
+
+	return astrometry.NewRefractor(astrometry.RefractionParams{
+		Observer:    observer,
+		Datetime:    datetime,
+		Temperature: temperature,
+		Pressure:    pressure,
+	})
+}
+
+/*****************************************************************************************************************/
+
+// writeJSONSolution writes the WCS solution, or the solutions of every frame, to the "<stem>.wcs.json" output file.
+func writeJSONSolution(solution interface{}, stem string) error {
+	// Join directory with the new filename and extension for the JSON output file:
+	wcsOutputFile, err := os.Create(fmt.Sprintf("%s.wcs.json", stem))
+	if err != nil {
+		fmt.Println("failed to create output file:", err)
+		return err
+	}
+
+	// Defer closing the output file:
 	defer wcsOutputFile.Close()
 
 	// Attempt to write the WCS solution to the output file:
 	encoder := json.NewEncoder(wcsOutputFile)
 	// Set the indentation for the JSON encoder:
 	encoder.SetIndent("", "\t")
-	if err := encoder.Encode(wcs); err != nil {
+	if err := encoder.Encode(solution); err != nil {
 		// log.Fatalf("failed to encode WCS solution to JSON: %v", err)
 		return err
 	}
 
-	fmt.Printf("Solution written to: %s\n", outputFile.Name())
+	fmt.Printf("Solution written to: %s\n", wcsOutputFile.Name())
 
-	// Return nil if the solver ran successfully:
 	return nil
 }
 
 /*****************************************************************************************************************/
+
+// setObservationDate sets the DATE-OBS and MJD-OBS of the WCS from the DATE-OBS header of the frame, if any.
+func setObservationDate(w *wcs.WCS, header fits.FITSHeader) {
+	if w == nil {
+		return
+	}
+
+	date, ok := header.Strings["DATE-OBS"]
+	if !ok || strings.TrimSpace(date.Value) == "" {
+		return
+	}
+
+	w.DATEOBS = strings.TrimSpace(date.Value)
+
+	// The modified Julian date is derived from DATE-OBS, as the (single precision) MJD-OBS header is too coarse:
+	if t, err := wcs.ParseDateObs(w.DATEOBS); err == nil {
+		w.MJDOBS = wcs.GetModifiedJulianDate(t)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// setWCSHeaders writes the full set of standard WCS keywords of the solution into the header, including the SIP
+// distortion polynomials, removing the keywords of any existing WCS, e.g., a PC matrix or stale SIP terms.
+func setWCSHeaders(header *fitsio.Header, w *wcs.WCS) {
+	w.UpdateFITSHeader(header)
+}
+
+/*****************************************************************************************************************/
+
+// writeFITSSolution writes the WCS solutions into the headers of their image HDUs, and writes every HDU of the
+// file to the "<stem>.wcs.fits" output file, returning the name of the output file. The planes of a data cube share
+// the header of their HDU, which receives the solution of the first solved plane. The WCS of a tile-compressed
+// image is written into the header of its binary table, leaving the compressed pixel data untouched, and the
+// output file is named "<stem>.wcs.fits.fz".
+func writeFITSSolution(hdus []*fitsio.HDU, solutions []frameSolution, stem string) (string, error) {
+	written := map[int]bool{}
+
+	extension := "fits"
+
+	for _, solution := range solutions {
+		if written[solution.HDU] {
+			continue
+		}
+
+		// Attempt to write the WCS solution to the header of the HDU:
+		setWCSHeaders(hdus[solution.HDU].Header, solution.WCS)
+
+		if fitsio.IsCompressedImageHDU(hdus[solution.HDU]) {
+			extension = "fits.fz"
+		}
+
+		written[solution.HDU] = true
+	}
+
+	// Join directory with the new filename and extension for the FITS output file:
+	outputFile, err := os.Create(fmt.Sprintf("%s.wcs.%s", stem, extension))
+	if err != nil {
+		fmt.Println("failed to create output file:", err)
+		return "", err
+	}
+
+	// Defer closing the output file:
+	defer outputFile.Close()
+
+	// Attempt to write the header and data units to the output file:
+	if err := fitsio.WriteHDUs(outputFile, hdus); err != nil {
+		fmt.Println("failed to write to output file:", err)
+		return "", err
+	}
+
+	return outputFile.Name(), nil
+}
+
+/*****************************************************************************************************************/
+
+// getDebayerParams resolves the debayering of the image from the requested mode and the BAYERPAT, XBAYROFF and
+// YBAYROFF headers, returning nil for monochrome images or when debayering is disabled.
+func getDebayerParams(mode string, header fits.FITSHeader) (*bayer.Params, error) {
+	if mode == "none" {
+		return nil, nil
+	}
+
+	name, xoffset, yoffset, exists := utils.ExtractBayerPatternFromHeaders(header)
+
+	if !exists {
+		// Automatic debayering only applies to images that declare a Bayer pattern:
+		if mode == "" || mode == "auto" {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("debayering requires the BAYERPAT header, which is missing from the image")
+	}
+
+	if mode == "auto" {
+		mode = "luminance"
+	}
+
+	m, err := bayer.ParseMode(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern, err := bayer.ParsePattern(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bayer.Params{
+		Pattern: pattern.Offset(int(xoffset), int(yoffset)),
+		Mode:    m,
+	}, nil
+}
+
+/*****************************************************************************************************************/
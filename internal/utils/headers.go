@@ -13,23 +13,33 @@ package utils
 import (
 	"fmt"
 	"math"
+	"strings"
+	"time"
 
 	"github.com/observerly/iris/pkg/fits"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/fitsio"
+	"github.com/observerly/skysolve/pkg/fov"
+	"github.com/observerly/skysolve/pkg/wcs"
 )
 
 /*****************************************************************************************************************/
 
+// ResolveOrExtractRAFromHeaders returns the right ascension hint, in degrees, from the value, or otherwise from the
+// RA header (in degrees, unless its comment or the OBJCTRA header show it to be in hours), the sexagesimal OBJCTRA
+// header (in hours), or the CRVAL1 of an existing equatorial WCS, in that order.
 func ResolveOrExtractRAFromHeaders(value float32, header fits.FITSHeader) (float32, error) {
 	// First, pick a candidate RA (v):
 	v := value
 
-	// If the candidate RA (v) is NaN, try to get it from the header:
+	// If the candidate RA (v) is NaN, try to get it from the headers:
 	if math.IsNaN(float64(v)) {
-		ra, exists := header.Floats["RA"]
-		if !exists {
-			return float32(math.NaN()), fmt.Errorf("ra header not found in the supplied FITS file")
+		ra, err := extractRAFromHeaders(header)
+		if err != nil {
+			return float32(math.NaN()), err
 		}
-		v = ra.Value
+		v = float32(ra)
 	}
 
 	// Validate the candidate RA (v) is a valid float32:
@@ -48,17 +58,19 @@ func ResolveOrExtractRAFromHeaders(value float32, header fits.FITSHeader) (float
 
 /*****************************************************************************************************************/
 
+// ResolveOrExtractDecFromHeaders returns the declination hint, in degrees, from the value, or otherwise from the DEC
+// header, the sexagesimal OBJCTDEC header, or the CRVAL2 of an existing equatorial WCS, in that order.
 func ResolveOrExtractDecFromHeaders(value float32, header fits.FITSHeader) (float32, error) {
 	// First, pick a candidate Dec (v):
 	v := value
 
-	// If the candidate Dec (v) is NaN, try to get it from the header:
+	// If the candidate Dec (v) is NaN, try to get it from the headers:
 	if math.IsNaN(float64(v)) {
-		dec, exists := header.Floats["DEC"]
-		if !exists {
-			return float32(math.NaN()), fmt.Errorf("dec header not found in the supplied FITS file")
+		dec, err := extractDecFromHeaders(header)
+		if err != nil {
+			return float32(math.NaN()), err
 		}
-		v = dec.Value
+		v = float32(dec)
 	}
 
 	// Validate the candidate Dec (v) is a valid float32:
@@ -77,6 +89,147 @@ func ResolveOrExtractDecFromHeaders(value float32, header fits.FITSHeader) (floa
 
 /*****************************************************************************************************************/
 
+// ParseRAFlag parses the right ascension flag, in decimal degrees, sexagesimal hours, or marked hours or degrees, e.g.,
+// "83.82", "05 35 17.3" or "5.588h", where an empty flag is NaN, i.e., to be extracted from the headers.
+func ParseRAFlag(value string) (float32, error) {
+	if strings.TrimSpace(value) == "" {
+		return float32(math.NaN()), nil
+	}
+
+	ra, err := astrometry.ParseRightAscension(value)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+
+	return float32(ra), nil
+}
+
+/*****************************************************************************************************************/
+
+// ParseDecFlag parses the declination flag, in decimal or sexagesimal degrees, e.g., "-5.391" or "-05:23:28", where
+// an empty flag is NaN, i.e., to be extracted from the headers.
+func ParseDecFlag(value string) (float32, error) {
+	if strings.TrimSpace(value) == "" {
+		return float32(math.NaN()), nil
+	}
+
+	dec, err := astrometry.ParseDeclination(value)
+	if err != nil {
+		return float32(math.NaN()), err
+	}
+
+	return float32(dec), nil
+}
+
+/*****************************************************************************************************************/
+
+// extractRAFromHeaders returns the right ascension, in degrees, from the RA, OBJCTRA or CRVAL1 headers.
+func extractRAFromHeaders(header fits.FITSHeader) (float64, error) {
+	// The right ascension of the OBJCTRA header, which is in hours unless marked in degrees:
+	objctra := math.NaN()
+
+	if v, exists := header.Strings["OBJCTRA"]; exists && strings.TrimSpace(v.Value) != "" {
+		ra, err := parseObjectRA(v.Value)
+		if err != nil {
+			return math.NaN(), fmt.Errorf("objctra value is invalid: %v", err)
+		}
+		objctra = ra
+	}
+
+	if ra, exists := header.Floats["RA"]; exists {
+		v := float64(ra.Value)
+
+		// A right ascension in hours is identified by its comment, or by its agreement with the OBJCTRA header:
+		comment := strings.ToLower(ra.Comment)
+
+		hours := strings.Contains(comment, "hour") || strings.Contains(comment, "[h]")
+
+		if !math.IsNaN(objctra) && v >= 0 && v <= 24 && math.Abs(v*15-objctra) < math.Abs(v-objctra) {
+			hours = true
+		}
+
+		if hours {
+			v *= 15
+		}
+
+		return v, nil
+	}
+
+	if ra, exists := header.Ints["RA"]; exists {
+		return float64(ra.Value), nil
+	}
+
+	if ra, exists := header.Strings["RA"]; exists && strings.TrimSpace(ra.Value) != "" {
+		return astrometry.ParseRightAscension(ra.Value)
+	}
+
+	if !math.IsNaN(objctra) {
+		return objctra, nil
+	}
+
+	// The reference point of an existing equatorial WCS:
+	if ctype, exists := header.Strings["CTYPE1"]; exists && strings.HasPrefix(strings.TrimSpace(ctype.Value), "RA--") {
+		if crval, exists := header.Floats["CRVAL1"]; exists {
+			return float64(crval.Value), nil
+		}
+	}
+
+	return math.NaN(), fmt.Errorf("ra header not found in the supplied FITS file")
+}
+
+/*****************************************************************************************************************/
+
+// extractDecFromHeaders returns the declination, in degrees, from the DEC, OBJCTDEC or CRVAL2 headers.
+func extractDecFromHeaders(header fits.FITSHeader) (float64, error) {
+	if dec, exists := header.Floats["DEC"]; exists {
+		return float64(dec.Value), nil
+	}
+
+	if dec, exists := header.Ints["DEC"]; exists {
+		return float64(dec.Value), nil
+	}
+
+	for _, key := range []string{"DEC", "OBJCTDEC"} {
+		if dec, exists := header.Strings[key]; exists && strings.TrimSpace(dec.Value) != "" {
+			v, err := astrometry.ParseDeclination(dec.Value)
+			if err != nil {
+				return math.NaN(), fmt.Errorf("%s value is invalid: %v", strings.ToLower(key), err)
+			}
+			return v, nil
+		}
+	}
+
+	// The reference point of an existing equatorial WCS:
+	if ctype, exists := header.Strings["CTYPE2"]; exists && strings.HasPrefix(strings.TrimSpace(ctype.Value), "DEC-") {
+		if crval, exists := header.Floats["CRVAL2"]; exists {
+			return float64(crval.Value), nil
+		}
+	}
+
+	return math.NaN(), fmt.Errorf("dec header not found in the supplied FITS file")
+}
+
+/*****************************************************************************************************************/
+
+// parseObjectRA parses the right ascension of the OBJCTRA header, in degrees, which is in (decimal or sexagesimal)
+// hours unless it is marked in degrees.
+func parseObjectRA(value string) (float64, error) {
+	s := strings.ToLower(value)
+
+	if strings.ContainsAny(s, "d°") {
+		return astrometry.ParseRightAscension(value)
+	}
+
+	hours, err := astrometry.ParseSexagesimal(strings.TrimSuffix(strings.TrimSpace(s), "h"))
+	if err != nil || hours < 0 || hours > 24 {
+		return math.NaN(), fmt.Errorf("invalid right ascension: %q", value)
+	}
+
+	return hours * 15, nil
+}
+
+/*****************************************************************************************************************/
+
 func ExtractImageWidthFromHeaders(header fits.FITSHeader) (int32, error) {
 	// Attempt to get the width header from the FITS file:
 	width, exists := header.Ints["NAXIS1"]
@@ -112,3 +265,312 @@ func ExtractImageHeightFromHeaders(header fits.FITSHeader) (int32, error) {
 }
 
 /*****************************************************************************************************************/
+
+// ExtractSaturationFromHeaders returns the saturation level of the image from the DATAMAX or SATURATE headers, or
+// otherwise zero, so that saturated stars are not rejected against the peak of the data itself.
+func ExtractSaturationFromHeaders(header fits.FITSHeader) float32 {
+	for _, key := range []string{"DATAMAX", "SATURATE"} {
+		if v, exists := header.Floats[key]; exists && v.Value > 0 && !math.IsNaN(float64(v.Value)) {
+			return v.Value
+		}
+
+		if v, exists := header.Ints[key]; exists && v.Value > 0 {
+			return float32(v.Value)
+		}
+	}
+
+	return 0
+}
+
+/*****************************************************************************************************************/
+
+// ExtractBayerPatternFromHeaders returns the Bayer pattern of a one-shot-colour image from the BAYERPAT header,
+// along with the XBAYROFF and YBAYROFF offsets of the pattern (which default to zero), if the pattern exists.
+func ExtractBayerPatternFromHeaders(header fits.FITSHeader) (pattern string, xoffset int32, yoffset int32, exists bool) {
+	bayerpat, exists := header.Strings["BAYERPAT"]
+	if !exists || strings.TrimSpace(bayerpat.Value) == "" {
+		return "", 0, 0, false
+	}
+
+	if v, ok := header.Ints["XBAYROFF"]; ok {
+		xoffset = v.Value
+	}
+
+	if v, ok := header.Ints["YBAYROFF"]; ok {
+		yoffset = v.Value
+	}
+
+	return strings.TrimSpace(bayerpat.Value), xoffset, yoffset, true
+}
+
+/*****************************************************************************************************************/
+
+// NewFITSHeaderFromHeader converts an ordered, full precision, fitsio header into the iris FITSHeader consumed by the
+// header utilities, e.g., for the headers of FITS extensions, which the iris FITS image does not read.
+func NewFITSHeaderFromHeader(header *fitsio.Header) fits.FITSHeader {
+	naxis, _ := header.Int("NAXIS")
+	naxis1, _ := header.Int("NAXIS1")
+	naxis2, _ := header.Int("NAXIS2")
+
+	h := fits.FITSHeader{
+		Naxis:    int32(naxis),
+		Naxis1:   int32(naxis1),
+		Naxis2:   int32(naxis2),
+		Bools:    make(map[string]fits.FITSHeaderBool),
+		Ints:     make(map[string]fits.FITSHeaderInt),
+		Floats:   make(map[string]fits.FITSHeaderFloat),
+		Strings:  make(map[string]fits.FITSHeaderString),
+		Dates:    make(map[string]fits.FITSHeaderString),
+		Comments: make([]string, 0),
+		History:  make([]string, 0),
+		End:      true,
+	}
+
+	if bitpix, ok := header.Int("BITPIX"); ok {
+		h.Bitpix = int32(bitpix)
+	}
+
+	seen := map[string]bool{}
+
+	for _, card := range header.Cards {
+		// The first card of a given key takes precedence, as for the fitsio header itself:
+		if card.Value == nil || seen[card.Key] {
+			continue
+		}
+
+		seen[card.Key] = true
+
+		switch v := card.Value.(type) {
+		case bool:
+			h.Bools[card.Key] = fits.FITSHeaderBool{Value: v, Comment: card.Comment}
+		case int64:
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				h.Floats[card.Key] = fits.FITSHeaderFloat{Value: float32(v), Comment: card.Comment}
+				continue
+			}
+
+			h.Ints[card.Key] = fits.FITSHeaderInt{Value: int32(v), Comment: card.Comment}
+		case float64:
+			h.Floats[card.Key] = fits.FITSHeaderFloat{Value: float32(v), Comment: card.Comment}
+		case string:
+			h.Strings[card.Key] = fits.FITSHeaderString{Value: v, Comment: card.Comment}
+		}
+	}
+
+	return h
+}
+
+/*****************************************************************************************************************/
+
+// ExtractADUFromHeaders returns the maximum ADU of the image from the ADU header, or otherwise from the integer
+// BITPIX of the raw data, or, for floating point data, the maximum value of the image itself.
+func ExtractADUFromHeaders(header fits.FITSHeader, data []float32) int32 {
+	if adu, exists := header.Ints["ADU"]; exists && adu.Value > 0 {
+		return adu.Value
+	}
+
+	if header.Bitpix > 0 && header.Bitpix < 32 {
+		return int32(1)<<header.Bitpix - 1
+	}
+
+	if header.Bitpix >= 32 {
+		return math.MaxInt32
+	}
+
+	peak := float32(0)
+
+	for _, v := range data {
+		if v > peak && !math.IsInf(float64(v), 0) {
+			peak = v
+		}
+	}
+
+	return int32(math.Min(math.Ceil(float64(peak)), math.MaxInt32))
+}
+
+/*****************************************************************************************************************/
+
+// ExtractObserverFromHeaders returns the observing site from the SITELAT, SITELONG and SITEELEV headers, or their
+// LAT-OBS, LONG-OBS and ALT-OBS or OBSGEO-B, OBSGEO-L and OBSGEO-H equivalents, where the latitude and (east
+// positive) longitude are decimal or sexagesimal degrees, and a missing elevation is taken to be sea level.
+func ExtractObserverFromHeaders(header fits.FITSHeader) (astrometry.Observer, error) {
+	latitude, exists, err := extractFloatFromHeaders(header, "SITELAT", "LAT-OBS", "OBSGEO-B")
+	if err != nil {
+		return astrometry.Observer{}, err
+	}
+
+	if !exists {
+		return astrometry.Observer{}, fmt.Errorf("site latitude header not found in the supplied FITS file")
+	}
+
+	// Validate the latitude is within the range [-90, 90]:
+	if latitude < -90 || latitude > 90 {
+		return astrometry.Observer{}, fmt.Errorf("site latitude value is out of range: %v", latitude)
+	}
+
+	longitude, exists, err := extractFloatFromHeaders(header, "SITELONG", "LONG-OBS", "OBSGEO-L")
+	if err != nil {
+		return astrometry.Observer{}, err
+	}
+
+	if !exists {
+		return astrometry.Observer{}, fmt.Errorf("site longitude header not found in the supplied FITS file")
+	}
+
+	// Validate the longitude is within the range [-180, 360], and wrap it into the range (-180, 180]:
+	if longitude < -180 || longitude > 360 {
+		return astrometry.Observer{}, fmt.Errorf("site longitude value is out of range: %v", longitude)
+	}
+
+	if longitude > 180 {
+		longitude -= 360
+	}
+
+	elevation, _, err := extractFloatFromHeaders(header, "SITEELEV", "ALT-OBS", "OBSGEO-H")
+	if err != nil {
+		return astrometry.Observer{}, err
+	}
+
+	return astrometry.Observer{Latitude: latitude, Longitude: longitude, Elevation: elevation}, nil
+}
+
+/*****************************************************************************************************************/
+
+// ExtractObservationTimeFromHeaders returns the (UTC) time of the middle of the exposure, from the DATE-OBS header of
+// the start of the exposure plus half of the EXPTIME (or EXPOSURE) header, if any.
+func ExtractObservationTimeFromHeaders(header fits.FITSHeader) (time.Time, error) {
+	date, exists := header.Strings["DATE-OBS"]
+	if !exists || strings.TrimSpace(date.Value) == "" {
+		return time.Time{}, fmt.Errorf("date-obs header not found in the supplied FITS file")
+	}
+
+	t, err := wcs.ParseDateObs(strings.TrimSpace(date.Value))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, key := range []string{"EXPTIME", "EXPOSURE"} {
+		if v, exists := header.Floats[key]; exists && v.Value > 0 {
+			return t.Add(time.Duration(float64(v.Value) / 2 * float64(time.Second))), nil
+		}
+
+		if v, exists := header.Ints[key]; exists && v.Value > 0 {
+			return t.Add(time.Duration(v.Value) * time.Second / 2), nil
+		}
+	}
+
+	return t, nil
+}
+
+/*****************************************************************************************************************/
+
+// ExtractAtmosphereFromHeaders returns the ambient temperature, in degrees Celsius, from the AMBTEMP header, and the
+// atmospheric pressure, in hPa, from the PRESSURE header, where either is NaN if its header does not exist.
+func ExtractAtmosphereFromHeaders(header fits.FITSHeader) (temperature float64, pressure float64, err error) {
+	temperature, exists, err := extractFloatFromHeaders(header, "AMBTEMP")
+	if err != nil {
+		return math.NaN(), math.NaN(), err
+	}
+
+	if !exists {
+		temperature = math.NaN()
+	}
+
+	pressure, exists, err = extractFloatFromHeaders(header, "PRESSURE")
+	if err != nil {
+		return math.NaN(), math.NaN(), err
+	}
+
+	if !exists {
+		pressure = math.NaN()
+	}
+
+	return temperature, pressure, nil
+}
+
+/*****************************************************************************************************************/
+
+// ExtractBinningFromHeaders returns the XBINNING and YBINNING binning factors of the image, which default to one.
+func ExtractBinningFromHeaders(header fits.FITSHeader) (x int, y int) {
+	x, y = 1, 1
+
+	if v, exists := header.Ints["XBINNING"]; exists && v.Value > 0 {
+		x = int(v.Value)
+	}
+
+	if v, exists := header.Ints["YBINNING"]; exists && v.Value > 0 {
+		y = int(v.Value)
+	}
+
+	return x, y
+}
+
+/*****************************************************************************************************************/
+
+// ExtractEquipmentFromHeaders returns the equipment of the image from the FOCALLEN (in millimetres), XPIXSZ and YPIXSZ
+// (in microns) headers, where, as in the MaxIm DL convention, the pixel sizes are those of the binned pixels, so the
+// equipment is unbinned.
+func ExtractEquipmentFromHeaders(header fits.FITSHeader) (fov.EquipmentParams, error) {
+	focalLength, exists, err := extractFloatFromHeaders(header, "FOCALLEN")
+	if err != nil {
+		return fov.EquipmentParams{}, err
+	}
+
+	if !exists {
+		return fov.EquipmentParams{}, fmt.Errorf("focallen header not found in the supplied FITS file")
+	}
+
+	pixelSizeX, exists, err := extractFloatFromHeaders(header, "XPIXSZ", "PIXSIZE1")
+	if err != nil {
+		return fov.EquipmentParams{}, err
+	}
+
+	if !exists {
+		return fov.EquipmentParams{}, fmt.Errorf("xpixsz header not found in the supplied FITS file")
+	}
+
+	// The pixels are square unless the YPIXSZ header says otherwise:
+	pixelSizeY, _, err := extractFloatFromHeaders(header, "YPIXSZ", "PIXSIZE2")
+	if err != nil {
+		return fov.EquipmentParams{}, err
+	}
+
+	return fov.EquipmentParams{
+		FocalLength: focalLength,
+		PixelSizeX:  pixelSizeX,
+		PixelSizeY:  pixelSizeY,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// extractFloatFromHeaders returns the value of the first of the headers that exists, as a float, an integer, or a
+// decimal or sexagesimal string, and whether any of the headers exist.
+func extractFloatFromHeaders(header fits.FITSHeader, keys ...string) (float64, bool, error) {
+	for _, key := range keys {
+		if v, exists := header.Floats[key]; exists {
+			if math.IsNaN(float64(v.Value)) {
+				return 0, true, fmt.Errorf("%s value needs to be a valid float32", strings.ToLower(key))
+			}
+
+			return float64(v.Value), true, nil
+		}
+
+		if v, exists := header.Ints[key]; exists {
+			return float64(v.Value), true, nil
+		}
+
+		if v, exists := header.Strings[key]; exists && strings.TrimSpace(v.Value) != "" {
+			d, err := astrometry.ParseSexagesimal(v.Value)
+			if err != nil {
+				return 0, true, fmt.Errorf("%s value is invalid: %v", strings.ToLower(key), err)
+			}
+
+			return d, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+/*****************************************************************************************************************/
@@ -13,8 +13,11 @@ package utils
 import (
 	"math"
 	"testing"
+	"time"
 
 	"github.com/observerly/iris/pkg/fits"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
 )
 
 /*****************************************************************************************************************/
@@ -428,3 +431,352 @@ func TestHeightIsOutOfRangeMaxInt32(t *testing.T) {
 }
 
 /*****************************************************************************************************************/
+
+func TestSaturationIsExtractedFromDATAMAX(t *testing.T) {
+	header := fits.FITSHeader{
+		Ints: map[string]fits.FITSHeaderInt{
+			"DATAMAX": {
+				Value:   60000,
+				Comment: "Maximum data value",
+			},
+		},
+		Floats: map[string]fits.FITSHeaderFloat{},
+	}
+
+	got := ExtractSaturationFromHeaders(header)
+
+	if got != 60000 {
+		t.Errorf("Expected 60000, got %v", got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSaturationIsZeroWithoutHeaders(t *testing.T) {
+	header := fits.FITSHeader{
+		Ints:   map[string]fits.FITSHeaderInt{},
+		Floats: map[string]fits.FITSHeaderFloat{},
+	}
+
+	got := ExtractSaturationFromHeaders(header)
+
+	if got != 0 {
+		t.Errorf("Expected 0, got %v", got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestBayerPatternIsExtractedWithOffsets(t *testing.T) {
+	header := fits.FITSHeader{
+		Ints: map[string]fits.FITSHeaderInt{
+			"XBAYROFF": {
+				Value:   1,
+				Comment: "X offset of Bayer array",
+			},
+		},
+		Strings: map[string]fits.FITSHeaderString{
+			"BAYERPAT": {
+				Value:   "RGGB",
+				Comment: "Bayer color pattern",
+			},
+		},
+	}
+
+	pattern, xoffset, yoffset, exists := ExtractBayerPatternFromHeaders(header)
+
+	if !exists || pattern != "RGGB" || xoffset != 1 || yoffset != 0 {
+		t.Errorf("Expected RGGB with offsets (1, 0), got %q with offsets (%d, %d)", pattern, xoffset, yoffset)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestBayerPatternIsMissingFromHeader(t *testing.T) {
+	header := fits.FITSHeader{
+		Strings: map[string]fits.FITSHeaderString{},
+	}
+
+	if _, _, _, exists := ExtractBayerPatternFromHeaders(header); exists {
+		t.Errorf("Expected no Bayer pattern for a monochrome image")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFITSHeaderIsConvertedFromHeader(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("BITPIX", 16, "")
+	header.Set("NAXIS", 2, "")
+	header.Set("NAXIS1", 4096, "")
+	header.Set("NAXIS2", 2048, "")
+	header.Set("RA", 98.5, "Right Ascension")
+	header.Set("BAYERPAT", "RGGB", "")
+	header.Set("EXTEND", true, "")
+
+	h := NewFITSHeaderFromHeader(header)
+
+	width, err := ExtractImageWidthFromHeaders(h)
+	if err != nil || width != 4096 {
+		t.Errorf("Expected a width of 4096, got %v (%v)", width, err)
+	}
+
+	ra, err := ResolveOrExtractRAFromHeaders(float32(math.NaN()), h)
+	if err != nil || ra != 98.5 {
+		t.Errorf("Expected an RA of 98.5, got %v (%v)", ra, err)
+	}
+
+	if pattern, _, _, exists := ExtractBayerPatternFromHeaders(h); !exists || pattern != "RGGB" {
+		t.Errorf("Expected the RGGB Bayer pattern, got %q", pattern)
+	}
+
+	if !h.Bools["EXTEND"].Value || h.Bitpix != 16 {
+		t.Errorf("Expected EXTEND and a BITPIX of 16, got %v and %d", h.Bools["EXTEND"].Value, h.Bitpix)
+	}
+
+	// Without an ADU header, the ADU follows from the integer BITPIX:
+	if adu := ExtractADUFromHeaders(h, nil); adu != 65535 {
+		t.Errorf("Expected an ADU of 65535, got %v", adu)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestADUIsExtractedFromFloatingPointData(t *testing.T) {
+	header := fits.FITSHeader{
+		Bitpix: -32,
+		Ints:   map[string]fits.FITSHeaderInt{},
+	}
+
+	if adu := ExtractADUFromHeaders(header, []float32{0, 1200.5, float32(math.NaN()), 42}); adu != 1201 {
+		t.Errorf("Expected an ADU of 1201, got %v", adu)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestObserverIsExtractedFromHeaders(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("SITELAT", "+52 12 36", "")
+	header.Set("SITELONG", "-0 07 12", "")
+	header.Set("SITEELEV", 25, "")
+
+	observer, err := ExtractObserverFromHeaders(NewFITSHeaderFromHeader(header))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if math.Abs(observer.Latitude-52.21) > 1e-9 || math.Abs(observer.Longitude-(-0.12)) > 1e-9 || observer.Elevation != 25 {
+		t.Errorf("Expected an observer at (52.21, -0.12, 25), got %+v", observer)
+	}
+
+	// The FITS standard OBSGEO keywords, where a longitude beyond 180° is to the west:
+	header = fitsio.NewHeader()
+	header.Set("OBSGEO-B", 19.8207, "")
+	header.Set("OBSGEO-L", 204.5283, "")
+
+	observer, err = ExtractObserverFromHeaders(NewFITSHeaderFromHeader(header))
+	if err != nil || math.Abs(observer.Latitude-19.8207) > 1e-4 || math.Abs(observer.Longitude-(-155.4717)) > 1e-4 || observer.Elevation != 0 {
+		t.Errorf("Expected an observer at (19.8207, -155.4717, 0), got %+v (%v)", observer, err)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestObserverIsMissingOrInvalidInHeaders(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("SITELONG", 12.5, "")
+
+	if _, err := ExtractObserverFromHeaders(NewFITSHeaderFromHeader(header)); err == nil {
+		t.Errorf("Expected an error for a missing site latitude")
+	}
+
+	header.Set("SITELAT", 95.0, "")
+
+	if _, err := ExtractObserverFromHeaders(NewFITSHeaderFromHeader(header)); err == nil {
+		t.Errorf("Expected an error for an out of range site latitude")
+	}
+
+	header.Set("SITELAT", "north", "")
+
+	if _, err := ExtractObserverFromHeaders(NewFITSHeaderFromHeader(header)); err == nil {
+		t.Errorf("Expected an error for an invalid site latitude")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestObservationTimeIsTheMiddleOfTheExposure(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("DATE-OBS", "2025-03-20T21:30:00", "")
+	header.Set("EXPTIME", 300.0, "")
+
+	datetime, err := ExtractObservationTimeFromHeaders(NewFITSHeaderFromHeader(header))
+	if err != nil || !datetime.Equal(time.Date(2025, 3, 20, 21, 32, 30, 0, time.UTC)) {
+		t.Errorf("Expected 2025-03-20T21:32:30Z, got %v (%v)", datetime, err)
+	}
+
+	if _, err := ExtractObservationTimeFromHeaders(NewFITSHeaderFromHeader(fitsio.NewHeader())); err == nil {
+		t.Errorf("Expected an error for a missing DATE-OBS")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestAtmosphereIsExtractedFromHeaders(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("AMBTEMP", -2.5, "")
+	header.Set("PRESSURE", 985, "")
+
+	temperature, pressure, err := ExtractAtmosphereFromHeaders(NewFITSHeaderFromHeader(header))
+	if err != nil || temperature != -2.5 || pressure != 985 {
+		t.Errorf("Expected a temperature of -2.5°C and a pressure of 985 hPa, got %v and %v (%v)", temperature, pressure, err)
+	}
+
+	temperature, pressure, err = ExtractAtmosphereFromHeaders(NewFITSHeaderFromHeader(fitsio.NewHeader()))
+	if err != nil || !math.IsNaN(temperature) || !math.IsNaN(pressure) {
+		t.Errorf("Expected a NaN temperature and pressure, got %v and %v (%v)", temperature, pressure, err)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRAIsExtractedFromSexagesimalObjectHeaders(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("OBJCTRA", "05 35 17.3", "")
+	header.Set("OBJCTDEC", "-05 23 28", "")
+
+	h := NewFITSHeaderFromHeader(header)
+
+	ra, err := ResolveOrExtractRAFromHeaders(float32(math.NaN()), h)
+	if err != nil || math.Abs(float64(ra)-83.82208) > 1e-4 {
+		t.Errorf("Expected an RA of 83.82208, got %v (%v)", ra, err)
+	}
+
+	dec, err := ResolveOrExtractDecFromHeaders(float32(math.NaN()), h)
+	if err != nil || math.Abs(float64(dec)-(-5.39111)) > 1e-4 {
+		t.Errorf("Expected a Dec of -5.39111, got %v (%v)", dec, err)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRAInHoursIsConvertedToDegrees(t *testing.T) {
+	// A decimal RA header in hours, as identified by its comment:
+	header := fitsio.NewHeader()
+	header.Set("RA", 5.588139, "[hours] Right Ascension")
+
+	ra, err := ResolveOrExtractRAFromHeaders(float32(math.NaN()), NewFITSHeaderFromHeader(header))
+	if err != nil || math.Abs(float64(ra)-83.82208) > 1e-4 {
+		t.Errorf("Expected an RA of 83.82208, got %v (%v)", ra, err)
+	}
+
+	// A decimal RA header in hours, as identified by its agreement with the OBJCTRA header:
+	header = fitsio.NewHeader()
+	header.Set("RA", 5.588139, "")
+	header.Set("OBJCTRA", "05 35 17.3", "")
+
+	ra, err = ResolveOrExtractRAFromHeaders(float32(math.NaN()), NewFITSHeaderFromHeader(header))
+	if err != nil || math.Abs(float64(ra)-83.82208) > 1e-4 {
+		t.Errorf("Expected an RA of 83.82208, got %v (%v)", ra, err)
+	}
+
+	// A decimal RA header in degrees, which agrees with the OBJCTRA header in hours:
+	header = fitsio.NewHeader()
+	header.Set("RA", 5.0, "")
+	header.Set("OBJCTRA", "00 20 00", "")
+
+	ra, err = ResolveOrExtractRAFromHeaders(float32(math.NaN()), NewFITSHeaderFromHeader(header))
+	if err != nil || ra != 5 {
+		t.Errorf("Expected an RA of 5, got %v (%v)", ra, err)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRAAndDecAreExtractedFromAnExistingWCS(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("CTYPE1", "RA---TAN", "")
+	header.Set("CTYPE2", "DEC--TAN", "")
+	header.Set("CRVAL1", 83.82208, "")
+	header.Set("CRVAL2", -5.39111, "")
+
+	h := NewFITSHeaderFromHeader(header)
+
+	ra, err := ResolveOrExtractRAFromHeaders(float32(math.NaN()), h)
+	if err != nil || math.Abs(float64(ra)-83.82208) > 1e-4 {
+		t.Errorf("Expected an RA of 83.82208, got %v (%v)", ra, err)
+	}
+
+	dec, err := ResolveOrExtractDecFromHeaders(float32(math.NaN()), h)
+	if err != nil || math.Abs(float64(dec)-(-5.39111)) > 1e-4 {
+		t.Errorf("Expected a Dec of -5.39111, got %v (%v)", dec, err)
+	}
+
+	// The reference point of a galactic WCS is not a right ascension:
+	header.Set("CTYPE1", "GLON-TAN", "")
+
+	if _, err := ResolveOrExtractRAFromHeaders(float32(math.NaN()), NewFITSHeaderFromHeader(header)); err == nil {
+		t.Errorf("Expected an error for the CRVAL1 of a galactic WCS")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRAAndDecFlagsAreParsed(t *testing.T) {
+	for _, value := range []string{"83.82208", "05 35 17.3", "05:35:17.3", "5.588139h"} {
+		ra, err := ParseRAFlag(value)
+		if err != nil || math.Abs(float64(ra)-83.82208) > 1e-4 {
+			t.Errorf("%q: expected an RA of 83.82208, got %v (%v)", value, ra, err)
+		}
+	}
+
+	for _, value := range []string{"-5.39111", "-05 23 28", "-05:23:28"} {
+		dec, err := ParseDecFlag(value)
+		if err != nil || math.Abs(float64(dec)-(-5.39111)) > 1e-4 {
+			t.Errorf("%q: expected a Dec of -5.39111, got %v (%v)", value, dec, err)
+		}
+	}
+
+	if ra, err := ParseRAFlag(""); err != nil || !math.IsNaN(float64(ra)) {
+		t.Errorf("Expected an empty RA flag to be NaN, got %v (%v)", ra, err)
+	}
+
+	if _, err := ParseDecFlag("north"); err == nil {
+		t.Errorf("Expected an error for an invalid Dec flag")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestEquipmentIsExtractedFromHeaders(t *testing.T) {
+	header := fitsio.NewHeader()
+	header.Set("FOCALLEN", 250, "")
+	header.Set("XPIXSZ", 7.52, "")
+	header.Set("YPIXSZ", 7.52, "")
+	header.Set("XBINNING", 2, "")
+	header.Set("YBINNING", 2, "")
+
+	h := NewFITSHeaderFromHeader(header)
+
+	equipment, err := ExtractEquipmentFromHeaders(h)
+	if err != nil || equipment.FocalLength != 250 || math.Abs(equipment.PixelSizeX-7.52) > 1e-6 || equipment.BinningX != 0 {
+		t.Errorf("Expected an unbinned 250 mm focal length with 7.52 µm (binned) pixels, got %+v (%v)", equipment, err)
+	}
+
+	if x, y := ExtractBinningFromHeaders(h); x != 2 || y != 2 {
+		t.Errorf("Expected a binning of 2x2, got %dx%d", x, y)
+	}
+
+	if x, y := ExtractBinningFromHeaders(NewFITSHeaderFromHeader(fitsio.NewHeader())); x != 1 || y != 1 {
+		t.Errorf("Expected a default binning of 1x1, got %dx%d", x, y)
+	}
+
+	header.Delete("FOCALLEN")
+
+	if _, err := ExtractEquipmentFromHeaders(NewFITSHeaderFromHeader(header)); err == nil {
+		t.Errorf("Expected an error for a missing FOCALLEN")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,318 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"time"
+
+	"github.com/observerly/sidera/pkg/epoch"
+)
+
+/*****************************************************************************************************************/
+
+// The difference between Terrestrial Time and UTC, in seconds, i.e., 32.184 seconds plus the 37 leap seconds in
+// effect since 2017 January 1:
+const TT_MINUS_UTC = 69.184
+
+/*****************************************************************************************************************/
+
+// The constant of annual aberration, in arcseconds, i.e., the mean orbital velocity of the Earth over the speed of
+// light:
+const ABERRATION_CONSTANT = 20.49552
+
+/*****************************************************************************************************************/
+
+// The Schwarzschild radius of the Sun, 2GM/c², in astronomical units:
+const SCHWARZSCHILD_RADIUS_SUN = 1.97412574336e-8
+
+/*****************************************************************************************************************/
+
+// The number of iterations when inverting the apparent place, each of which reduces the error by a factor of ~10⁴:
+const APPARENT_PLACE_ITERATIONS = 4
+
+/*****************************************************************************************************************/
+
+// ApparentParams configures the conversion between the astrometric (ICRS) and the apparent place of a coordinate.
+type ApparentParams struct {
+	Datetime   time.Time // The (UTC) time of the observation
+	Deflection bool      // Whether to include the gravitational deflection of light by the Sun
+}
+
+/*****************************************************************************************************************/
+
+// ApparentEquatorialCoordinate is the apparent place of a coordinate, i.e., its right ascension and declination, in
+// degrees, referred to the true equator and equinox of date ("JNow"), as used to sync many telescope mounts.
+type ApparentEquatorialCoordinate struct {
+	RA  float64
+	Dec float64
+}
+
+/*****************************************************************************************************************/
+
+// nutationTerm is a single periodic term of the nutation in longitude and obliquity, in units of 0.0001 arcseconds,
+// with the multiples of the fundamental arguments D, M, M', F and Ω.
+type nutationTerm struct {
+	d, m, mp, f, omega int
+	psi, psiT          float64
+	eps, epsT          float64
+}
+
+/*****************************************************************************************************************/
+
+// The principal terms of the IAU 1980 theory of nutation (Meeus 1998, Table 22.A), where each of the omitted terms
+// is less than 0.005 arcseconds:
+var nutationTerms = []nutationTerm{
+	{0, 0, 0, 0, 1, -171996, -174.2, 92025, 8.9},
+	{-2, 0, 0, 2, 2, -13187, -1.6, 5736, -3.1},
+	{0, 0, 0, 2, 2, -2274, -0.2, 977, -0.5},
+	{0, 0, 0, 0, 2, 2062, 0.2, -895, 0.5},
+	{0, 1, 0, 0, 0, 1426, -3.4, 54, -0.1},
+	{0, 0, 1, 0, 0, 712, 0.1, -7, 0},
+	{-2, 1, 0, 2, 2, -517, 1.2, 224, -0.6},
+	{0, 0, 0, 2, 1, -386, -0.4, 200, 0},
+	{0, 0, 1, 2, 2, -301, 0, 129, -0.1},
+	{-2, -1, 0, 2, 2, 217, -0.5, -95, 0.3},
+	{-2, 0, 1, 0, 0, -158, 0, 0, 0},
+	{-2, 0, 0, 2, 1, 129, 0.1, -70, 0},
+	{0, 0, -1, 2, 2, 123, 0, -53, 0},
+	{2, 0, 0, 0, 0, 63, 0, 0, 0},
+	{0, 0, 1, 0, 1, 63, 0.1, -33, 0},
+	{2, 0, -1, 2, 2, -59, 0, 26, 0},
+	{0, 0, -1, 0, 1, -58, -0.1, 32, 0},
+	{0, 0, 1, 2, 1, -51, 0, 27, 0},
+}
+
+/*****************************************************************************************************************/
+
+// ToApparent converts the astrometric (ICRS, i.e., J2000) coordinate to its apparent place at the time of the params,
+// applying the gravitational deflection of light by the Sun (if enabled), the annual aberration, the frame bias, the
+// IAU 2006 precession and the nutation, which is accurate to ~0.05 arcseconds.
+func (c ICRSEquatorialCoordinate) ToApparent(params ApparentParams) ApparentEquatorialCoordinate {
+	t := getJulianCenturies(params.Datetime)
+
+	ra, dec := toSpherical(toApparentPlace(toCartesian(c.RA, c.Dec), t, params.Deflection))
+
+	return ApparentEquatorialCoordinate{RA: ra, Dec: dec}
+}
+
+/*****************************************************************************************************************/
+
+// ToICRS converts the apparent place at the time of the params to its astrometric (ICRS, i.e., J2000) coordinate, by
+// iteratively inverting the conversion to the apparent place.
+func (c ApparentEquatorialCoordinate) ToICRS(params ApparentParams) ICRSEquatorialCoordinate {
+	t := getJulianCenturies(params.Datetime)
+
+	target := toCartesian(c.RA, c.Dec)
+
+	// The inverse of the rotation to the true equator and equinox of date:
+	inverse := transpose(getBiasPrecessionNutationMatrix(t))
+
+	p := apply(inverse, target)
+
+	for iteration := 0; iteration < APPARENT_PLACE_ITERATIONS; iteration++ {
+		q := toApparentPlace(p, t, params.Deflection)
+
+		correction := apply(inverse, [3]float64{target[0] - q[0], target[1] - q[1], target[2] - q[2]})
+
+		p = normalise([3]float64{p[0] + correction[0], p[1] + correction[1], p[2] + correction[2]})
+	}
+
+	ra, dec := toSpherical(p)
+
+	return ICRSEquatorialCoordinate{RA: ra, Dec: dec}
+}
+
+/*****************************************************************************************************************/
+
+// GetNutation returns the nutation in longitude, Δψ, and in obliquity, Δε, in arcseconds, at the (UTC) time.
+func GetNutation(datetime time.Time) (dpsi float64, deps float64) {
+	return getNutation(getJulianCenturies(datetime))
+}
+
+/*****************************************************************************************************************/
+
+// GetMeanObliquity returns the mean obliquity of the ecliptic (IAU 2006), in degrees, at the (UTC) time.
+func GetMeanObliquity(datetime time.Time) float64 {
+	return getMeanObliquity(getJulianCenturies(datetime)) / 3600
+}
+
+/*****************************************************************************************************************/
+
+// getJulianCenturies returns the Julian centuries of Terrestrial Time since J2000.0 of the (UTC) time.
+func getJulianCenturies(datetime time.Time) float64 {
+	return (epoch.GetJulianDate(datetime) + TT_MINUS_UTC/86400 - epoch.J2000) / 36525
+}
+
+/*****************************************************************************************************************/
+
+// toApparentPlace converts the unit vector of the astrometric place to the unit vector of the apparent place, at t
+// Julian centuries (TT) since J2000.0.
+func toApparentPlace(p [3]float64, t float64, deflection bool) [3]float64 {
+	beta, sun, distance := getEarthVelocityAndSun(t)
+
+	// The gravitational deflection of light by the Sun, away from the Sun, where e is the unit vector from the Sun to
+	// the Earth (IERS Conventions 2003, §5.5.1):
+	if deflection {
+		e := [3]float64{-sun[0], -sun[1], -sun[2]}
+
+		pe := dot(p, e)
+
+		w := SCHWARZSCHILD_RADIUS_SUN / distance / math.Max(1+pe, 1e-6)
+
+		p = normalise([3]float64{
+			p[0] + w*(e[0]-pe*p[0]),
+			p[1] + w*(e[1]-pe*p[1]),
+			p[2] + w*(e[2]-pe*p[2]),
+		})
+	}
+
+	// The rotation from the ICRS to the true equator and equinox of date, of the aberrated direction:
+	return apply(getBiasPrecessionNutationMatrix(t), aberrate(p, beta))
+}
+
+/*****************************************************************************************************************/
+
+// aberrate applies the (relativistic) annual aberration to the unit vector, which displaces it towards the apex of
+// the velocity of the Earth, β = v/c.
+func aberrate(p [3]float64, beta [3]float64) [3]float64 {
+	pb := dot(p, beta)
+
+	inverseLorentz := math.Sqrt(1 - dot(beta, beta))
+
+	w := 1 + pb/(1+inverseLorentz)
+
+	return normalise([3]float64{
+		inverseLorentz*p[0] + w*beta[0],
+		inverseLorentz*p[1] + w*beta[1],
+		inverseLorentz*p[2] + w*beta[2],
+	})
+}
+
+/*****************************************************************************************************************/
+
+// getBiasPrecessionNutationMatrix returns the rotation matrix from the ICRS to the true equator and equinox of date.
+func getBiasPrecessionNutationMatrix(t float64) [3][3]float64 {
+	return multiply(getNutationMatrix(t), multiply(getPrecessionMatrix(t), frameBias))
+}
+
+/*****************************************************************************************************************/
+
+// getPrecessionMatrix returns the IAU 2006 precession matrix from the mean equator and equinox of J2000.0 to the mean
+// equator and equinox of date, from the equatorial precession angles ζA, zA and θA (Capitaine et al. 2003).
+func getPrecessionMatrix(t float64) [3][3]float64 {
+	zeta := (2.650545 + t*(2306.083227+t*(0.2988499+t*(0.01801828+t*(-0.000005971+t*-0.0000003173))))) * arcsecond
+	z := (-2.650545 + t*(2306.077181+t*(1.0927348+t*(0.01826837+t*(-0.000028596+t*-0.0000002904))))) * arcsecond
+	theta := t * (2004.191903 + t*(-0.4294934+t*(-0.04182264+t*(-0.000007089+t*-0.0000001274)))) * arcsecond
+
+	return multiply(rotateZ(-z), multiply(rotateY(theta), rotateZ(-zeta)))
+}
+
+/*****************************************************************************************************************/
+
+// getNutationMatrix returns the nutation matrix from the mean equator and equinox of date to the true equator and
+// equinox of date.
+func getNutationMatrix(t float64) [3][3]float64 {
+	dpsi, deps := getNutation(t)
+
+	epsilon := getMeanObliquity(t) * arcsecond
+
+	return multiply(rotateX(-(epsilon + deps*arcsecond)), multiply(rotateZ(-dpsi*arcsecond), rotateX(epsilon)))
+}
+
+/*****************************************************************************************************************/
+
+// getMeanObliquity returns the mean obliquity of the ecliptic (IAU 2006), in arcseconds.
+func getMeanObliquity(t float64) float64 {
+	return OBLIQUITY_J2000 + t*(-46.836769+t*(-0.0001831+t*(0.00200340+t*(-0.000000576+t*-0.0000000434))))
+}
+
+/*****************************************************************************************************************/
+
+// getNutation returns the nutation in longitude, Δψ, and in obliquity, Δε, in arcseconds.
+func getNutation(t float64) (dpsi float64, deps float64) {
+	degree := math.Pi / 180
+
+	// The mean elongation of the Moon from the Sun, the mean anomalies of the Sun and the Moon, the argument of
+	// latitude of the Moon, and the longitude of the ascending node of the Moon's orbit:
+	d := (297.85036 + t*(445267.111480+t*(-0.0019142+t/189474))) * degree
+	m := (357.52772 + t*(35999.050340+t*(-0.0001603-t/300000))) * degree
+	mp := (134.96298 + t*(477198.867398+t*(0.0086972+t/56250))) * degree
+	f := (93.27191 + t*(483202.017538+t*(-0.0036825+t/327270))) * degree
+	omega := (125.04452 + t*(-1934.136261+t*(0.0020708+t/450000))) * degree
+
+	for _, term := range nutationTerms {
+		argument := float64(term.d)*d + float64(term.m)*m + float64(term.mp)*mp + float64(term.f)*f + float64(term.omega)*omega
+
+		dpsi += (term.psi + term.psiT*t) * math.Sin(argument)
+		deps += (term.eps + term.epsT*t) * math.Cos(argument)
+	}
+
+	return dpsi * 1e-4, deps * 1e-4
+}
+
+/*****************************************************************************************************************/
+
+// getEarthVelocityAndSun returns the velocity of the Earth over the speed of light, β, the unit vector from the Earth
+// to the Sun, both in the ICRS, and the distance of the Sun in astronomical units, from the elliptical orbit of the
+// Earth about the Sun (Meeus 1998, Chapters 23 and 25).
+func getEarthVelocityAndSun(t float64) (beta [3]float64, sun [3]float64, distance float64) {
+	degree := math.Pi / 180
+
+	// The eccentricity of the orbit of the Earth, and the longitude of its perihelion:
+	e := 0.016708634 - t*(0.000042037+t*0.0000001267)
+	perihelion := (102.93735 + t*(1.71946+t*0.00046)) * degree
+
+	// The geometric mean longitude and mean anomaly of the Sun, its equation of the centre, and its true longitude:
+	l0 := 280.46646 + t*(36000.76983+t*0.0003032)
+	m := (357.52911 + t*(35999.05029-t*0.0001537)) * degree
+
+	centre := (1.914602-t*(0.004817+t*0.000014))*math.Sin(m) + (0.019993-t*0.000101)*math.Sin(2*m) + 0.000289*math.Sin(3*m)
+
+	longitude := (l0 + centre) * degree
+
+	// The distance of the Sun, from its true anomaly:
+	distance = 1.000001018 * (1 - e*e) / (1 + e*math.Cos(m+centre*degree))
+
+	// The velocity of the Earth is directed 90° behind the longitude of the Sun, with the elliptical component:
+	kappa := ABERRATION_CONSTANT * arcsecond
+
+	velocity := [3]float64{
+		kappa * (math.Sin(longitude) - e*math.Sin(perihelion)),
+		kappa * (-math.Cos(longitude) + e*math.Cos(perihelion)),
+		0,
+	}
+
+	direction := [3]float64{math.Cos(longitude), math.Sin(longitude), 0}
+
+	// The rotation from the ecliptic of date to the ICRS, via the mean equator and equinox of date:
+	toICRS := transpose(multiply(rotateX(getMeanObliquity(t)*arcsecond), multiply(getPrecessionMatrix(t), frameBias)))
+
+	return apply(toICRS, velocity), apply(toICRS, direction), distance
+}
+
+/*****************************************************************************************************************/
+
+// dot returns the scalar product of the vectors.
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+/*****************************************************************************************************************/
+
+// normalise returns the unit vector in the direction of the vector.
+func normalise(v [3]float64) [3]float64 {
+	n := math.Sqrt(dot(v, v))
+
+	return [3]float64{v[0] / n, v[1] / n, v[2] / n}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,108 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+/*****************************************************************************************************************/
+
+// The Julian centuries (TT) since J2000.0 of 2028 November 13.19 TD (Meeus 1998, Examples 21.b and 23.a):
+const meeusExampleT = (2462088.69 - 2451545.0) / 36525
+
+/*****************************************************************************************************************/
+
+// The mean place of θ Persei at J2000.0, with its proper motion applied to 2028 November 13.19 TD:
+var thetaPersei = ICRSEquatorialCoordinate{RA: 41.0540613, Dec: 49.2277489}
+
+/*****************************************************************************************************************/
+
+func TestNutation(t *testing.T) {
+	// 1987 April 10, 0h TD (Meeus 1998, Example 22.a), where Δψ = -3.788" and Δε = +9.443":
+	dpsi, deps := getNutation((2446895.5 - 2451545.0) / 36525)
+
+	if math.Abs(dpsi-(-3.788)) > 0.005 || math.Abs(deps-9.443) > 0.005 {
+		t.Errorf("expected (Δψ, Δε) = (-3.788, 9.443), got (%v, %v)", dpsi, deps)
+	}
+
+	// The mean obliquity of 1987 April 10, 23°26'27.407" (to within the difference of the IAU 1980 and 2006 models):
+	epsilon := getMeanObliquity((2446895.5-2451545.0)/36525) / 3600
+
+	if math.Abs(epsilon-(23+26.0/60+27.407/3600)) > 0.1/3600 {
+		t.Errorf("expected a mean obliquity of 23°26'27.407\", got %v", epsilon)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestPrecession(t *testing.T) {
+	// The mean place of θ Persei of the equinox of 2028 November 13.19 TD (Meeus 1998, Example 21.b):
+	ra, dec := toSpherical(apply(multiply(getPrecessionMatrix(meeusExampleT), frameBias), toCartesian(thetaPersei.RA, thetaPersei.Dec)))
+
+	// To within 0.1", i.e., the difference between the IAU 1976 and 2006 precession, and the frame bias:
+	if math.Abs(ra-41.547214)*math.Cos(dec*math.Pi/180) > 0.1/3600 || math.Abs(dec-49.348483) > 0.1/3600 {
+		t.Errorf("expected (41.547214, 49.348483), got (%v, %v)", ra, dec)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApparentPlace(t *testing.T) {
+	// The apparent place of θ Persei of 2028 November 13.19 TD (Meeus 1998, Example 23.a), which omits the deflection
+	// of light, and is computed from the low precision theory of the Sun, to ~0.1":
+	ra, dec := toSpherical(toApparentPlace(toCartesian(thetaPersei.RA, thetaPersei.Dec), meeusExampleT, false))
+
+	if math.Abs(ra-41.5599646)*math.Cos(dec*math.Pi/180) > 0.2/3600 || math.Abs(dec-49.3520685) > 0.2/3600 {
+		t.Errorf("expected (41.5599646, 49.3520685), got (%v, %v)", ra, dec)
+	}
+
+	// The deflection of light by the Sun is at most a few milliarcseconds away from the Sun:
+	dra, ddec := toSpherical(toApparentPlace(toCartesian(thetaPersei.RA, thetaPersei.Dec), meeusExampleT, true))
+
+	if separation := math.Hypot((dra-ra)*math.Cos(dec*math.Pi/180), ddec-dec) * 3600; separation > 0.01 {
+		t.Errorf("expected a deflection of light of less than 0.01\", got %v\"", separation)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApparentPlaceRoundTrip(t *testing.T) {
+	params := ApparentParams{
+		Datetime:   time.Date(2025, 3, 20, 21, 30, 0, 0, time.UTC),
+		Deflection: true,
+	}
+
+	for _, c := range []ICRSEquatorialCoordinate{
+		{RA: 0, Dec: 0},
+		{RA: 83.63308, Dec: 22.01450},
+		{RA: 10.68471, Dec: 41.26875},
+		{RA: 279.23473, Dec: 38.78369},
+		{RA: 37.95456, Dec: 89.26411},
+	} {
+		apparent := c.ToApparent(params)
+
+		// The apparent place of 2025 differs from the J2000 place by a third of a degree of precession:
+		if math.Abs(apparent.RA-c.RA) < 0.01 && math.Abs(apparent.Dec-c.Dec) < 0.01 {
+			t.Errorf("expected the apparent place of (%v, %v) to differ from J2000, got (%v, %v)", c.RA, c.Dec, apparent.RA, apparent.Dec)
+		}
+
+		icrs := apparent.ToICRS(params)
+
+		if math.Abs(math.Mod(icrs.RA-c.RA+540, 360)-180)*math.Cos(c.Dec*math.Pi/180) > 1e-9 || math.Abs(icrs.Dec-c.Dec) > 1e-9 {
+			t.Errorf("expected (%v, %v), got (%v, %v)", c.RA, c.Dec, icrs.RA, icrs.Dec)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,288 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// CoordinateFrame is a celestial coordinate frame, in which positions are reported as a longitude and a latitude.
+type CoordinateFrame string
+
+/*****************************************************************************************************************/
+
+const (
+	// The International Celestial Reference System, i.e., right ascension and declination:
+	FRAME_ICRS CoordinateFrame = "icrs"
+	// The IAU 1958 galactic coordinates, as realised in the ICRS by the Hipparcos catalogue:
+	FRAME_GALACTIC CoordinateFrame = "galactic"
+	// The mean ecliptic and equinox of J2000.0, of the IAU 2006 obliquity:
+	FRAME_ECLIPTIC CoordinateFrame = "ecliptic"
+)
+
+/*****************************************************************************************************************/
+
+// The mean obliquity of the ecliptic at J2000.0 (IAU 2006), in arcseconds:
+const OBLIQUITY_J2000 = 84381.406
+
+/*****************************************************************************************************************/
+
+// The rotation matrix from the ICRS to galactic coordinates (ESA 1997, The Hipparcos and Tycho Catalogues, Vol. 1,
+// §1.5.3), where the rows are the galactic x (towards the centre), y (towards l = 90°) and z (the north pole) axes:
+var icrsToGalactic = [3][3]float64{
+	{-0.0548755604162154, -0.8734370902348850, -0.4838350155487132},
+	{+0.4941094278755837, -0.4448296299600112, +0.7469822444972189},
+	{-0.8676661490190047, -0.1980763734312015, +0.4559837761750669},
+}
+
+/*****************************************************************************************************************/
+
+// The frame bias matrix, from the ICRS to the mean equator and equinox of J2000.0 (IERS Conventions 2003):
+var frameBias = getFrameBiasMatrix()
+
+/*****************************************************************************************************************/
+
+// The rotation matrix from the ICRS to the mean ecliptic and equinox of J2000.0, i.e., the frame bias followed by
+// the rotation about the equinox by the mean obliquity:
+var icrsToEcliptic = multiply(rotateX(OBLIQUITY_J2000*arcsecond), frameBias)
+
+/*****************************************************************************************************************/
+
+// One arcsecond, in radians:
+const arcsecond = math.Pi / (180 * 3600)
+
+/*****************************************************************************************************************/
+
+// ParseCoordinateFrame resolves the name of a celestial coordinate frame, e.g., "galactic", where an empty name is
+// the ICRS.
+func ParseCoordinateFrame(name string) (CoordinateFrame, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "icrs", "equatorial":
+		return FRAME_ICRS, nil
+	case "galactic":
+		return FRAME_GALACTIC, nil
+	case "ecliptic":
+		return FRAME_ECLIPTIC, nil
+	default:
+		return "", fmt.Errorf("unknown coordinate frame: %s", name)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// GalacticCoordinate is a position in galactic coordinates, i.e., the galactic longitude L and latitude B, in degrees.
+type GalacticCoordinate struct {
+	L float64
+	B float64
+}
+
+/*****************************************************************************************************************/
+
+// EclipticCoordinate is a position in the mean ecliptic coordinates of J2000.0, i.e., the ecliptic longitude Lon and
+// latitude Lat, in degrees.
+type EclipticCoordinate struct {
+	Lon float64
+	Lat float64
+}
+
+/*****************************************************************************************************************/
+
+// ToGalactic converts the equatorial coordinate to galactic coordinates.
+func (c ICRSEquatorialCoordinate) ToGalactic() GalacticCoordinate {
+	l, b := rotate(icrsToGalactic, c.RA, c.Dec, false)
+
+	return GalacticCoordinate{L: l, B: b}
+}
+
+/*****************************************************************************************************************/
+
+// ToICRS converts the galactic coordinate to equatorial coordinates.
+func (c GalacticCoordinate) ToICRS() ICRSEquatorialCoordinate {
+	ra, dec := rotate(icrsToGalactic, c.L, c.B, true)
+
+	return ICRSEquatorialCoordinate{RA: ra, Dec: dec}
+}
+
+/*****************************************************************************************************************/
+
+// ToEcliptic converts the equatorial coordinate to the mean ecliptic coordinates of J2000.0.
+func (c ICRSEquatorialCoordinate) ToEcliptic() EclipticCoordinate {
+	lon, lat := rotate(icrsToEcliptic, c.RA, c.Dec, false)
+
+	return EclipticCoordinate{Lon: lon, Lat: lat}
+}
+
+/*****************************************************************************************************************/
+
+// ToICRS converts the ecliptic coordinate to equatorial coordinates.
+func (c EclipticCoordinate) ToICRS() ICRSEquatorialCoordinate {
+	ra, dec := rotate(icrsToEcliptic, c.Lon, c.Lat, true)
+
+	return ICRSEquatorialCoordinate{RA: ra, Dec: dec}
+}
+
+/*****************************************************************************************************************/
+
+// ToFrame returns the longitude and latitude, in degrees, of the equatorial coordinate in the coordinate frame, where
+// an unknown frame is the ICRS.
+func (c ICRSEquatorialCoordinate) ToFrame(frame CoordinateFrame) (lon float64, lat float64) {
+	switch frame {
+	case FRAME_GALACTIC:
+		g := c.ToGalactic()
+		return g.L, g.B
+	case FRAME_ECLIPTIC:
+		e := c.ToEcliptic()
+		return e.Lon, e.Lat
+	default:
+		return c.RA, c.Dec
+	}
+}
+
+/*****************************************************************************************************************/
+
+// NewICRSEquatorialCoordinateFromFrame returns the equatorial coordinate of the longitude and latitude, in degrees,
+// in the coordinate frame, where an unknown frame is the ICRS.
+func NewICRSEquatorialCoordinateFromFrame(frame CoordinateFrame, lon, lat float64) ICRSEquatorialCoordinate {
+	switch frame {
+	case FRAME_GALACTIC:
+		return GalacticCoordinate{L: lon, B: lat}.ToICRS()
+	case FRAME_ECLIPTIC:
+		return EclipticCoordinate{Lon: lon, Lat: lat}.ToICRS()
+	default:
+		return ICRSEquatorialCoordinate{RA: lon, Dec: lat}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// rotate applies the rotation matrix, or its transpose (i.e., its inverse), to the unit vector of the longitude and
+// latitude, in degrees, returning the rotated longitude in [0, 360) and latitude in degrees.
+func rotate(m [3][3]float64, lon, lat float64, inverse bool) (float64, float64) {
+	if inverse {
+		m = transpose(m)
+	}
+
+	return toSpherical(apply(m, toCartesian(lon, lat)))
+}
+
+/*****************************************************************************************************************/
+
+// toCartesian returns the unit vector of the longitude and latitude, in degrees.
+func toCartesian(lon, lat float64) [3]float64 {
+	lambda, beta := lon*math.Pi/180, lat*math.Pi/180
+
+	return [3]float64{
+		math.Cos(beta) * math.Cos(lambda),
+		math.Cos(beta) * math.Sin(lambda),
+		math.Sin(beta),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// toSpherical returns the longitude in [0, 360) and latitude, in degrees, of the (not necessarily unit) vector, where
+// the latitude is found from atan2, rather than asin, to retain its precision near the poles.
+func toSpherical(v [3]float64) (lon float64, lat float64) {
+	lon = math.Mod(math.Atan2(v[1], v[0])*180/math.Pi+360, 360)
+	lat = math.Atan2(v[2], math.Hypot(v[0], v[1])) * 180 / math.Pi
+
+	return lon, lat
+}
+
+/*****************************************************************************************************************/
+
+// getFrameBiasMatrix returns the rotation matrix from the ICRS to the mean equator and equinox of J2000.0, from the
+// offsets of the pole of the ICRS (in longitude and obliquity) and of its origin of right ascension.
+func getFrameBiasMatrix() [3][3]float64 {
+	psib, epsb, alphab := -0.041775*arcsecond, -0.0068192*arcsecond, -0.0146*arcsecond
+
+	return multiply(rotateX(-epsb), multiply(rotateY(psib*math.Sin(OBLIQUITY_J2000*arcsecond)), rotateZ(alphab)))
+}
+
+/*****************************************************************************************************************/
+
+// rotateX returns the rotation of the coordinate axes about the x axis by the angle (in radians).
+func rotateX(phi float64) [3][3]float64 {
+	s, c := math.Sincos(phi)
+
+	return [3][3]float64{{1, 0, 0}, {0, c, s}, {0, -s, c}}
+}
+
+/*****************************************************************************************************************/
+
+// rotateY returns the rotation of the coordinate axes about the y axis by the angle (in radians).
+func rotateY(theta float64) [3][3]float64 {
+	s, c := math.Sincos(theta)
+
+	return [3][3]float64{{c, 0, -s}, {0, 1, 0}, {s, 0, c}}
+}
+
+/*****************************************************************************************************************/
+
+// rotateZ returns the rotation of the coordinate axes about the z axis by the angle (in radians).
+func rotateZ(psi float64) [3][3]float64 {
+	s, c := math.Sincos(psi)
+
+	return [3][3]float64{{c, s, 0}, {-s, c, 0}, {0, 0, 1}}
+}
+
+/*****************************************************************************************************************/
+
+// apply returns the product of the matrix and the vector, m·v.
+func apply(m [3][3]float64, v [3]float64) [3]float64 {
+	var r [3]float64
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i] += m[i][j] * v[j]
+		}
+	}
+
+	return r
+}
+
+/*****************************************************************************************************************/
+
+// transpose returns the transpose of the matrix, i.e., the inverse of a rotation matrix.
+func transpose(m [3][3]float64) [3][3]float64 {
+	var t [3][3]float64
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			t[i][j] = m[j][i]
+		}
+	}
+
+	return t
+}
+
+/*****************************************************************************************************************/
+
+// multiply returns the matrix product a·b.
+func multiply(a, b [3][3]float64) [3][3]float64 {
+	var m [3][3]float64
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+
+	return m
+}
+
+/*****************************************************************************************************************/
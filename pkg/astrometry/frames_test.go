@@ -0,0 +1,103 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestGalacticCoordinates(t *testing.T) {
+	tests := []struct {
+		name      string
+		ra, dec   float64
+		l, b      float64
+		tolerance float64
+	}{
+		// The galactic centre and north galactic pole, as defined in the ICRS by the Hipparcos catalogue:
+		{"galactic centre", 266.40499, -28.93617, 0, 0, 1e-5},
+		{"north galactic pole", 192.85948, 27.12825, 0, 90, 1e-5},
+		// The Crab Nebula (M1), and the Andromeda Galaxy (M31), to the four decimal places of SIMBAD:
+		{"M1", 83.63308, 22.01450, 184.5575, -5.7843, 1e-4},
+		{"M31", 10.68471, 41.26875, 121.1743, -21.5733, 1e-4},
+	}
+
+	for _, tt := range tests {
+		g := ICRSEquatorialCoordinate{RA: tt.ra, Dec: tt.dec}.ToGalactic()
+
+		// The longitude is undefined at the pole:
+		if math.Abs(g.B-tt.b) > tt.tolerance || (tt.b != 90 && math.Abs(math.Mod(g.L-tt.l+540, 360)-180) > tt.tolerance) {
+			t.Errorf("%s: expected (l, b) = (%v, %v), got (%v, %v)", tt.name, tt.l, tt.b, g.L, g.B)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestEclipticCoordinates(t *testing.T) {
+	obliquity := OBLIQUITY_J2000 / 3600
+
+	// The north ecliptic pole is at right ascension 18h, and a declination of 90° less the obliquity, to within the
+	// tens of milliarcseconds of the frame bias of the ICRS:
+	pole := EclipticCoordinate{Lon: 0, Lat: 90}.ToICRS()
+
+	if math.Abs(pole.RA-270) > 1e-4 || math.Abs(pole.Dec-(90-obliquity)) > 1e-5 {
+		t.Errorf("expected the north ecliptic pole at (270, %v), got (%v, %v)", 90-obliquity, pole.RA, pole.Dec)
+	}
+
+	// The June solstice is at an ecliptic longitude of 90°, on the equatorial colure of 6h:
+	solstice := ICRSEquatorialCoordinate{RA: 90, Dec: obliquity}.ToEcliptic()
+
+	if math.Abs(solstice.Lon-90) > 1e-5 || math.Abs(solstice.Lat) > 1e-5 {
+		t.Errorf("expected the June solstice at (90, 0), got (%v, %v)", solstice.Lon, solstice.Lat)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCoordinateFrameRoundTrip(t *testing.T) {
+	for _, frame := range []CoordinateFrame{FRAME_ICRS, FRAME_GALACTIC, FRAME_ECLIPTIC} {
+		for _, eq := range []ICRSEquatorialCoordinate{{RA: 0, Dec: 0}, {RA: 123.4, Dec: -67.8}, {RA: 359.99, Dec: 89.999}} {
+			lon, lat := eq.ToFrame(frame)
+
+			got := NewICRSEquatorialCoordinateFromFrame(frame, lon, lat)
+
+			if math.Abs(math.Mod(got.RA-eq.RA+540, 360)-180)*math.Cos(eq.Dec*math.Pi/180) > 1e-10 || math.Abs(got.Dec-eq.Dec) > 1e-10 {
+				t.Errorf("%s: expected (%v, %v) to round trip, got (%v, %v)", frame, eq.RA, eq.Dec, got.RA, got.Dec)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestParseCoordinateFrame(t *testing.T) {
+	tests := map[string]CoordinateFrame{
+		"":         FRAME_ICRS,
+		"ICRS":     FRAME_ICRS,
+		"Galactic": FRAME_GALACTIC,
+		"ecliptic": FRAME_ECLIPTIC,
+	}
+
+	for name, want := range tests {
+		if got, err := ParseCoordinateFrame(name); err != nil || got != want {
+			t.Errorf("expected %q to parse as %s, got %s (%v)", name, want, got, err)
+		}
+	}
+
+	if _, err := ParseCoordinateFrame("supergalactic"); err == nil {
+		t.Errorf("expected an error for an unknown coordinate frame")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,134 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"time"
+
+	"github.com/observerly/sidera/pkg/epoch"
+)
+
+/*****************************************************************************************************************/
+
+// Observer is the geodetic location of an observing site, i.e., its latitude and (east positive) longitude, in
+// degrees, and its elevation above sea level, in metres.
+type Observer struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Elevation float64 `json:"elevation"`
+}
+
+/*****************************************************************************************************************/
+
+// HorizontalCoordinate is a position in the horizontal coordinates of an observer, i.e., the altitude above the
+// horizon and the azimuth, measured from the north through the east, in degrees.
+type HorizontalCoordinate struct {
+	Altitude float64 `json:"altitude"`
+	Azimuth  float64 `json:"azimuth"`
+}
+
+/*****************************************************************************************************************/
+
+// Observation describes a coordinate as seen by an observer at a given time, e.g., for the quality control of frames
+// taken at a low altitude.
+type Observation struct {
+	Datetime         time.Time `json:"datetime"`         // The (UTC) time of the observation
+	Altitude         float64   `json:"altitude"`         // The geometric altitude above the horizon, in degrees
+	Azimuth          float64   `json:"azimuth"`          // The azimuth, from the north through the east, in degrees
+	HourAngle        float64   `json:"hourAngle"`        // The local hour angle in [-180, 180), in degrees
+	Airmass          float64   `json:"airmass"`          // The relative airmass along the line of sight
+	ParallacticAngle float64   `json:"parallacticAngle"` // The parallactic angle in (-180, 180], in degrees
+}
+
+/*****************************************************************************************************************/
+
+// ToHorizontal converts the equatorial coordinate to the horizontal coordinates of the observer at the (UTC) time,
+// from its apparent place and the local apparent sidereal time, neglecting atmospheric refraction.
+func (c ICRSEquatorialCoordinate) ToHorizontal(observer Observer, datetime time.Time) HorizontalCoordinate {
+	o := c.Observe(observer, datetime)
+
+	return HorizontalCoordinate{Altitude: o.Altitude, Azimuth: o.Azimuth}
+}
+
+/*****************************************************************************************************************/
+
+// Observe returns the altitude, azimuth, hour angle, airmass and parallactic angle of the equatorial coordinate, as
+// seen by the observer at the (UTC) time, where UT1 is taken to be UTC, i.e., to within ~15 arcseconds of hour angle.
+func (c ICRSEquatorialCoordinate) Observe(observer Observer, datetime time.Time) Observation {
+	datetime = datetime.UTC()
+
+	apparent := c.ToApparent(ApparentParams{Datetime: datetime, Deflection: true})
+
+	// The local hour angle of the apparent place, in [-180, 180):
+	ha := math.Mod(GetLocalApparentSiderealTime(datetime, observer.Longitude)-apparent.RA+540, 360) - 180
+
+	degree := math.Pi / 180
+
+	sinH, cosH := math.Sincos(ha * degree)
+	sinD, cosD := math.Sincos(apparent.Dec * degree)
+	sinP, cosP := math.Sincos(observer.Latitude * degree)
+
+	altitude := math.Asin(math.Max(-1, math.Min(1, sinP*sinD+cosP*cosD*cosH))) / degree
+
+	azimuth := math.Mod(math.Atan2(-cosD*sinH, sinD*cosP-cosD*sinP*cosH)/degree+360, 360)
+
+	// The parallactic angle, i.e., the position angle of the zenith, from the north through the east:
+	q := math.Atan2(cosP*sinH, sinP*cosD-cosP*sinD*cosH) / degree
+
+	return Observation{
+		Datetime:         datetime,
+		Altitude:         altitude,
+		Azimuth:          azimuth,
+		HourAngle:        ha,
+		Airmass:          GetAirmass(altitude),
+		ParallacticAngle: q,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// GetLocalApparentSiderealTime returns the local apparent sidereal time, in degrees in [0, 360), at the (UTC) time
+// and the (east positive) longitude, in degrees, from the Earth rotation angle and the IAU 2006 Greenwich mean
+// sidereal time, plus the equation of the equinoxes.
+func GetLocalApparentSiderealTime(datetime time.Time, longitude float64) float64 {
+	jd := epoch.GetJulianDate(datetime.UTC())
+
+	t := getJulianCenturies(datetime)
+
+	// The Earth rotation angle, in degrees (IERS Conventions 2010, eq. 5.15), which separates the whole days from the
+	// fraction of the day to retain its precision:
+	du := jd - epoch.J2000
+
+	era := 360 * (math.Mod(du, 1) + 0.7790572732640 + 0.00273781191135448*du)
+
+	// The Greenwich mean sidereal time (IERS Conventions 2010, eq. 5.32), in degrees:
+	gmst := era + (0.014506+t*(4612.156534+t*(1.3915817+t*(-0.00000044+t*(-0.000029956+t*-0.0000000368)))))/3600
+
+	// The equation of the equinoxes, Δψ·cos(εA):
+	dpsi, _ := getNutation(t)
+
+	gast := gmst + dpsi*math.Cos(getMeanObliquity(t)*arcsecond)/3600
+
+	return math.Mod(math.Mod(gast+longitude, 360)+360, 360)
+}
+
+/*****************************************************************************************************************/
+
+// GetAirmass returns the relative airmass of the geometric altitude, in degrees, from the formula of Kasten & Young
+// (1989), which remains finite at the horizon, where an altitude below the horizon is taken to be on the horizon.
+func GetAirmass(altitude float64) float64 {
+	altitude = math.Max(altitude, 0)
+
+	return 1 / (math.Sin(altitude*math.Pi/180) + 0.50572*math.Pow(altitude+6.07995, -1.6364))
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,127 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+/*****************************************************************************************************************/
+
+func TestGetLocalApparentSiderealTime(t *testing.T) {
+	// The apparent sidereal time at Greenwich of 1987 April 10, 0h UT is 13h10m46.1351s (Meeus 1998, Example 12.a):
+	expected := (13 + 10.0/60 + 46.1351/3600) * 15
+
+	got := GetLocalApparentSiderealTime(time.Date(1987, 4, 10, 0, 0, 0, 0, time.UTC), 0)
+
+	// To within the millisecond of time of the difference between the IAU 1982 and 2006 models:
+	if math.Abs(got-expected) > 0.01/3600*15 {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// The local sidereal time is ahead of Greenwich by the (east positive) longitude:
+	if local := GetLocalApparentSiderealTime(time.Date(1987, 4, 10, 0, 0, 0, 0, time.UTC), -77.065556); math.Abs(math.Mod(got-local+360, 360)-77.065556) > 1e-9 {
+		t.Errorf("expected the local sidereal time 77.065556° behind Greenwich, got %v", local)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestObserve(t *testing.T) {
+	// Venus from the US Naval Observatory, on 1987 April 10 at 19:21:00 UT (Meeus 1998, Example 13.b), where the
+	// apparent place is converted back to its astrometric place:
+	observer := Observer{Latitude: 38 + 55.0/60 + 17.0/3600, Longitude: -(77 + 3.0/60 + 56.0/3600)}
+
+	datetime := time.Date(1987, 4, 10, 19, 21, 0, 0, time.UTC)
+
+	venus := ApparentEquatorialCoordinate{
+		RA:  (23 + 9.0/60 + 16.641/3600) * 15,
+		Dec: -(6 + 43.0/60 + 11.61/3600),
+	}.ToICRS(ApparentParams{Datetime: datetime, Deflection: true})
+
+	o := venus.Observe(observer, datetime)
+
+	// The hour angle of 64.352133°, altitude of 15.1249° and azimuth of 68.0337° west of south:
+	if math.Abs(o.HourAngle-64.352133) > 1e-3 || math.Abs(o.Altitude-15.1249) > 1e-3 || math.Abs(o.Azimuth-248.0337) > 1e-3 {
+		t.Errorf("expected (H, h, A) = (64.352133, 15.1249, 248.0337), got (%v, %v, %v)", o.HourAngle, o.Altitude, o.Azimuth)
+	}
+
+	// Setting in the west, the zenith is to the west of the north of Venus:
+	if o.ParallacticAngle <= 0 || o.ParallacticAngle >= 180 {
+		t.Errorf("expected a positive parallactic angle, got %v", o.ParallacticAngle)
+	}
+
+	if o.Airmass < 3.5 || o.Airmass > 4 {
+		t.Errorf("expected an airmass of ~3.8 at an altitude of 15°, got %v", o.Airmass)
+	}
+
+	horizontal := venus.ToHorizontal(observer, datetime)
+
+	if horizontal.Altitude != o.Altitude || horizontal.Azimuth != o.Azimuth {
+		t.Errorf("expected (%v, %v), got (%v, %v)", o.Altitude, o.Azimuth, horizontal.Altitude, horizontal.Azimuth)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestParallacticAngleOnTheMeridian(t *testing.T) {
+	observer := Observer{Latitude: 52}
+
+	datetime := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	lst := GetLocalApparentSiderealTime(datetime, observer.Longitude)
+
+	// On the meridian, the zenith is to the north of a coordinate south of the zenith, and to its south otherwise:
+	for _, tt := range []struct {
+		dec      float64
+		expected float64
+	}{
+		{dec: 20, expected: 0},
+		{dec: 70, expected: 180},
+	} {
+		apparent := ApparentEquatorialCoordinate{RA: lst, Dec: tt.dec}.ToICRS(ApparentParams{Datetime: datetime, Deflection: true})
+
+		o := apparent.Observe(observer, datetime)
+
+		if math.Abs(o.HourAngle) > 1e-6 || math.Abs(math.Mod(o.ParallacticAngle-tt.expected+540, 360)-180) > 1e-4 {
+			t.Errorf("dec %v: expected a parallactic angle of %v on the meridian, got %v (H = %v)", tt.dec, tt.expected, o.ParallacticAngle, o.HourAngle)
+		}
+
+		if math.Abs(o.Altitude-(90-math.Abs(observer.Latitude-tt.dec))) > 1e-6 {
+			t.Errorf("dec %v: expected an altitude of %v on the meridian, got %v", tt.dec, 90-math.Abs(observer.Latitude-tt.dec), o.Altitude)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetAirmass(t *testing.T) {
+	tests := []struct {
+		altitude float64
+		expected float64
+	}{
+		{altitude: 90, expected: 1},
+		{altitude: 30, expected: 1.9954},
+		{altitude: 10, expected: 5.6},
+		{altitude: 0, expected: 38.09},
+		{altitude: -5, expected: 38.09},
+	}
+
+	for _, tt := range tests {
+		if got := GetAirmass(tt.altitude); math.Abs(got-tt.expected)/tt.expected > 0.01 {
+			t.Errorf("altitude %v: expected an airmass of %v, got %v", tt.altitude, tt.expected, got)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
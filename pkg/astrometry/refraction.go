@@ -0,0 +1,217 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/*****************************************************************************************************************/
+
+// The standard atmospheric pressure at sea level, in hPa:
+const STANDARD_PRESSURE = 1013.25
+
+/*****************************************************************************************************************/
+
+// The standard ambient temperature of the refraction formula, in degrees Celsius:
+const STANDARD_TEMPERATURE = 10.0
+
+/*****************************************************************************************************************/
+
+// The number of iterations when removing the refraction of an observed place, each of which reduces the error by the
+// rate of change of the refraction with altitude, i.e., by a factor of ~10² at an altitude of 10°:
+const REFRACTION_ITERATIONS = 5
+
+/*****************************************************************************************************************/
+
+// RefractionParams describes the atmosphere of the observer at the time of an observation.
+type RefractionParams struct {
+	Observer    Observer  // The observing site
+	Datetime    time.Time // The (UTC) time of the observation
+	Temperature float64   // The ambient temperature, in degrees Celsius
+	Pressure    float64   // The atmospheric pressure, in hPa, where zero is the standard pressure at the elevation
+}
+
+/*****************************************************************************************************************/
+
+// Refractor displaces coordinates between their astrometric (ICRS) place and their observed place, i.e., as refracted
+// towards the zenith by the atmosphere, where the observed place is expressed in the axes of the ICRS, such that only
+// the (differential) atmospheric refraction separates the two.
+type Refractor struct {
+	zenith [3]float64 // The unit vector of the zenith of the observer, in the axes of the ICRS
+	beta   [3]float64 // The velocity of the Earth over the speed of light, in the axes of the ICRS
+	scale  float64    // The scale of the refraction of the standard atmosphere for the temperature and pressure
+}
+
+/*****************************************************************************************************************/
+
+// NewRefractor returns the refractor of the observer, at the time and in the atmosphere of the params.
+func NewRefractor(params RefractionParams) (*Refractor, error) {
+	if params.Observer.Latitude < -90 || params.Observer.Latitude > 90 {
+		return nil, fmt.Errorf("invalid observer latitude: %v", params.Observer.Latitude)
+	}
+
+	if params.Temperature <= -273.15 {
+		return nil, fmt.Errorf("invalid temperature: %v°C", params.Temperature)
+	}
+
+	if params.Pressure < 0 {
+		return nil, fmt.Errorf("invalid pressure: %v hPa", params.Pressure)
+	}
+
+	pressure := params.Pressure
+
+	if pressure == 0 {
+		pressure = GetStandardPressure(params.Observer.Elevation)
+	}
+
+	t := getJulianCenturies(params.Datetime)
+
+	beta, _, _ := getEarthVelocityAndSun(t)
+
+	// The zenith is at the declination of the latitude, on the meridian of the local apparent sidereal time:
+	lst := GetLocalApparentSiderealTime(params.Datetime, params.Observer.Longitude)
+
+	zenith := apply(transpose(getBiasPrecessionNutationMatrix(t)), toCartesian(lst, params.Observer.Latitude))
+
+	return &Refractor{
+		zenith: zenith,
+		beta:   beta,
+		scale:  getRefractionScale(params.Temperature, pressure),
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// GetAltitude returns the geometric (unrefracted) altitude of the coordinate above the horizon, in degrees.
+func (r *Refractor) GetAltitude(c ICRSEquatorialCoordinate) float64 {
+	return r.getAltitude(toCartesian(c.RA, c.Dec))
+}
+
+/*****************************************************************************************************************/
+
+// GetRefraction returns the atmospheric refraction of the coordinate, in degrees, towards the zenith.
+func (r *Refractor) GetRefraction(c ICRSEquatorialCoordinate) float64 {
+	return r.getRefraction(toCartesian(c.RA, c.Dec))
+}
+
+/*****************************************************************************************************************/
+
+// ToObserved displaces the astrometric coordinate towards the zenith by the atmospheric refraction at its altitude.
+func (r *Refractor) ToObserved(c ICRSEquatorialCoordinate) ICRSEquatorialCoordinate {
+	p := toCartesian(c.RA, c.Dec)
+
+	ra, dec := toSpherical(r.displace(p, r.getRefraction(p)))
+
+	return ICRSEquatorialCoordinate{RA: ra, Dec: dec}
+}
+
+/*****************************************************************************************************************/
+
+// ToAstrometric displaces the observed coordinate away from the zenith by the atmospheric refraction at its geometric
+// altitude, by iteratively inverting ToObserved.
+func (r *Refractor) ToAstrometric(c ICRSEquatorialCoordinate) ICRSEquatorialCoordinate {
+	observed := toCartesian(c.RA, c.Dec)
+
+	p := observed
+
+	for iteration := 0; iteration < REFRACTION_ITERATIONS; iteration++ {
+		p = r.displace(observed, -r.getRefraction(p))
+	}
+
+	ra, dec := toSpherical(p)
+
+	return ICRSEquatorialCoordinate{RA: ra, Dec: dec}
+}
+
+/*****************************************************************************************************************/
+
+// GetRefraction returns the atmospheric refraction, in degrees, of the geometric altitude, in degrees, at the ambient
+// temperature, in degrees Celsius, and atmospheric pressure, in hPa, from the formula of Sæmundsson (1986), corrected
+// to vanish at the zenith, where an altitude more than 1° below the horizon is taken to be at 1° below the horizon.
+func GetRefraction(altitude, temperature, pressure float64) float64 {
+	return getRefractionOfStandardAtmosphere(altitude) * getRefractionScale(temperature, pressure)
+}
+
+/*****************************************************************************************************************/
+
+// GetStandardPressure returns the atmospheric pressure, in hPa, of the International Standard Atmosphere at the
+// elevation above sea level, in metres.
+func GetStandardPressure(elevation float64) float64 {
+	return STANDARD_PRESSURE * math.Pow(1-2.25577e-5*elevation, 5.25588)
+}
+
+/*****************************************************************************************************************/
+
+// getAltitude returns the geometric altitude, in degrees, of the unit vector of the astrometric place, from its
+// (aberrated) apparent direction.
+func (r *Refractor) getAltitude(p [3]float64) float64 {
+	sinh := dot(aberrate(p, r.beta), r.zenith)
+
+	return math.Asin(math.Max(-1, math.Min(1, sinh))) * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
+
+// getRefraction returns the atmospheric refraction, in degrees, of the unit vector of the astrometric place.
+func (r *Refractor) getRefraction(p [3]float64) float64 {
+	return getRefractionOfStandardAtmosphere(r.getAltitude(p)) * r.scale
+}
+
+/*****************************************************************************************************************/
+
+// displace rotates the unit vector towards the zenith, along its vertical circle, by the angle in degrees, where a
+// negative angle rotates it away from the zenith.
+func (r *Refractor) displace(p [3]float64, angle float64) [3]float64 {
+	pz := dot(p, r.zenith)
+
+	// The unit tangent towards the zenith, which is undefined at the zenith itself, where the refraction vanishes:
+	t := [3]float64{r.zenith[0] - pz*p[0], r.zenith[1] - pz*p[1], r.zenith[2] - pz*p[2]}
+
+	n := math.Sqrt(dot(t, t))
+
+	if n < 1e-12 {
+		return p
+	}
+
+	s, c := math.Sincos(angle * math.Pi / 180)
+
+	return normalise([3]float64{
+		c*p[0] + s*t[0]/n,
+		c*p[1] + s*t[1]/n,
+		c*p[2] + s*t[2]/n,
+	})
+}
+
+/*****************************************************************************************************************/
+
+// getRefractionOfStandardAtmosphere returns the refraction, in degrees, of the geometric altitude, in degrees, for
+// the pressure of 1010 hPa and temperature of 10°C of the formula of Sæmundsson (1986).
+func getRefractionOfStandardAtmosphere(altitude float64) float64 {
+	altitude = math.Max(altitude, -1)
+
+	// The refraction, in arcminutes, with the correction of 0.0019279' such that it vanishes at the zenith:
+	r := 1.02/math.Tan((altitude+10.3/(altitude+5.11))*math.Pi/180) + 0.0019279
+
+	return r / 60
+}
+
+/*****************************************************************************************************************/
+
+// getRefractionScale returns the scale of the refraction of the standard atmosphere of the formula of Sæmundsson
+// (1986) at the ambient temperature, in degrees Celsius, and the atmospheric pressure, in hPa.
+func getRefractionScale(temperature, pressure float64) float64 {
+	return (pressure / 1010) * (283 / (273 + temperature))
+}
+
+/*****************************************************************************************************************/
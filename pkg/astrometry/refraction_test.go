@@ -0,0 +1,98 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+/*****************************************************************************************************************/
+
+func TestGetRefraction(t *testing.T) {
+	tests := []struct {
+		altitude    float64
+		temperature float64
+		pressure    float64
+		expected    float64 // arcminutes
+		tolerance   float64 // arcminutes
+	}{
+		// The refraction vanishes at the zenith, and is ~1' at 45° and ~29' at a geometric altitude of 0°:
+		{altitude: 90, temperature: 10, pressure: 1010, expected: 0, tolerance: 1e-6},
+		{altitude: 45, temperature: 10, pressure: 1010, expected: 1.015, tolerance: 0.002},
+		{altitude: 0, temperature: 10, pressure: 1010, expected: 28.98, tolerance: 0.05},
+		// The refraction scales with the density of the atmosphere:
+		{altitude: 45, temperature: 10, pressure: 505, expected: 0.5073, tolerance: 0.001},
+		{altitude: 45, temperature: -10, pressure: 1010, expected: 1.015 * 283 / 263, tolerance: 0.002},
+	}
+
+	for _, tt := range tests {
+		if got := GetRefraction(tt.altitude, tt.temperature, tt.pressure) * 60; math.Abs(got-tt.expected) > tt.tolerance {
+			t.Errorf("altitude %v: expected %v', got %v'", tt.altitude, tt.expected, got)
+		}
+	}
+
+	if p := GetStandardPressure(2000); math.Abs(p-795) > 1 {
+		t.Errorf("expected a standard pressure of ~795 hPa at 2000 m, got %v", p)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRefractor(t *testing.T) {
+	datetime := time.Date(2025, 3, 20, 21, 30, 0, 0, time.UTC)
+
+	observer := Observer{Latitude: 52.21, Longitude: -0.12, Elevation: 25}
+
+	r, err := NewRefractor(RefractionParams{Observer: observer, Datetime: datetime, Temperature: 5, Pressure: 1000})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	lst := GetLocalApparentSiderealTime(datetime, observer.Longitude)
+
+	// Coordinates on the meridian and the prime vertical, from the zenith to near the horizon:
+	for _, c := range []ICRSEquatorialCoordinate{
+		{RA: lst, Dec: 52.21},
+		{RA: lst, Dec: 20},
+		{RA: lst, Dec: -30},
+		{RA: math.Mod(lst+80, 360), Dec: 5},
+		{RA: math.Mod(lst+300, 360), Dec: 45},
+	} {
+		altitude := r.GetAltitude(c)
+
+		// The geometric altitude agrees with the altitude of the observation:
+		if o := c.Observe(observer, datetime); math.Abs(o.Altitude-altitude) > 1e-3 {
+			t.Errorf("(%v, %v): expected an altitude of %v, got %v", c.RA, c.Dec, o.Altitude, altitude)
+		}
+
+		observed := r.ToObserved(c)
+
+		// The observed place is raised towards the zenith by the refraction, to within the ~10⁻⁴ (relative) distortion of
+		// the aberration between the axes of the ICRS and the apparent place:
+		if raised := r.GetAltitude(observed) - altitude; math.Abs(raised-GetRefraction(altitude, 5, 1000)) > 2e-4*raised {
+			t.Errorf("(%v, %v): expected a refraction of %v°, got %v°", c.RA, c.Dec, GetRefraction(altitude, 5, 1000), raised)
+		}
+
+		astrometric := r.ToAstrometric(observed)
+
+		if math.Abs(math.Mod(astrometric.RA-c.RA+540, 360)-180)*math.Cos(c.Dec*math.Pi/180) > 1e-9 || math.Abs(astrometric.Dec-c.Dec) > 1e-9 {
+			t.Errorf("expected (%v, %v), got (%v, %v)", c.RA, c.Dec, astrometric.RA, astrometric.Dec)
+		}
+	}
+
+	if _, err := NewRefractor(RefractionParams{Observer: observer, Datetime: datetime, Temperature: -300}); err == nil {
+		t.Errorf("expected an error for a temperature below absolute zero")
+	}
+}
+
+/*****************************************************************************************************************/
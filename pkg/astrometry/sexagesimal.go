@@ -0,0 +1,124 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// ParseSexagesimal parses a decimal or sexagesimal value, e.g., "+52 12 34.5", "-52:12:34.5", "52d12m34.5s" or
+// "12h30m00s", returning the value in the units of its leading component, i.e., degrees or hours, where the sign of
+// the leading component applies to the whole value, including "-00 30 00".
+func ParseSexagesimal(value string) (float64, error) {
+	s := strings.TrimSpace(value)
+
+	sign := 1.0
+
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = -1
+		}
+
+		s = strings.TrimSpace(s[1:])
+	}
+
+	// Separate the components on any of the common delimiters of degrees, hours, minutes and seconds:
+	components := strings.FieldsFunc(s, func(r rune) bool {
+		return strings.ContainsRune(" :dhms°'\"′″", r)
+	})
+
+	if len(components) == 0 || len(components) > 3 {
+		return 0, fmt.Errorf("invalid sexagesimal value: %q", value)
+	}
+
+	v := 0.0
+
+	scale := 1.0
+
+	for i, component := range components {
+		c, err := strconv.ParseFloat(component, 64)
+		if err != nil || c < 0 || (i > 0 && c >= 60) {
+			return 0, fmt.Errorf("invalid sexagesimal value: %q", value)
+		}
+
+		v += c / scale
+
+		scale *= 60
+	}
+
+	return sign * v, nil
+}
+
+/*****************************************************************************************************************/
+
+// ParseRightAscension parses a right ascension, returning it in degrees, where a sexagesimal value, e.g., "05 35 17.3"
+// or "05:35:17.3", is in hours unless it is marked in degrees, e.g., "83d49m20s", a value marked with "h", e.g.,
+// "5.588h" or "05h35m17.3s", is in hours, and an unmarked decimal value, e.g., "83.82", is in degrees.
+func ParseRightAscension(value string) (float64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+
+	hours := false
+
+	switch {
+	case strings.HasSuffix(s, "deg"):
+		s = strings.TrimSpace(strings.TrimSuffix(s, "deg"))
+	case strings.ContainsRune(s, 'h'):
+		hours = true
+	case strings.ContainsAny(s, "d°"):
+	default:
+		// A sexagesimal value without units follows the convention of hours, minutes and seconds:
+		hours = len(strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ':' })) > 1
+	}
+
+	v, err := ParseSexagesimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid right ascension: %q", value)
+	}
+
+	if hours {
+		if v < 0 || v > 24 {
+			return 0, fmt.Errorf("right ascension is out of range: %vh", v)
+		}
+
+		return v * 15, nil
+	}
+
+	if v < 0 || v > 360 {
+		return 0, fmt.Errorf("right ascension is out of range: %v°", v)
+	}
+
+	return v, nil
+}
+
+/*****************************************************************************************************************/
+
+// ParseDeclination parses a declination in decimal or sexagesimal degrees, e.g., "-5.391", "-05 23 28" or
+// "-05d23m28s", returning it in degrees.
+func ParseDeclination(value string) (float64, error) {
+	s := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), "deg")
+
+	v, err := ParseSexagesimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid declination: %q", value)
+	}
+
+	if v < -90 || v > 90 {
+		return 0, fmt.Errorf("declination is out of range: %v°", v)
+	}
+
+	return v, nil
+}
+
+/*****************************************************************************************************************/
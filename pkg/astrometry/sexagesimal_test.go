@@ -0,0 +1,110 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package astrometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestParseSexagesimal(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		{"52.21", 52.21},
+		{"+52 12 36", 52.21},
+		{"-52:12:36", -52.21},
+		{"-00 30 00", -0.5},
+		{"52d12m36s", 52.21},
+		{"12h30m", 12.5},
+		{"12°30′36″", 12.51},
+		{" 05 34 31.94 ", 5 + 34.0/60 + 31.94/3600},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSexagesimal(tt.value)
+		if err != nil || math.Abs(got-tt.expected) > 1e-12 {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.value, tt.expected, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "north", "12 60 00", "1 2 3 4", "12 -30"} {
+		if _, err := ParseSexagesimal(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestParseRightAscension(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		// Unmarked decimal values are in degrees, and unmarked sexagesimal values are in hours:
+		{"83.82", 83.82},
+		{"05 35 17.3", (5 + 35.0/60 + 17.3/3600) * 15},
+		{"05:35:17.3", (5 + 35.0/60 + 17.3/3600) * 15},
+		{"05h35m17.3s", (5 + 35.0/60 + 17.3/3600) * 15},
+		{"5.588h", 5.588 * 15},
+		{"83d49m20s", 83 + 49.0/60 + 20.0/3600},
+		{"83.82deg", 83.82},
+		{"83.82°", 83.82},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRightAscension(tt.value)
+		if err != nil || math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.value, tt.expected, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "-5.5", "25h", "25 00 00", "361", "ra"} {
+		if _, err := ParseRightAscension(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestParseDeclination(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected float64
+	}{
+		{"-5.391", -5.391},
+		{"-05 23 28", -(5 + 23.0/60 + 28.0/3600)},
+		{"-05:23:28", -(5 + 23.0/60 + 28.0/3600)},
+		{"+22d00m52s", 22 + 52.0/3600},
+		{"-0 30 00", -0.5},
+		{"41.27deg", 41.27},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDeclination(tt.value)
+		if err != nil || math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.value, tt.expected, got, err)
+		}
+	}
+
+	for _, value := range []string{"", "91", "-90 30 00", "dec"} {
+		if _, err := ParseDeclination(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,319 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package bayer
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// Channel is the colour of a single photosite of the colour filter array.
+type Channel int
+
+/*****************************************************************************************************************/
+
+const (
+	Red Channel = iota
+	Green
+	Blue
+)
+
+/*****************************************************************************************************************/
+
+// Pattern is the 2x2 colour filter array of a one-shot-colour (OSC) sensor, given as the channels of the pixels
+// (0, 0), (1, 0), (0, 1) and (1, 1), in that order, e.g., "RGGB".
+type Pattern [4]Channel
+
+/*****************************************************************************************************************/
+
+// ParsePattern parses a Bayer pattern, e.g., the BAYERPAT header of "RGGB", "BGGR", "GRBG" or "GBRG".
+func ParsePattern(s string) (Pattern, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	if len(s) != 4 {
+		return Pattern{}, fmt.Errorf("invalid Bayer pattern: %q", s)
+	}
+
+	p := Pattern{}
+
+	reds, greens, blues := 0, 0, 0
+
+	for i, c := range s {
+		switch c {
+		case 'R':
+			p[i] = Red
+			reds++
+		case 'G':
+			p[i] = Green
+			greens++
+		case 'B':
+			p[i] = Blue
+			blues++
+		default:
+			return Pattern{}, fmt.Errorf("invalid Bayer pattern: %q", s)
+		}
+	}
+
+	if reds != 1 || greens != 2 || blues != 1 {
+		return Pattern{}, fmt.Errorf("invalid Bayer pattern: %q", s)
+	}
+
+	return p, nil
+}
+
+/*****************************************************************************************************************/
+
+// String returns the Bayer pattern as a string, e.g., "RGGB".
+func (p Pattern) String() string {
+	var b strings.Builder
+
+	for _, c := range p {
+		b.WriteByte("RGB"[c])
+	}
+
+	return b.String()
+}
+
+/*****************************************************************************************************************/
+
+// At returns the channel of the photosite at the pixel (x, y).
+func (p Pattern) At(x int, y int) Channel {
+	return p[(y&1)*2+(x&1)]
+}
+
+/*****************************************************************************************************************/
+
+// Offset returns the pattern as seen from the pixel (x, y) of the original pattern, e.g., applying the XBAYROFF
+// and YBAYROFF headers of a sub-frame whose origin is not aligned with the colour filter array.
+func (p Pattern) Offset(x int, y int) Pattern {
+	return Pattern{
+		p.At(x, y),
+		p.At(x+1, y),
+		p.At(x, y+1),
+		p.At(x+1, y+1),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Mode is the method used to produce a single monochrome plane from the Bayer mosaic for star extraction.
+type Mode int
+
+/*****************************************************************************************************************/
+
+const (
+	ModeNone       Mode = iota // the raw mosaic is used as is
+	ModeLuminance              // bilinear demosaicing into a full resolution luminance image
+	ModeSuperpixel             // each 2x2 cell of the mosaic is combined into a single, half resolution, pixel
+)
+
+// Superpixels are faster and less noisy than luminance demosaicing, but shift the centroids of strongly coloured
+// stars by up to a quarter of an original pixel towards the photosite of their dominant channel.
+
+/*****************************************************************************************************************/
+
+// String returns the name of the debayering mode, e.g., "superpixel".
+func (m Mode) String() string {
+	switch m {
+	case ModeLuminance:
+		return "luminance"
+	case ModeSuperpixel:
+		return "superpixel"
+	default:
+		return "none"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// ParseMode resolves the name of a debayering mode, e.g., "none", "luminance" or "superpixel".
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(name) {
+	case "none":
+		return ModeNone, nil
+	case "", "luminance":
+		return ModeLuminance, nil
+	case "superpixel":
+		return ModeSuperpixel, nil
+	default:
+		return ModeNone, fmt.Errorf("unknown debayering mode: %s", name)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Params are the parameters of the debayering of a one-shot-colour image.
+type Params struct {
+	Pattern Pattern // the Bayer pattern of the image, with any XBAYROFF and YBAYROFF offsets already applied
+	Mode    Mode    // the debayering mode
+}
+
+/*****************************************************************************************************************/
+
+// Image is a monochrome plane produced from a Bayer mosaic, along with the mapping back to the original pixels.
+type Image struct {
+	Data   []float32 // the luminance of each pixel
+	Peak   []float32 // the maximum raw photosite value contributing to each pixel, e.g., to detect saturation
+	Width  int       // the width of the plane (in pixels)
+	Height int       // the height of the plane (in pixels)
+	Scale  float64   // the size of each pixel of the plane, in original pixels
+	Offset float64   // the original pixel coordinate of the centre of the pixel (0, 0) of the plane
+}
+
+/*****************************************************************************************************************/
+
+// ToOriginal maps the pixel coordinate (x, y) of the plane onto the pixel coordinate of the original mosaic.
+func (i *Image) ToOriginal(x float64, y float64) (float64, float64) {
+	return x*i.Scale + i.Offset, y*i.Scale + i.Offset
+}
+
+/*****************************************************************************************************************/
+
+// Debayer produces a monochrome plane from the raw Bayer mosaic, suitable for star extraction. Extracting stars
+// directly from the mosaic biases the centroids towards the brightest colour channel of each star, and corrupts
+// the shape of the PSF with the 2x2 modulation of the colour filter array.
+func Debayer(data []float32, width int, height int, params Params) (*Image, error) {
+	if width <= 0 || height <= 0 || len(data) != width*height {
+		return nil, fmt.Errorf("invalid image dimensions for debayering: %dx%d", width, height)
+	}
+
+	switch params.Mode {
+	case ModeNone:
+		peak := make([]float32, len(data))
+		copy(peak, data)
+
+		return &Image{
+			Data:   data,
+			Peak:   peak,
+			Width:  width,
+			Height: height,
+			Scale:  1,
+		}, nil
+	case ModeLuminance:
+		return luminance(data, width, height, params.Pattern), nil
+	case ModeSuperpixel:
+		if width < 2 || height < 2 {
+			return nil, fmt.Errorf("image of %dx%d is too small for superpixel debayering", width, height)
+		}
+
+		return superpixel(data, width, height), nil
+	default:
+		return nil, fmt.Errorf("unknown debayering mode: %d", params.Mode)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// luminance bilinearly interpolates each colour channel at every pixel, from the photosites of that channel in the
+// surrounding 3x3 neighbourhood, and combines them into a luminance of (R + 2G + B) / 4.
+func luminance(data []float32, width int, height int, pattern Pattern) *Image {
+	out := make([]float32, width*height)
+
+	peak := make([]float32, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sums := [3]float64{}
+			counts := [3]int{}
+
+			own := pattern.At(x, y)
+
+			p := float32(math.Inf(-1))
+
+			for j := max(y-1, 0); j <= min(y+1, height-1); j++ {
+				for i := max(x-1, 0); i <= min(x+1, width-1); i++ {
+					v := data[j*width+i]
+
+					p = max(p, v)
+
+					c := pattern.At(i, j)
+
+					// The channel of the photosite itself is known exactly, and is not interpolated:
+					if c == own && (i != x || j != y) {
+						continue
+					}
+
+					sums[c] += float64(v)
+					counts[c]++
+				}
+			}
+
+			rgb := [3]float64{}
+
+			for c := range rgb {
+				if counts[c] > 0 {
+					rgb[c] = sums[c] / float64(counts[c])
+				}
+			}
+
+			out[y*width+x] = float32((rgb[Red] + 2*rgb[Green] + rgb[Blue]) / 4)
+
+			peak[y*width+x] = p
+		}
+	}
+
+	return &Image{
+		Data:   out,
+		Peak:   peak,
+		Width:  width,
+		Height: height,
+		Scale:  1,
+		Offset: 0,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// superpixel combines each 2x2 cell of the mosaic, i.e., one red, two green and one blue photosite, into a single
+// pixel of (R + 2G + B) / 4, centred at the middle of the cell. As every cell holds the same photosites, the
+// pattern itself is not required.
+func superpixel(data []float32, width int, height int) *Image {
+	w, h := width/2, height/2
+
+	out := make([]float32, w*h)
+
+	peak := make([]float32, w*h)
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum := float32(0)
+
+			p := float32(math.Inf(-1))
+
+			// Each 2x2 cell contains exactly one red, two green and one blue photosite:
+			for _, o := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				v := data[(2*y+o[1])*width+2*x+o[0]]
+
+				sum += v
+
+				p = max(p, v)
+			}
+
+			out[y*w+x] = sum / 4
+
+			peak[y*w+x] = p
+		}
+	}
+
+	return &Image{
+		Data:   out,
+		Peak:   peak,
+		Width:  w,
+		Height: h,
+		Scale:  2,
+		Offset: 0.5,
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,159 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package bayer
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// newMosaic renders a star with the given response of each channel onto a Bayer mosaic of the given pattern.
+func newMosaic(width int, height int, pattern Pattern, x0 float64, y0 float64, fwhm float64, response map[Channel]float64) []float32 {
+	data := make([]float32, width*height)
+
+	sigma := fwhm / (2 * math.Sqrt(2*math.Ln2))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := float64(x)-x0, float64(y)-y0
+
+			v := 10000 * math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma))
+
+			data[y*width+x] = float32(100 + v*response[pattern.At(x, y)])
+		}
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+// centroid computes the background subtracted, intensity weighted centroid within a window around (x, y).
+func centroid(data []float32, width int, x int, y int, radius int) (float64, float64) {
+	sx, sy, sum := 0.0, 0.0, 0.0
+
+	for j := y - radius; j <= y+radius; j++ {
+		for i := x - radius; i <= x+radius; i++ {
+			v := math.Max(float64(data[j*width+i])-100, 0)
+
+			sx += v * float64(i)
+			sy += v * float64(j)
+			sum += v
+		}
+	}
+
+	return sx / sum, sy / sum
+}
+
+/*****************************************************************************************************************/
+
+func TestParsePattern(t *testing.T) {
+	p, err := ParsePattern("rggb")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if p.At(0, 0) != Red || p.At(1, 0) != Green || p.At(0, 1) != Green || p.At(1, 1) != Blue || p.At(3, 3) != Blue {
+		t.Errorf("unexpected channels for %s", p)
+	}
+
+	// An odd XBAYROFF swaps the columns of the pattern, and an odd YBAYROFF swaps the rows:
+	if got := p.Offset(1, 0).String(); got != "GRBG" {
+		t.Errorf("expected GRBG, got %s", got)
+	}
+
+	if got := p.Offset(1, 1).String(); got != "BGGR" {
+		t.Errorf("expected BGGR, got %s", got)
+	}
+
+	for _, invalid := range []string{"RGB", "RRGB", "RGGX"} {
+		if _, err := ParsePattern(invalid); err == nil {
+			t.Errorf("expected an error for the pattern %q", invalid)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDebayerLuminance(t *testing.T) {
+	pattern, _ := ParsePattern("RGGB")
+
+	width, height := 64, 64
+
+	x0, y0 := 31.3, 30.8
+
+	// A red star, i.e., bright in red and faint in blue:
+	mosaic := newMosaic(width, height, pattern, x0, y0, 3, map[Channel]float64{Red: 1, Green: 0.5, Blue: 0.2})
+
+	image, err := Debayer(mosaic, width, height, Params{Pattern: pattern, Mode: ModeLuminance})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if image.Width != width || image.Height != height || image.Scale != 1 {
+		t.Fatalf("expected a full resolution plane, got %dx%d at scale %v", image.Width, image.Height, image.Scale)
+	}
+
+	x, y := centroid(image.Data, image.Width, 31, 31, 6)
+
+	x, y = image.ToOriginal(x, y)
+
+	if math.Hypot(x-x0, y-y0) > 0.05 {
+		t.Errorf("expected a centroid of (%v, %v), got (%v, %v)", x0, y0, x, y)
+	}
+
+	// The peak plane retains the raw photosite values, e.g., to detect saturation of a single channel:
+	peak := float32(0)
+
+	for j := 30; j <= 32; j++ {
+		for i := 30; i <= 32; i++ {
+			peak = max(peak, mosaic[j*width+i])
+		}
+	}
+
+	if image.Peak[31*width+31] != peak {
+		t.Errorf("expected the peak to retain the brightest raw photosite of %v, got %v", peak, image.Peak[31*width+31])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDebayerSuperpixel(t *testing.T) {
+	pattern, _ := ParsePattern("GBRG")
+
+	width, height := 64, 64
+
+	x0, y0 := 30.6, 33.2
+
+	// A neutral star, as superpixels shift the centroids of strongly coloured stars towards their dominant channel:
+	mosaic := newMosaic(width, height, pattern, x0, y0, 4, map[Channel]float64{Red: 1, Green: 1, Blue: 1})
+
+	image, err := Debayer(mosaic, width, height, Params{Pattern: pattern, Mode: ModeSuperpixel})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if image.Width != width/2 || image.Height != height/2 || image.Scale != 2 {
+		t.Fatalf("expected a half resolution plane, got %dx%d at scale %v", image.Width, image.Height, image.Scale)
+	}
+
+	x, y := centroid(image.Data, image.Width, 15, 16, 4)
+
+	x, y = image.ToOriginal(x, y)
+
+	if math.Hypot(x-x0, y-y0) > 0.05 {
+		t.Errorf("expected a centroid of (%v, %v), got (%v, %v)", x0, y0, x, y)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -196,3 +196,58 @@ func (s *SIMBADServiceClient) PerformRadialSearch(eq astrometry.ICRSEquatorialCo
 }
 
 /*****************************************************************************************************************/
+
+// ResolveIdentifier returns the target of the identifier, e.g., "M 42" or "NGC 7000", from the identifiers of SIMBAD.
+func (s *SIMBADServiceClient) ResolveIdentifier(identifier string) (Target, error) {
+	// Define the ADQL query template for resolving an identifier with the SIMBAD TAP service:
+	// @see https://simbad.cds.unistra.fr/simbad/tap/tapsearch.html
+	const simbadIdentifierADQLTemplate = `
+		SELECT TOP 1 basic.main_id AS designation, basic.ra AS ra, basic.dec AS dec, basic.otype AS otype
+		FROM basic
+		JOIN ident
+			ON basic.oid = ident.oidref
+		WHERE ident.id = '{{.Identifier}}';
+	`
+
+	// Construct the ADQL query from the template, escaping any quotes within the identifier:
+	adqlQuery, err := s.BuildADQLQuery(simbadIdentifierADQLTemplate, struct {
+		Identifier string
+	}{
+		Identifier: strings.ReplaceAll(strings.TrimSpace(identifier), "'", "''"),
+	})
+	if err != nil {
+		return Target{}, err
+	}
+
+	// Execute the query and get the response:
+	tapResponse, err := s.ExecuteADQLQuery(adqlQuery)
+	if err != nil {
+		return Target{}, err
+	}
+
+	if len(tapResponse.Data) == 0 || len(tapResponse.Data[0]) < 4 {
+		return Target{}, fmt.Errorf("target not found in SIMBAD: %q", identifier)
+	}
+
+	record := tapResponse.Data[0]
+
+	ra, ok := record[1].(float64)
+	if !ok {
+		return Target{}, fmt.Errorf("target has no coordinates in SIMBAD: %q", identifier)
+	}
+
+	dec, ok := record[2].(float64)
+	if !ok {
+		return Target{}, fmt.Errorf("target has no coordinates in SIMBAD: %q", identifier)
+	}
+
+	return Target{
+		Name:   strings.Join(strings.Fields(fmt.Sprintf("%v", record[0])), " "),
+		Type:   fmt.Sprintf("%v", record[3]),
+		RA:     ra,
+		Dec:    dec,
+		Source: "simbad",
+	}, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,234 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package catalog
+
+/*****************************************************************************************************************/
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+)
+
+/*****************************************************************************************************************/
+
+// The bundled offline catalog of the Messier objects, popular NGC and IC objects, and the bright (named) stars, as
+// the name, the semicolon separated aliases, the object type, and the sexagesimal J2000 right ascension (in hours)
+// and declination (in degrees) of each target:
+//
+//go:embed targets.csv
+var targetsCSV string
+
+/*****************************************************************************************************************/
+
+// The maximum number of consecutive words of a filename that may form the name of a target, e.g., "North America
+// Nebula":
+const TARGET_MAXIMUM_WORDS = 4
+
+/*****************************************************************************************************************/
+
+// Target is a named celestial object, e.g., "M 42", resolved to its equatorial coordinates.
+type Target struct {
+	Name   string  `json:"name"`   // The designation of the target, e.g., "M 42"
+	Type   string  `json:"type"`   // The object type, e.g., "nebula"
+	RA     float64 `json:"ra"`     // Right Ascension (in degrees)
+	Dec    float64 `json:"dec"`    // Declination (in degrees)
+	Source string  `json:"source"` // The catalog the target was resolved from, i.e., "bundled" or "simbad"
+}
+
+/*****************************************************************************************************************/
+
+// TargetResolverParams configures the resolution of target names.
+type TargetResolverParams struct {
+	SIMBAD bool // Whether names missing from the bundled catalog are looked up in SIMBAD
+}
+
+/*****************************************************************************************************************/
+
+// TargetResolver resolves the names of targets to their equatorial coordinates, from the bundled offline catalog,
+// and optionally from SIMBAD.
+type TargetResolver struct {
+	simbad *SIMBADServiceClient
+}
+
+/*****************************************************************************************************************/
+
+// The bundled targets, keyed by the normalised form of each of their names and aliases:
+var (
+	bundledTargets     map[string]Target
+	bundledTargetsErr  error
+	bundledTargetsOnce sync.Once
+)
+
+/*****************************************************************************************************************/
+
+// The abbreviations of the Greek letters of Bayer designations, e.g., "alpha" in "alpha Ori":
+var greekLetters = map[string]string{
+	"alpha":   "alf",
+	"beta":    "bet",
+	"gamma":   "gam",
+	"delta":   "del",
+	"epsilon": "eps",
+	"zeta":    "zet",
+	"theta":   "tet",
+	"iota":    "iot",
+	"kappa":   "kap",
+	"lambda":  "lam",
+	"omicron": "omi",
+	"sigma":   "sig",
+	"upsilon": "ups",
+	"omega":   "ome",
+}
+
+/*****************************************************************************************************************/
+
+// The leading zeros of the number of a catalog designation, e.g., "M 042" or "NGC 0224":
+var leadingZeros = regexp.MustCompile(`^(m|ngc|ic)0+([1-9])`)
+
+/*****************************************************************************************************************/
+
+// NewTargetResolver returns a resolver of target names, backed by the bundled offline catalog, and by SIMBAD when
+// enabled in the params.
+func NewTargetResolver(params TargetResolverParams) *TargetResolver {
+	r := &TargetResolver{}
+
+	if params.SIMBAD {
+		r.simbad = NewSIMBADServiceClient()
+	}
+
+	return r
+}
+
+/*****************************************************************************************************************/
+
+// Resolve returns the target of the name, e.g., "M42", "NGC 7000", "Orion Nebula" or "alf Ori", from the bundled
+// offline catalog, falling back to SIMBAD when it is enabled.
+func (r *TargetResolver) Resolve(name string) (Target, error) {
+	target, err := GetBundledTarget(name)
+	if err == nil || r.simbad == nil {
+		return target, err
+	}
+
+	return r.simbad.ResolveIdentifier(name)
+}
+
+/*****************************************************************************************************************/
+
+// Find returns the target named within the text, e.g., the OBJECT keyword or the filename "M42_Ha_300s", from the
+// bundled offline catalog, preferring the longest run of words, e.g., "North_America_Nebula" over "Nebula". SIMBAD
+// is only consulted, when enabled, for the text as a whole.
+func (r *TargetResolver) Find(text string) (Target, error) {
+	words := strings.FieldsFunc(text, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+
+	for n := min(TARGET_MAXIMUM_WORDS, len(words)); n > 0; n-- {
+		for i := 0; i+n <= len(words); i++ {
+			if target, err := GetBundledTarget(strings.Join(words[i:i+n], " ")); err == nil {
+				return target, nil
+			}
+		}
+	}
+
+	return r.Resolve(text)
+}
+
+/*****************************************************************************************************************/
+
+// GetBundledTarget returns the target of the name from the bundled offline catalog.
+func GetBundledTarget(name string) (Target, error) {
+	bundledTargetsOnce.Do(func() {
+		bundledTargets, bundledTargetsErr = readBundledTargets(targetsCSV)
+	})
+
+	if bundledTargetsErr != nil {
+		return Target{}, bundledTargetsErr
+	}
+
+	target, ok := bundledTargets[normaliseTargetName(name)]
+	if !ok {
+		return Target{}, fmt.Errorf("target not found in the bundled catalog: %q", name)
+	}
+
+	return target, nil
+}
+
+/*****************************************************************************************************************/
+
+// readBundledTargets parses the bundled catalog, keying each target by the normalised form of its name and aliases.
+func readBundledTargets(data string) (map[string]Target, error) {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the bundled targets: %w", err)
+	}
+
+	targets := make(map[string]Target)
+
+	// Skip the header row of the column names:
+	for _, record := range records[1:] {
+		ra, err := astrometry.ParseRightAscension(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid right ascension of the bundled target %s: %w", record[0], err)
+		}
+
+		dec, err := astrometry.ParseDeclination(record[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid declination of the bundled target %s: %w", record[0], err)
+		}
+
+		target := Target{
+			Name:   record[0],
+			Type:   record[2],
+			RA:     ra,
+			Dec:    dec,
+			Source: "bundled",
+		}
+
+		targets[normaliseTargetName(record[0])] = target
+
+		for _, alias := range strings.Split(record[1], ";") {
+			if alias != "" {
+				targets[normaliseTargetName(alias)] = target
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+/*****************************************************************************************************************/
+
+// normaliseTargetName reduces the name to lower case letters and digits, with "Messier" abbreviated to "M", the
+// Greek letters of Bayer designations abbreviated, e.g., "alpha" to "alf", and the leading zeros of a catalog
+// number removed, such that "Messier 42", "M 42", "m042" and "M42" share the same form.
+func normaliseTargetName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+
+	for i, word := range words {
+		if word == "messier" {
+			words[i] = "m"
+		}
+
+		if abbreviation, ok := greekLetters[word]; ok {
+			words[i] = abbreviation
+		}
+	}
+
+	return leadingZeros.ReplaceAllString(strings.Join(words, ""), "$1$2")
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,124 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package catalog
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestGetBundledTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		ra, dec float64
+	}{
+		// The Orion Nebula, by its Messier and NGC designations, and its common name:
+		{"M42", "M 42", 83.8221, -5.3911},
+		{"M 42", "M 42", 83.8221, -5.3911},
+		{"messier 042", "M 42", 83.8221, -5.3911},
+		{"NGC 1976", "M 42", 83.8221, -5.3911},
+		{"orion nebula", "M 42", 83.8221, -5.3911},
+		// The North America Nebula, without a Messier designation:
+		{"NGC7000", "NGC 7000", 314.8213, 44.5289},
+		{"North_America_Nebula", "NGC 7000", 314.8213, 44.5289},
+		// Betelgeuse, by its proper name and its Bayer designation:
+		{"Betelgeuse", "Betelgeuse", 88.7930, 7.4071},
+		{"alpha Ori", "Betelgeuse", 88.7930, 7.4071},
+		{"alf Ori", "Betelgeuse", 88.7930, 7.4071},
+	}
+
+	for _, tt := range tests {
+		target, err := GetBundledTarget(tt.name)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if target.Name != tt.target || target.Source != "bundled" {
+			t.Errorf("%s: expected the bundled target %s, got %s (%s)", tt.name, tt.target, target.Name, target.Source)
+		}
+
+		if math.Abs(target.RA-tt.ra) > 1e-3 || math.Abs(target.Dec-tt.dec) > 1e-3 {
+			t.Errorf("%s: expected (%v, %v), got (%v, %v)", tt.name, tt.ra, tt.dec, target.RA, target.Dec)
+		}
+	}
+
+	if _, err := GetBundledTarget("NGC 99999"); err == nil {
+		t.Errorf("expected an error for a target missing from the bundled catalog")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestBundledTargetNamesAreUnique(t *testing.T) {
+	records, err := csv.NewReader(strings.NewReader(targetsCSV)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read the bundled targets: %v", err)
+	}
+
+	names := make(map[string]string)
+
+	for _, record := range records[1:] {
+		for _, name := range append([]string{record[0]}, strings.Split(record[1], ";")...) {
+			if name == "" {
+				continue
+			}
+
+			key := normaliseTargetName(name)
+
+			if other, ok := names[key]; ok && other != record[0] {
+				t.Errorf("the name %q of %s is also a name of %s", name, record[0], other)
+			}
+
+			names[key] = record[0]
+		}
+	}
+
+	// Every one of the Messier objects is bundled:
+	for i := 1; i <= 110; i++ {
+		if _, err := GetBundledTarget(fmt.Sprintf("M%d", i)); err != nil {
+			t.Errorf("expected M %d in the bundled catalog: %v", i, err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFindTarget(t *testing.T) {
+	r := NewTargetResolver(TargetResolverParams{})
+
+	tests := map[string]string{
+		"M42_Ha_300s_2024-11-26T17_20_00Z":      "M 42",
+		"North_America_Nebula_[OIII]_Light_001": "NGC 7000",
+		"Light_NGC_7000_180s_Bin1_gain100":      "NGC 7000",
+		"2024-12-01 Pleiades LRGB":              "M 45",
+		"IC1805":                                "IC 1805",
+	}
+
+	for text, want := range tests {
+		target, err := r.Find(text)
+		if err != nil || target.Name != want {
+			t.Errorf("%s: expected %s, got %s (%v)", text, want, target.Name, err)
+		}
+	}
+
+	if _, err := r.Find("Light_Frame_300s"); err == nil {
+		t.Errorf("expected an error for text without a bundled target")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,326 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package extract
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+/*****************************************************************************************************************/
+
+// Background is a smoothly varying estimate of the sky background level and its RMS noise, computed on a coarse
+// mesh and bilinearly interpolated to the full resolution of the image.
+type Background struct {
+	Width       int       // the width of the image (in pixels)
+	Height      int       // the height of the image (in pixels)
+	MeshSize    int       // the size of each (square) background mesh (in pixels)
+	Level       []float32 // the background level of each pixel
+	RMS         []float32 // the background RMS noise of each pixel
+	GlobalLevel float64   // the median background level across all meshes
+	GlobalRMS   float64   // the median background RMS noise across all meshes
+}
+
+/*****************************************************************************************************************/
+
+// EstimateBackground computes the background level and RMS noise map of the image. Each mesh is iteratively sigma
+// clipped, the mode of the clipped distribution is taken as the level, and the mesh grid is median filtered to
+// suppress meshes dominated by bright stars or nebulosity before being interpolated back to full resolution.
+func EstimateBackground(data []float32, width int, height int, params Params) (*Background, error) {
+	if width <= 0 || height <= 0 || len(data) < width*height {
+		return nil, errors.New("image data does not match the image dimensions")
+	}
+
+	params = params.withDefaults()
+
+	mesh := params.MeshSize
+
+	// The number of meshes along each axis, where the final mesh may be partial:
+	nx := (width + mesh - 1) / mesh
+	ny := (height + mesh - 1) / mesh
+
+	levels := make([]float64, nx*ny)
+	rms := make([]float64, nx*ny)
+
+	values := make([]float64, 0, mesh*mesh)
+
+	differences := make([]float64, 0, mesh*mesh)
+
+	for my := 0; my < ny; my++ {
+		for mx := 0; mx < nx; mx++ {
+			values = values[:0]
+
+			differences = differences[:0]
+
+			for y := my * mesh; y < min((my+1)*mesh, height); y++ {
+				for x := mx * mesh; x < min((mx+1)*mesh, width); x++ {
+					v := float64(data[y*width+x])
+
+					if math.IsNaN(v) || math.IsInf(v, 0) {
+						continue
+					}
+
+					values = append(values, v)
+
+					// Pair each pixel with its right-hand neighbour within the same mesh:
+					if x+1 < min((mx+1)*mesh, width) {
+						n := float64(data[y*width+x+1])
+
+						if !math.IsNaN(n) && !math.IsInf(n, 0) {
+							differences = append(differences, v-n)
+						}
+					}
+				}
+			}
+
+			levels[my*nx+mx], rms[my*nx+mx] = estimateMeshBackground(values, differences)
+		}
+	}
+
+	// Replace any meshes without valid pixels with the global estimate:
+	globalLevel := medianOfFinite(levels)
+	globalRMS := medianOfFinite(rms)
+
+	for i := range levels {
+		if math.IsNaN(levels[i]) {
+			levels[i] = globalLevel
+			rms[i] = globalRMS
+		}
+	}
+
+	// Median filter the mesh grid to reject meshes contaminated by bright sources, where only meshes that deviate
+	// from the median of their neighbours by more than the filter threshold are replaced, such that genuine smooth
+	// structure, e.g., nebulosity, is not flattened by the filter:
+	filteredLevels := medianFilterGrid(levels, nx, ny, params.FilterSize)
+	filteredRMS := medianFilterGrid(rms, nx, ny, params.FilterSize)
+
+	for i := range levels {
+		if math.Abs(levels[i]-filteredLevels[i]) > params.FilterThreshold*rms[i] {
+			levels[i] = filteredLevels[i]
+			rms[i] = filteredRMS[i]
+		}
+	}
+
+	background := &Background{
+		Width:       width,
+		Height:      height,
+		MeshSize:    mesh,
+		Level:       make([]float32, width*height),
+		RMS:         make([]float32, width*height),
+		GlobalLevel: globalLevel,
+		GlobalRMS:   globalRMS,
+	}
+
+	// Precompute the bilinear interpolation indices and weights along each axis:
+	x0, x1, tx := interpolationWeights(width, mesh, nx)
+	y0, y1, ty := interpolationWeights(height, mesh, ny)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+
+			background.Level[i] = float32(bilinear(levels, nx, x0[x], x1[x], y0[y], y1[y], tx[x], ty[y]))
+			background.RMS[i] = float32(bilinear(rms, nx, x0[x], x1[x], y0[y], y1[y], tx[x], ty[y]))
+		}
+	}
+
+	return background, nil
+}
+
+/*****************************************************************************************************************/
+
+// estimateMeshBackground returns the background level and RMS noise of a mesh. The level is the mode of the
+// iteratively 3σ clipped values, using the SExtractor mode estimator (2.5 × median - 1.5 × mean) for uncrowded
+// meshes. The RMS is estimated from the clipped differences between adjacent pixels, which, unlike the spread of the
+// values themselves, is insensitive to gradients across the mesh, e.g., from nebulosity or vignetting.
+func estimateMeshBackground(values []float64, differences []float64) (float64, float64) {
+	if len(values) == 0 {
+		return math.NaN(), math.NaN()
+	}
+
+	median, mean, std := sigmaClip(values)
+
+	level := 2.5*median - 1.5*mean
+
+	// If the distribution is heavily skewed, e.g., crowded by sources, the median is a more robust estimate:
+	if std > 0 && math.Abs(mean-median)/std >= 0.3 {
+		level = median
+	}
+
+	if len(differences) == 0 {
+		return level, std
+	}
+
+	// The difference of two independent pixels has √2 times the noise of a single pixel:
+	_, _, rms := sigmaClip(differences)
+
+	return level, rms / math.Sqrt2
+}
+
+/*****************************************************************************************************************/
+
+// sigmaClip iteratively clips the values beyond 3σ of the median, returning the median, mean and standard deviation
+// of the clipped values. The values are sorted in place.
+func sigmaClip(values []float64) (float64, float64, float64) {
+	sort.Float64s(values)
+
+	lo, hi := 0, len(values)
+
+	mean, std, median := 0.0, 0.0, 0.0
+
+	for iteration := 0; iteration < 10; iteration++ {
+		clipped := values[lo:hi]
+
+		median = clipped[len(clipped)/2]
+
+		mean, std = meanAndStd(clipped)
+
+		if std == 0 {
+			break
+		}
+
+		// As the values are sorted, the clipped range is always contiguous:
+		nlo := lo + sort.SearchFloat64s(clipped, median-3*std)
+		nhi := lo + sort.SearchFloat64s(clipped, median+3*std+math.SmallestNonzeroFloat64)
+
+		if nlo == lo && nhi == hi || nhi-nlo < 3 {
+			break
+		}
+
+		lo, hi = nlo, nhi
+	}
+
+	return median, mean, std
+}
+
+/*****************************************************************************************************************/
+
+func meanAndStd(values []float64) (float64, float64) {
+	sum := 0.0
+
+	for _, v := range values {
+		sum += v
+	}
+
+	mean := sum / float64(len(values))
+
+	variance := 0.0
+
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+/*****************************************************************************************************************/
+
+func medianOfFinite(values []float64) float64 {
+	finite := make([]float64, 0, len(values))
+
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			finite = append(finite, v)
+		}
+	}
+
+	if len(finite) == 0 {
+		return 0
+	}
+
+	sort.Float64s(finite)
+
+	return finite[len(finite)/2]
+}
+
+/*****************************************************************************************************************/
+
+func medianFilterGrid(grid []float64, nx int, ny int, size int) []float64 {
+	if size <= 1 {
+		return grid
+	}
+
+	r := size / 2
+
+	filtered := make([]float64, len(grid))
+
+	window := make([]float64, 0, size*size)
+
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			window = window[:0]
+
+			// Shrink the window symmetrically at the edges of the grid, such that linear gradients are preserved:
+			rx := min(r, x, nx-1-x)
+			ry := min(r, y, ny-1-y)
+
+			for j := y - ry; j <= y+ry; j++ {
+				for i := x - rx; i <= x+rx; i++ {
+					window = append(window, grid[j*nx+i])
+				}
+			}
+
+			sort.Float64s(window)
+
+			filtered[y*nx+x] = window[len(window)/2]
+		}
+	}
+
+	return filtered
+}
+
+/*****************************************************************************************************************/
+
+// interpolationWeights returns, for each pixel along an axis, the indices of the two mesh centres either side of
+// the pixel and the fractional distance between them. Pixels beyond the outermost mesh centres are linearly
+// extrapolated, such that gradients are followed all the way to the edges of the image.
+func interpolationWeights(n int, mesh int, meshes int) ([]int, []int, []float64) {
+	centres := make([]float64, meshes)
+
+	for i := range centres {
+		start := i * mesh
+		end := min((i+1)*mesh, n)
+		centres[i] = float64(start+end-1) / 2
+	}
+
+	i0 := make([]int, n)
+	i1 := make([]int, n)
+	t := make([]float64, n)
+
+	// With a single mesh, the background is constant along the axis:
+	if meshes == 1 {
+		return i0, i1, t
+	}
+
+	k := 0
+
+	for p := 0; p < n; p++ {
+		for k < meshes-2 && float64(p) > centres[k+1] {
+			k++
+		}
+
+		i0[p], i1[p] = k, k+1
+		t[p] = (float64(p) - centres[k]) / (centres[k+1] - centres[k])
+	}
+
+	return i0, i1, t
+}
+
+/*****************************************************************************************************************/
+
+func bilinear(grid []float64, nx int, x0, x1, y0, y1 int, tx, ty float64) float64 {
+	top := grid[y0*nx+x0]*(1-tx) + grid[y0*nx+x1]*tx
+	bottom := grid[y1*nx+x0]*(1-tx) + grid[y1*nx+x1]*tx
+	return top*(1-ty) + bottom*ty
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,295 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package extract
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+)
+
+/*****************************************************************************************************************/
+
+// connectedComponents labels the 8-connected regions of the mask, returning the pixel indices of each region.
+func connectedComponents(mask []bool, width int, height int) [][]int {
+	visited := make([]bool, len(mask))
+
+	components := [][]int{}
+
+	stack := []int{}
+
+	for start := range mask {
+		if !mask[start] || visited[start] {
+			continue
+		}
+
+		component := []int{}
+
+		visited[start] = true
+
+		stack = append(stack[:0], start)
+
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			component = append(component, i)
+
+			x, y := i%width, i/width
+
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+
+					if nx < 0 || ny < 0 || nx >= width || ny >= height {
+						continue
+					}
+
+					j := ny*width + nx
+
+					if mask[j] && !visited[j] {
+						visited[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+		}
+
+		components = append(components, component)
+	}
+
+	return components
+}
+
+/*****************************************************************************************************************/
+
+// subComponents labels the 8-connected regions of the pixels of a component whose value exceeds the threshold,
+// using a local grid over the bounding box of the component.
+func subComponents(component []int, values []float32, width int, threshold float64) [][]int {
+	minX, minY, maxX, maxY := math.MaxInt, math.MaxInt, 0, 0
+
+	for _, i := range component {
+		x, y := i%width, i/width
+		minX, minY = min(minX, x), min(minY, y)
+		maxX, maxY = max(maxX, x), max(maxY, y)
+	}
+
+	w, h := maxX-minX+1, maxY-minY+1
+
+	mask := make([]bool, w*h)
+
+	for _, i := range component {
+		if float64(values[i]) > threshold {
+			mask[(i/width-minY)*w+(i%width-minX)] = true
+		}
+	}
+
+	local := connectedComponents(mask, w, h)
+
+	// Map the local indices of the bounding box back to indices of the image:
+	for _, c := range local {
+		for k, i := range c {
+			c[k] = (i/w+minY)*width + (i%w + minX)
+		}
+	}
+
+	return local
+}
+
+/*****************************************************************************************************************/
+
+// deblend splits a connected component into separate sources using multi-threshold deblending: the component is
+// re-thresholded at exponentially spaced levels between its lowest and peak value, and whenever a level splits it
+// into two or more branches that each hold a significant fraction of the total flux, the component is divided.
+//
+// @see Bertin & Arnouts (1996), A&AS, 117, 393 (Section 6)
+func deblend(component []int, values []float32, width int, params Params) [][]int {
+	lo, peak, total := math.Inf(1), math.Inf(-1), 0.0
+
+	for _, i := range component {
+		v := float64(values[i])
+		lo = math.Min(lo, v)
+		peak = math.Max(peak, v)
+		total += math.Max(v, 0)
+	}
+
+	if lo <= 0 || peak <= lo || total <= 0 {
+		return [][]int{component}
+	}
+
+	return deblendFromLevel(component, values, width, params, lo, peak, total, 1)
+}
+
+/*****************************************************************************************************************/
+
+func deblendFromLevel(
+	component []int,
+	values []float32,
+	width int,
+	params Params,
+	lo float64,
+	peak float64,
+	total float64,
+	start int,
+) [][]int {
+	n := params.DeblendLevels
+
+	for level := start; level < n; level++ {
+		threshold := lo * math.Pow(peak/lo, float64(level)/float64(n))
+
+		branches := [][]int{}
+
+		for _, branch := range subComponents(component, values, width, threshold) {
+			flux := 0.0
+
+			for _, i := range branch {
+				flux += float64(values[i])
+			}
+
+			// Only branches with a significant fraction of the total flux of the original component are retained:
+			if flux >= params.DeblendContrast*total {
+				branches = append(branches, branch)
+			}
+		}
+
+		if len(branches) < 2 {
+			continue
+		}
+
+		// Assign the remaining pixels of the component to the branches, and recursively deblend each branch:
+		segments := [][]int{}
+
+		for _, segment := range assignToBranches(component, branches, values, width) {
+			segments = append(segments, deblendFromLevel(segment, values, width, params, lo, peak, total, level+1)...)
+		}
+
+		return segments
+	}
+
+	return [][]int{component}
+}
+
+/*****************************************************************************************************************/
+
+// assignToBranches assigns every pixel of the component to the branch that most likely contributes to it, by
+// modelling each branch as a bivariate Gaussian with the peak and second order moments of the branch.
+func assignToBranches(component []int, branches [][]int, values []float32, width int) [][]int {
+	type model struct {
+		x, y, sigma2, peak float64
+	}
+
+	models := make([]model, len(branches))
+
+	owner := make(map[int]int, len(component))
+
+	for b, branch := range branches {
+		sw, sx, sy, peak := 0.0, 0.0, 0.0, 0.0
+
+		for _, i := range branch {
+			v := math.Max(float64(values[i]), 0)
+			sw += v
+			sx += v * float64(i%width)
+			sy += v * float64(i/width)
+			peak = math.Max(peak, v)
+			owner[i] = b
+		}
+
+		cx, cy := sx/sw, sy/sw
+
+		s2 := 0.0
+
+		for _, i := range branch {
+			v := math.Max(float64(values[i]), 0)
+			dx, dy := float64(i%width)-cx, float64(i/width)-cy
+			s2 += v * (dx*dx + dy*dy)
+		}
+
+		models[b] = model{x: cx, y: cy, sigma2: math.Max(s2/(2*sw), 1), peak: peak}
+	}
+
+	segments := make([][]int, len(branches))
+
+	for _, i := range component {
+		if b, ok := owner[i]; ok {
+			segments[b] = append(segments[b], i)
+			continue
+		}
+
+		best, likelihood := 0, math.Inf(-1)
+
+		for b, m := range models {
+			dx, dy := float64(i%width)-m.x, float64(i/width)-m.y
+
+			l := math.Log(m.peak) - (dx*dx+dy*dy)/(2*m.sigma2)
+
+			if l > likelihood {
+				best, likelihood = b, l
+			}
+		}
+
+		segments[best] = append(segments[best], i)
+	}
+
+	return segments
+}
+
+/*****************************************************************************************************************/
+
+// convolveGaussian convolves the image with a normalised, separable Gaussian kernel of the given FWHM.
+func convolveGaussian(data []float32, width int, height int, fwhm float64) []float32 {
+	sigma := fwhm / (2 * math.Sqrt(2*math.Ln2))
+
+	r := max(1, int(math.Ceil(2*sigma)))
+
+	kernel := make([]float64, 2*r+1)
+
+	sum := 0.0
+
+	for k := -r; k <= r; k++ {
+		kernel[k+r] = math.Exp(-float64(k*k) / (2 * sigma * sigma))
+		sum += kernel[k+r]
+	}
+
+	for k := range kernel {
+		kernel[k] /= sum
+	}
+
+	// Convolve along the rows, then along the columns, clamping at the edges of the image:
+	rows := make([]float32, len(data))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := 0.0
+
+			for k := -r; k <= r; k++ {
+				v += kernel[k+r] * float64(data[y*width+min(max(x+k, 0), width-1)])
+			}
+
+			rows[y*width+x] = float32(v)
+		}
+	}
+
+	convolved := make([]float32, len(data))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := 0.0
+
+			for k := -r; k <= r; k++ {
+				v += kernel[k+r] * float64(rows[min(max(y+k, 0), height-1)*width+x])
+			}
+
+			convolved[y*width+x] = float32(v)
+		}
+	}
+
+	return convolved
+}
+
+/*****************************************************************************************************************/
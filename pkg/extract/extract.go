@@ -0,0 +1,546 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package extract
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"sort"
+
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// Flag is a bit mask describing the quality of a detection.
+type Flag uint16
+
+/*****************************************************************************************************************/
+
+const (
+	FlagBlended          Flag = 1 << iota // the detection was deblended from a larger connected component
+	FlagSaturated                         // at least one pixel of the detection is at or above saturation
+	FlagTruncated                         // the detection touches the edge of the image
+	FlagCentroidDiverged                  // the windowed centroid diverged, so the isophotal centroid is used
+	FlagPSFFitFailed                      // the PSF fit failed, so the windowed centroid and moments are used
+)
+
+/*****************************************************************************************************************/
+
+// Has determines whether all of the given flags are set.
+func (f Flag) Has(flag Flag) bool {
+	return f&flag == flag
+}
+
+/*****************************************************************************************************************/
+
+// Params are the parameters of the background estimation and source extraction.
+type Params struct {
+	MeshSize          int      // the size of each background mesh (in pixels), e.g., 32
+	FilterSize        int      // the size of the median filter applied to the background mesh grid, e.g., 3
+	FilterThreshold   float64  // the deviation (in units of the mesh RMS) above which a mesh is median filtered
+	DetectionSigma    float64  // the detection threshold, in units of the local background RMS, e.g., 3
+	MinimumArea       int      // the minimum number of connected pixels above threshold of a detection, e.g., 5
+	FilterFWHM        float64  // the FWHM (in pixels) of the Gaussian smoothing kernel applied before detection, e.g., 2
+	DeblendLevels     int      // the number of exponentially spaced deblending thresholds, e.g., 32
+	DeblendContrast   float64  // the minimum fraction of the total flux for a branch to be deblended, e.g., 0.005
+	Saturation        float64  // the saturation level (in ADU), at or above which detections are flagged (0 disables)
+	Gain              float64  // the detector gain (e-/ADU), used for the Poisson noise of the SNR (0 disables)
+	MaximumDetections int      // the maximum number of detections returned, brightest first (0 returns all)
+	PSF               PSFModel // the PSF model fitted to each detection (PSFNone retains the windowed centroid)
+}
+
+/*****************************************************************************************************************/
+
+// DefaultParams are sensible extraction parameters for most images.
+var DefaultParams = Params{
+	MeshSize:        32,
+	FilterSize:      3,
+	FilterThreshold: 3,
+	DetectionSigma:  3,
+	MinimumArea:     5,
+	FilterFWHM:      2,
+	DeblendLevels:   32,
+	DeblendContrast: 0.005,
+}
+
+/*****************************************************************************************************************/
+
+// withDefaults returns a copy of the parameters, with any unset parameters replaced by their defaults.
+func (p Params) withDefaults() Params {
+	if p.MeshSize <= 0 {
+		p.MeshSize = DefaultParams.MeshSize
+	}
+
+	if p.FilterSize <= 0 {
+		p.FilterSize = DefaultParams.FilterSize
+	}
+
+	if p.FilterThreshold <= 0 {
+		p.FilterThreshold = DefaultParams.FilterThreshold
+	}
+
+	if p.DetectionSigma <= 0 {
+		p.DetectionSigma = DefaultParams.DetectionSigma
+	}
+
+	if p.MinimumArea <= 0 {
+		p.MinimumArea = DefaultParams.MinimumArea
+	}
+
+	if p.FilterFWHM <= 0 {
+		p.FilterFWHM = DefaultParams.FilterFWHM
+	}
+
+	if p.DeblendLevels <= 0 {
+		p.DeblendLevels = DefaultParams.DeblendLevels
+	}
+
+	if p.DeblendContrast <= 0 {
+		p.DeblendContrast = DefaultParams.DeblendContrast
+	}
+
+	return p
+}
+
+/*****************************************************************************************************************/
+
+// Detection is a single source extracted from the image.
+type Detection struct {
+	X          float64 `json:"x"`          // the windowed centroid in the x-axis (in pixels)
+	Y          float64 `json:"y"`          // the windowed centroid in the y-axis (in pixels)
+	Flux       float64 `json:"flux"`       // the background subtracted isophotal flux (in ADU)
+	Peak       float64 `json:"peak"`       // the background subtracted peak pixel value (in ADU)
+	Background float64 `json:"background"` // the local background level at the centroid (in ADU)
+	SNR        float64 `json:"snr"`        // the signal to noise ratio of the isophotal flux
+	FWHM       float64 `json:"fwhm"`       // the full width at half maximum, from the second order moments (in pixels)
+	Area       int     `json:"area"`       // the number of pixels belonging to the detection
+	Flags      Flag    `json:"flags"`      // the quality flags of the detection
+	// The covariance of the centroid (in pixels²), from the PSF fit, or estimated from the FWHM and SNR:
+	Covariance  [2][2]float64 `json:"covariance"`
+	Ellipticity float64       `json:"ellipticity"` // the ellipticity, 1 - minor / major
+	Angle       float64       `json:"angle"`       // the angle of the major axis, counter-clockwise from the x-axis (in degrees)
+	PSF         *PSFFit       `json:"psf"`         // the PSF fit of the detection, if a PSF model was fitted
+}
+
+/*****************************************************************************************************************/
+
+// Uncertainty returns the 1σ positional uncertainty of the centroid (in pixels), averaged over both axes.
+func (d Detection) Uncertainty() float64 {
+	return math.Sqrt((d.Covariance[0][0] + d.Covariance[1][1]) / 2)
+}
+
+/*****************************************************************************************************************/
+
+// Extract estimates the background of the image, and detects, deblends and measures all sources above the
+// detection threshold. Detections are returned sorted by flux, in descending order.
+func Extract(data []float32, width int, height int, params Params) ([]Detection, *Background, error) {
+	params = params.withDefaults()
+
+	background, err := EstimateBackground(data, width, height, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Subtract the background from the image:
+	residual := make([]float32, width*height)
+
+	for i := range residual {
+		residual[i] = data[i] - background.Level[i]
+
+		if math.IsNaN(float64(residual[i])) || math.IsInf(float64(residual[i]), 0) {
+			residual[i] = 0
+		}
+	}
+
+	// Smooth the residual with a Gaussian kernel matched to a typical stellar profile, improving the detectability
+	// of faint sources whilst suppressing single pixel noise:
+	filtered := convolveGaussian(residual, width, height, params.FilterFWHM)
+
+	// Threshold the filtered residual against the local background RMS:
+	mask := make([]bool, width*height)
+
+	for i := range mask {
+		mask[i] = float64(filtered[i]) > params.DetectionSigma*float64(background.RMS[i])
+	}
+
+	type labelled struct {
+		pixels  []int
+		blended bool
+	}
+
+	segments := []labelled{}
+
+	// The segmentation map labels each pixel with the (1-based) index of the segment it belongs to:
+	segmentation := make([]int32, width*height)
+
+	for _, component := range connectedComponents(mask, width, height) {
+		if len(component) < params.MinimumArea {
+			continue
+		}
+
+		children := deblend(component, filtered, width, params)
+
+		for _, child := range children {
+			if len(child) < params.MinimumArea {
+				continue
+			}
+
+			segments = append(segments, labelled{pixels: child, blended: len(children) > 1})
+
+			for _, i := range child {
+				segmentation[i] = int32(len(segments))
+			}
+		}
+	}
+
+	detections := make([]Detection, 0, len(segments))
+
+	for s, segment := range segments {
+		detection := measure(segment.pixels, data, residual, background, width, height, params)
+
+		if segment.blended {
+			detection.Flags |= FlagBlended
+		}
+
+		if params.PSF != PSFNone {
+			stamp := newStamp(detection, int32(s+1), segmentation, residual, background, width, height, params)
+
+			fit, err := FitPSF(params.PSF, stamp, detection.X, detection.Y, detection.Peak, detection.FWHM)
+
+			// Only accept fits which remain close to the measured centroid:
+			if err == nil && math.Hypot(fit.X-detection.X, fit.Y-detection.Y) < math.Max(detection.FWHM, 1) {
+				detection.X, detection.Y = fit.X, fit.Y
+				detection.FWHM = fit.FWHM
+				detection.Ellipticity, detection.Angle = fit.Ellipticity, fit.Angle
+				detection.Covariance = fit.Covariance
+				detection.PSF = &fit
+			} else {
+				detection.Flags |= FlagPSFFitFailed
+			}
+		}
+
+		detections = append(detections, detection)
+	}
+
+	sort.SliceStable(detections, func(i, j int) bool {
+		return detections[i].Flux > detections[j].Flux
+	})
+
+	if params.MaximumDetections > 0 && len(detections) > params.MaximumDetections {
+		detections = detections[:params.MaximumDetections]
+	}
+
+	return detections, background, nil
+}
+
+/*****************************************************************************************************************/
+
+// measure computes the photometric and astrometric properties of a single segment of pixels.
+func measure(
+	segment []int,
+	data []float32,
+	residual []float32,
+	background *Background,
+	width int,
+	height int,
+	params Params,
+) Detection {
+	detection := Detection{
+		Area: len(segment),
+	}
+
+	flux, variance, sx, sy := 0.0, 0.0, 0.0, 0.0
+
+	peak := math.Inf(-1)
+
+	for _, i := range segment {
+		x, y := i%width, i/width
+
+		v := float64(residual[i])
+
+		flux += v
+
+		variance += float64(background.RMS[i]) * float64(background.RMS[i])
+
+		if v > peak {
+			peak = v
+		}
+
+		if params.Saturation > 0 && float64(data[i]) >= params.Saturation {
+			detection.Flags |= FlagSaturated
+		}
+
+		if x == 0 || y == 0 || x == width-1 || y == height-1 {
+			detection.Flags |= FlagTruncated
+		}
+
+		// Only positive pixels contribute to the isophotal barycentre:
+		if v > 0 {
+			sx += v * float64(x)
+			sy += v * float64(y)
+		}
+	}
+
+	positive := 0.0
+
+	for _, i := range segment {
+		positive += math.Max(float64(residual[i]), 0)
+	}
+
+	// Fall back to the geometric centre for segments without any positive pixels:
+	if positive == 0 {
+		for _, i := range segment {
+			sx += float64(i % width)
+			sy += float64(i / width)
+		}
+		positive = float64(len(segment))
+	}
+
+	cx, cy := sx/positive, sy/positive
+
+	// The second order moments of the positive pixels give the size of the source:
+	sxx, syy, sxy := 0.0, 0.0, 0.0
+
+	for _, i := range segment {
+		v := math.Max(float64(residual[i]), 0)
+		dx, dy := float64(i%width)-cx, float64(i/width)-cy
+		sxx += v * dx * dx
+		syy += v * dy * dy
+		sxy += v * dx * dy
+	}
+
+	sigma := math.Sqrt(math.Max((sxx+syy)/(2*positive), 1.0/12))
+
+	detection.FWHM = 2 * math.Sqrt(2*math.Ln2) * sigma
+
+	// The shape follows from the principal axes of the second order moments, where the moment matrix is the inverse
+	// of the quadratic form of an equivalent Gaussian:
+	mxx, myy, mxy := math.Max(sxx/positive, 1.0/12), math.Max(syy/positive, 1.0/12), sxy/positive
+
+	if det := mxx*myy - mxy*mxy; det > 0 {
+		major, minor, angle := principalAxes(myy/det, -mxy/det, mxx/det)
+		detection.Ellipticity = 1 - minor/major
+		detection.Angle = angle
+	}
+
+	detection.Flux = flux
+
+	detection.Peak = peak
+
+	// The noise is the background RMS over the segment, plus the Poisson noise of the source when the gain is known:
+	if params.Gain > 0 {
+		variance += math.Max(flux, 0) / params.Gain
+	}
+
+	detection.SNR = flux / math.Sqrt(variance)
+
+	// Refine the centroid with a Gaussian window matched to the size of the source:
+	x, y, ok := windowedCentroid(segment, residual, width, cx, cy, sigma)
+
+	if !ok {
+		x, y = cx, cy
+		detection.Flags |= FlagCentroidDiverged
+	}
+
+	detection.X, detection.Y = x, y
+
+	// Without a PSF fit, the centroid variance is approximated by (σ / SNR)² on each axis:
+	if detection.SNR > 0 {
+		variance := math.Pow(sigma/detection.SNR, 2)
+		detection.Covariance = [2][2]float64{{variance, 0}, {0, variance}}
+	}
+
+	px := min(max(int(math.Round(x)), 0), width-1)
+	py := min(max(int(math.Round(y)), 0), height-1)
+
+	detection.Background = float64(background.Level[py*width+px])
+
+	return detection
+}
+
+/*****************************************************************************************************************/
+
+// windowedCentroid iteratively computes the Gaussian windowed centroid of the segment, which is considerably more
+// precise than the isophotal barycentre for faint and undersampled sources.
+//
+// @see https://sextractor.readthedocs.io/en/latest/Position.html#windowed-positional-parameters
+func windowedCentroid(segment []int, residual []float32, width int, x float64, y float64, sigma float64) (float64, float64, bool) {
+	x0, y0 := x, y
+
+	for iteration := 0; iteration < 16; iteration++ {
+		sw, dx, dy := 0.0, 0.0, 0.0
+
+		for _, i := range segment {
+			px, py := float64(i%width)-x, float64(i/width)-y
+
+			w := math.Exp(-(px*px + py*py) / (2 * sigma * sigma))
+
+			v := w * float64(residual[i])
+
+			sw += v
+			dx += v * px
+			dy += v * py
+		}
+
+		if sw <= 0 {
+			return x0, y0, false
+		}
+
+		x += 2 * dx / sw
+		y += 2 * dy / sw
+
+		// Guard against the window wandering away from the source:
+		if math.Hypot(x-x0, y-y0) > 2*sigma+1 {
+			return x0, y0, false
+		}
+
+		if math.Abs(dx/sw) < 1e-4 && math.Abs(dy/sw) < 1e-4 {
+			break
+		}
+	}
+
+	return x, y, true
+}
+
+/*****************************************************************************************************************/
+
+// RefineCentroid refines an approximate centroid, e.g., measured on a binned image, with the Gaussian windowed
+// centroid of the pixels within twice the FWHM, after subtracting the median of the border of the window as the
+// local background. The approximate centroid is returned if the windowed centroid diverges.
+func RefineCentroid(data []float32, width int, height int, x float64, y float64, fwhm float64) (float64, float64, bool) {
+	r := int(math.Min(math.Max(math.Ceil(2*fwhm), 3), 31))
+
+	cx, cy := int(math.Round(x)), int(math.Round(y))
+
+	x0, x1 := max(cx-r, 0), min(cx+r, width-1)
+
+	y0, y1 := max(cy-r, 0), min(cy+r, height-1)
+
+	if x1-x0 < 2 || y1-y0 < 2 {
+		return x, y, false
+	}
+
+	w := x1 - x0 + 1
+
+	border := []float64{}
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			if v := float64(data[py*width+px]); (px == x0 || px == x1 || py == y0 || py == y1) && !math.IsNaN(v) {
+				border = append(border, v)
+			}
+		}
+	}
+
+	background := medianOfFinite(border)
+
+	// The background subtracted window, whose pixels form the segment of the windowed centroid:
+	residual := make([]float32, w*(y1-y0+1))
+
+	segment := make([]int, 0, len(residual))
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			v := float64(data[py*width+px])
+
+			if math.IsNaN(v) {
+				continue
+			}
+
+			i := (py-y0)*w + (px - x0)
+
+			residual[i] = float32(v - background)
+
+			segment = append(segment, i)
+		}
+	}
+
+	sigma := math.Max(fwhm, 1) / (2 * math.Sqrt(2*math.Ln2))
+
+	wx, wy, ok := windowedCentroid(segment, residual, w, x-float64(x0), y-float64(y0), sigma)
+
+	if !ok {
+		return x, y, false
+	}
+
+	return wx + float64(x0), wy + float64(y0), true
+}
+
+/*****************************************************************************************************************/
+
+// newStamp cuts out the pixels within twice the FWHM of the detection, excluding pixels which belong to any other
+// detection, along with the noise of each pixel.
+func newStamp(
+	detection Detection,
+	label int32,
+	segmentation []int32,
+	residual []float32,
+	background *Background,
+	width int,
+	height int,
+	params Params,
+) Stamp {
+	r := int(math.Min(math.Max(math.Ceil(2*detection.FWHM), 3), 15))
+
+	cx, cy := int(math.Round(detection.X)), int(math.Round(detection.Y))
+
+	stamp := Stamp{}
+
+	for y := max(cy-r, 0); y <= min(cy+r, height-1); y++ {
+		for x := max(cx-r, 0); x <= min(cx+r, width-1); x++ {
+			i := y*width + x
+
+			if segmentation[i] != 0 && segmentation[i] != label {
+				continue
+			}
+
+			v := float64(residual[i])
+
+			variance := float64(background.RMS[i]) * float64(background.RMS[i])
+
+			if params.Gain > 0 {
+				variance += math.Max(v, 0) / params.Gain
+			}
+
+			if variance <= 0 {
+				continue
+			}
+
+			stamp.X = append(stamp.X, float64(x))
+			stamp.Y = append(stamp.Y, float64(y))
+			stamp.Value = append(stamp.Value, v)
+			stamp.Sigma = append(stamp.Sigma, math.Sqrt(variance))
+		}
+	}
+
+	return stamp
+}
+
+/*****************************************************************************************************************/
+
+// Stars converts the detections into photometry stars, such that they can be passed to the plate solver.
+func Stars(detections []Detection) []photometry.Star {
+	stars := make([]photometry.Star, len(detections))
+
+	for i, d := range detections {
+		stars[i] = photometry.Star{
+			X:         float32(d.X),
+			Y:         float32(d.Y),
+			Intensity: float32(d.Flux),
+			HFR:       float32(d.FWHM / 2),
+			Value:     float32(d.Peak),
+		}
+	}
+
+	return stars
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,218 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package extract
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+type syntheticStar struct {
+	x, y, amplitude float64
+}
+
+/*****************************************************************************************************************/
+
+// newSyntheticImage renders Gaussian stars of the given FWHM on top of a sloping background, an (optional) broad
+// patch of nebulosity and Gaussian read noise.
+func newSyntheticImage(width int, height int, stars []syntheticStar, fwhm float64, noise float64, nebulosity float64) []float32 {
+	r := rand.New(rand.NewSource(42))
+
+	sigma := fwhm / (2 * math.Sqrt(2*math.Ln2))
+
+	data := make([]float32, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// A gentle gradient across the image:
+			v := 1000 + 0.25*float64(x) + 0.1*float64(y)
+
+			// A broad patch of nebulosity, much larger than the background mesh:
+			dx, dy := float64(x)-300, float64(y)-200
+			v += nebulosity * math.Exp(-(dx*dx+dy*dy)/(2*150*150))
+
+			for _, s := range stars {
+				dx, dy := float64(x)-s.x, float64(y)-s.y
+				v += s.amplitude * math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma))
+			}
+
+			data[y*width+x] = float32(v + r.NormFloat64()*noise)
+		}
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+func TestEstimateBackground(t *testing.T) {
+	width, height := 256, 256
+
+	data := newSyntheticImage(width, height, nil, 3, 10, 400)
+
+	background, err := EstimateBackground(data, width, height, DefaultParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if math.Abs(background.GlobalRMS-10) > 1 {
+		t.Errorf("expected a background RMS of ~10, got %v", background.GlobalRMS)
+	}
+
+	// The interpolated background should follow the gradient to well within the noise:
+	for _, p := range [][2]int{{32, 32}, {128, 128}, {200, 100}} {
+		i := p[1]*width + p[0]
+
+		dx, dy := float64(p[0])-300, float64(p[1])-200
+
+		want := 1000 + 0.25*float64(p[0]) + 0.1*float64(p[1]) + 400*math.Exp(-(dx*dx+dy*dy)/(2*150*150))
+
+		if math.Abs(float64(background.Level[i])-want) > 10 {
+			t.Errorf("pixel %v: expected a background of %v, got %v", p, want, background.Level[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestExtract(t *testing.T) {
+	width, height := 512, 384
+
+	stars := []syntheticStar{
+		{100.3, 80.7, 2000},
+		{250.6, 300.2, 800},
+		{400.1, 150.9, 400},
+		{310.4, 210.5, 1500}, // in the nebulosity
+		{60.8, 320.4, 250},
+	}
+
+	data := newSyntheticImage(width, height, stars, 3, 10, 400)
+
+	detections, _, err := Extract(data, width, height, DefaultParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(detections) != len(stars) {
+		t.Fatalf("expected %d detections, got %d: %+v", len(stars), len(detections), detections)
+	}
+
+	for _, s := range stars {
+		found := false
+
+		for _, d := range detections {
+			if math.Hypot(d.X-s.x, d.Y-s.y) < 0.1 {
+				found = true
+
+				if math.Abs(d.FWHM-3) > 0.75 {
+					t.Errorf("star at (%v, %v): expected a FWHM of ~3, got %v", s.x, s.y, d.FWHM)
+				}
+
+				if d.SNR < 10 {
+					t.Errorf("star at (%v, %v): expected a SNR above 10, got %v", s.x, s.y, d.SNR)
+				}
+			}
+		}
+
+		if !found {
+			t.Errorf("star at (%v, %v) was not detected to within 0.1 pixels", s.x, s.y)
+		}
+	}
+
+	// Detections are sorted by flux, so the brightest star comes first:
+	if math.Hypot(detections[0].X-100.3, detections[0].Y-80.7) > 0.1 {
+		t.Errorf("expected the brightest star first, got (%v, %v)", detections[0].X, detections[0].Y)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestExtractDeblendsClosePairs(t *testing.T) {
+	width, height := 128, 128
+
+	stars := []syntheticStar{
+		{60, 64, 1000},
+		{66.5, 64, 600},
+	}
+
+	data := newSyntheticImage(width, height, stars, 3, 5, 0)
+
+	detections, _, err := Extract(data, width, height, DefaultParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(detections) != 2 {
+		t.Fatalf("expected the close pair to be deblended into 2 detections, got %d: %+v", len(detections), detections)
+	}
+
+	for _, d := range detections {
+		if !d.Flags.Has(FlagBlended) {
+			t.Errorf("expected the detection at (%v, %v) to be flagged as blended", d.X, d.Y)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestExtractFlagsSaturatedAndTruncatedDetections(t *testing.T) {
+	width, height := 128, 128
+
+	stars := []syntheticStar{
+		{64, 64, 5000},
+		{0.5, 100, 1000},
+	}
+
+	data := newSyntheticImage(width, height, stars, 3, 5, 0)
+
+	params := DefaultParams
+	params.Saturation = 4000
+
+	detections, _, err := Extract(data, width, height, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(detections) != 2 {
+		t.Fatalf("expected 2 detections, got %d: %+v", len(detections), detections)
+	}
+
+	if !detections[0].Flags.Has(FlagSaturated) {
+		t.Errorf("expected the brightest detection to be flagged as saturated")
+	}
+
+	if !detections[1].Flags.Has(FlagTruncated) {
+		t.Errorf("expected the edge detection to be flagged as truncated")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRefineCentroid(t *testing.T) {
+	width, height := 256, 192
+
+	data := newSyntheticImage(width, height, []syntheticStar{{100.3, 80.7, 1500}}, 4, 5, 0)
+
+	// An approximate centroid, e.g., from a 2×2 binned image, is refined to the true centroid:
+	x, y, ok := RefineCentroid(data, width, height, 101.2, 79.9, 4)
+	if !ok {
+		t.Fatalf("expected the windowed centroid to converge")
+	}
+
+	if math.Hypot(x-100.3, y-80.7) > 0.05 {
+		t.Errorf("expected a centroid of (100.3, 80.7), got (%v, %v)", x, y)
+	}
+}
+
+/*****************************************************************************************************************/
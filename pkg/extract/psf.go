@@ -0,0 +1,381 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package extract
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*****************************************************************************************************************/
+
+// PSFModel is the analytic point spread function model fitted to each detection.
+type PSFModel int
+
+/*****************************************************************************************************************/
+
+const (
+	PSFNone     PSFModel = iota // no PSF is fitted, and the windowed centroid is retained
+	PSFGaussian                 // an elliptical Gaussian: A·exp(-Q/2) + B
+	PSFMoffat                   // an elliptical Moffat: A·(1 + Q)^-β + B
+)
+
+/*****************************************************************************************************************/
+
+// String returns the name of the PSF model, e.g., "gaussian".
+func (m PSFModel) String() string {
+	switch m {
+	case PSFGaussian:
+		return "gaussian"
+	case PSFMoffat:
+		return "moffat"
+	default:
+		return "none"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// ParsePSFModel resolves the name of a PSF model, e.g., "gaussian", "moffat" or "none".
+func ParsePSFModel(name string) (PSFModel, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return PSFNone, nil
+	case "gaussian":
+		return PSFGaussian, nil
+	case "moffat":
+		return PSFMoffat, nil
+	default:
+		return PSFNone, fmt.Errorf("unknown PSF model: %s", name)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// PSFFit is the outcome of fitting an elliptical PSF model to a single star, where the shape of the PSF is given by
+// the quadratic form Q = Cxx·dx² + 2·Cxy·dx·dy + Cyy·dy².
+type PSFFit struct {
+	Model       PSFModel      `json:"model"`       // the fitted PSF model
+	X           float64       `json:"x"`           // the fitted centre in the x-axis (in pixels)
+	Y           float64       `json:"y"`           // the fitted centre in the y-axis (in pixels)
+	Covariance  [2][2]float64 `json:"covariance"`  // the covariance of the fitted centre (in pixels²)
+	Amplitude   float64       `json:"amplitude"`   // the fitted peak amplitude above the background (in ADU)
+	Background  float64       `json:"background"`  // the fitted residual background offset (in ADU)
+	Beta        float64       `json:"beta"`        // the fitted Moffat β (Moffat only)
+	FWHM        float64       `json:"fwhm"`        // the geometric mean of the major and minor axis FWHM (in pixels)
+	FWHMMajor   float64       `json:"fwhmMajor"`   // the FWHM along the major axis (in pixels)
+	FWHMMinor   float64       `json:"fwhmMinor"`   // the FWHM along the minor axis (in pixels)
+	Ellipticity float64       `json:"ellipticity"` // the ellipticity, 1 - minor / major
+	Angle       float64       `json:"angle"`       // the angle of the major axis, counter-clockwise from the x-axis (in degrees)
+	ChiSquared  float64       `json:"chiSquared"`  // the reduced χ² of the fit
+}
+
+/*****************************************************************************************************************/
+
+// Stamp is a small cut-out of (background subtracted) pixels around a star, with the noise of each pixel.
+type Stamp struct {
+	X     []float64 // the x pixel coordinate of each pixel
+	Y     []float64 // the y pixel coordinate of each pixel
+	Value []float64 // the background subtracted value of each pixel
+	Sigma []float64 // the 1σ noise of each pixel
+}
+
+/*****************************************************************************************************************/
+
+// psfParameters indexes the parameter vector of the PSF models:
+const (
+	psfA = iota
+	psfX
+	psfY
+	psfCxx
+	psfCxy
+	psfCyy
+	psfB
+	psfBeta
+)
+
+/*****************************************************************************************************************/
+
+// evaluate returns the model value at (x, y), and its partial derivatives with respect to each parameter.
+func (m PSFModel) evaluate(p []float64, x float64, y float64, gradient []float64) float64 {
+	dx, dy := x-p[psfX], y-p[psfY]
+
+	q := p[psfCxx]*dx*dx + 2*p[psfCxy]*dx*dy + p[psfCyy]*dy*dy
+
+	var f, dfdq float64
+
+	switch m {
+	case PSFMoffat:
+		base := math.Pow(1+q, -p[psfBeta])
+		f = p[psfA] * base
+		dfdq = -p[psfA] * p[psfBeta] * base / (1 + q)
+		gradient[psfA] = base
+		gradient[psfBeta] = -f * math.Log1p(q)
+	default:
+		base := math.Exp(-q / 2)
+		f = p[psfA] * base
+		dfdq = -f / 2
+		gradient[psfA] = base
+	}
+
+	gradient[psfX] = dfdq * -2 * (p[psfCxx]*dx + p[psfCxy]*dy)
+	gradient[psfY] = dfdq * -2 * (p[psfCxy]*dx + p[psfCyy]*dy)
+	gradient[psfCxx] = dfdq * dx * dx
+	gradient[psfCxy] = dfdq * 2 * dx * dy
+	gradient[psfCyy] = dfdq * dy * dy
+	gradient[psfB] = 1
+
+	return f + p[psfB]
+}
+
+/*****************************************************************************************************************/
+
+// valid determines whether the parameters describe a physical PSF, i.e., a positive amplitude, a positive definite
+// shape and a finite Moffat β.
+func (m PSFModel) valid(p []float64) bool {
+	if p[psfA] <= 0 || p[psfCxx] <= 0 || p[psfCyy] <= 0 || p[psfCxx]*p[psfCyy]-p[psfCxy]*p[psfCxy] <= 0 {
+		return false
+	}
+
+	if m == PSFMoffat && (p[psfBeta] < 0.5 || p[psfBeta] > 20) {
+		return false
+	}
+
+	for _, v := range p {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*****************************************************************************************************************/
+
+// FitPSF fits the PSF model to the stamp with a weighted Levenberg-Marquardt least squares fit, starting from the
+// initial centre (x, y), peak amplitude and FWHM. The covariance of the fitted centre is taken from the inverse of
+// the normal matrix at the solution, scaled by the reduced χ² where the fit is worse than the pixel noise implies.
+func FitPSF(model PSFModel, stamp Stamp, x float64, y float64, amplitude float64, fwhm float64) (PSFFit, error) {
+	if model == PSFNone {
+		return PSFFit{}, errors.New("no PSF model to fit")
+	}
+
+	n := 7
+
+	if model == PSFMoffat {
+		n = 8
+	}
+
+	if len(stamp.Value) <= n {
+		return PSFFit{}, errors.New("not enough pixels to fit the PSF model")
+	}
+
+	p := make([]float64, 8)
+
+	p[psfA], p[psfX], p[psfY] = amplitude, x, y
+
+	// Initialise a circular PSF with the estimated FWHM:
+	switch model {
+	case PSFMoffat:
+		p[psfBeta] = 2.5
+		alpha := fwhm / (2 * math.Sqrt(math.Pow(2, 1/p[psfBeta])-1))
+		p[psfCxx], p[psfCyy] = 1/(alpha*alpha), 1/(alpha*alpha)
+	default:
+		sigma := fwhm / (2 * math.Sqrt(2*math.Ln2))
+		p[psfCxx], p[psfCyy] = 1/(sigma*sigma), 1/(sigma*sigma)
+	}
+
+	if !model.valid(p) {
+		return PSFFit{}, errors.New("invalid initial PSF parameters")
+	}
+
+	gradient := make([]float64, 8)
+
+	// chiSquared computes the weighted sum of squared residuals of the parameters:
+	chiSquared := func(p []float64) float64 {
+		chi2 := 0.0
+
+		for i := range stamp.Value {
+			r := (stamp.Value[i] - model.evaluate(p, stamp.X[i], stamp.Y[i], gradient)) / stamp.Sigma[i]
+			chi2 += r * r
+		}
+
+		return chi2
+	}
+
+	// normal computes the normal matrix JᵀWJ and the vector JᵀWr of the parameters:
+	normal := func(p []float64) (*mat.SymDense, *mat.VecDense) {
+		jtj := mat.NewSymDense(n, nil)
+		jtr := mat.NewVecDense(n, nil)
+
+		for i := range stamp.Value {
+			f := model.evaluate(p, stamp.X[i], stamp.Y[i], gradient)
+
+			w := 1 / (stamp.Sigma[i] * stamp.Sigma[i])
+
+			r := stamp.Value[i] - f
+
+			for j := 0; j < n; j++ {
+				jtr.SetVec(j, jtr.AtVec(j)+w*gradient[j]*r)
+
+				for k := j; k < n; k++ {
+					jtj.SetSym(j, k, jtj.At(j, k)+w*gradient[j]*gradient[k])
+				}
+			}
+		}
+
+		return jtj, jtr
+	}
+
+	chi2 := chiSquared(p)
+
+	lambda := 1e-3
+
+	trial := make([]float64, 8)
+
+	for iteration := 0; iteration < 100; iteration++ {
+		jtj, jtr := normal(p)
+
+		improved := false
+
+		// Increase the damping until a step reduces χ², or the damping becomes excessive:
+		for lambda < 1e10 {
+			damped := mat.NewSymDense(n, nil)
+			damped.CopySym(jtj)
+
+			for j := 0; j < n; j++ {
+				damped.SetSym(j, j, jtj.At(j, j)*(1+lambda))
+			}
+
+			var step mat.VecDense
+
+			if err := step.SolveVec(damped, jtr); err != nil {
+				lambda *= 10
+				continue
+			}
+
+			copy(trial, p)
+
+			for j := 0; j < n; j++ {
+				trial[j] += step.AtVec(j)
+			}
+
+			if !model.valid(trial) {
+				lambda *= 10
+				continue
+			}
+
+			trialChi2 := chiSquared(trial)
+
+			if trialChi2 < chi2 {
+				converged := (chi2-trialChi2)/chi2 < 1e-10
+
+				copy(p, trial)
+
+				chi2 = trialChi2
+
+				lambda = math.Max(lambda/10, 1e-10)
+
+				improved = !converged
+
+				break
+			}
+
+			lambda *= 10
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	// The covariance of the parameters is the inverse of the (undamped) normal matrix:
+	jtj, _ := normal(p)
+
+	var covariance mat.Dense
+
+	if err := covariance.Inverse(jtj); err != nil {
+		return PSFFit{}, errors.New("the PSF fit is degenerate")
+	}
+
+	dof := float64(len(stamp.Value) - n)
+
+	reduced := chi2 / dof
+
+	scale := math.Max(reduced, 1)
+
+	fit := PSFFit{
+		Model:      model,
+		X:          p[psfX],
+		Y:          p[psfY],
+		Amplitude:  p[psfA],
+		Background: p[psfB],
+		Beta:       p[psfBeta],
+		ChiSquared: reduced,
+		Covariance: [2][2]float64{
+			{covariance.At(psfX, psfX) * scale, covariance.At(psfX, psfY) * scale},
+			{covariance.At(psfY, psfX) * scale, covariance.At(psfY, psfY) * scale},
+		},
+	}
+
+	if fit.Covariance[0][0] <= 0 || fit.Covariance[1][1] <= 0 {
+		return PSFFit{}, errors.New("the PSF fit has a non-positive centroid variance")
+	}
+
+	// The FWHM of each principal axis follows from the eigenvalues of the quadratic form of the shape:
+	major, minor, angle := principalAxes(p[psfCxx], p[psfCxy], p[psfCyy])
+
+	switch model {
+	case PSFMoffat:
+		k := 2 * math.Sqrt(math.Pow(2, 1/p[psfBeta])-1)
+		fit.FWHMMajor, fit.FWHMMinor = k*major, k*minor
+	default:
+		k := 2 * math.Sqrt(2*math.Ln2)
+		fit.FWHMMajor, fit.FWHMMinor = k*major, k*minor
+	}
+
+	fit.FWHM = math.Sqrt(fit.FWHMMajor * fit.FWHMMinor)
+
+	fit.Ellipticity = 1 - fit.FWHMMinor/fit.FWHMMajor
+
+	fit.Angle = angle
+
+	return fit, nil
+}
+
+/*****************************************************************************************************************/
+
+// principalAxes returns the scale lengths of the major and minor axes of the quadratic form with the matrix
+// [[cxx, cxy], [cxy, cyy]], i.e., 1/√λ for each eigenvalue λ, and the angle of the major axis (in degrees,
+// counter-clockwise from the x-axis, in the range (-90, 90]).
+func principalAxes(cxx float64, cxy float64, cyy float64) (float64, float64, float64) {
+	mean := (cxx + cyy) / 2
+
+	delta := math.Sqrt((cxx-cyy)*(cxx-cyy)/4 + cxy*cxy)
+
+	// The smallest eigenvalue corresponds to the major (longest) axis:
+	lmin, lmax := mean-delta, mean+delta
+
+	// The eigenvector of the largest eigenvalue lies at ½·atan2(2·cxy, cxx - cyy), so the major axis is orthogonal:
+	angle := 0.5*math.Atan2(2*cxy, cxx-cyy)*180/math.Pi + 90
+
+	if angle > 90 {
+		angle -= 180
+	}
+
+	return 1 / math.Sqrt(lmin), 1 / math.Sqrt(lmax), angle
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,204 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package extract
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// newSyntheticStamp renders an elliptical PSF with the given principal axis FWHMs and major axis angle (in degrees)
+// onto a stamp with Gaussian noise.
+func newSyntheticStamp(r *rand.Rand, model PSFModel, x0, y0, amplitude, major, minor, angle, beta, noise float64) Stamp {
+	theta := angle * math.Pi / 180
+
+	// Convert the FWHM of each axis into the scale length of the model:
+	k := 2 * math.Sqrt(2*math.Ln2)
+
+	if model == PSFMoffat {
+		k = 2 * math.Sqrt(math.Pow(2, 1/beta)-1)
+	}
+
+	a, b := major/k, minor/k
+
+	stamp := Stamp{}
+
+	for y := math.Round(y0) - 10; y <= math.Round(y0)+10; y++ {
+		for x := math.Round(x0) - 10; x <= math.Round(x0)+10; x++ {
+			dx, dy := x-x0, y-y0
+
+			u := dx*math.Cos(theta) + dy*math.Sin(theta)
+			v := -dx*math.Sin(theta) + dy*math.Cos(theta)
+
+			q := u*u/(a*a) + v*v/(b*b)
+
+			value := amplitude * math.Exp(-q/2)
+
+			if model == PSFMoffat {
+				value = amplitude * math.Pow(1+q, -beta)
+			}
+
+			stamp.X = append(stamp.X, x)
+			stamp.Y = append(stamp.Y, y)
+			stamp.Value = append(stamp.Value, value+r.NormFloat64()*noise)
+			stamp.Sigma = append(stamp.Sigma, noise)
+		}
+	}
+
+	return stamp
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFGaussian(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+
+	stamp := newSyntheticStamp(r, PSFGaussian, 50.3, 40.6, 1000, 5, 3, 30, 0, 5)
+
+	fit, err := FitPSF(PSFGaussian, stamp, 50, 41, 900, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if math.Abs(fit.X-50.3) > 4*math.Sqrt(fit.Covariance[0][0]) || math.Abs(fit.Y-40.6) > 4*math.Sqrt(fit.Covariance[1][1]) {
+		t.Errorf("expected a centre of (50.3, 40.6), got (%v, %v) ± (%v, %v)", fit.X, fit.Y, math.Sqrt(fit.Covariance[0][0]), math.Sqrt(fit.Covariance[1][1]))
+	}
+
+	if math.Abs(fit.FWHMMajor-5) > 0.1 || math.Abs(fit.FWHMMinor-3) > 0.1 {
+		t.Errorf("expected FWHMs of 5 and 3, got %v and %v", fit.FWHMMajor, fit.FWHMMinor)
+	}
+
+	if math.Abs(fit.Ellipticity-0.4) > 0.03 {
+		t.Errorf("expected an ellipticity of 0.4, got %v", fit.Ellipticity)
+	}
+
+	if math.Abs(fit.Angle-30) > 2 {
+		t.Errorf("expected an angle of 30°, got %v", fit.Angle)
+	}
+
+	if math.Abs(fit.ChiSquared-1) > 0.25 {
+		t.Errorf("expected a reduced χ² of ~1, got %v", fit.ChiSquared)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFMoffat(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+
+	stamp := newSyntheticStamp(r, PSFMoffat, 20.7, 30.2, 2000, 4, 4, 0, 3, 5)
+
+	fit, err := FitPSF(PSFMoffat, stamp, 21, 30, 1800, 3.5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if math.Hypot(fit.X-20.7, fit.Y-30.2) > 0.05 {
+		t.Errorf("expected a centre of (20.7, 30.2), got (%v, %v)", fit.X, fit.Y)
+	}
+
+	if math.Abs(fit.FWHM-4) > 0.1 {
+		t.Errorf("expected a FWHM of 4, got %v", fit.FWHM)
+	}
+
+	if math.Abs(fit.Beta-3) > 0.5 {
+		t.Errorf("expected a β of 3, got %v", fit.Beta)
+	}
+
+	if fit.Ellipticity > 0.05 {
+		t.Errorf("expected a round PSF, got an ellipticity of %v", fit.Ellipticity)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFCovarianceMatchesScatter(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+
+	n := 200
+
+	xs := make([]float64, 0, n)
+
+	reported := 0.0
+
+	for i := 0; i < n; i++ {
+		stamp := newSyntheticStamp(r, PSFGaussian, 10.25, 10.75, 200, 3, 3, 0, 0, 10)
+
+		fit, err := FitPSF(PSFGaussian, stamp, 10, 11, 200, 3)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		xs = append(xs, fit.X)
+
+		reported += math.Sqrt(fit.Covariance[0][0]) / float64(n)
+	}
+
+	mean, std := meanAndStd(xs)
+
+	if math.Abs(mean-10.25) > 3*std/math.Sqrt(float64(n)) {
+		t.Errorf("expected an unbiased centre of 10.25, got %v", mean)
+	}
+
+	// The reported uncertainty should agree with the empirical scatter of the fitted centres:
+	if math.Abs(reported-std)/std > 0.2 {
+		t.Errorf("expected a reported uncertainty of ~%v, got %v", std, reported)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestExtractWithPSF(t *testing.T) {
+	width, height := 256, 256
+
+	stars := []syntheticStar{
+		{100.3, 80.7, 2000},
+		{180.6, 200.2, 800},
+	}
+
+	data := newSyntheticImage(width, height, stars, 3, 10, 0)
+
+	params := DefaultParams
+	params.PSF = PSFGaussian
+
+	detections, _, err := Extract(data, width, height, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(detections) != len(stars) {
+		t.Fatalf("expected %d detections, got %d", len(stars), len(detections))
+	}
+
+	for i, d := range detections {
+		if d.PSF == nil || d.Flags.Has(FlagPSFFitFailed) {
+			t.Fatalf("expected a PSF fit for detection %d", i)
+		}
+
+		if math.Hypot(d.X-stars[i].x, d.Y-stars[i].y) > 5*d.Uncertainty() {
+			t.Errorf("detection %d: expected (%v, %v), got (%v, %v) ± %v", i, stars[i].x, stars[i].y, d.X, d.Y, d.Uncertainty())
+		}
+
+		if math.Abs(d.FWHM-3) > 0.2 {
+			t.Errorf("detection %d: expected a FWHM of 3, got %v", i, d.FWHM)
+		}
+	}
+
+	// The brighter star should have the smaller positional uncertainty:
+	if detections[0].Uncertainty() >= detections[1].Uncertainty() {
+		t.Errorf("expected the brighter star to be more precisely located")
+	}
+}
+
+/*****************************************************************************************************************/
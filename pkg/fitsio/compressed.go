@@ -0,0 +1,701 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"regexp"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// The number of values in the table of uniform random numbers used to dither quantized floating point tiles:
+const N_RANDOM = 10000
+
+/*****************************************************************************************************************/
+
+// The quantized value representing an exact zero for the SUBTRACTIVE_DITHER_2 quantization method:
+const ZERO_VALUE = -2147483646
+
+/*****************************************************************************************************************/
+
+// The keywords of the binary table that describe the compression of the image, and so are removed, or restored to
+// their image keywords (e.g., ZBITPIX to BITPIX), when recovering the header of the uncompressed image:
+var compressionKeyword = regexp.MustCompile(
+	`^(XTENSION|BITPIX|NAXIS\d*|PCOUNT|GCOUNT|TFIELDS|TTYPE\d+|TFORM\d+|TUNIT\d+|TSCAL\d+|TZERO\d+|TDIM\d+|THEAP|` +
+		`CHECKSUM|DATASUM|ZIMAGE|ZCMPTYPE|ZSIMPLE|ZTENSION|ZBITPIX|ZNAXIS\d*|ZTILE\d+|ZNAME\d+|ZVAL\d+|ZQUANTIZ|` +
+		`ZDITHER0|ZSCALE|ZZERO|ZBLANK|ZMASKCMP|ZEXTEND|ZBLOCKED|ZPCOUNT|ZGCOUNT|ZHECKSUM|ZDATASUM)$`,
+)
+
+/*****************************************************************************************************************/
+
+// randoms is the table of N_RANDOM uniform random numbers of the tiled image convention, generated with the Park
+// and Miller minimal standard generator, shared by all compressors to reproduce the dithering of each tile, where
+// the values are single precision, as in CFITSIO, such that the offset of each tile into the table is identical.
+//
+// @see https://fits.gsfc.nasa.gov/registry/tilecompression/tilecompression2.3.pdf (Section 4.1)
+var randoms = func() []float64 {
+	const a, m = 16807.0, 2147483647.0
+
+	values := make([]float64, N_RANDOM)
+
+	seed := 1.0
+
+	for i := range values {
+		temp := a * seed
+		seed = temp - m*math.Floor(temp/m)
+		values[i] = float64(float32(seed / m))
+	}
+
+	return values
+}()
+
+/*****************************************************************************************************************/
+
+// IsCompressedImageHDU determines whether the HDU is an image compressed with the FITS tiled image convention, e.g.,
+// by fpack, i.e., a binary table extension with ZIMAGE = T.
+func IsCompressedImageHDU(hdu *HDU) bool {
+	if hdu.Extension() != "BINTABLE" {
+		return false
+	}
+
+	zimage, ok := hdu.Header.Bool("ZIMAGE")
+
+	return ok && zimage
+}
+
+/*****************************************************************************************************************/
+
+// NewHeaderFromCompressedHDU recovers the header of the uncompressed image from the header of a tile-compressed
+// HDU, restoring the Z-prefixed image keywords (e.g., ZBITPIX and ZNAXISn) and removing the binary table and
+// compression keywords, whilst all other keywords, e.g., the WCS, are retained in their order.
+func NewHeaderFromCompressedHDU(hdu *HDU) (*Header, error) {
+	if !IsCompressedImageHDU(hdu) {
+		return nil, errors.New("not a tile-compressed image HDU")
+	}
+
+	h := hdu.Header
+
+	header := NewHeader()
+
+	if _, ok := h.Get("ZSIMPLE"); ok {
+		header.Set("SIMPLE", true, "conforms to FITS standard")
+	} else {
+		xtension, ok := h.String("ZTENSION")
+		if !ok {
+			xtension = "IMAGE"
+		}
+
+		header.Set("XTENSION", xtension, "image extension")
+	}
+
+	bitpix, ok := h.Int("ZBITPIX")
+	if !ok {
+		return nil, errors.New("compressed image header is missing the ZBITPIX keyword")
+	}
+
+	header.Set("BITPIX", bitpix, "array data type")
+
+	naxis, ok := h.Int("ZNAXIS")
+	if !ok {
+		return nil, errors.New("compressed image header is missing the ZNAXIS keyword")
+	}
+
+	header.Set("NAXIS", naxis, "number of array dimensions")
+
+	for i := 1; i <= int(naxis); i++ {
+		n, ok := h.Int(fmt.Sprintf("ZNAXIS%d", i))
+		if !ok {
+			return nil, fmt.Errorf("compressed image header is missing the ZNAXIS%d keyword", i)
+		}
+
+		header.Set(fmt.Sprintf("NAXIS%d", i), n, "")
+	}
+
+	if header.Has("SIMPLE") {
+		if extend, ok := h.Bool("ZEXTEND"); ok {
+			header.Set("EXTEND", extend, "FITS dataset may contain extensions")
+		}
+	} else {
+		pcount, ok := h.Int("ZPCOUNT")
+		if !ok {
+			pcount = 0
+		}
+
+		gcount, ok := h.Int("ZGCOUNT")
+		if !ok {
+			gcount = 1
+		}
+
+		header.Set("PCOUNT", pcount, "number of parameters")
+		header.Set("GCOUNT", gcount, "number of groups")
+	}
+
+	for _, card := range h.Cards {
+		if compressionKeyword.MatchString(card.Key) || card.Value != nil && header.Has(card.Key) {
+			continue
+		}
+
+		header.Cards = append(header.Cards, card)
+	}
+
+	return header, nil
+}
+
+/*****************************************************************************************************************/
+
+// NewImageFromCompressedHDU decompresses the tiles of an image compressed with the FITS tiled image convention, e.g.,
+// a .fits.fz file written by fpack, into physical values. The RICE_1, GZIP_1, GZIP_2 and NOCOMPRESS algorithms are
+// supported, along with the (optionally dithered) quantization of floating point images.
+//
+// @see https://fits.gsfc.nasa.gov/registry/tilecompression/tilecompression2.3.pdf
+func NewImageFromCompressedHDU(hdu *HDU) (*Image, error) {
+	header, err := NewHeaderFromCompressedHDU(hdu)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := NewBinaryTableFromHDU(hdu)
+	if err != nil {
+		return nil, err
+	}
+
+	h := hdu.Header
+
+	bitpix, _ := header.Int("BITPIX")
+	naxis, _ := header.Int("NAXIS")
+
+	axes := make([]int, naxis)
+	tiles := make([]int, naxis)
+
+	n := 1
+
+	for i := range axes {
+		v, _ := header.Int(fmt.Sprintf("NAXIS%d", i+1))
+
+		axes[i] = int(v)
+
+		// The tiles default to whole rows of the image:
+		tiles[i] = 1
+
+		if i == 0 {
+			tiles[i] = axes[i]
+		}
+
+		if t, ok := h.Int(fmt.Sprintf("ZTILE%d", i+1)); ok && t > 0 {
+			tiles[i] = int(t)
+		}
+
+		n *= axes[i]
+	}
+
+	algorithm, ok := h.String("ZCMPTYPE")
+	if !ok {
+		return nil, errors.New("compressed image header is missing the ZCMPTYPE keyword")
+	}
+
+	params := getCompressionParameters(h)
+
+	// The size (in bytes) of the integers of each tile, which are quantized for floating point images:
+	bytepix := int(math.Abs(float64(bitpix))) / 8
+
+	quantize, _ := h.String("ZQUANTIZ")
+	quantize = strings.TrimSpace(quantize)
+
+	quantized := bitpix < 0 && table.ColumnIndex("ZSCALE") >= 0 || bitpix < 0 && h.Has("ZSCALE")
+
+	if quantized {
+		bytepix = 4
+	}
+
+	if strings.HasPrefix(algorithm, "RICE") {
+		if v, ok := params["BYTEPIX"]; ok {
+			bytepix = int(v)
+		}
+	}
+
+	scales, zeros, blanks := getTileColumn(table, h, "ZSCALE", 1), getTileColumn(table, h, "ZZERO", 0), getTileColumn(table, h, "ZBLANK", math.NaN())
+
+	// Integer images may instead flag their undefined pixels with the BLANK keyword of the image:
+	if blank, ok := header.Int("BLANK"); ok && bitpix > 0 && !h.Has("ZBLANK") && table.ColumnIndex("ZBLANK") < 0 {
+		for i := range blanks {
+			blanks[i] = float64(blank)
+		}
+	}
+
+	bscale, ok := header.Float("BSCALE")
+	if !ok {
+		bscale = 1
+	}
+
+	bzero, ok := header.Float("BZERO")
+	if !ok {
+		bzero = 0
+	}
+
+	dither, ok := h.Int("ZDITHER0")
+	if !ok {
+		dither = 1
+	}
+
+	data := make([]float32, n)
+
+	// The number of tiles along each axis of the image:
+	counts := make([]int, naxis)
+
+	total := 1
+
+	for i := range axes {
+		counts[i] = (axes[i] + tiles[i] - 1) / tiles[i]
+		total *= counts[i]
+	}
+
+	if total != table.Rows {
+		return nil, fmt.Errorf("compressed image has %d rows, expected %d tiles", table.Rows, total)
+	}
+
+	for row := 0; row < table.Rows; row++ {
+		// The first pixel and the dimensions of the tile, where tiles at the edges of the image are truncated:
+		start := make([]int, naxis)
+		shape := make([]int, naxis)
+
+		size := 1
+
+		for i, r := 0, row; i < int(naxis); i++ {
+			start[i] = (r % counts[i]) * tiles[i]
+			shape[i] = min(tiles[i], axes[i]-start[i])
+			size *= shape[i]
+			r /= counts[i]
+		}
+
+		values, lossless, err := decompressTile(table, row, algorithm, params, bytepix, size, quantized, bitpix)
+		if err != nil {
+			return nil, fmt.Errorf("tile %d: %w", row+1, err)
+		}
+
+		// The offset into the table of random numbers used to dither the tile:
+		r := (row + int(dither) - 1) % N_RANDOM
+		next := int(randoms[r] * 500)
+
+		for k, raw := range values {
+			// The index of the pixel within the image, from its index within the tile:
+			index, stride := 0, 1
+
+			for i, rest := 0, k; i < int(naxis); i++ {
+				index += (start[i] + rest%shape[i]) * stride
+				stride *= axes[i]
+				rest /= shape[i]
+			}
+
+			v := raw
+
+			switch {
+			case lossless:
+				// A tile of a floating point image that could not be quantized holds the raw values:
+			case !math.IsNaN(blanks[row]) && v == blanks[row]:
+				v = math.NaN()
+			case quantized && quantize == "SUBTRACTIVE_DITHER_2" && v == ZERO_VALUE:
+				v = 0
+			case quantized && strings.HasPrefix(quantize, "SUBTRACTIVE_DITHER"):
+				v = (v-randoms[next]+0.5)*scales[row] + zeros[row]
+			case quantized:
+				v = v*scales[row] + zeros[row]
+			default:
+				v = bzero + bscale*v
+			}
+
+			data[index] = float32(v)
+
+			// Advance the dithering to the next random number, wrapping around the table:
+			if quantized {
+				next++
+
+				if next == N_RANDOM {
+					r = (r + 1) % N_RANDOM
+					next = int(randoms[r] * 500)
+				}
+			}
+		}
+	}
+
+	return &Image{
+		Axes: axes,
+		Data: data,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// getCompressionParameters returns the ZNAMEn and ZVALn parameters of the compression algorithm, e.g., BLOCKSIZE.
+func getCompressionParameters(h *Header) map[string]float64 {
+	params := map[string]float64{}
+
+	for i := 1; ; i++ {
+		name, ok := h.String(fmt.Sprintf("ZNAME%d", i))
+		if !ok {
+			break
+		}
+
+		if v, ok := h.Float(fmt.Sprintf("ZVAL%d", i)); ok {
+			params[strings.ToUpper(strings.TrimSpace(name))] = v
+		}
+	}
+
+	return params
+}
+
+/*****************************************************************************************************************/
+
+// getTileColumn returns the value of the named per-tile column (e.g., ZSCALE) for every tile, falling back to the
+// header keyword of the same name when the value is constant, or otherwise to the given default.
+func getTileColumn(table *BinaryTable, h *Header, name string, fallback float64) []float64 {
+	if values, err := table.Float64Column(name); err == nil {
+		return values
+	}
+
+	if v, ok := h.Float(name); ok {
+		fallback = v
+	}
+
+	values := make([]float64, table.Rows)
+
+	for i := range values {
+		values[i] = fallback
+	}
+
+	return values
+}
+
+/*****************************************************************************************************************/
+
+// decompressTile decompresses the integer (or raw floating point) values of a single tile, and whether the tile of a
+// quantized image was instead stored losslessly, i.e., its values are not quantized.
+func decompressTile(
+	table *BinaryTable,
+	row int,
+	algorithm string,
+	params map[string]float64,
+	bytepix int,
+	size int,
+	quantized bool,
+	bitpix int64,
+) ([]float64, bool, error) {
+	compressed, _, err := table.Array("COMPRESSED_DATA", row)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Tiles that could not be quantized are stored losslessly in a separate column:
+	if len(compressed) == 0 {
+		if raw, _, err := table.Array("GZIP_COMPRESSED_DATA", row); err == nil && len(raw) > 0 {
+			values, err := decodeRawTile(raw, "GZIP_1", bitpix, size)
+			return values, true, err
+		}
+
+		if raw, _, err := table.Array("UNCOMPRESSED_DATA", row); err == nil && len(raw) > 0 {
+			values, err := decodeRawTile(raw, "NOCOMPRESS", bitpix, size)
+			return values, true, err
+		}
+
+		return nil, false, errors.New("the tile has no compressed data")
+	}
+
+	// The element type of the quantized integers, or of the image itself:
+	elementBitpix := bitpix
+
+	if quantized {
+		elementBitpix = 32
+	}
+
+	switch strings.TrimSpace(algorithm) {
+	case "RICE_1", "RICE_ONE":
+		blocksize := 32
+
+		if v, ok := params["BLOCKSIZE"]; ok {
+			blocksize = int(v)
+		}
+
+		values, err := RiceDecompress(compressed, size, blocksize, bytepix)
+		return values, false, err
+	case "GZIP_1", "GZIP_2", "NOCOMPRESS":
+		values, err := decodeRawTile(compressed, strings.TrimSpace(algorithm), elementBitpix, size)
+		return values, false, err
+	default:
+		return nil, false, fmt.Errorf("unsupported tile compression algorithm: %s", algorithm)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// decodeRawTile decodes a tile of big-endian values of the given BITPIX, which may be GZIP compressed, and, for
+// GZIP_2, byte shuffled, i.e., the most significant bytes of every value precede the next most significant bytes.
+func decodeRawTile(compressed []byte, algorithm string, bitpix int64, size int) ([]float64, error) {
+	raw := compressed
+
+	if strings.HasPrefix(algorithm, "GZIP") {
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress GZIP tile: %w", err)
+		}
+
+		raw, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress GZIP tile: %w", err)
+		}
+	}
+
+	width := int(math.Abs(float64(bitpix))) / 8
+
+	if len(raw) < size*width {
+		return nil, fmt.Errorf("the tile is truncated: %d of %d bytes", len(raw), size*width)
+	}
+
+	if algorithm == "GZIP_2" {
+		shuffled := raw
+
+		raw = make([]byte, size*width)
+
+		for i := 0; i < size; i++ {
+			for b := 0; b < width; b++ {
+				raw[i*width+b] = shuffled[b*size+i]
+			}
+		}
+	}
+
+	values := make([]float64, size)
+
+	for i := range values {
+		b := raw[i*width : (i+1)*width]
+
+		switch bitpix {
+		case 8:
+			values[i] = float64(b[0])
+		case 16:
+			values[i] = float64(int16(binary.BigEndian.Uint16(b)))
+		case 32:
+			values[i] = float64(int32(binary.BigEndian.Uint32(b)))
+		case 64:
+			values[i] = float64(int64(binary.BigEndian.Uint64(b)))
+		case -32:
+			values[i] = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case -64:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(b))
+		default:
+			return nil, fmt.Errorf("unsupported BITPIX: %d", bitpix)
+		}
+	}
+
+	return values, nil
+}
+
+/*****************************************************************************************************************/
+
+// RiceDecompress decodes n integers of bytepix (1, 2 or 4) bytes from the Rice compressed stream, where each block
+// of pixels encodes the differences between consecutive pixels with a common number of split bits (fs).
+//
+// @see https://fits.gsfc.nasa.gov/registry/tilecompression/tilecompression2.3.pdf (Section 5.1)
+func RiceDecompress(compressed []byte, n int, blocksize int, bytepix int) ([]float64, error) {
+	var fsbits, fsmax, bbits int
+
+	switch bytepix {
+	case 1:
+		fsbits, fsmax, bbits = 3, 6, 8
+	case 2:
+		fsbits, fsmax, bbits = 4, 14, 16
+	case 4:
+		fsbits, fsmax, bbits = 5, 25, 32
+	default:
+		return nil, fmt.Errorf("unsupported Rice BYTEPIX: %d", bytepix)
+	}
+
+	if blocksize <= 0 {
+		return nil, fmt.Errorf("invalid Rice BLOCKSIZE: %d", blocksize)
+	}
+
+	if len(compressed) < bytepix+1 {
+		return nil, errors.New("the Rice compressed tile is truncated")
+	}
+
+	c := 0
+
+	next := func() (uint32, error) {
+		if c >= len(compressed) {
+			return 0, errors.New("the Rice compressed tile is truncated")
+		}
+
+		v := uint32(compressed[c])
+		c++
+
+		return v, nil
+	}
+
+	// The first pixel is stored uncompressed:
+	lastpix := uint32(0)
+
+	for i := 0; i < bytepix; i++ {
+		lastpix = lastpix<<8 | uint32(compressed[i])
+	}
+
+	c = bytepix
+
+	// The mask that truncates each pixel to its width, as the differences wrap around:
+	mask := uint32(math.MaxUint32)
+
+	if bbits < 32 {
+		mask = 1<<bbits - 1
+	}
+
+	values := make([]float64, n)
+
+	// Interpret the unsigned pixel as a signed integer of its width (except bytes, which are unsigned):
+	signed := func(v uint32) float64 {
+		switch bytepix {
+		case 1:
+			return float64(uint8(v))
+		case 2:
+			return float64(int16(v))
+		default:
+			return float64(int32(v))
+		}
+	}
+
+	// Undo the mapping of the signed differences onto the non-negative integers:
+	unmap := func(diff uint32) uint32 {
+		if diff&1 == 0 {
+			return diff >> 1
+		}
+
+		return ^(diff >> 1)
+	}
+
+	b, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	nbits := 8
+
+	for i := 0; i < n; {
+		// Read the split bits (fs) of the block:
+		nbits -= fsbits
+
+		for nbits < 0 {
+			v, err := next()
+			if err != nil {
+				return nil, err
+			}
+
+			b = b<<8 | v
+			nbits += 8
+		}
+
+		fs := int(b>>uint(nbits)) - 1
+
+		b &= 1<<uint(nbits) - 1
+
+		imax := min(i+blocksize, n)
+
+		switch {
+		case fs < 0:
+			// A low entropy block, where every difference is zero:
+			for ; i < imax; i++ {
+				values[i] = signed(lastpix)
+			}
+		case fs == fsmax:
+			// A high entropy block, where every difference is stored uncompressed:
+			for ; i < imax; i++ {
+				k := bbits - nbits
+
+				diff := uint32(0)
+
+				if k < 32 {
+					diff = b << uint(k)
+				}
+
+				for k -= 8; k >= 0; k -= 8 {
+					v, err := next()
+					if err != nil {
+						return nil, err
+					}
+
+					b = v
+					diff |= b << uint(k)
+				}
+
+				if nbits > 0 {
+					v, err := next()
+					if err != nil {
+						return nil, err
+					}
+
+					b = v
+					diff |= b >> uint(-k)
+					b &= 1<<uint(nbits) - 1
+				} else {
+					b = 0
+				}
+
+				lastpix = (unmap(diff) + lastpix) & mask
+				values[i] = signed(lastpix)
+			}
+		default:
+			// Each difference is a unary coded quotient, followed by fs bits of remainder:
+			for ; i < imax; i++ {
+				for b == 0 {
+					v, err := next()
+					if err != nil {
+						return nil, err
+					}
+
+					nbits += 8
+					b = v
+				}
+
+				nzero := nbits - bits.Len32(b)
+
+				nbits -= nzero + 1
+
+				b ^= 1 << uint(nbits)
+
+				nbits -= fs
+
+				for nbits < 0 {
+					v, err := next()
+					if err != nil {
+						return nil, err
+					}
+
+					b = b<<8 | v
+					nbits += 8
+				}
+
+				diff := uint32(nzero)<<uint(fs) | b>>uint(nbits)
+
+				b &= 1<<uint(nbits) - 1
+
+				lastpix = (unmap(diff) + lastpix) & mask
+				values[i] = signed(lastpix)
+			}
+		}
+	}
+
+	return values, nil
+}
+
+/*****************************************************************************************************************/
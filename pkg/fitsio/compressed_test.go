@@ -0,0 +1,579 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// bitWriter writes a big-endian stream of bits, as used by the Rice compressor.
+type bitWriter struct {
+	buf   []byte
+	word  uint64
+	nbits int
+}
+
+func (w *bitWriter) write(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.word = w.word<<1 | uint64(v>>uint(i)&1)
+		w.nbits++
+
+		if w.nbits == 8 {
+			w.buf = append(w.buf, byte(w.word))
+			w.word, w.nbits = 0, 0
+		}
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.write(0, 8-w.nbits)
+	}
+
+	return w.buf
+}
+
+/*****************************************************************************************************************/
+
+// riceCompress is a reference Rice compressor of the tiled image convention, after fits_rcomp of CFITSIO.
+func riceCompress(values []int64, blocksize int, bytepix int) []byte {
+	p := map[int][3]int{1: {3, 6, 8}, 2: {4, 14, 16}, 4: {5, 25, 32}}[bytepix]
+
+	fsbits, fsmax, bbits := p[0], p[1], p[2]
+
+	mask := uint32(1<<uint(bbits) - 1)
+
+	w := &bitWriter{}
+
+	w.write(uint32(values[0])&mask, bbits)
+
+	lastpix := uint32(values[0]) & mask
+
+	for i := 0; i < len(values); i += blocksize {
+		block := values[i:min(i+blocksize, len(values))]
+
+		diffs := make([]uint32, len(block))
+
+		sum := 0.0
+
+		for j, v := range block {
+			next := uint32(v) & mask
+
+			// The difference, sign extended from the width of the pixels:
+			pdiff := int32((next-lastpix)&mask) << uint(32-bbits) >> uint(32-bbits)
+
+			if pdiff < 0 {
+				diffs[j] = ^uint32(pdiff << 1)
+			} else {
+				diffs[j] = uint32(pdiff << 1)
+			}
+
+			diffs[j] &= mask
+
+			sum += float64(diffs[j])
+
+			lastpix = next
+		}
+
+		dpsum := math.Max((sum-float64(len(block)/2)-1)/float64(len(block)), 0)
+
+		fs := 0
+
+		for psum := uint32(dpsum) >> 1; psum > 0; psum >>= 1 {
+			fs++
+		}
+
+		switch {
+		case fs >= fsmax:
+			w.write(uint32(fsmax+1), fsbits)
+
+			for _, d := range diffs {
+				w.write(d, bbits)
+			}
+		case fs == 0 && sum == 0:
+			w.write(0, fsbits)
+		default:
+			w.write(uint32(fs+1), fsbits)
+
+			for _, d := range diffs {
+				// The unary coded quotient, followed by the remainder in fs bits:
+				for top := d >> uint(fs); top > 0; top-- {
+					w.write(0, 1)
+				}
+
+				w.write(1, 1)
+
+				if fs > 0 {
+					w.write(d&(1<<uint(fs)-1), fs)
+				}
+			}
+		}
+	}
+
+	return w.bytes()
+}
+
+/*****************************************************************************************************************/
+
+// newCompressedImageHDU compresses an image into a tile-compressed binary table, with the per-tile ZSCALE and ZZERO
+// columns of a quantized floating point image when scales is non-nil.
+func newCompressedImageHDU(
+	axes []int,
+	tiles []int,
+	algorithm string,
+	bitpix int,
+	compress func(tile []int64, row int) []byte,
+	values []int64,
+	scales []float64,
+	zeros []float64,
+) *HDU {
+	counts := make([]int, len(axes))
+	total := 1
+
+	for i := range axes {
+		counts[i] = (axes[i] + tiles[i] - 1) / tiles[i]
+		total *= counts[i]
+	}
+
+	rowSize := 8
+
+	if scales != nil {
+		rowSize += 16
+	}
+
+	main := make([]byte, total*rowSize)
+	heap := []byte{}
+
+	for row := 0; row < total; row++ {
+		start := make([]int, len(axes))
+		shape := make([]int, len(axes))
+
+		size := 1
+
+		for i, r := 0, row; i < len(axes); i++ {
+			start[i] = (r % counts[i]) * tiles[i]
+			shape[i] = min(tiles[i], axes[i]-start[i])
+			size *= shape[i]
+			r /= counts[i]
+		}
+
+		tile := make([]int64, size)
+
+		for k := range tile {
+			index, stride := 0, 1
+
+			for i, rest := 0, k; i < len(axes); i++ {
+				index += (start[i] + rest%shape[i]) * stride
+				stride *= axes[i]
+				rest /= shape[i]
+			}
+
+			tile[k] = values[index]
+		}
+
+		compressed := compress(tile, row)
+
+		binary.BigEndian.PutUint32(main[row*rowSize:], uint32(len(compressed)))
+		binary.BigEndian.PutUint32(main[row*rowSize+4:], uint32(len(heap)))
+
+		if scales != nil {
+			binary.BigEndian.PutUint64(main[row*rowSize+8:], math.Float64bits(scales[row]))
+			binary.BigEndian.PutUint64(main[row*rowSize+16:], math.Float64bits(zeros[row]))
+		}
+
+		heap = append(heap, compressed...)
+	}
+
+	header := NewHeader()
+	header.Set("XTENSION", "BINTABLE", "")
+	header.Set("BITPIX", 8, "")
+	header.Set("NAXIS", 2, "")
+	header.Set("NAXIS1", rowSize, "")
+	header.Set("NAXIS2", total, "")
+	header.Set("PCOUNT", len(heap), "")
+	header.Set("GCOUNT", 1, "")
+	header.Set("TFIELDS", map[bool]int{true: 3, false: 1}[scales != nil], "")
+	header.Set("TTYPE1", "COMPRESSED_DATA", "")
+	header.Set("TFORM1", "1PB(4096)", "")
+
+	if scales != nil {
+		header.Set("TTYPE2", "ZSCALE", "")
+		header.Set("TFORM2", "1D", "")
+		header.Set("TTYPE3", "ZZERO", "")
+		header.Set("TFORM3", "1D", "")
+	}
+
+	header.Set("ZIMAGE", true, "")
+	header.Set("ZCMPTYPE", algorithm, "")
+	header.Set("ZBITPIX", bitpix, "")
+	header.Set("ZNAXIS", len(axes), "")
+
+	for i := range axes {
+		header.Set(fmt.Sprintf("ZNAXIS%d", i+1), axes[i], "")
+		header.Set(fmt.Sprintf("ZTILE%d", i+1), tiles[i], "")
+	}
+
+	header.Set("RA", 98.5, "")
+
+	return &HDU{
+		Header: header,
+		Data:   append(main, heap...),
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRandomNumbersMatchTheTiledImageConvention(t *testing.T) {
+	// The final seed of the generator, as verified by CFITSIO when initialising its table of single precision values:
+	if want := float64(float32(1043618065 / 2147483647.0)); randoms[N_RANDOM-1] != want {
+		t.Errorf("expected the final value %v of the seed 1043618065, got %v", want, randoms[N_RANDOM-1])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRiceDecompress(t *testing.T) {
+	for _, bytepix := range []int{1, 2, 4} {
+		bbits := 8 * bytepix
+
+		values := make([]int64, 200)
+
+		for i := range values {
+			switch {
+			case i < 40:
+				// A constant, i.e., low entropy, run:
+				values[i] = 7
+			case i < 80:
+				// Large jumps, i.e., high entropy blocks:
+				values[i] = int64((i * 7919 * 104729) % (1 << uint(bbits-1)))
+			default:
+				// A slowly varying signal:
+				values[i] = int64(20 + i%5 - 2*(i%3))
+			}
+		}
+
+		// Bytes are unsigned, whereas wider pixels are signed:
+		if bytepix > 1 {
+			values[150] = -3
+		}
+
+		decoded, err := RiceDecompress(riceCompress(values, 32, bytepix), len(values), 32, bytepix)
+		if err != nil {
+			t.Fatalf("bytepix %d: unexpected error: %v", bytepix, err)
+		}
+
+		for i, v := range decoded {
+			if v != float64(values[i]) {
+				t.Fatalf("bytepix %d, pixel %d: expected %v, got %v", bytepix, i, values[i], v)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewImageFromCompressedHDUWithRiceIntegers(t *testing.T) {
+	axes, tiles := []int{20, 10}, []int{8, 4}
+
+	values := make([]int64, 200)
+
+	for i := range values {
+		values[i] = int64(1000 + (i*37)%500 - 250)
+	}
+
+	hdu := newCompressedImageHDU(axes, tiles, "RICE_1", 16, func(tile []int64, row int) []byte {
+		return riceCompress(tile, 32, 2)
+	}, values, nil, nil)
+
+	hdu.Header.Set("ZNAME1", "BLOCKSIZE", "")
+	hdu.Header.Set("ZVAL1", 32, "")
+	hdu.Header.Set("ZNAME2", "BYTEPIX", "")
+	hdu.Header.Set("ZVAL2", 2, "")
+
+	if !IsCompressedImageHDU(hdu) {
+		t.Fatalf("expected a tile-compressed image HDU")
+	}
+
+	image, err := NewImageFromCompressedHDU(hdu)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if image.Width() != 20 || image.Height() != 10 {
+		t.Fatalf("expected a 20x10 image, got %dx%d", image.Width(), image.Height())
+	}
+
+	for i, v := range image.Data {
+		if v != float32(values[i]) {
+			t.Fatalf("pixel %d: expected %v, got %v", i, values[i], v)
+		}
+	}
+
+	header, err := NewHeaderFromCompressedHDU(hdu)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if xtension, _ := header.String("XTENSION"); xtension != "IMAGE" || header.Has("ZCMPTYPE") || header.Has("TFORM1") {
+		t.Errorf("expected the header of an image extension without compression keywords, got %v", header.Cards)
+	}
+
+	if width, _ := header.Int("NAXIS1"); width != 20 {
+		t.Errorf("expected NAXIS1 of 20, got %d", width)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewImageFromCompressedHDUWithGZIPFloats(t *testing.T) {
+	axes, tiles := []int{6, 5}, []int{6, 1}
+
+	floats := make([]float32, 30)
+
+	for i := range floats {
+		floats[i] = float32(i)*0.25 - 3
+	}
+
+	// The raw IEEE floating point values, byte shuffled for GZIP_2:
+	values := make([]int64, len(floats))
+
+	for i, f := range floats {
+		values[i] = int64(math.Float32bits(f))
+	}
+
+	hdu := newCompressedImageHDU(axes, tiles, "GZIP_2", -32, func(tile []int64, row int) []byte {
+		shuffled := make([]byte, 4*len(tile))
+
+		for i, v := range tile {
+			for b := 0; b < 4; b++ {
+				shuffled[b*len(tile)+i] = byte(uint32(v) >> uint(24-8*b))
+			}
+		}
+
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+		w.Write(shuffled)
+		w.Close()
+
+		return buf.Bytes()
+	}, values, nil, nil)
+
+	image, err := NewImageFromCompressedHDU(hdu)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, v := range image.Data {
+		if v != floats[i] {
+			t.Fatalf("pixel %d: expected %v, got %v", i, floats[i], v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewImageFromCompressedHDUWithDitheredQuantization(t *testing.T) {
+	axes, tiles := []int{16, 4}, []int{16, 1}
+
+	floats := make([]float64, 64)
+
+	for i := range floats {
+		floats[i] = 100 + 10*math.Sin(float64(i))
+	}
+
+	scales, zeros := []float64{0.05, 0.05, 0.1, 0.1}, []float64{100, 100, 100, 100}
+
+	// Quantize each tile with the subtractive dithering of the convention:
+	values := make([]int64, len(floats))
+
+	for row := 0; row < 4; row++ {
+		r := row % N_RANDOM
+		next := int(randoms[r] * 500)
+
+		for k := 0; k < 16; k++ {
+			i := row*16 + k
+			values[i] = int64(math.Round((floats[i]-zeros[row])/scales[row] + randoms[next] - 0.5))
+			next++
+		}
+	}
+
+	hdu := newCompressedImageHDU(axes, tiles, "RICE_1", -32, func(tile []int64, row int) []byte {
+		return riceCompress(tile, 32, 4)
+	}, values, scales, zeros)
+
+	hdu.Header.Set("ZQUANTIZ", "SUBTRACTIVE_DITHER_1", "")
+	hdu.Header.Set("ZDITHER0", 1, "")
+
+	image, err := NewImageFromCompressedHDU(hdu)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, v := range image.Data {
+		if math.Abs(float64(v)-floats[i]) > scales[i/16]/2+1e-4 {
+			t.Fatalf("pixel %d: expected %v within half a quantization step, got %v", i, floats[i], v)
+		}
+	}
+
+	// The frames of a compressed file are read from their decompressed image and header:
+	frames, err := GetFrames([]*HDU{NewPrimaryHDU(), hdu})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(frames) != 1 || frames[0].Width != 16 || frames[0].Height != 4 {
+		t.Fatalf("expected a single 16x4 frame, got %v", frames)
+	}
+
+	if ra, _ := frames[0].Header.Float("RA"); ra != 98.5 {
+		t.Errorf("expected the RA of the compressed header, got %v", ra)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// readCompressedFixture reads the frame of a tile-compressed image written in the layout of fpack, as generated by
+// testdata/generate.py:
+func readCompressedFixture(t *testing.T, name string) Frame {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", name, err)
+	}
+	defer f.Close()
+
+	hdus, err := ReadHDUs(f)
+	if err != nil {
+		t.Fatalf("%s: failed to read the HDUs: %v", name, err)
+	}
+
+	if len(hdus) != 2 || !IsCompressedImageHDU(hdus[1]) {
+		t.Fatalf("%s: expected an empty primary HDU and a tile-compressed image", name)
+	}
+
+	frames, err := GetFrames(hdus)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", name, err)
+	}
+
+	if len(frames) != 1 {
+		t.Fatalf("%s: expected a single frame, got %d", name, len(frames))
+	}
+
+	if object, _ := frames[0].Header.String("OBJECT"); object != "M 42" {
+		t.Errorf("%s: expected the OBJECT of the compressed header, got %q", name, object)
+	}
+
+	return frames[0]
+}
+
+/*****************************************************************************************************************/
+
+func TestReadRiceInt16Fixture(t *testing.T) {
+	frame := readCompressedFixture(t, "rice-int16.fits.fz")
+
+	if frame.Width != 16 || frame.Height != 8 {
+		t.Fatalf("expected a 16x8 frame, got %dx%d", frame.Width, frame.Height)
+	}
+
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			want := (x*37+y*101)%2000 - 1000
+
+			if x == 8 {
+				want += 500
+			}
+
+			// The extremes of the 16-bit integers:
+			switch {
+			case x == 0 && y == 0:
+				want = math.MinInt16
+			case x == 15 && y == 7:
+				want = math.MaxInt16
+			}
+
+			if got := frame.Data[y*16+x]; got != float32(want) {
+				t.Errorf("pixel (%d, %d): expected %v, got %v", x, y, want, got)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadGZIP2FloatFixture(t *testing.T) {
+	frame := readCompressedFixture(t, "gzip2-float.fits.fz")
+
+	if frame.Width != 12 || frame.Height != 6 {
+		t.Fatalf("expected a 12x6 frame, got %dx%d", frame.Width, frame.Height)
+	}
+
+	// The floating point values are compressed losslessly:
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 12; x++ {
+			want := float32((float64(x)-5.5)*0.125 + float64(y)*1000 + 1.0/3.0)
+
+			if got := frame.Data[y*12+x]; got != want {
+				t.Errorf("pixel (%d, %d): expected %v, got %v", x, y, want, got)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadSubtractiveDither1Fixture(t *testing.T) {
+	frame := readCompressedFixture(t, "dither1-float.fits.fz")
+
+	if frame.Width != 20 || frame.Height != 10 {
+		t.Fatalf("expected a 20x10 frame, got %dx%d", frame.Width, frame.Height)
+	}
+
+	for y := 0; y < 10; y++ {
+		// The quantization step of each row (tile) of the image:
+		step := 0.05 + 0.01*float64(y)
+
+		for x := 0; x < 20; x++ {
+			want := float64(float32(1000 + 25*math.Sin(0.7*float64(x))*math.Cos(0.3*float64(y)) + 0.5*float64(y)))
+
+			if got := float64(frame.Data[y*20+x]); math.Abs(got-want) > step/2+1e-4 {
+				t.Errorf("pixel (%d, %d): expected %v within half a quantization step, got %v", x, y, want, got)
+			}
+		}
+	}
+
+	// The dequantized values, which depend on the random numbers of each tile offset by ZDITHER0 = 3:
+	for _, pixel := range []struct {
+		x, y int
+		want float32
+	}{
+		{0, 0, 1000.003173828125},
+		{7, 3, 986.2672119140625},
+		{19, 9, 989.390869140625},
+	} {
+		if got := frame.Data[pixel.y*20+pixel.x]; got != pixel.want {
+			t.Errorf("pixel (%d, %d): expected %v, got %v", pixel.x, pixel.y, pixel.want, got)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,269 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*****************************************************************************************************************/
+
+// The structural keywords of a header, which describe its own data array and are never inherited from the primary
+// header by an extension:
+var structuralKeywords = map[string]bool{
+	"SIMPLE":   true,
+	"XTENSION": true,
+	"BITPIX":   true,
+	"NAXIS":    true,
+	"NAXIS1":   true,
+	"NAXIS2":   true,
+	"NAXIS3":   true,
+	"NAXIS4":   true,
+	"EXTEND":   true,
+	"PCOUNT":   true,
+	"GCOUNT":   true,
+	"BSCALE":   true,
+	"BZERO":    true,
+	"BLANK":    true,
+	"EXTNAME":  true,
+	"EXTVER":   true,
+	"INHERIT":  true,
+	"CHECKSUM": true,
+	"DATASUM":  true,
+	"COMMENT":  true,
+	"HISTORY":  true,
+	"":         true,
+}
+
+/*****************************************************************************************************************/
+
+// Frame is a single two dimensional image plane of a FITS file, e.g., the primary image, one CCD of a mosaic camera
+// written as an IMAGE extension, or one plane of a data cube.
+type Frame struct {
+	HDU    int       // the index of the HDU within the file, where 0 is the primary HDU
+	Plane  int       // the index of the plane within the data cube of the HDU
+	Planes int       // the number of planes within the data cube of the HDU
+	Name   string    // the EXTNAME of the HDU, if any
+	Header *Header   // the header of the HDU, including any keywords inherited from the primary header
+	Width  int       // the length of the first axis (NAXIS1)
+	Height int       // the length of the second axis (NAXIS2)
+	Data   []float32 // the physical values of the plane
+}
+
+/*****************************************************************************************************************/
+
+// String returns a short description of the frame, e.g., "HDU 2 (CCD2) plane 1/3".
+func (f Frame) String() string {
+	s := fmt.Sprintf("HDU %d", f.HDU)
+
+	if f.Name != "" {
+		s += fmt.Sprintf(" (%s)", f.Name)
+	}
+
+	if f.Planes > 1 {
+		s += fmt.Sprintf(" plane %d/%d", f.Plane+1, f.Planes)
+	}
+
+	return s
+}
+
+/*****************************************************************************************************************/
+
+// IsImageHDU determines whether the HDU holds an image of at least two dimensions, i.e., a primary HDU or an IMAGE
+// extension with NAXIS >= 2 and non-empty axes.
+func IsImageHDU(hdu *HDU) bool {
+	if xtension := hdu.Extension(); xtension != "" && xtension != "IMAGE" {
+		return false
+	}
+
+	naxis, ok := hdu.Header.Int("NAXIS")
+	if !ok || naxis < 2 {
+		return false
+	}
+
+	for i := 1; i <= int(naxis); i++ {
+		if n, ok := hdu.Header.Int(fmt.Sprintf("NAXIS%d", i)); !ok || n <= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*****************************************************************************************************************/
+
+// InheritHeader returns a copy of the extension header with the non-structural keywords of the primary header that
+// the extension does not define, e.g., the pointing, exposure and site keywords that mosaic cameras often only
+// record once in the primary header.
+func InheritHeader(primary *Header, extension *Header) *Header {
+	header := extension.Copy()
+
+	if primary == nil || primary == extension {
+		return header
+	}
+
+	// An explicit INHERIT = F disables the inheritance of the primary keywords:
+	if inherit, ok := extension.Bool("INHERIT"); ok && !inherit {
+		return header
+	}
+
+	for _, card := range primary.Cards {
+		if structuralKeywords[card.Key] || card.Value == nil || header.Has(card.Key) {
+			continue
+		}
+
+		header.Cards = append(header.Cards, card)
+	}
+
+	return header
+}
+
+/*****************************************************************************************************************/
+
+// GetFrames returns every two dimensional image plane of the header and data units, in file order, decompressing any
+// tile-compressed images and skipping any HDUs that do not hold an image, e.g., an empty primary HDU or tables.
+func GetFrames(hdus []*HDU) ([]Frame, error) {
+	frames := []Frame{}
+
+	if len(hdus) == 0 {
+		return frames, errors.New("no FITS header and data units found")
+	}
+
+	primary := hdus[0].Header
+
+	for i, hdu := range hdus {
+		var image *Image
+
+		var err error
+
+		// The header of the (decompressed) image, which differs from that of a tile-compressed binary table:
+		h := hdu.Header
+
+		switch {
+		case IsImageHDU(hdu):
+			image, err = NewImageFromHDU(hdu)
+		case IsCompressedImageHDU(hdu):
+			h, err = NewHeaderFromCompressedHDU(hdu)
+
+			if err == nil {
+				image, err = NewImageFromCompressedHDU(hdu)
+			}
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("HDU %d: %w", i, err)
+		}
+
+		name, _ := h.String("EXTNAME")
+
+		header := InheritHeader(primary, h)
+
+		for k := 0; k < image.Planes(); k++ {
+			data, err := image.Plane(k)
+			if err != nil {
+				return nil, fmt.Errorf("HDU %d: %w", i, err)
+			}
+
+			frames = append(frames, Frame{
+				HDU:    i,
+				Plane:  k,
+				Planes: image.Planes(),
+				Name:   name,
+				Header: header,
+				Width:  image.Width(),
+				Height: image.Height(),
+				Data:   data,
+			})
+		}
+	}
+
+	if len(frames) == 0 {
+		return frames, errors.New("no image HDUs found")
+	}
+
+	return frames, nil
+}
+
+/*****************************************************************************************************************/
+
+// StackFrames combines the planes of a data cube into a single frame of their mean, ignoring NaN (BLANK) values,
+// such that the stars are extracted once, at a higher signal to noise, rather than from every plane.
+func StackFrames(frames []Frame) (Frame, error) {
+	if len(frames) == 0 {
+		return Frame{}, errors.New("no frames to stack")
+	}
+
+	stack := frames[0]
+
+	for _, frame := range frames[1:] {
+		if frame.Width != stack.Width || frame.Height != stack.Height {
+			return Frame{}, fmt.Errorf("cannot stack frames of %dx%d and %dx%d pixels", stack.Width, stack.Height, frame.Width, frame.Height)
+		}
+	}
+
+	data := make([]float32, len(stack.Data))
+
+	for i := range data {
+		sum, n := 0.0, 0
+
+		for _, frame := range frames {
+			if v := float64(frame.Data[i]); !math.IsNaN(v) {
+				sum += v
+				n++
+			}
+		}
+
+		if n == 0 {
+			data[i] = float32(math.NaN())
+			continue
+		}
+
+		data[i] = float32(sum / float64(n))
+	}
+
+	stack.Data = data
+	stack.Plane = 0
+	stack.Planes = 1
+
+	return stack, nil
+}
+
+/*****************************************************************************************************************/
+
+// StackFramesByHDU stacks the planes of each data cube, returning a single frame per image HDU, in file order.
+func StackFramesByHDU(frames []Frame) ([]Frame, error) {
+	stacks := []Frame{}
+
+	for start := 0; start < len(frames); {
+		end := start + 1
+
+		for end < len(frames) && frames[end].HDU == frames[start].HDU {
+			end++
+		}
+
+		stack, err := StackFrames(frames[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("HDU %d: %w", frames[start].HDU, err)
+		}
+
+		stacks = append(stacks, stack)
+
+		start = end
+	}
+
+	return stacks, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,206 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*****************************************************************************************************************/
+
+// HDU is a single FITS header and data unit, where the data is the raw (big-endian) data array of the unit,
+// excluding any trailing padding.
+type HDU struct {
+	Header *Header
+	Data   []byte
+}
+
+/*****************************************************************************************************************/
+
+// NewPrimaryHDU creates a primary HDU without a data array, e.g., to precede one or more extensions.
+func NewPrimaryHDU() *HDU {
+	header := NewHeader()
+
+	header.Set("SIMPLE", true, "conforms to FITS standard")
+	header.Set("BITPIX", 8, "array data type")
+	header.Set("NAXIS", 0, "number of array dimensions")
+	header.Set("EXTEND", true, "FITS dataset may contain extensions")
+
+	return &HDU{
+		Header: header,
+		Data:   []byte{},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// IsPrimary determines whether the HDU is a primary HDU, i.e., its header begins with the SIMPLE keyword.
+func (h *HDU) IsPrimary() bool {
+	return len(h.Header.Cards) > 0 && h.Header.Cards[0].Key == "SIMPLE"
+}
+
+/*****************************************************************************************************************/
+
+// Extension returns the XTENSION type of the HDU, e.g., "IMAGE" or "BINTABLE", or an empty string for a primary HDU.
+func (h *HDU) Extension() string {
+	xtension, _ := h.Header.String("XTENSION")
+	return xtension
+}
+
+/*****************************************************************************************************************/
+
+// DataSize returns the size (in bytes) of the data array described by the header, excluding any padding.
+//
+// @see https://fits.gsfc.nasa.gov/standard40/fits_standard40aa-le.pdf (Section 4.4.1.1)
+func DataSize(h *Header) (int64, error) {
+	bitpix, ok := h.Int("BITPIX")
+	if !ok {
+		return 0, errors.New("FITS header is missing the BITPIX keyword")
+	}
+
+	naxis, ok := h.Int("NAXIS")
+	if !ok {
+		return 0, errors.New("FITS header is missing the NAXIS keyword")
+	}
+
+	if naxis == 0 {
+		return 0, nil
+	}
+
+	size := int64(1)
+
+	for i := int64(1); i <= naxis; i++ {
+		n, ok := h.Int(fmt.Sprintf("NAXIS%d", i))
+		if !ok {
+			return 0, fmt.Errorf("FITS header is missing the NAXIS%d keyword", i)
+		}
+		size *= n
+	}
+
+	// Random groups and tables may carry a heap (PCOUNT) and multiple groups (GCOUNT):
+	pcount, ok := h.Int("PCOUNT")
+	if !ok {
+		pcount = 0
+	}
+
+	gcount, ok := h.Int("GCOUNT")
+	if !ok {
+		gcount = 1
+	}
+
+	if bitpix < 0 {
+		bitpix = -bitpix
+	}
+
+	return (bitpix / 8) * gcount * (pcount + size), nil
+}
+
+/*****************************************************************************************************************/
+
+// padding returns the number of bytes required to pad n bytes to a whole number of FITS blocks.
+func padding(n int64) int64 {
+	if partial := n % BLOCK_SIZE; partial > 0 {
+		return BLOCK_SIZE - partial
+	}
+
+	return 0
+}
+
+/*****************************************************************************************************************/
+
+// ReadHDU reads the next header and data unit from the reader.
+func ReadHDU(r io.Reader) (*HDU, error) {
+	header, _, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := DataSize(header)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, size)
+
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read FITS data array: %w", err)
+	}
+
+	// Discard the padding at the end of the data array, tolerating truncated final blocks:
+	if _, err := io.CopyN(io.Discard, r, padding(size)); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read FITS data padding: %w", err)
+	}
+
+	return &HDU{
+		Header: header,
+		Data:   data,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// ReadHDUs reads all of the header and data units from the reader, e.g., the primary HDU and all extensions.
+func ReadHDUs(r io.Reader) ([]*HDU, error) {
+	hdus := []*HDU{}
+
+	for {
+		hdu, err := ReadHDU(r)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		hdus = append(hdus, hdu)
+	}
+
+	if len(hdus) == 0 {
+		return nil, errors.New("no FITS header and data units found")
+	}
+
+	return hdus, nil
+}
+
+/*****************************************************************************************************************/
+
+// Bytes serialises the HDU into FITS blocks, padding the data array with zeros.
+func (h *HDU) Bytes() []byte {
+	var buf bytes.Buffer
+
+	buf.Write(h.Header.Bytes())
+
+	buf.Write(h.Data)
+
+	buf.Write(make([]byte, padding(int64(len(h.Data)))))
+
+	return buf.Bytes()
+}
+
+/*****************************************************************************************************************/
+
+// WriteHDUs writes all of the given header and data units to the writer.
+func WriteHDUs(w io.Writer, hdus []*HDU) error {
+	for _, hdu := range hdus {
+		if _, err := w.Write(hdu.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,506 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// The size of a FITS logical block, in bytes:
+const BLOCK_SIZE = 2880
+
+/*****************************************************************************************************************/
+
+// The size of a FITS header card, in bytes:
+const CARD_SIZE = 80
+
+/*****************************************************************************************************************/
+
+// Card is a single 80 character FITS header record, where the value is one of bool, int64, float64, string, or
+// nil for commentary cards, e.g., COMMENT, HISTORY or blank keywords.
+//
+// @see https://fits.gsfc.nasa.gov/standard40/fits_standard40aa-le.pdf
+type Card struct {
+	Key     string
+	Value   interface{}
+	Comment string
+}
+
+/*****************************************************************************************************************/
+
+// Header is an ordered list of FITS header cards. Unlike the iris FITSHeader, values are kept at full (float64)
+// precision, and the order of the cards is preserved, which matters for WCS and extension headers.
+type Header struct {
+	Cards []Card
+}
+
+/*****************************************************************************************************************/
+
+// NewHeader creates a new, empty FITS header.
+func NewHeader() *Header {
+	return &Header{
+		Cards: []Card{},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Index returns the index of the first card with the given key, or -1 if the key does not exist.
+func (h *Header) Index(key string) int {
+	for i, card := range h.Cards {
+		if card.Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+/*****************************************************************************************************************/
+
+// Has determines whether a valued card with the given key exists in the header.
+func (h *Header) Has(key string) bool {
+	i := h.Index(key)
+	return i >= 0 && h.Cards[i].Value != nil
+}
+
+/*****************************************************************************************************************/
+
+// Get returns the value of the first card with the given key.
+func (h *Header) Get(key string) (interface{}, bool) {
+	i := h.Index(key)
+
+	if i < 0 || h.Cards[i].Value == nil {
+		return nil, false
+	}
+
+	return h.Cards[i].Value, true
+}
+
+/*****************************************************************************************************************/
+
+// Float returns the value of the given key as a float64, converting integer values where necessary.
+func (h *Header) Float(key string) (float64, bool) {
+	v, ok := h.Get(key)
+	if !ok {
+		return math.NaN(), false
+	}
+
+	switch value := v.(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	default:
+		return math.NaN(), false
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Int returns the value of the given key as an int64, converting integral float values where necessary.
+func (h *Header) Int(key string) (int64, bool) {
+	v, ok := h.Get(key)
+	if !ok {
+		return 0, false
+	}
+
+	switch value := v.(type) {
+	case int64:
+		return value, true
+	case float64:
+		if value == math.Trunc(value) {
+			return int64(value), true
+		}
+		return 0, false
+	default:
+		return 0, false
+	}
+}
+
+/*****************************************************************************************************************/
+
+// String returns the value of the given key as a string.
+func (h *Header) String(key string) (string, bool) {
+	v, ok := h.Get(key)
+	if !ok {
+		return "", false
+	}
+
+	value, ok := v.(string)
+
+	return value, ok
+}
+
+/*****************************************************************************************************************/
+
+// Bool returns the value of the given key as a bool.
+func (h *Header) Bool(key string) (bool, bool) {
+	v, ok := h.Get(key)
+	if !ok {
+		return false, false
+	}
+
+	value, ok := v.(bool)
+
+	return value, ok
+}
+
+/*****************************************************************************************************************/
+
+// Set replaces the value and comment of the first card with the given key, or appends a new card if the key does
+// not yet exist. Integer and float values of any width are normalised to int64 and float64 respectively.
+func (h *Header) Set(key string, value interface{}, comment string) error {
+	switch v := value.(type) {
+	case bool, string, int64, float64:
+	case int:
+		value = int64(v)
+	case int32:
+		value = int64(v)
+	case int16:
+		value = int64(v)
+	case uint8:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	default:
+		return fmt.Errorf("unsupported FITS header value type %T for key %s", value, key)
+	}
+
+	if len(key) > 8 {
+		return fmt.Errorf("FITS header key %s exceeds 8 characters", key)
+	}
+
+	card := Card{
+		Key:     key,
+		Value:   value,
+		Comment: comment,
+	}
+
+	if i := h.Index(key); i >= 0 {
+		h.Cards[i] = card
+		return nil
+	}
+
+	h.Cards = append(h.Cards, card)
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Delete removes all cards with the given key from the header.
+func (h *Header) Delete(key string) {
+	cards := h.Cards[:0]
+
+	for _, card := range h.Cards {
+		if card.Key != key {
+			cards = append(cards, card)
+		}
+	}
+
+	h.Cards = cards
+}
+
+/*****************************************************************************************************************/
+
+// Copy returns a deep copy of the header.
+func (h *Header) Copy() *Header {
+	cards := make([]Card, len(h.Cards))
+	copy(cards, h.Cards)
+
+	return &Header{
+		Cards: cards,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// ReadHeader reads FITS header blocks from the reader until the END card is found, returning the header and the
+// number of bytes consumed from the reader.
+func ReadHeader(r io.Reader) (*Header, int64, error) {
+	h := NewHeader()
+
+	block := make([]byte, BLOCK_SIZE)
+
+	n := int64(0)
+
+	for {
+		read, err := io.ReadFull(r, block)
+		n += int64(read)
+
+		if err != nil {
+			if err == io.EOF && n == 0 {
+				return nil, n, io.EOF
+			}
+			return nil, n, fmt.Errorf("failed to read FITS header block: %w", err)
+		}
+
+		for i := 0; i < BLOCK_SIZE/CARD_SIZE; i++ {
+			line := string(block[i*CARD_SIZE : (i+1)*CARD_SIZE])
+
+			if strings.TrimRight(line[0:8], " ") == "END" {
+				return h, n, nil
+			}
+
+			card, err := ParseCard(line)
+			if err != nil {
+				return nil, n, err
+			}
+
+			// Long string values are continued with the CONTINUE convention:
+			if card.Key == "CONTINUE" && len(h.Cards) > 0 {
+				previous := &h.Cards[len(h.Cards)-1]
+
+				if s, ok := previous.Value.(string); ok && strings.HasSuffix(s, "&") {
+					continuation, _ := card.Value.(string)
+					previous.Value = strings.TrimSuffix(s, "&") + continuation
+					continue
+				}
+			}
+
+			h.Cards = append(h.Cards, card)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// ParseCard parses a single 80 character FITS header record.
+func ParseCard(line string) (Card, error) {
+	if len(line) < CARD_SIZE {
+		line += strings.Repeat(" ", CARD_SIZE-len(line))
+	}
+
+	key := strings.TrimRight(line[0:8], " ")
+
+	// Commentary keywords, or keywords without a value indicator, carry no value:
+	if line[8:10] != "= " && !(key == "CONTINUE") {
+		return Card{
+			Key:     key,
+			Comment: strings.TrimRight(line[8:], " "),
+		}, nil
+	}
+
+	rest := line[10:]
+
+	if key == "CONTINUE" {
+		rest = line[8:]
+	}
+
+	value, comment, err := parseValue(rest)
+	if err != nil {
+		return Card{}, fmt.Errorf("failed to parse FITS header card %q: %w", key, err)
+	}
+
+	return Card{
+		Key:     key,
+		Value:   value,
+		Comment: comment,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+func parseValue(s string) (interface{}, string, error) {
+	trimmed := strings.TrimLeft(s, " ")
+
+	// String values are enclosed in single quotes, with embedded quotes escaped by doubling:
+	if strings.HasPrefix(trimmed, "'") {
+		var b strings.Builder
+
+		i := 1
+
+		for i < len(trimmed) {
+			if trimmed[i] == '\'' {
+				if i+1 < len(trimmed) && trimmed[i+1] == '\'' {
+					b.WriteByte('\'')
+					i += 2
+					continue
+				}
+				break
+			}
+
+			b.WriteByte(trimmed[i])
+			i++
+		}
+
+		if i >= len(trimmed) {
+			return nil, "", errors.New("unterminated string value")
+		}
+
+		return strings.TrimRight(b.String(), " "), parseComment(trimmed[i+1:]), nil
+	}
+
+	raw := trimmed
+	comment := ""
+
+	if i := strings.Index(trimmed, "/"); i >= 0 {
+		raw = trimmed[:i]
+		comment = parseComment(trimmed[i:])
+	}
+
+	raw = strings.TrimSpace(raw)
+
+	switch {
+	case raw == "":
+		return nil, comment, nil
+	case raw == "T":
+		return true, comment, nil
+	case raw == "F":
+		return false, comment, nil
+	}
+
+	if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return v, comment, nil
+	}
+
+	// FITS permits a "D" exponent for double precision values:
+	v, err := strconv.ParseFloat(strings.NewReplacer("D", "E", "d", "e").Replace(raw), 64)
+	if err != nil {
+		// Complex and other unsupported values are retained as their raw string:
+		return raw, comment, nil
+	}
+
+	return v, comment, nil
+}
+
+/*****************************************************************************************************************/
+
+func parseComment(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "/")
+	return strings.TrimSpace(s)
+}
+
+/*****************************************************************************************************************/
+
+// FormatFloat formats a float64 header value with full round-trip precision, in a FITS compliant form.
+func FormatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'G', -1, 64)
+
+	if !strings.ContainsAny(s, ".E") {
+		s += ".0"
+	}
+
+	return s
+}
+
+/*****************************************************************************************************************/
+
+// FormatCard formats a card as a single 80 character FITS header record.
+func FormatCard(card Card) string {
+	key := fmt.Sprintf("%-8s", card.Key)
+
+	if card.Value == nil {
+		return pad(key + card.Comment)
+	}
+
+	value := ""
+
+	switch v := card.Value.(type) {
+	case bool:
+		value = "F"
+		if v {
+			value = "T"
+		}
+		value = fmt.Sprintf("%20s", value)
+	case int64:
+		value = fmt.Sprintf("%20d", v)
+	case float64:
+		value = fmt.Sprintf("%20s", FormatFloat(v))
+	case string:
+		value = fmt.Sprintf("'%-8s'", strings.ReplaceAll(v, "'", "''"))
+		if len(value) < 20 {
+			value = fmt.Sprintf("%-20s", value)
+		}
+	}
+
+	line := key + "= " + value
+
+	if card.Comment != "" {
+		line += " / " + card.Comment
+	}
+
+	return pad(line)
+}
+
+/*****************************************************************************************************************/
+
+func pad(line string) string {
+	if len(line) > CARD_SIZE {
+		return line[:CARD_SIZE]
+	}
+
+	return line + strings.Repeat(" ", CARD_SIZE-len(line))
+}
+
+/*****************************************************************************************************************/
+
+// Bytes serialises the header into FITS header blocks, terminated by an END card and padded with spaces.
+func (h *Header) Bytes() []byte {
+	var buf bytes.Buffer
+
+	for _, card := range h.Cards {
+		// Long strings that do not fit within a single card are split using the CONTINUE convention:
+		if s, ok := card.Value.(string); ok && len(s) > 67 {
+			writeLongString(&buf, card, s)
+			continue
+		}
+
+		buf.WriteString(FormatCard(card))
+	}
+
+	buf.WriteString(pad("END"))
+
+	if partial := buf.Len() % BLOCK_SIZE; partial > 0 {
+		buf.WriteString(strings.Repeat(" ", BLOCK_SIZE-partial))
+	}
+
+	return buf.Bytes()
+}
+
+/*****************************************************************************************************************/
+
+func writeLongString(buf *bytes.Buffer, card Card, s string) {
+	first := true
+
+	for len(s) > 0 {
+		n := int(math.Min(float64(len(s)), 66))
+
+		chunk := s[:n]
+		s = s[n:]
+
+		if len(s) > 0 {
+			chunk += "&"
+		}
+
+		value := "'" + strings.ReplaceAll(chunk, "'", "''") + "'"
+
+		if first {
+			buf.WriteString(pad(fmt.Sprintf("%-8s= %s", card.Key, value)))
+			first = false
+			continue
+		}
+
+		buf.WriteString(pad("CONTINUE  " + value))
+	}
+}
+
+/*****************************************************************************************************************/
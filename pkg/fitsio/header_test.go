@@ -0,0 +1,264 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestParseCard(t *testing.T) {
+	tests := []struct {
+		line    string
+		key     string
+		value   interface{}
+		comment string
+	}{
+		{"SIMPLE  =                    T / conforms to FITS standard", "SIMPLE", true, "conforms to FITS standard"},
+		{"NAXIS1  =                 4096", "NAXIS1", int64(4096), ""},
+		{"CRVAL1  =    83.82208333333333 / RA at reference point", "CRVAL1", 83.82208333333333, "RA at reference point"},
+		{"CD1_1   = -1.2345678901234D-04", "CD1_1", -1.2345678901234e-04, ""},
+		{"CTYPE1  = 'RA---TAN-SIP'       / TAN with SIP distortions", "CTYPE1", "RA---TAN-SIP", "TAN with SIP distortions"},
+		{"OBJECT  = 'O''Brien / test'", "OBJECT", "O'Brien / test", ""},
+		{"COMMENT This is a comment", "COMMENT", nil, "This is a comment"},
+	}
+
+	for _, tt := range tests {
+		card, err := ParseCard(tt.line)
+		if err != nil {
+			t.Fatalf("ParseCard(%q) returned an error: %v", tt.line, err)
+		}
+
+		if card.Key != tt.key || card.Value != tt.value || strings.TrimSpace(card.Comment) != tt.comment {
+			t.Errorf("ParseCard(%q) = %+v, want {%s %v %s}", tt.line, card, tt.key, tt.value, tt.comment)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestHeaderRoundTrip(t *testing.T) {
+	h := NewHeader()
+
+	h.Set("SIMPLE", true, "conforms to FITS standard")
+	h.Set("NAXIS", 2, "")
+	h.Set("CRVAL1", 83.82208333333333, "RA at reference point")
+	h.Set("CD1_1", -1.2345678901234e-10, "")
+	h.Set("CTYPE1", "RA---TAN-SIP", "")
+	h.Set("OBSERVER", strings.Repeat("a long value ", 10)+"end", "")
+
+	b := h.Bytes()
+
+	if len(b)%BLOCK_SIZE != 0 {
+		t.Fatalf("expected the header to be padded to a whole number of blocks, got %d bytes", len(b))
+	}
+
+	got, n, err := ReadHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadHeader returned an error: %v", err)
+	}
+
+	if n != int64(len(b)) {
+		t.Errorf("expected %d bytes to be read, got %d", len(b), n)
+	}
+
+	for _, card := range h.Cards {
+		v, ok := got.Get(card.Key)
+		if !ok || v != card.Value {
+			t.Errorf("expected %s = %v, got %v", card.Key, card.Value, v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestBinaryTableRoundTrip(t *testing.T) {
+	xs := []float64{10.5, 200.25, 3000.125}
+	ys := []float64{20.5, 400.75, 1500.0}
+
+	table, err := NewBinaryTableHDU([]string{"X", "Y"}, [][]float64{xs, ys})
+	if err != nil {
+		t.Fatalf("NewBinaryTableHDU returned an error: %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteHDUs(&buf, []*HDU{NewPrimaryHDU(), table}); err != nil {
+		t.Fatalf("WriteHDUs returned an error: %v", err)
+	}
+
+	hdus, err := ReadHDUs(&buf)
+	if err != nil {
+		t.Fatalf("ReadHDUs returned an error: %v", err)
+	}
+
+	if len(hdus) != 2 || !hdus[0].IsPrimary() || hdus[1].Extension() != "BINTABLE" {
+		t.Fatalf("expected a primary HDU and a binary table extension")
+	}
+
+	got, err := NewBinaryTableFromHDU(hdus[1])
+	if err != nil {
+		t.Fatalf("NewBinaryTableFromHDU returned an error: %v", err)
+	}
+
+	y, err := got.Float64Column("y")
+	if err != nil {
+		t.Fatalf("Float64Column returned an error: %v", err)
+	}
+
+	for i := range ys {
+		if y[i] != ys[i] {
+			t.Errorf("row %d: expected %v, got %v", i, ys[i], y[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestImageRoundTrip(t *testing.T) {
+	data := []float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+
+	hdu, err := NewImageHDU([]int{3, 2, 2}, data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteHDUs(&buf, []*HDU{hdu}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := ReadHDU(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	image, err := NewImageFromHDU(read)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if image.Width() != 3 || image.Height() != 2 || image.Planes() != 2 {
+		t.Fatalf("expected a 3x2x2 image, got %dx%dx%d", image.Width(), image.Height(), image.Planes())
+	}
+
+	plane, err := image.Plane(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, v := range plane {
+		if v != data[6+i] {
+			t.Errorf("plane 1, pixel %d: expected %v, got %v", i, data[6+i], v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewImageFromHDUScalesIntegers(t *testing.T) {
+	header := NewHeader()
+	header.Set("SIMPLE", true, "")
+	header.Set("BITPIX", 16, "")
+	header.Set("NAXIS", 2, "")
+	header.Set("NAXIS1", 2, "")
+	header.Set("NAXIS2", 1, "")
+	header.Set("BZERO", 32768, "")
+	header.Set("BLANK", -1, "")
+
+	// The raw values are -32768 (i.e., zero) and -1 (i.e., blank):
+	image, err := NewImageFromHDU(&HDU{Header: header, Data: []byte{0x80, 0x00, 0xff, 0xff}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if image.Data[0] != 0 {
+		t.Errorf("expected 0, got %v", image.Data[0])
+	}
+
+	if !math.IsNaN(float64(image.Data[1])) {
+		t.Errorf("expected a blank value to be NaN, got %v", image.Data[1])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetFramesFromExtensionsAndCubes(t *testing.T) {
+	primary := NewPrimaryHDU()
+	primary.Header.Set("RA", 98.5, "")
+	primary.Header.Set("EXPTIME", 30.0, "")
+
+	ccd, err := NewImageHDU([]int{2, 2}, []float32{1, 2, 3, 4}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ccd.Header.Set("EXTNAME", "CCD1", "")
+	ccd.Header.Set("EXPTIME", 60.0, "")
+
+	table, err := NewBinaryTableHDU([]string{"X"}, [][]float64{{1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cube, err := NewImageHDU([]int{2, 2, 2}, []float32{1, 1, 1, 1, 3, 3, float32(math.NaN()), 3}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	frames, err := GetFrames([]*HDU{primary, ccd, table, cube})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The empty primary HDU and the binary table are skipped, and the cube contributes one frame per plane:
+	if len(frames) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(frames))
+	}
+
+	if frames[0].HDU != 1 || frames[0].Name != "CCD1" || frames[1].HDU != 3 || frames[2].Plane != 1 {
+		t.Errorf("unexpected frames: %v, %v, %v", frames[0], frames[1], frames[2])
+	}
+
+	// The extension inherits the pointing of the primary header, but keeps its own exposure time:
+	if ra, ok := frames[0].Header.Float("RA"); !ok || ra != 98.5 {
+		t.Errorf("expected the RA to be inherited from the primary header, got %v", ra)
+	}
+
+	if exptime, _ := frames[0].Header.Float("EXPTIME"); exptime != 60 {
+		t.Errorf("expected the extension EXPTIME of 60, got %v", exptime)
+	}
+
+	stacks, err := StackFramesByHDU(frames)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(stacks) != 2 {
+		t.Fatalf("expected a frame per image HDU, got %d", len(stacks))
+	}
+
+	// The mean of each pixel of the cube, ignoring the blank value of the second plane:
+	expected := []float32{2, 2, 1, 2}
+
+	for i, v := range stacks[1].Data {
+		if v != expected[i] {
+			t.Errorf("pixel %d: expected %v, got %v", i, expected[i], v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,231 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*****************************************************************************************************************/
+
+// Image is the decoded (physical) data array of an image HDU, of any BITPIX, where the axes are ordered as in the
+// header, i.e., NAXIS1 (the width) varies fastest.
+type Image struct {
+	Axes []int
+	Data []float32
+}
+
+/*****************************************************************************************************************/
+
+// Width returns the length of the first axis of the image (NAXIS1).
+func (i *Image) Width() int {
+	if len(i.Axes) < 1 {
+		return 0
+	}
+
+	return i.Axes[0]
+}
+
+/*****************************************************************************************************************/
+
+// Height returns the length of the second axis of the image (NAXIS2).
+func (i *Image) Height() int {
+	if len(i.Axes) < 2 {
+		return 1
+	}
+
+	return i.Axes[1]
+}
+
+/*****************************************************************************************************************/
+
+// Planes returns the number of two dimensional planes of the image, i.e., the product of NAXIS3 onwards.
+func (i *Image) Planes() int {
+	planes := 1
+
+	for _, n := range i.Axes[min(len(i.Axes), 2):] {
+		planes *= n
+	}
+
+	return planes
+}
+
+/*****************************************************************************************************************/
+
+// Plane returns the k-th two dimensional plane of the image, e.g., a single frame of a data cube.
+func (i *Image) Plane(k int) ([]float32, error) {
+	if k < 0 || k >= i.Planes() {
+		return nil, fmt.Errorf("plane %d is out of range [0, %d)", k, i.Planes())
+	}
+
+	size := i.Width() * i.Height()
+
+	return i.Data[k*size : (k+1)*size], nil
+}
+
+/*****************************************************************************************************************/
+
+// NewImageFromHDU decodes the big-endian data array of an image HDU into physical values, applying BSCALE and
+// BZERO, and replacing BLANK integer values with NaN.
+func NewImageFromHDU(hdu *HDU) (*Image, error) {
+	if xtension := hdu.Extension(); xtension != "" && xtension != "IMAGE" {
+		return nil, fmt.Errorf("not an image HDU: %s", xtension)
+	}
+
+	bitpix, ok := hdu.Header.Int("BITPIX")
+	if !ok {
+		return nil, errors.New("FITS header is missing the BITPIX keyword")
+	}
+
+	naxis, ok := hdu.Header.Int("NAXIS")
+	if !ok {
+		return nil, errors.New("FITS header is missing the NAXIS keyword")
+	}
+
+	if naxis == 0 {
+		return nil, errors.New("the HDU does not contain an image")
+	}
+
+	axes := make([]int, naxis)
+
+	n := 1
+
+	for i := range axes {
+		v, ok := hdu.Header.Int(fmt.Sprintf("NAXIS%d", i+1))
+		if !ok {
+			return nil, fmt.Errorf("FITS header is missing the NAXIS%d keyword", i+1)
+		}
+
+		axes[i] = int(v)
+
+		n *= int(v)
+	}
+
+	size := int(bitpix) / 8
+	if size < 0 {
+		size = -size
+	}
+
+	if len(hdu.Data) < n*size {
+		return nil, fmt.Errorf("FITS data array is truncated: %d of %d bytes", len(hdu.Data), n*size)
+	}
+
+	bscale, ok := hdu.Header.Float("BSCALE")
+	if !ok {
+		bscale = 1
+	}
+
+	bzero, ok := hdu.Header.Float("BZERO")
+	if !ok {
+		bzero = 0
+	}
+
+	blank, hasBlank := hdu.Header.Int("BLANK")
+
+	data := make([]float32, n)
+
+	for i := 0; i < n; i++ {
+		b := hdu.Data[i*size : (i+1)*size]
+
+		var v float64
+
+		var raw int64
+
+		integer := true
+
+		switch bitpix {
+		case 8:
+			raw = int64(b[0])
+		case 16:
+			raw = int64(int16(binary.BigEndian.Uint16(b)))
+		case 32:
+			raw = int64(int32(binary.BigEndian.Uint32(b)))
+		case 64:
+			raw = int64(binary.BigEndian.Uint64(b))
+		case -32:
+			v, integer = float64(math.Float32frombits(binary.BigEndian.Uint32(b))), false
+		case -64:
+			v, integer = math.Float64frombits(binary.BigEndian.Uint64(b)), false
+		default:
+			return nil, fmt.Errorf("unsupported BITPIX: %d", bitpix)
+		}
+
+		if integer {
+			if hasBlank && raw == blank {
+				data[i] = float32(math.NaN())
+				continue
+			}
+
+			v = float64(raw)
+		}
+
+		data[i] = float32(bzero + bscale*v)
+	}
+
+	return &Image{
+		Axes: axes,
+		Data: data,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// NewImageHDU creates an image HDU with a 32-bit floating point (BITPIX = -32) data array of the given axes, either
+// as the primary HDU or as an IMAGE extension.
+func NewImageHDU(axes []int, data []float32, primary bool) (*HDU, error) {
+	n := 1
+
+	for _, a := range axes {
+		n *= a
+	}
+
+	if n != len(data) {
+		return nil, fmt.Errorf("the data array of %d values does not match the axes %v", len(data), axes)
+	}
+
+	header := NewHeader()
+
+	if primary {
+		header.Set("SIMPLE", true, "conforms to FITS standard")
+	} else {
+		header.Set("XTENSION", "IMAGE", "image extension")
+	}
+
+	header.Set("BITPIX", -32, "array data type")
+	header.Set("NAXIS", len(axes), "number of array dimensions")
+
+	for i, a := range axes {
+		header.Set(fmt.Sprintf("NAXIS%d", i+1), a, "")
+	}
+
+	if primary {
+		header.Set("EXTEND", true, "FITS dataset may contain extensions")
+	} else {
+		header.Set("PCOUNT", 0, "number of parameters")
+		header.Set("GCOUNT", 1, "number of groups")
+	}
+
+	bytes := make([]byte, 4*n)
+
+	for i, v := range data {
+		binary.BigEndian.PutUint32(bytes[4*i:], math.Float32bits(v))
+	}
+
+	return &HDU{
+		Header: header,
+		Data:   bytes,
+	}, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,349 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fitsio
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// Column describes a single column of a FITS binary table.
+type Column struct {
+	Name    string  // the TTYPEn name of the column, e.g., "X"
+	Format  string  // the TFORMn data type code of the column, e.g., "E" or "D"
+	Element string  // the data type code of the elements of a variable length array ("P" or "Q") column, e.g., "B"
+	Repeat  int     // the number of elements in each cell of the column
+	Offset  int     // the byte offset of the column within each row
+	Scale   float64 // the TSCALn linear scaling factor (default 1)
+	Zero    float64 // the TZEROn linear scaling offset (default 0)
+}
+
+/*****************************************************************************************************************/
+
+// BinaryTable is a FITS binary table (BINTABLE) extension.
+//
+// @see https://fits.gsfc.nasa.gov/standard40/fits_standard40aa-le.pdf (Section 7.3)
+type BinaryTable struct {
+	Columns []Column
+	Rows    int
+	RowSize int
+	Heap    int // the byte offset of the heap of variable length arrays within the data (THEAP)
+	Data    []byte
+}
+
+/*****************************************************************************************************************/
+
+// The size (in bytes) of each supported binary table data type:
+var columnTypeSizes = map[string]int{
+	"L": 1,
+	"B": 1,
+	"I": 2,
+	"J": 4,
+	"K": 8,
+	"E": 4,
+	"D": 8,
+	"P": 8,
+	"Q": 16,
+}
+
+/*****************************************************************************************************************/
+
+// parseColumnFormat parses a TFORMn value, e.g., "1E" or "D", into its repeat count and data type code.
+func parseColumnFormat(format string) (int, string, error) {
+	format = strings.TrimSpace(format)
+
+	i := 0
+
+	for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+		i++
+	}
+
+	if i == len(format) {
+		return 0, "", fmt.Errorf("invalid binary table column format: %q", format)
+	}
+
+	repeat := 1
+
+	if i > 0 {
+		r, err := strconv.Atoi(format[:i])
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid binary table column format: %q", format)
+		}
+		repeat = r
+	}
+
+	return repeat, format[i : i+1], nil
+}
+
+/*****************************************************************************************************************/
+
+// NewBinaryTableFromHDU parses the columns of a BINTABLE extension.
+func NewBinaryTableFromHDU(hdu *HDU) (*BinaryTable, error) {
+	if hdu.Extension() != "BINTABLE" {
+		return nil, fmt.Errorf("expected a BINTABLE extension, got %q", hdu.Extension())
+	}
+
+	rowSize, _ := hdu.Header.Int("NAXIS1")
+	rows, _ := hdu.Header.Int("NAXIS2")
+
+	fields, ok := hdu.Header.Int("TFIELDS")
+	if !ok {
+		return nil, fmt.Errorf("binary table is missing the TFIELDS keyword")
+	}
+
+	columns := make([]Column, 0, fields)
+
+	offset := 0
+
+	for i := int64(1); i <= fields; i++ {
+		tform, ok := hdu.Header.String(fmt.Sprintf("TFORM%d", i))
+		if !ok {
+			return nil, fmt.Errorf("binary table is missing the TFORM%d keyword", i)
+		}
+
+		repeat, code, err := parseColumnFormat(tform)
+		if err != nil {
+			return nil, err
+		}
+
+		size, ok := columnTypeSizes[code]
+
+		// Character columns are supported for their width, but cannot be read as numeric values:
+		if code == "A" {
+			size, ok = 1, true
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("unsupported binary table column format: %q", tform)
+		}
+
+		element := ""
+
+		// Variable length array descriptors are followed by the data type code of their elements, e.g., "1PB(2048)":
+		if code == "P" || code == "Q" {
+			j := strings.Index(tform, code) + 1
+
+			if j >= len(tform) {
+				return nil, fmt.Errorf("invalid binary table column format: %q", tform)
+			}
+
+			element = tform[j : j+1]
+		}
+
+		name, _ := hdu.Header.String(fmt.Sprintf("TTYPE%d", i))
+
+		scale, ok := hdu.Header.Float(fmt.Sprintf("TSCAL%d", i))
+		if !ok {
+			scale = 1
+		}
+
+		zero, ok := hdu.Header.Float(fmt.Sprintf("TZERO%d", i))
+		if !ok {
+			zero = 0
+		}
+
+		columns = append(columns, Column{
+			Name:    name,
+			Format:  code,
+			Element: element,
+			Repeat:  repeat,
+			Offset:  offset,
+			Scale:   scale,
+			Zero:    zero,
+		})
+
+		offset += repeat * size
+	}
+
+	if int64(offset) != rowSize {
+		return nil, fmt.Errorf("binary table row size %d does not match the column formats (%d)", rowSize, offset)
+	}
+
+	if int64(len(hdu.Data)) < rowSize*rows {
+		return nil, fmt.Errorf("binary table data is truncated")
+	}
+
+	heap, ok := hdu.Header.Int("THEAP")
+	if !ok {
+		heap = rowSize * rows
+	}
+
+	return &BinaryTable{
+		Columns: columns,
+		Rows:    int(rows),
+		RowSize: int(rowSize),
+		Heap:    int(heap),
+		Data:    hdu.Data,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// ColumnIndex returns the index of the column with the given (case insensitive) name, or -1 if it does not exist.
+func (t *BinaryTable) ColumnIndex(name string) int {
+	for i, column := range t.Columns {
+		if strings.EqualFold(strings.TrimSpace(column.Name), name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+/*****************************************************************************************************************/
+
+// Float64Column returns the (scaled) values of the first element of each cell of the named numeric column.
+func (t *BinaryTable) Float64Column(name string) ([]float64, error) {
+	i := t.ColumnIndex(name)
+	if i < 0 {
+		return nil, fmt.Errorf("binary table has no column named %q", name)
+	}
+
+	column := t.Columns[i]
+
+	values := make([]float64, t.Rows)
+
+	for row := 0; row < t.Rows; row++ {
+		cell := t.Data[row*t.RowSize+column.Offset:]
+
+		var v float64
+
+		switch column.Format {
+		case "B":
+			v = float64(cell[0])
+		case "I":
+			v = float64(int16(binary.BigEndian.Uint16(cell)))
+		case "J":
+			v = float64(int32(binary.BigEndian.Uint32(cell)))
+		case "K":
+			v = float64(int64(binary.BigEndian.Uint64(cell)))
+		case "E":
+			v = float64(math.Float32frombits(binary.BigEndian.Uint32(cell)))
+		case "D":
+			v = math.Float64frombits(binary.BigEndian.Uint64(cell))
+		default:
+			return nil, fmt.Errorf("binary table column %q is not numeric", name)
+		}
+
+		values[row] = column.Zero + column.Scale*v
+	}
+
+	return values, nil
+}
+
+/*****************************************************************************************************************/
+
+// Array returns the raw (big-endian) bytes of the variable length array of the named column at the given row, along
+// with the data type code of its elements, where the array is read from the heap of the table.
+func (t *BinaryTable) Array(name string, row int) ([]byte, string, error) {
+	i := t.ColumnIndex(name)
+	if i < 0 {
+		return nil, "", fmt.Errorf("binary table has no column named %q", name)
+	}
+
+	column := t.Columns[i]
+
+	if row < 0 || row >= t.Rows {
+		return nil, "", fmt.Errorf("binary table row %d is out of range [0, %d)", row, t.Rows)
+	}
+
+	cell := t.Data[row*t.RowSize+column.Offset:]
+
+	var count, offset int64
+
+	switch column.Format {
+	case "P":
+		count, offset = int64(binary.BigEndian.Uint32(cell)), int64(binary.BigEndian.Uint32(cell[4:]))
+	case "Q":
+		count, offset = int64(binary.BigEndian.Uint64(cell)), int64(binary.BigEndian.Uint64(cell[8:]))
+	default:
+		return nil, "", fmt.Errorf("binary table column %q is not a variable length array", name)
+	}
+
+	size, ok := columnTypeSizes[column.Element]
+
+	if column.Element == "A" {
+		size, ok = 1, true
+	}
+
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported variable length array element type: %q", column.Element)
+	}
+
+	start := int64(t.Heap) + offset
+	end := start + count*int64(size)
+
+	if start < 0 || end > int64(len(t.Data)) {
+		return nil, "", fmt.Errorf("variable length array of column %q, row %d, exceeds the heap", name, row)
+	}
+
+	return t.Data[start:end], column.Element, nil
+}
+
+/*****************************************************************************************************************/
+
+// NewBinaryTableHDU creates a BINTABLE extension of double precision columns, where each of the columns must have
+// the same number of rows.
+func NewBinaryTableHDU(names []string, columns [][]float64) (*HDU, error) {
+	if len(names) != len(columns) {
+		return nil, fmt.Errorf("expected %d columns, got %d", len(names), len(columns))
+	}
+
+	rows := 0
+
+	if len(columns) > 0 {
+		rows = len(columns[0])
+	}
+
+	for i, column := range columns {
+		if len(column) != rows {
+			return nil, fmt.Errorf("column %q has %d rows, expected %d", names[i], len(column), rows)
+		}
+	}
+
+	rowSize := 8 * len(columns)
+
+	header := NewHeader()
+
+	header.Set("XTENSION", "BINTABLE", "binary table extension")
+	header.Set("BITPIX", 8, "array data type")
+	header.Set("NAXIS", 2, "number of array dimensions")
+	header.Set("NAXIS1", rowSize, "length of each row (in bytes)")
+	header.Set("NAXIS2", rows, "number of rows")
+	header.Set("PCOUNT", 0, "number of group parameters")
+	header.Set("GCOUNT", 1, "number of groups")
+	header.Set("TFIELDS", len(columns), "number of table fields")
+
+	for i, name := range names {
+		header.Set(fmt.Sprintf("TTYPE%d", i+1), name, "")
+		header.Set(fmt.Sprintf("TFORM%d", i+1), "D", "")
+	}
+
+	data := make([]byte, rows*rowSize)
+
+	for row := 0; row < rows; row++ {
+		for i, column := range columns {
+			binary.BigEndian.PutUint64(data[row*rowSize+i*8:], math.Float64bits(column[row]))
+		}
+	}
+
+	return &HDU{
+		Header: header,
+		Data:   data,
+	}, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,281 @@
+"""
+Generates the tile-compressed (.fits.fz) images used by the Go tests of the fitsio package, in the layout written by
+fpack (CFITSIO), i.e., an empty primary HDU followed by a COMPRESSED_IMAGE binary table of row-by-row tiles:
+
+    rice-int16.fits.fz     fpack -r          a 16 x 8 BITPIX = 16 image, RICE_1 with BYTEPIX = 2
+    gzip2-float.fits.fz    fpack -g2 -q 0    a 12 x 6 BITPIX = -32 image, losslessly byte shuffled GZIP_2
+    dither1-float.fits.fz  fpack -r -q 4     a 20 x 10 BITPIX = -32 image, SUBTRACTIVE_DITHER_1 quantized RICE_1
+
+The pixel values of each image are the functions below, which the Go tests evaluate to assert the decompressed
+values. The quantization step (ZSCALE) of each tile is fixed, rather than estimated from the noise of the tile.
+
+Usage: python generate.py (requires only the standard library)
+"""
+
+import math
+import struct
+import zlib
+
+N_RANDOM = 10000
+
+
+def randoms():
+    # The single precision table of fits_init_randoms, with the final seed verified by CFITSIO:
+    a, m, seed = 16807.0, 2147483647.0, 1.0
+    values = []
+
+    for _ in range(N_RANDOM):
+        temp = a * seed
+        seed = temp - m * int(temp / m)
+        values.append(struct.unpack(">f", struct.pack(">f", seed / m))[0])
+
+    assert int(seed) == 1043618065
+
+    return values
+
+
+def nint(x):
+    return int(x + 0.5) if x >= 0 else int(x - 0.5)
+
+
+def card(key, value=None, comment=""):
+    if value is None:
+        return f"{key:<80}"
+
+    if isinstance(value, bool):
+        text = f"{key:<8}= {'T' if value else 'F':>20}"
+    elif isinstance(value, str):
+        text = f"{key:<8}= '{value:<8}'"
+        text = f"{text:<30}"
+    elif isinstance(value, float):
+        text = f"{key:<8}= {repr(value).upper():>20}"
+    else:
+        text = f"{key:<8}= {value:>20}"
+
+    if comment:
+        text += f" / {comment}"
+
+    return f"{text:<80}"[:80]
+
+
+def block(cards):
+    header = "".join(cards + [card("END")])
+    return (header + " " * (-len(header) % 2880)).encode("ascii")
+
+
+def pad(data):
+    return data + b"\0" * (-len(data) % 2880)
+
+
+def rice(values, bytepix, blocksize=32):
+    # fits_rcomp (bytepix = 4) and fits_rcomp_short (bytepix = 2) of CFITSIO:
+    fsbits, fsmax, bbits = {2: (4, 14, 16), 4: (5, 25, 32)}[bytepix]
+    mask = (1 << bbits) - 1
+
+    bits = []
+
+    def write(v, n):
+        bits.extend((v >> i) & 1 for i in range(n - 1, -1, -1))
+
+    write(values[0] & mask, bbits)
+
+    lastpix = values[0] & mask
+
+    for i in range(0, len(values), blocksize):
+        block = values[i:i + blocksize]
+        diffs = []
+
+        for v in block:
+            nextpix = v & mask
+            pdiff = (nextpix - lastpix) & mask
+
+            # Sign extend the difference from the width of the pixels:
+            if pdiff >= 1 << (bbits - 1):
+                pdiff -= 1 << bbits
+
+            diffs.append((~(pdiff << 1) if pdiff < 0 else pdiff << 1) & mask)
+            lastpix = nextpix
+
+        pixelsum = sum(diffs)
+
+        dpsum = max((pixelsum - (len(block) // 2) - 1) / len(block), 0.0)
+
+        psum, fs = int(dpsum) >> 1, 0
+
+        while psum > 0:
+            psum >>= 1
+            fs += 1
+
+        if fs >= fsmax:
+            write(fsmax + 1, fsbits)
+            for d in diffs:
+                write(d, bbits)
+        elif fs == 0 and pixelsum == 0:
+            write(0, fsbits)
+        else:
+            write(fs + 1, fsbits)
+            for d in diffs:
+                bits.extend([0] * (d >> fs) + [1])
+                if fs > 0:
+                    write(d & ((1 << fs) - 1), fs)
+
+    bits.extend([0] * (-len(bits) % 8))
+
+    return bytes(int("".join(map(str, bits[i:i + 8])), 2) for i in range(0, len(bits), 8))
+
+
+def gzip2(values, fmt):
+    # The bytes of every value are shuffled, most significant first, before GZIP compression:
+    raw = b"".join(struct.pack(">" + fmt, v) for v in values)
+    size = struct.calcsize(fmt)
+    shuffled = bytes(raw[i + b] for b in range(size) for i in range(0, len(raw), size))
+
+    compressor = zlib.compressobj(zlib.Z_DEFAULT_COMPRESSION, zlib.DEFLATED, 31)
+
+    return compressor.compress(shuffled) + compressor.flush()
+
+
+def write(name, width, height, bitpix, algorithm, tiles, columns, keywords):
+    # The descriptor of the COMPRESSED_DATA column of each tile, followed by its ZSCALE and ZZERO, if quantized:
+    heap = b""
+    rows = []
+
+    for i, tile in enumerate(tiles):
+        row = struct.pack(">ii", len(tile), len(heap))
+        heap += tile
+
+        for _, values in columns:
+            row += struct.pack(">d", values[i])
+
+        rows.append(row)
+
+    table = b"".join(rows)
+
+    primary = block([
+        card("SIMPLE", True, "file does conform to FITS standard"),
+        card("BITPIX", 16, "number of bits per data pixel"),
+        card("NAXIS", 0, "number of data axes"),
+        card("EXTEND", True, "FITS dataset may contain extensions"),
+    ])
+
+    cards = [
+        card("XTENSION", "BINTABLE", "binary table extension"),
+        card("BITPIX", 8, "8-bit bytes"),
+        card("NAXIS", 2, "2-dimensional binary table"),
+        card("NAXIS1", len(rows[0]), "width of table in bytes"),
+        card("NAXIS2", len(rows), "number of rows in table"),
+        card("PCOUNT", len(heap), "size of special data area"),
+        card("GCOUNT", 1, "one data group (required keyword)"),
+        card("TFIELDS", 1 + len(columns), "number of fields in each row"),
+        card("TTYPE1", "COMPRESSED_DATA", "label for field   1"),
+        card("TFORM1", f"1PB({max(len(t) for t in tiles)})", "data format of field: variable length array"),
+    ]
+
+    for i, (column, _) in enumerate(columns):
+        cards += [
+            card(f"TTYPE{i + 2}", column, f"label for field {i + 2:>3}"),
+            card(f"TFORM{i + 2}", "1D", "data format of field: 8-byte DOUBLE"),
+        ]
+
+    cards += [
+        card("ZIMAGE", True, "extension contains compressed image"),
+        card("ZSIMPLE", True, "file does conform to FITS standard"),
+        card("ZBITPIX", bitpix, "data type of original image"),
+        card("ZNAXIS", 2, "dimension of original image"),
+        card("ZNAXIS1", width, "length of original image axis"),
+        card("ZNAXIS2", height, "length of original image axis"),
+        card("ZEXTEND", True, "FITS dataset may contain extensions"),
+        card("ZTILE1", width, "size of tiles to be compressed"),
+        card("ZTILE2", 1, "size of tiles to be compressed"),
+        card("ZCMPTYPE", algorithm, "compression algorithm"),
+    ] + keywords + [
+        card("OBJECT", "M 42", "name of the target"),
+        card("EXTNAME", "COMPRESSED_IMAGE", "name of this binary table extension"),
+    ]
+
+    with open(name, "wb") as f:
+        f.write(primary + block(cards) + pad(table + heap))
+
+
+def rice_int16():
+    width, height = 16, 8
+
+    def value(x, y):
+        if (x, y) == (0, 0):
+            return -32768
+        if (x, y) == (15, 7):
+            return 32767
+        return (x * 37 + y * 101) % 2000 - 1000 + (500 if x == 8 else 0)
+
+    tiles = [rice([value(x, y) for x in range(width)], 2) for y in range(height)]
+
+    write("rice-int16.fits.fz", width, height, 16, "RICE_1", tiles, [], [
+        card("ZNAME1", "BLOCKSIZE", "compression block size"),
+        card("ZVAL1", 32, "pixels per block"),
+        card("ZNAME2", "BYTEPIX", "bytes per pixel (1, 2, 4, or 8)"),
+        card("ZVAL2", 2, "bytes per pixel (1, 2, 4, or 8)"),
+    ])
+
+
+def gzip2_float():
+    width, height = 12, 6
+
+    def value(x, y):
+        return struct.unpack(">f", struct.pack(">f", (x - 5.5) * 0.125 + y * 1000.0 + 1.0 / 3.0))[0]
+
+    tiles = [gzip2([value(x, y) for x in range(width)], "f") for y in range(height)]
+
+    write("gzip2-float.fits.fz", width, height, -32, "GZIP_2", tiles, [], [
+        card("ZQUANTIZ", "NONE", "Lossless compression without quantization"),
+    ])
+
+
+def dither1_float():
+    width, height, seed = 20, 10, 3
+
+    r = randoms()
+
+    def value(x, y):
+        return 1000.0 + 25.0 * math.sin(0.7 * x) * math.cos(0.3 * y) + 0.5 * y
+
+    tiles, scales, zeros = [], [], []
+
+    for y in range(height):
+        pixels = [struct.unpack(">f", struct.pack(">f", value(x, y)))[0] for x in range(width)]
+
+        delta = 0.05 + 0.01 * y
+
+        # The zero point of fits_quantize_float, shifted such that zero is quantized to an integer:
+        zeropt = min(pixels)
+        zeropt = -nint((0.0 - zeropt) / delta) * delta
+
+        # The dithering of the tile begins at the random number of its row, offset by ZDITHER0:
+        iseed = (y + seed - 1) % N_RANDOM
+        nextrand = int(r[iseed] * 500.0)
+
+        quantized = []
+
+        for v in pixels:
+            quantized.append(nint((v - zeropt) / delta + r[nextrand] - 0.5))
+            nextrand += 1
+
+        tiles.append(rice(quantized, 4))
+        scales.append(delta)
+        zeros.append(zeropt)
+
+    write("dither1-float.fits.fz", width, height, -32, "RICE_1", tiles, [
+        ("ZSCALE", scales),
+        ("ZZERO", zeros),
+    ], [
+        card("ZNAME1", "BLOCKSIZE", "compression block size"),
+        card("ZVAL1", 32, "pixels per block"),
+        card("ZNAME2", "BYTEPIX", "bytes per pixel (1, 2, 4, or 8)"),
+        card("ZVAL2", 4, "bytes per pixel (1, 2, 4, or 8)"),
+        card("ZQUANTIZ", "SUBTRACTIVE_DITHER_1", "Pixel Quantization Algorithm"),
+        card("ZDITHER0", seed, "dithering offset when quantizing floats"),
+    ])
+
+
+rice_int16()
+gzip2_float()
+dither1_float()
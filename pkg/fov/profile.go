@@ -0,0 +1,155 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fov
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// The name of the equipment profiles file within the configuration directory of the user:
+const PROFILES_FILENAME = "skysolve/profiles.json"
+
+/*****************************************************************************************************************/
+
+// Profile is a named pairing of a telescope and a camera, e.g., "redcat51-asi2600", from which the pixel scale of the
+// images it captures follows.
+type Profile struct {
+	Name        string  `json:"name"`                 // The unique name of the profile
+	Telescope   string  `json:"telescope,omitempty"`  // The description of the telescope, e.g., "RedCat 51"
+	Camera      string  `json:"camera,omitempty"`     // The description of the camera, e.g., "ZWO ASI2600MM Pro"
+	FocalLength float64 `json:"focalLength"`          // The (effective) focal length of the telescope (in millimetres)
+	PixelSizeX  float64 `json:"pixelSizeX"`           // The size of the unbinned pixels in the x direction (in microns)
+	PixelSizeY  float64 `json:"pixelSizeY,omitempty"` // The size of the unbinned pixels in the y direction (in microns)
+}
+
+/*****************************************************************************************************************/
+
+// Profiles is the equipment profiles configuration file.
+type Profiles struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+/*****************************************************************************************************************/
+
+// GetDefaultProfilesLocation returns the location of the equipment profiles file within the configuration directory
+// of the user, e.g., "~/.config/skysolve/profiles.json" on Linux.
+func GetDefaultProfilesLocation() (string, error) {
+	directory, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(directory, PROFILES_FILENAME), nil
+}
+
+/*****************************************************************************************************************/
+
+// ReadProfiles reads the equipment profiles file, where a file that does not exist has no profiles.
+func ReadProfiles(location string) (*Profiles, error) {
+	data, err := os.ReadFile(location)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Profiles{Profiles: []Profile{}}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := &Profiles{}
+
+	if err := json.Unmarshal(data, profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse the equipment profiles %s: %v", location, err)
+	}
+
+	return profiles, nil
+}
+
+/*****************************************************************************************************************/
+
+// WriteProfiles writes the equipment profiles file, creating its directory if necessary.
+func WriteProfiles(location string, profiles *Profiles) error {
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(profiles, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(location, append(data, '\n'), 0o644)
+}
+
+/*****************************************************************************************************************/
+
+// Get returns the profile of the (case insensitive) name.
+func (p *Profiles) Get(name string) (Profile, error) {
+	for _, profile := range p.Profiles {
+		if strings.EqualFold(profile.Name, strings.TrimSpace(name)) {
+			return profile, nil
+		}
+	}
+
+	return Profile{}, fmt.Errorf("unknown equipment profile: %s", name)
+}
+
+/*****************************************************************************************************************/
+
+// Set adds the profile, or replaces the existing profile of the same (case insensitive) name, keeping the profiles
+// sorted by name.
+func (p *Profiles) Set(profile Profile) error {
+	if strings.TrimSpace(profile.Name) == "" {
+		return errors.New("an equipment profile requires a name")
+	}
+
+	// Validate that the pixel scale follows from the profile:
+	if _, err := profile.GetPixelScale(1, 1); err != nil {
+		return err
+	}
+
+	for i, existing := range p.Profiles {
+		if strings.EqualFold(existing.Name, profile.Name) {
+			p.Profiles[i] = profile
+			return nil
+		}
+	}
+
+	p.Profiles = append(p.Profiles, profile)
+
+	sort.Slice(p.Profiles, func(i, j int) bool {
+		return strings.ToLower(p.Profiles[i].Name) < strings.ToLower(p.Profiles[j].Name)
+	})
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// GetPixelScale returns the pixel scale (in degrees) of the profile at the binning of an image.
+func (p Profile) GetPixelScale(binningX, binningY int) (PixelScale, error) {
+	return NewPixelScaleFromEquipment(EquipmentParams{
+		FocalLength: p.FocalLength,
+		PixelSizeX:  p.PixelSizeX,
+		PixelSizeY:  p.PixelSizeY,
+		BinningX:    binningX,
+		BinningY:    binningY,
+	})
+}
+
+/*****************************************************************************************************************/
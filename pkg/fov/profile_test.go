@@ -0,0 +1,72 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fov
+
+/*****************************************************************************************************************/
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestProfilesRoundTrip(t *testing.T) {
+	location := filepath.Join(t.TempDir(), "skysolve", "profiles.json")
+
+	// A profiles file that does not exist yet has no profiles:
+	profiles, err := ReadProfiles(location)
+	if err != nil || len(profiles.Profiles) != 0 {
+		t.Fatalf("expected no profiles, got %v (%v)", profiles, err)
+	}
+
+	for _, profile := range []Profile{
+		{Name: "redcat51-asi2600", Telescope: "RedCat 51", Camera: "ZWO ASI2600MM Pro", FocalLength: 250, PixelSizeX: 3.76},
+		{Name: "c11-asi183", Telescope: "Celestron C11", Camera: "ZWO ASI183MM", FocalLength: 2800, PixelSizeX: 2.4},
+		{Name: "RedCat51-ASI2600", Telescope: "RedCat 51", Camera: "ZWO ASI2600MC Pro", FocalLength: 250, PixelSizeX: 3.76},
+	} {
+		if err := profiles.Set(profile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := profiles.Set(Profile{Name: "broken", FocalLength: 250}); err == nil {
+		t.Errorf("expected an error for a profile without a pixel size")
+	}
+
+	if err := WriteProfiles(location, profiles); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := ReadProfiles(location)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The profile of the same name (in any case) is replaced, and the profiles are sorted by name:
+	if len(read.Profiles) != 2 || read.Profiles[0].Name != "c11-asi183" {
+		t.Fatalf("expected 2 sorted profiles, got %+v", read.Profiles)
+	}
+
+	profile, err := read.Get("REDCAT51-asi2600")
+	if err != nil || profile.Camera != "ZWO ASI2600MC Pro" {
+		t.Errorf("expected the replaced profile, got %+v (%v)", profile, err)
+	}
+
+	scale, err := profile.GetPixelScale(2, 2)
+	if err != nil || !floatEquals(scale.X*3600, 2*3.1023, 1e-3) {
+		t.Errorf("expected a binned pixel scale of 6.2046\"/pixel, got %+v (%v)", scale, err)
+	}
+
+	if _, err := read.Get("unknown"); err == nil {
+		t.Errorf("expected an error for an unknown profile")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,78 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fov
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+)
+
+/*****************************************************************************************************************/
+
+// EquipmentParams describes the optical train that the pixel scale of an image follows from.
+type EquipmentParams struct {
+	FocalLength float64 // The (effective) focal length of the telescope (in millimetres)
+	PixelSizeX  float64 // The size of the unbinned pixels of the camera in the x direction (in microns)
+	PixelSizeY  float64 // The size of the unbinned pixels of the camera in the y direction (in microns), zero is square
+	BinningX    int     // The binning factor in the x direction, where zero is unbinned
+	BinningY    int     // The binning factor in the y direction, where zero is the binning in the x direction
+}
+
+/*****************************************************************************************************************/
+
+// NewPixelScaleFromEquipment returns the pixel scale (in degrees) of the focal length, pixel size and binning of the
+// equipment, i.e., the angle subtended by a binned pixel at the focal length.
+func NewPixelScaleFromEquipment(params EquipmentParams) (PixelScale, error) {
+	if params.FocalLength <= 0 || math.IsNaN(params.FocalLength) || math.IsInf(params.FocalLength, 0) {
+		return PixelScale{}, fmt.Errorf("invalid focal length: %v mm", params.FocalLength)
+	}
+
+	if params.PixelSizeX <= 0 || math.IsNaN(params.PixelSizeX) || math.IsInf(params.PixelSizeX, 0) {
+		return PixelScale{}, fmt.Errorf("invalid pixel size: %v µm", params.PixelSizeX)
+	}
+
+	if params.PixelSizeY < 0 || math.IsNaN(params.PixelSizeY) || math.IsInf(params.PixelSizeY, 0) {
+		return PixelScale{}, fmt.Errorf("invalid pixel size: %v µm", params.PixelSizeY)
+	}
+
+	if params.BinningX < 0 || params.BinningY < 0 {
+		return PixelScale{}, fmt.Errorf("invalid binning: %dx%d", params.BinningX, params.BinningY)
+	}
+
+	// Square pixels, and unbinned or symmetrically binned images, are the default:
+	py := params.PixelSizeY
+
+	if py == 0 {
+		py = params.PixelSizeX
+	}
+
+	bx := max(params.BinningX, 1)
+
+	by := params.BinningY
+
+	if by == 0 {
+		by = bx
+	}
+
+	return PixelScale{
+		X: getAngularSize(params.PixelSizeX*float64(bx), params.FocalLength),
+		Y: getAngularSize(py*float64(by), params.FocalLength),
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// getAngularSize returns the angle (in degrees) subtended by a length (in microns) at the focal length (in mm).
+func getAngularSize(size, focalLength float64) float64 {
+	return 2 * math.Atan(size/(2000*focalLength)) * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
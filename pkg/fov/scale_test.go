@@ -0,0 +1,62 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fov
+
+/*****************************************************************************************************************/
+
+import (
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestNewPixelScaleFromEquipment(t *testing.T) {
+	// A 250 mm focal length with 3.76 µm pixels has a plate scale of 206.265 × 3.76 / 250 = 3.1023"/pixel:
+	scale, err := NewPixelScaleFromEquipment(EquipmentParams{FocalLength: 250, PixelSizeX: 3.76})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !floatEquals(scale.X*3600, 3.1023, 1e-4) || !floatEquals(scale.Y, scale.X, 1e-12) {
+		t.Errorf("expected a pixel scale of 3.1023\"/pixel, got %+v", scale)
+	}
+
+	// Binning enlarges the pixels by the binning factor in each direction:
+	binned, err := NewPixelScaleFromEquipment(EquipmentParams{FocalLength: 250, PixelSizeX: 3.76, BinningX: 2, BinningY: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !floatEquals(binned.X, 2*scale.X, 1e-10) || !floatEquals(binned.Y, 3*scale.Y, 1e-10) {
+		t.Errorf("expected a binned pixel scale of (%v, %v), got %+v", 2*scale.X, 3*scale.Y, binned)
+	}
+
+	// Rectangular pixels, symmetrically binned:
+	rectangular, err := NewPixelScaleFromEquipment(EquipmentParams{FocalLength: 1000, PixelSizeX: 8.6, PixelSizeY: 8.3, BinningX: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !floatEquals(rectangular.X*3600, 2*1.77388, 1e-4) || !floatEquals(rectangular.Y*3600, 2*1.71200, 1e-4) {
+		t.Errorf("expected a pixel scale of (3.54776, 3.42400)\"/pixel, got (%v, %v)", rectangular.X*3600, rectangular.Y*3600)
+	}
+
+	for _, params := range []EquipmentParams{
+		{FocalLength: 0, PixelSizeX: 3.76},
+		{FocalLength: 250, PixelSizeX: 0},
+		{FocalLength: 250, PixelSizeX: 3.76, PixelSizeY: -1},
+		{FocalLength: 250, PixelSizeX: 3.76, BinningX: -1},
+	} {
+		if _, err := NewPixelScaleFromEquipment(params); err == nil {
+			t.Errorf("expected an error for %+v", params)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,49 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package imageio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// FITSReader reads every header and data unit of a FITS file, including multi-extension and tile-compressed files.
+type FITSReader struct{}
+
+/*****************************************************************************************************************/
+
+func (f *FITSReader) Name() string {
+	return "FITS"
+}
+
+/*****************************************************************************************************************/
+
+func (f *FITSReader) Extensions() []string {
+	return []string{".fits", ".fit", ".fts", ".fz"}
+}
+
+/*****************************************************************************************************************/
+
+func (f *FITSReader) Magic(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("SIMPLE  ="))
+}
+
+/*****************************************************************************************************************/
+
+func (f *FITSReader) Read(r io.Reader) ([]*fitsio.HDU, error) {
+	return fitsio.ReadHDUs(r)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,280 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package imageio
+
+/*****************************************************************************************************************/
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// The number of leading bytes of a file that are inspected to detect its format:
+const MAGIC_SIZE = 16
+
+/*****************************************************************************************************************/
+
+// Reader reads an image file format into FITS header and data units, such that every format shares the frame,
+// header resolution and WCS output logic of FITS images. The metadata of the format, e.g., the exposure time or the
+// approximate coordinates of the image, is mapped onto the equivalent FITS keywords of the header.
+type Reader interface {
+	// Name returns the name of the image format, e.g., "XISF":
+	Name() string
+	// Extensions returns the (lower case) file extensions of the format, e.g., ".xisf":
+	Extensions() []string
+	// Magic determines whether the leading bytes of a file match the signature of the format:
+	Magic(b []byte) bool
+	// Read reads the image(s) of the file as FITS header and data units:
+	Read(r io.Reader) ([]*fitsio.HDU, error)
+}
+
+/*****************************************************************************************************************/
+
+var (
+	mu      sync.RWMutex
+	readers = []Reader{
+		&FITSReader{},
+		&XISFReader{},
+		&TIFFReader{},
+		&PNGReader{},
+	}
+)
+
+/*****************************************************************************************************************/
+
+// Register adds an image reader, which takes precedence over the existing readers of the same extension or magic.
+func Register(reader Reader) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	readers = append([]Reader{reader}, readers...)
+}
+
+/*****************************************************************************************************************/
+
+// Readers returns the registered image readers, in order of precedence.
+func Readers() []Reader {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]Reader{}, readers...)
+}
+
+/*****************************************************************************************************************/
+
+// GetReader returns the reader of a file from its leading (magic) bytes or, failing that, its file extension.
+func GetReader(path string, magic []byte) (Reader, error) {
+	registered := Readers()
+
+	// The signature of a file is more reliable than its, possibly misleading, extension:
+	for _, reader := range registered {
+		if reader.Magic(magic) {
+			return reader, nil
+		}
+	}
+
+	extension := strings.ToLower(filepath.Ext(path))
+
+	for _, reader := range registered {
+		for _, e := range reader.Extensions() {
+			if e == extension {
+				return reader, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported image format: %s", filepath.Base(path))
+}
+
+/*****************************************************************************************************************/
+
+// Read detects the format of the image from its leading bytes or the extension of its path, and reads the image(s)
+// as FITS header and data units.
+func Read(path string, r io.Reader) ([]*fitsio.HDU, error) {
+	buffered := bufio.NewReader(r)
+
+	// Peek at the leading bytes, tolerating files shorter than the magic:
+	magic, err := buffered.Peek(MAGIC_SIZE)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	reader, err := GetReader(path, magic)
+	if err != nil {
+		return nil, err
+	}
+
+	hdus, err := reader.Read(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s image: %w", reader.Name(), err)
+	}
+
+	return hdus, nil
+}
+
+/*****************************************************************************************************************/
+
+// ReadFile reads the image(s) of an open file, of any registered format, as FITS header and data units.
+func ReadFile(f *os.File) ([]*fitsio.HDU, error) {
+	return Read(f.Name(), f)
+}
+
+/*****************************************************************************************************************/
+
+// newPrimaryImageHDU creates a primary HDU of a single two dimensional image, recording the maximum ADU of the
+// source format and any metadata keywords, excluding those that describe the data array itself.
+func newPrimaryImageHDU(width int, height int, data []float32, adu int64, metadata *fitsio.Header) (*fitsio.HDU, error) {
+	hdu, err := fitsio.NewImageHDU([]int{width, height}, data, true)
+	if err != nil {
+		return nil, err
+	}
+
+	hdu.Header.Set("ADU", adu, "Analog to Digital Units (ADU)")
+
+	if metadata == nil {
+		return hdu, nil
+	}
+
+	for _, card := range metadata.Cards {
+		switch {
+		case card.Key == "" || card.Key == "END":
+			continue
+		case card.Value == nil:
+			// Retain the commentary cards, e.g., HISTORY:
+			hdu.Header.Cards = append(hdu.Header.Cards, card)
+		case strings.HasPrefix(card.Key, "NAXIS") || isStructuralKeyword(card.Key):
+			continue
+		default:
+			hdu.Header.Set(card.Key, card.Value, card.Comment)
+		}
+	}
+
+	return hdu, nil
+}
+
+/*****************************************************************************************************************/
+
+// isStructuralKeyword determines whether the keyword describes the data array of the source, rather than the image.
+func isStructuralKeyword(key string) bool {
+	switch key {
+	case "SIMPLE", "XTENSION", "BITPIX", "EXTEND", "BZERO", "BSCALE", "BLANK", "PCOUNT", "GCOUNT", "ADU":
+		return true
+	}
+
+	return false
+}
+
+/*****************************************************************************************************************/
+
+// parseKeyword parses the textual value of a FITS keyword, e.g., "98.5", "'M 42'" or "T", into a header card.
+func parseKeyword(key string, value string, comment string) (fitsio.Card, bool) {
+	key = strings.ToUpper(strings.TrimSpace(key))
+
+	if key == "" || len(key) > 8 {
+		return fitsio.Card{}, false
+	}
+
+	value = strings.TrimSpace(value)
+
+	// Unquoted values that are not numeric or logical are treated as strings:
+	card, err := fitsio.ParseCard(fmt.Sprintf("%-8s= %s", key, value))
+	if err != nil || card.Value == nil {
+		return fitsio.Card{}, false
+	}
+
+	if s, ok := card.Value.(string); ok && !strings.HasPrefix(value, "'") {
+		card.Value = strings.TrimSpace(strings.Trim(s, "\""))
+	}
+
+	card.Comment = comment
+
+	return card, true
+}
+
+/*****************************************************************************************************************/
+
+// parseHeaderText parses FITS-like "KEY = value / comment" lines, e.g., of a TIFF ImageDescription or a PNG text
+// chunk, into the header, without replacing any existing keywords.
+func parseHeaderText(header *fitsio.Header, text string) {
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			continue
+		}
+
+		value, comment := line[i+1:], ""
+
+		// A comment follows the value, unless the slash is within a quoted string:
+		if j := strings.LastIndex(value, "/"); j >= 0 && strings.Count(value[:j], "'")%2 == 0 {
+			value, comment = value[:j], strings.TrimSpace(value[j+1:])
+		}
+
+		card, ok := parseKeyword(line[:i], value, comment)
+		if !ok || header.Has(card.Key) {
+			continue
+		}
+
+		header.Set(card.Key, card.Value, card.Comment)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// getImageData converts a decoded image into a single plane of luminance values, in row order from the top of the
+// image, returning the maximum ADU of its bit depth, where colour images are reduced to the mean of their channels.
+func getImageData(img image.Image) ([]float32, int64) {
+	bounds := img.Bounds()
+
+	width, height := bounds.Dx(), bounds.Dy()
+
+	data := make([]float32, width*height)
+
+	switch src := img.(type) {
+	case *image.Gray16:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				data[y*width+x] = float32(src.Gray16At(bounds.Min.X+x, bounds.Min.Y+y).Y)
+			}
+		}
+
+		return data, math.MaxUint16
+	case *image.Gray:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				data[y*width+x] = float32(src.GrayAt(bounds.Min.X+x, bounds.Min.Y+y).Y)
+			}
+		}
+
+		return data, math.MaxUint8
+	}
+
+	// Every other colour model is read at 16 bits per channel:
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+
+			data[y*width+x] = float32(r+g+b) / 3
+		}
+	}
+
+	return data, math.MaxUint16
+}
+
+/*****************************************************************************************************************/
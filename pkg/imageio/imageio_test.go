@@ -0,0 +1,318 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package imageio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/tiff"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// newGray16 creates a 16-bit grayscale image whose pixel values encode their position:
+func newGray16(width, height int) *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetGray16(x, y, color.Gray16{Y: uint16(1000*y + x)})
+		}
+	}
+
+	return img
+}
+
+/*****************************************************************************************************************/
+
+// newXISF creates a monolithic XISF file of a single image, whose pixel data is attached after the XML header:
+func newXISF(attributes string, children string, block []byte) []byte {
+	// The attachment position depends on the header length, so reserve a fixed-width position:
+	xml := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<xisf version="1.0" xmlns="http://www.pixinsight.com/xisf">
+<Image %s location="attachment:%08d:%d">%s</Image>
+<Metadata><Property id="Instrument:Telescope:FocalLength" type="Float32" value="0.53"/></Metadata>
+</xisf>`, attributes, 0, len(block), children)
+
+	position := 16 + len(xml)
+
+	xml = strings.Replace(xml, fmt.Sprintf("attachment:%08d:", 0), fmt.Sprintf("attachment:%08d:", position), 1)
+
+	var buf bytes.Buffer
+
+	buf.WriteString(XISF_SIGNATURE)
+	binary.Write(&buf, binary.LittleEndian, uint32(len(xml)))
+	buf.Write([]byte{0, 0, 0, 0})
+	buf.WriteString(xml)
+	buf.Write(block)
+
+	return buf.Bytes()
+}
+
+/*****************************************************************************************************************/
+
+func TestGetReaderByMagicAndExtension(t *testing.T) {
+	tests := []struct {
+		path  string
+		magic string
+		name  string
+	}{
+		{"image.fits", "SIMPLE  =                    T", "FITS"},
+		{"image.xisf", XISF_SIGNATURE, "XISF"},
+		{"image.tif", "II*\x00", "TIFF"},
+		{"image.png", PNG_SIGNATURE, "PNG"},
+		// The magic bytes take precedence over a misleading extension:
+		{"image.fits", PNG_SIGNATURE, "PNG"},
+		// The extension is used when the magic bytes are unknown:
+		{"IMAGE.TIFF", "", "TIFF"},
+		{"image.fts", "", "FITS"},
+	}
+
+	for _, tt := range tests {
+		reader, err := GetReader(tt.path, []byte(tt.magic))
+		if err != nil {
+			t.Fatalf("GetReader(%q) returned an error: %v", tt.path, err)
+		}
+
+		if reader.Name() != tt.name {
+			t.Errorf("GetReader(%q, %q) = %s, want %s", tt.path, tt.magic, reader.Name(), tt.name)
+		}
+	}
+
+	if _, err := GetReader("image.jpg", []byte{0xff, 0xd8}); err == nil {
+		t.Errorf("GetReader(image.jpg) did not return an error")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadXISFAttachment(t *testing.T) {
+	width, height := 4, 3
+
+	// Two planar UInt16 channels, whose mean is the luminance:
+	block := make([]byte, 2*width*height*2)
+
+	for i := 0; i < width*height; i++ {
+		binary.LittleEndian.PutUint16(block[2*i:], uint16(100*i))
+		binary.LittleEndian.PutUint16(block[2*(width*height+i):], uint16(100*i+50))
+	}
+
+	file := newXISF(
+		fmt.Sprintf(`geometry="%d:%d:2" sampleFormat="UInt16" colorSpace="RGB"`, width, height),
+		`<FITSKeyword name="EXPTIME" value="30." comment="Exposure time"/>
+<FITSKeyword name="OBJECT" value="'M 42'" comment=""/>
+<Property id="Observation:Center:RA" type="Float64" value="83.82"/>
+<Property id="Instrument:ExposureTime" type="Float32" value="60"/>`,
+		block,
+	)
+
+	hdus, err := Read("image.xisf", bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	frames, err := fitsio.GetFrames(hdus)
+	if err != nil {
+		t.Fatalf("GetFrames returned an error: %v", err)
+	}
+
+	frame := frames[0]
+
+	if frame.Width != width || frame.Height != height {
+		t.Fatalf("frame is %dx%d, want %dx%d", frame.Width, frame.Height, width, height)
+	}
+
+	for i, v := range frame.Data {
+		if want := float32(100*i + 25); v != want {
+			t.Fatalf("Data[%d] = %v, want %v", i, v, want)
+		}
+	}
+
+	if adu, _ := frame.Header.Int("ADU"); adu != math.MaxUint16 {
+		t.Errorf("ADU = %d, want %d", adu, math.MaxUint16)
+	}
+
+	// The FITS keywords take precedence over the equivalent properties:
+	if exposure, _ := frame.Header.Float("EXPTIME"); exposure != 30 {
+		t.Errorf("EXPTIME = %v, want 30", exposure)
+	}
+
+	if object, _ := frame.Header.String("OBJECT"); object != "M 42" {
+		t.Errorf("OBJECT = %q, want %q", object, "M 42")
+	}
+
+	if ra, _ := frame.Header.Float("RA"); ra != 83.82 {
+		t.Errorf("RA = %v, want 83.82", ra)
+	}
+
+	// The focal length property is converted from meters to millimeters:
+	if focal, _ := frame.Header.Float("FOCALLEN"); math.Abs(focal-530) > 1e-9 {
+		t.Errorf("FOCALLEN = %v, want 530", focal)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadXISFCompressedFloat(t *testing.T) {
+	width, height := 5, 2
+
+	n := width * height
+
+	samples := make([]byte, 4*n)
+
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint32(samples[4*i:], math.Float32bits(float32(i)/float32(n)))
+	}
+
+	// Shuffle the bytes, such that the n-th bytes of every sample are contiguous:
+	shuffled := make([]byte, len(samples))
+
+	for i := 0; i < n; i++ {
+		for b := 0; b < 4; b++ {
+			shuffled[b*n+i] = samples[4*i+b]
+		}
+	}
+
+	var compressed bytes.Buffer
+
+	w := zlib.NewWriter(&compressed)
+	w.Write(shuffled)
+	w.Close()
+
+	file := newXISF(
+		fmt.Sprintf(`geometry="%d:%d:1" sampleFormat="Float32" bounds="0:1" compression="zlib+sh:%d:4"`, width, height, len(samples)),
+		`<ColorFilterArray pattern="RGGB" width="2" height="2"/>`,
+		compressed.Bytes(),
+	)
+
+	hdus, err := (&XISFReader{}).Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	image, err := fitsio.NewImageFromHDU(hdus[0])
+	if err != nil {
+		t.Fatalf("NewImageFromHDU returned an error: %v", err)
+	}
+
+	for i, v := range image.Data {
+		if want := float64(i) / float64(n) * math.MaxUint16; math.Abs(float64(v)-want) > 1e-2 {
+			t.Fatalf("Data[%d] = %v, want %v", i, v, want)
+		}
+	}
+
+	if pattern, _ := hdus[0].Header.String("BAYERPAT"); pattern != "RGGB" {
+		t.Errorf("BAYERPAT = %q, want %q", pattern, "RGGB")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadPNGGray16WithText(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, newGray16(6, 4)); err != nil {
+		t.Fatalf("png.Encode returned an error: %v", err)
+	}
+
+	// Insert tEXt chunks after the IHDR chunk, i.e., the signature and 25 bytes:
+	chunk := func(keyword, text string) []byte {
+		data := append([]byte(keyword+"\x00"), text...)
+
+		var c bytes.Buffer
+
+		binary.Write(&c, binary.BigEndian, uint32(len(data)))
+		c.WriteString("tEXt")
+		c.Write(data)
+		binary.Write(&c, binary.BigEndian, crc32.ChecksumIEEE(append([]byte("tEXt"), data...)))
+
+		return c.Bytes()
+	}
+
+	encoded := buf.Bytes()
+
+	file := append([]byte{}, encoded[:33]...)
+	file = append(file, chunk("EXPTIME", "15")...)
+	file = append(file, chunk("Description", "OBJCTRA = '05 35 17.3' / Object RA\nFOCALLEN= 400")...)
+	file = append(file, encoded[33:]...)
+
+	hdus, err := Read("capture.png", bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	image, err := fitsio.NewImageFromHDU(hdus[0])
+	if err != nil {
+		t.Fatalf("NewImageFromHDU returned an error: %v", err)
+	}
+
+	if image.Width() != 6 || image.Height() != 4 || image.Data[2*6+3] != 2003 {
+		t.Errorf("image is %dx%d with Data[15] = %v, want 6x4 with 2003", image.Width(), image.Height(), image.Data[15])
+	}
+
+	if exposure, _ := hdus[0].Header.Int("EXPTIME"); exposure != 15 {
+		t.Errorf("EXPTIME = %v, want 15", exposure)
+	}
+
+	if ra, _ := hdus[0].Header.String("OBJCTRA"); ra != "05 35 17.3" {
+		t.Errorf("OBJCTRA = %q, want %q", ra, "05 35 17.3")
+	}
+
+	if focal, _ := hdus[0].Header.Int("FOCALLEN"); focal != 400 {
+		t.Errorf("FOCALLEN = %v, want 400", focal)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadTIFFGray16(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := tiff.Encode(&buf, newGray16(7, 5), nil); err != nil {
+		t.Fatalf("tiff.Encode returned an error: %v", err)
+	}
+
+	hdus, err := Read("capture.dat", &buf)
+	if err != nil {
+		t.Fatalf("Read returned an error: %v", err)
+	}
+
+	image, err := fitsio.NewImageFromHDU(hdus[0])
+	if err != nil {
+		t.Fatalf("NewImageFromHDU returned an error: %v", err)
+	}
+
+	if image.Width() != 7 || image.Height() != 5 {
+		t.Fatalf("image is %dx%d, want 7x5", image.Width(), image.Height())
+	}
+
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 7; x++ {
+			if v := image.Data[y*7+x]; v != float32(1000*y+x) {
+				t.Fatalf("Data[%d, %d] = %v, want %v", x, y, v, 1000*y+x)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,115 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package imageio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"io"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// The signature of a PNG file:
+const PNG_SIGNATURE = "\x89PNG\r\n\x1a\n"
+
+/*****************************************************************************************************************/
+
+// PNGReader reads an 8 or 16-bit PNG file, where the tEXt chunks are mapped into its header, i.e., keywords of up to
+// eight characters as FITS keywords, and FITS-like "KEY = value" lines of a "Description" or "Comment" chunk.
+type PNGReader struct{}
+
+/*****************************************************************************************************************/
+
+func (p *PNGReader) Name() string {
+	return "PNG"
+}
+
+/*****************************************************************************************************************/
+
+func (p *PNGReader) Extensions() []string {
+	return []string{".png"}
+}
+
+/*****************************************************************************************************************/
+
+func (p *PNGReader) Magic(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(PNG_SIGNATURE))
+}
+
+/*****************************************************************************************************************/
+
+func (p *PNGReader) Read(r io.Reader) ([]*fitsio.HDU, error) {
+	file, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := png.Decode(bytes.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+
+	data, adu := getImageData(img)
+
+	header := fitsio.NewHeader()
+
+	for _, chunk := range getPNGTextChunks(file) {
+		switch chunk[0] {
+		case "Description", "Comment":
+			parseHeaderText(header, chunk[1])
+		default:
+			if card, ok := parseKeyword(chunk[0], chunk[1], ""); ok && !header.Has(card.Key) {
+				header.Set(card.Key, card.Value, card.Comment)
+			}
+		}
+	}
+
+	hdu, err := newPrimaryImageHDU(img.Bounds().Dx(), img.Bounds().Dy(), data, adu, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*fitsio.HDU{hdu}, nil
+}
+
+/*****************************************************************************************************************/
+
+// getPNGTextChunks returns the keyword and text of every (uncompressed) tEXt chunk of a PNG file, in file order.
+func getPNGTextChunks(file []byte) [][2]string {
+	chunks := [][2]string{}
+
+	// Each chunk is its length, type, data and CRC:
+	for i := len(PNG_SIGNATURE); i+8 <= len(file); {
+		length := int(binary.BigEndian.Uint32(file[i : i+4]))
+
+		kind := string(file[i+4 : i+8])
+
+		if length < 0 || i+12+length > len(file) || kind == "IEND" {
+			break
+		}
+
+		if kind == "tEXt" {
+			if keyword, text, ok := bytes.Cut(file[i+8:i+8+length], []byte{0}); ok {
+				chunks = append(chunks, [2]string{string(keyword), string(text)})
+			}
+		}
+
+		i += 12 + length
+	}
+
+	return chunks
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,150 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package imageio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"golang.org/x/image/tiff"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// The TIFF tags of the first image file directory (IFD) that hold metadata:
+const (
+	TIFF_TAG_IMAGE_DESCRIPTION = 270
+	TIFF_TAG_DATE_TIME         = 306
+)
+
+/*****************************************************************************************************************/
+
+// TIFFReader reads the first image of an 8 or 16-bit TIFF file, e.g., as saved by capture software, where any
+// FITS-like "KEY = value" lines of the ImageDescription tag are mapped into its header.
+type TIFFReader struct{}
+
+/*****************************************************************************************************************/
+
+func (t *TIFFReader) Name() string {
+	return "TIFF"
+}
+
+/*****************************************************************************************************************/
+
+func (t *TIFFReader) Extensions() []string {
+	return []string{".tif", ".tiff"}
+}
+
+/*****************************************************************************************************************/
+
+func (t *TIFFReader) Magic(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("II*\x00")) || bytes.HasPrefix(b, []byte("MM\x00*"))
+}
+
+/*****************************************************************************************************************/
+
+func (t *TIFFReader) Read(r io.Reader) ([]*fitsio.HDU, error) {
+	file, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := tiff.Decode(bytes.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+
+	data, adu := getImageData(img)
+
+	header := fitsio.NewHeader()
+
+	tags := getTIFFASCIITags(file)
+
+	if description, ok := tags[TIFF_TAG_IMAGE_DESCRIPTION]; ok {
+		parseHeaderText(header, description)
+	}
+
+	// The TIFF date time is of the form "YYYY:MM:DD HH:MM:SS", in the local time of the capture software:
+	if datetime, ok := tags[TIFF_TAG_DATE_TIME]; ok && len(datetime) == 19 && !header.Has("DATE-OBS") {
+		date := strings.ReplaceAll(datetime[:10], ":", "-") + "T" + datetime[11:]
+
+		header.Set("DATE-OBS", date, "The date and time of the observation")
+	}
+
+	hdu, err := newPrimaryImageHDU(img.Bounds().Dx(), img.Bounds().Dy(), data, adu, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*fitsio.HDU{hdu}, nil
+}
+
+/*****************************************************************************************************************/
+
+// getTIFFASCIITags returns the ASCII valued tags of the first image file directory of a TIFF file, ignoring any
+// malformed entries, as the metadata is optional.
+func getTIFFASCIITags(file []byte) map[uint16]string {
+	tags := map[uint16]string{}
+
+	if len(file) < 8 {
+		return tags
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+
+	if file[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	offset := int(order.Uint32(file[4:8]))
+
+	if offset < 8 || offset+2 > len(file) {
+		return tags
+	}
+
+	entries := int(order.Uint16(file[offset : offset+2]))
+
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+
+		if entry+12 > len(file) {
+			break
+		}
+
+		tag, kind, count := order.Uint16(file[entry:]), order.Uint16(file[entry+2:]), int(order.Uint32(file[entry+4:]))
+
+		// Only the ASCII (type 2) tags are of interest:
+		if kind != 2 || count <= 0 {
+			continue
+		}
+
+		// Values of up to four bytes are stored within the entry itself, otherwise at an offset:
+		start := entry + 8
+
+		if count > 4 {
+			start = int(order.Uint32(file[entry+8:]))
+		}
+
+		if start < 0 || start+count > len(file) {
+			continue
+		}
+
+		tags[tag] = strings.TrimRight(string(file[start:start+count]), "\x00")
+	}
+
+	return tags
+}
+
+/*****************************************************************************************************************/
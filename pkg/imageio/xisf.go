@@ -0,0 +1,492 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package imageio
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// The signature of a monolithic XISF 1.0 file:
+const XISF_SIGNATURE = "XISF0100"
+
+/*****************************************************************************************************************/
+
+// xisfProperty maps an XISF property onto a FITS keyword, where the value is multiplied by the scale to convert its
+// units, e.g., the focal length from meters to millimeters, and a zero scale denotes a string value.
+type xisfProperty struct {
+	Key     string
+	Scale   float64
+	Comment string
+}
+
+/*****************************************************************************************************************/
+
+// The XISF properties that map onto the FITS keywords used to resolve the observation, e.g., the approximate
+// coordinates and the pixel scale of the image.
+//
+// @see https://pixinsight.com/doc/docs/XISF-1.0-spec/XISF-1.0-spec.html#__XISF_Core_Elements_:_Property_Namespaces__
+var xisfProperties = map[string]xisfProperty{
+	"Observation:Center:RA":            {"RA", 1, "Right Ascension of the center of the image (in degrees)"},
+	"Observation:Center:Dec":           {"DEC", 1, "Declination of the center of the image (in degrees)"},
+	"Observation:Time:Start":           {"DATE-OBS", 0, "The start time of the observation"},
+	"Observation:Object:Name":          {"OBJECT", 0, "The name of the observed object"},
+	"Observation:Location:Latitude":    {"SITELAT", 1, "The latitude of the observatory (in degrees)"},
+	"Observation:Location:Longitude":   {"SITELONG", 1, "The longitude of the observatory (in degrees)"},
+	"Observation:Location:Elevation":   {"SITEELEV", 1, "The elevation of the observatory (in meters)"},
+	"Instrument:ExposureTime":          {"EXPTIME", 1, "The exposure time (in seconds)"},
+	"Instrument:Telescope:FocalLength": {"FOCALLEN", 1000, "The focal length of the telescope (in mm)"},
+	"Instrument:Telescope:Aperture":    {"APTDIA", 1000, "The aperture diameter of the telescope (in mm)"},
+	"Instrument:Sensor:XPixelSize":     {"XPIXSZ", 1, "The pixel size in the x-axis (in microns)"},
+	"Instrument:Sensor:YPixelSize":     {"YPIXSZ", 1, "The pixel size in the y-axis (in microns)"},
+	"Instrument:Sensor:Temperature":    {"CCD-TEMP", 1, "The sensor temperature (in degrees C)"},
+	"Instrument:Camera:XBinning":       {"XBINNING", 1, "The binning factor in the x-axis"},
+	"Instrument:Camera:YBinning":       {"YBINNING", 1, "The binning factor in the y-axis"},
+	"Instrument:Camera:Gain":           {"GAIN", 1, "The sensor gain (in e-/ADU)"},
+	"Instrument:Filter:Name":           {"FILTER", 0, "The name of the filter"},
+}
+
+/*****************************************************************************************************************/
+
+type xisfDocument struct {
+	Images   []xisfImage `xml:"Image"`
+	Metadata struct {
+		Properties []xisfPropertyElement `xml:"Property"`
+	} `xml:"Metadata"`
+}
+
+/*****************************************************************************************************************/
+
+type xisfImage struct {
+	Geometry     string                `xml:"geometry,attr"`
+	SampleFormat string                `xml:"sampleFormat,attr"`
+	Bounds       string                `xml:"bounds,attr"`
+	PixelStorage string                `xml:"pixelStorage,attr"`
+	ByteOrder    string                `xml:"byteOrder,attr"`
+	Location     string                `xml:"location,attr"`
+	Compression  string                `xml:"compression,attr"`
+	Keywords     []xisfKeywordElement  `xml:"FITSKeyword"`
+	Properties   []xisfPropertyElement `xml:"Property"`
+	CFA          *struct {
+		Pattern string `xml:"pattern,attr"`
+		Width   int    `xml:"width,attr"`
+		Height  int    `xml:"height,attr"`
+	} `xml:"ColorFilterArray"`
+	Data *struct {
+		Encoding string `xml:"encoding,attr"`
+		Text     string `xml:",chardata"`
+	} `xml:"Data"`
+	Text string `xml:",chardata"`
+}
+
+/*****************************************************************************************************************/
+
+type xisfKeywordElement struct {
+	Name    string `xml:"name,attr"`
+	Value   string `xml:"value,attr"`
+	Comment string `xml:"comment,attr"`
+}
+
+/*****************************************************************************************************************/
+
+type xisfPropertyElement struct {
+	ID    string `xml:"id,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"`
+}
+
+/*****************************************************************************************************************/
+
+// XISFReader reads the first image of a monolithic XISF 1.0 file, e.g., as written by PixInsight, with attached,
+// inline or embedded pixel data, which may be zlib compressed and byte shuffled. Multi-channel images are reduced to
+// their mean (luminance), and the FITS keywords and properties of the image are mapped into its header.
+//
+// @see https://pixinsight.com/doc/docs/XISF-1.0-spec/XISF-1.0-spec.html
+type XISFReader struct{}
+
+/*****************************************************************************************************************/
+
+func (x *XISFReader) Name() string {
+	return "XISF"
+}
+
+/*****************************************************************************************************************/
+
+func (x *XISFReader) Extensions() []string {
+	return []string{".xisf"}
+}
+
+/*****************************************************************************************************************/
+
+func (x *XISFReader) Magic(b []byte) bool {
+	return bytes.HasPrefix(b, []byte(XISF_SIGNATURE))
+}
+
+/*****************************************************************************************************************/
+
+func (x *XISFReader) Read(r io.Reader) ([]*fitsio.HDU, error) {
+	// The attachments are located by their absolute position within the file:
+	file, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file) < 16 || string(file[:8]) != XISF_SIGNATURE {
+		return nil, errors.New("not a monolithic XISF 1.0 file")
+	}
+
+	length := int(binary.LittleEndian.Uint32(file[8:12]))
+
+	if 16+length > len(file) {
+		return nil, fmt.Errorf("the XISF header of %d bytes is truncated", length)
+	}
+
+	var document xisfDocument
+
+	if err := xml.Unmarshal(bytes.TrimRight(file[16:16+length], "\x00 "), &document); err != nil {
+		return nil, fmt.Errorf("failed to parse the XISF header: %w", err)
+	}
+
+	if len(document.Images) == 0 {
+		return nil, errors.New("the XISF file does not contain an image")
+	}
+
+	image := document.Images[0]
+
+	width, height, channels, err := parseXISFGeometry(image.Geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := getXISFBlock(file, image)
+	if err != nil {
+		return nil, err
+	}
+
+	data, adu, err := decodeXISFPixels(raw, image, width, height, channels)
+	if err != nil {
+		return nil, err
+	}
+
+	header := fitsio.NewHeader()
+
+	for _, keyword := range image.Keywords {
+		if card, ok := parseKeyword(keyword.Name, keyword.Value, keyword.Comment); ok && !header.Has(card.Key) {
+			header.Set(card.Key, card.Value, card.Comment)
+		}
+	}
+
+	// The properties only supplement the FITS keywords, which take precedence:
+	for _, property := range append(image.Properties, document.Metadata.Properties...) {
+		mapping, ok := xisfProperties[property.ID]
+		if !ok || header.Has(mapping.Key) {
+			continue
+		}
+
+		value := strings.TrimSpace(property.Value)
+
+		if value == "" {
+			value = strings.TrimSpace(property.Text)
+		}
+
+		if mapping.Scale == 0 {
+			header.Set(mapping.Key, value, mapping.Comment)
+			continue
+		}
+
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			header.Set(mapping.Key, v*mapping.Scale, mapping.Comment)
+		}
+	}
+
+	// The colour filter array of a raw one-shot-colour image is recorded as its Bayer pattern:
+	if image.CFA != nil && image.CFA.Width == 2 && image.CFA.Height == 2 && !header.Has("BAYERPAT") {
+		header.Set("BAYERPAT", image.CFA.Pattern, "The Bayer color pattern")
+	}
+
+	hdu, err := newPrimaryImageHDU(width, height, data, adu, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*fitsio.HDU{hdu}, nil
+}
+
+/*****************************************************************************************************************/
+
+// parseXISFGeometry parses the "width:height:channels" geometry of an XISF image.
+func parseXISFGeometry(geometry string) (width int, height int, channels int, err error) {
+	parts := strings.Split(geometry, ":")
+
+	if len(parts) < 3 {
+		return 0, 0, 0, fmt.Errorf("unsupported XISF image geometry: %q", geometry)
+	}
+
+	dimensions := make([]int, len(parts))
+
+	for i, part := range parts {
+		dimensions[i], err = strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || dimensions[i] <= 0 {
+			return 0, 0, 0, fmt.Errorf("invalid XISF image geometry: %q", geometry)
+		}
+	}
+
+	if len(dimensions) > 3 {
+		return 0, 0, 0, fmt.Errorf("unsupported XISF image geometry of %d dimensions: %q", len(dimensions)-1, geometry)
+	}
+
+	return dimensions[0], dimensions[1], dimensions[2], nil
+}
+
+/*****************************************************************************************************************/
+
+// getXISFBlock returns the (decompressed) pixel data of the image from its attachment, inline or embedded location.
+func getXISFBlock(file []byte, image xisfImage) ([]byte, error) {
+	location := strings.Split(image.Location, ":")
+
+	var block []byte
+
+	switch location[0] {
+	case "attachment":
+		if len(location) != 3 {
+			return nil, fmt.Errorf("invalid XISF attachment location: %q", image.Location)
+		}
+
+		position, err := strconv.Atoi(location[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid XISF attachment location: %q", image.Location)
+		}
+
+		size, err := strconv.Atoi(location[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid XISF attachment location: %q", image.Location)
+		}
+
+		if position < 0 || size < 0 || position+size > len(file) {
+			return nil, fmt.Errorf("the XISF attachment %q exceeds the file", image.Location)
+		}
+
+		block = file[position : position+size]
+	case "inline", "embedded":
+		encoding, text := "", image.Text
+
+		if len(location) > 1 {
+			encoding = location[1]
+		}
+
+		if location[0] == "embedded" {
+			if image.Data == nil {
+				return nil, errors.New("the embedded XISF data block is missing")
+			}
+
+			encoding, text = image.Data.Encoding, image.Data.Text
+		}
+
+		var err error
+
+		block, err = decodeXISFText(encoding, text)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported XISF data block location: %q", image.Location)
+	}
+
+	if image.Compression == "" {
+		return block, nil
+	}
+
+	return decompressXISFBlock(block, image.Compression)
+}
+
+/*****************************************************************************************************************/
+
+// decodeXISFText decodes the base64 or hexadecimal text of an inline or embedded XISF data block.
+func decodeXISFText(encoding string, text string) ([]byte, error) {
+	text = strings.Join(strings.Fields(text), "")
+
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.DecodeString(text)
+	case "hex":
+		return hex.DecodeString(text)
+	default:
+		return nil, fmt.Errorf("unsupported XISF data block encoding: %q", encoding)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// decompressXISFBlock decompresses a "codec:size[:item-size]" compressed XISF data block, where the "+sh" codecs
+// are byte shuffled in items of item-size bytes.
+func decompressXISFBlock(block []byte, compression string) ([]byte, error) {
+	parts := strings.Split(compression, ":")
+
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid XISF compression: %q", compression)
+	}
+
+	size, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid XISF compression: %q", compression)
+	}
+
+	codec := parts[0]
+
+	if codec != "zlib" && codec != "zlib+sh" {
+		return nil, fmt.Errorf("unsupported XISF compression codec: %s", codec)
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(block))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress XISF data block: %w", err)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress XISF data block: %w", err)
+	}
+
+	if len(data) != size {
+		return nil, fmt.Errorf("the XISF data block decompressed to %d bytes, expected %d", len(data), size)
+	}
+
+	if codec == "zlib+sh" {
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("invalid XISF compression: %q", compression)
+		}
+
+		item, err := strconv.Atoi(parts[2])
+		if err != nil || item <= 0 {
+			return nil, fmt.Errorf("invalid XISF compression: %q", compression)
+		}
+
+		// Unshuffle the bytes, where the n-th bytes of every item are stored contiguously:
+		shuffled, count := data, size/item
+
+		data = make([]byte, size)
+
+		copy(data[count*item:], shuffled[count*item:])
+
+		for i := 0; i < count; i++ {
+			for b := 0; b < item; b++ {
+				data[i*item+b] = shuffled[b*count+i]
+			}
+		}
+	}
+
+	return data, nil
+}
+
+/*****************************************************************************************************************/
+
+// decodeXISFPixels decodes the samples of the image into a single (mean) plane, returning the maximum ADU, where
+// floating point samples are rescaled from their bounds (default [0, 1]) onto the 16-bit range.
+func decodeXISFPixels(raw []byte, image xisfImage, width int, height int, channels int) ([]float32, int64, error) {
+	var size int
+
+	var adu int64
+
+	switch image.SampleFormat {
+	case "UInt8":
+		size, adu = 1, math.MaxUint8
+	case "UInt16":
+		size, adu = 2, math.MaxUint16
+	case "UInt32":
+		size, adu = 4, math.MaxInt32
+	case "Float32":
+		size, adu = 4, math.MaxUint16
+	case "Float64":
+		size, adu = 8, math.MaxUint16
+	default:
+		return nil, 0, fmt.Errorf("unsupported XISF sample format: %q", image.SampleFormat)
+	}
+
+	n := width * height
+
+	if len(raw) < n*channels*size {
+		return nil, 0, fmt.Errorf("the XISF data block of %d bytes is truncated, expected %d", len(raw), n*channels*size)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+
+	if image.ByteOrder == "big" {
+		order = binary.BigEndian
+	}
+
+	lower, upper := 0.0, 1.0
+
+	if image.Bounds != "" {
+		bounds := strings.Split(image.Bounds, ":")
+
+		if len(bounds) == 2 {
+			if v, err := strconv.ParseFloat(bounds[0], 64); err == nil {
+				lower = v
+			}
+
+			if v, err := strconv.ParseFloat(bounds[1], 64); err == nil {
+				upper = v
+			}
+		}
+	}
+
+	sample := func(i int) float64 {
+		b := raw[i*size : (i+1)*size]
+
+		switch image.SampleFormat {
+		case "UInt8":
+			return float64(b[0])
+		case "UInt16":
+			return float64(order.Uint16(b))
+		case "UInt32":
+			return float64(order.Uint32(b))
+		case "Float32":
+			return (float64(math.Float32frombits(order.Uint32(b))) - lower) / (upper - lower) * math.MaxUint16
+		default:
+			return (math.Float64frombits(order.Uint64(b)) - lower) / (upper - lower) * math.MaxUint16
+		}
+	}
+
+	data := make([]float32, n)
+
+	for i := 0; i < n; i++ {
+		sum := 0.0
+
+		for c := 0; c < channels; c++ {
+			// Planar storage holds each channel contiguously, whereas normal storage interleaves the channels:
+			if image.PixelStorage == "Normal" {
+				sum += sample(i*channels + c)
+			} else {
+				sum += sample(c*n + i)
+			}
+		}
+
+		data[i] = float32(sum / float64(channels))
+	}
+
+	return data, adu, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,228 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package reject
+
+/*****************************************************************************************************************/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// Mask is a bad pixel mask of the detector, e.g., hot pixels, dead columns or traps.
+type Mask struct {
+	Width  int
+	Height int
+	Bad    []bool
+}
+
+/*****************************************************************************************************************/
+
+// NewMask creates a new bad pixel mask of the given dimensions, without any bad pixels.
+func NewMask(width int, height int) *Mask {
+	return &Mask{
+		Width:  width,
+		Height: height,
+		Bad:    make([]bool, width*height),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Set marks the pixel (x, y) as bad, ignoring pixels outside of the mask.
+func (m *Mask) Set(x int, y int) {
+	if x < 0 || y < 0 || x >= m.Width || y >= m.Height {
+		return
+	}
+
+	m.Bad[y*m.Width+x] = true
+}
+
+/*****************************************************************************************************************/
+
+// IsBad determines whether the pixel (x, y) is bad.
+func (m *Mask) IsBad(x int, y int) bool {
+	if x < 0 || y < 0 || x >= m.Width || y >= m.Height {
+		return false
+	}
+
+	return m.Bad[y*m.Width+x]
+}
+
+/*****************************************************************************************************************/
+
+// Overlaps determines whether any pixel within the square of the given radius around (x, y) is bad.
+func (m *Mask) Overlaps(x int, y int, radius int) bool {
+	for j := y - radius; j <= y+radius; j++ {
+		for i := x - radius; i <= x+radius; i++ {
+			if m.IsBad(i, j) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*****************************************************************************************************************/
+
+// Downsample returns the mask binned by the given factor, e.g., to match a superpixel debayered image, where a
+// binned pixel is bad if any of its original pixels are bad.
+func (m *Mask) Downsample(factor int) *Mask {
+	if factor <= 1 {
+		return m
+	}
+
+	binned := NewMask(m.Width/factor, m.Height/factor)
+
+	for y := 0; y < binned.Height*factor; y++ {
+		for x := 0; x < binned.Width*factor; x++ {
+			if m.IsBad(x, y) {
+				binned.Set(x/factor, y/factor)
+			}
+		}
+	}
+
+	return binned
+}
+
+/*****************************************************************************************************************/
+
+// ReadMask reads a bad pixel mask from the given file, either a FITS image where any non-zero (or NaN) pixel is bad,
+// or a text file of 0-based bad pixels, e.g., "x y", along with bad columns and rows, e.g., "column x" or "row y".
+// The width and height are required for text masks, whose dimensions cannot otherwise be known.
+func ReadMask(path string, width int, height int) (*Mask, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bad pixel mask: %w", err)
+	}
+
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".fits", ".fit", ".fts":
+		return ReadFITSMask(f)
+	default:
+		return ReadTextMask(f, width, height)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// ReadFITSMask reads a bad pixel mask from the first image HDU of a FITS file.
+func ReadFITSMask(r io.Reader) (*Mask, error) {
+	hdus, err := fitsio.ReadHDUs(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hdu := range hdus {
+		if naxis, _ := hdu.Header.Int("NAXIS"); naxis < 2 {
+			continue
+		}
+
+		image, err := fitsio.NewImageFromHDU(hdu)
+		if err != nil {
+			continue
+		}
+
+		mask := NewMask(image.Width(), image.Height())
+
+		for i := range mask.Bad {
+			v := float64(image.Data[i])
+			mask.Bad[i] = v != 0 || math.IsNaN(v)
+		}
+
+		return mask, nil
+	}
+
+	return nil, fmt.Errorf("no image HDU found in the bad pixel mask")
+}
+
+/*****************************************************************************************************************/
+
+// ReadTextMask reads a bad pixel mask from a text file, where each line is either a bad pixel, e.g., "x y", a bad
+// column, e.g., "column x", or a bad row, e.g., "row y". Blank lines and lines beginning with "#" are ignored.
+func ReadTextMask(r io.Reader, width int, height int) (*Mask, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid bad pixel mask dimensions: %dx%d", width, height)
+	}
+
+	mask := NewMask(width, height)
+
+	scanner := bufio.NewScanner(r)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ','
+		})
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid bad pixel mask entry on line %d: %q", line, text)
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "column", "col":
+			x, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid bad column on line %d: %q", line, text)
+			}
+
+			for y := 0; y < height; y++ {
+				mask.Set(x, y)
+			}
+		case "row":
+			y, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid bad row on line %d: %q", line, text)
+			}
+
+			for x := 0; x < width; x++ {
+				mask.Set(x, y)
+			}
+		default:
+			x, errX := strconv.Atoi(fields[0])
+			y, errY := strconv.Atoi(fields[1])
+
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("invalid bad pixel on line %d: %q", line, text)
+			}
+
+			mask.Set(x, y)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bad pixel mask: %w", err)
+	}
+
+	return mask, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,299 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package reject
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"sort"
+
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// Reason is the reason a star was rejected before matching.
+type Reason int
+
+/*****************************************************************************************************************/
+
+const (
+	ReasonNone      Reason = iota // the star was accepted
+	ReasonEdge                    // the star lies within the edge margin of the image
+	ReasonMasked                  // the star overlaps a pixel of the bad pixel mask
+	ReasonSaturated               // the star contains a pixel at or above the saturation level
+	ReasonNarrow                  // the FWHM of the star is below the minimum, e.g., a hot pixel
+	ReasonSharp                   // the star is too sharp to be a PSF, e.g., a cosmic ray hit
+)
+
+/*****************************************************************************************************************/
+
+// String returns the name of the rejection reason, e.g., "saturated".
+func (r Reason) String() string {
+	switch r {
+	case ReasonEdge:
+		return "edge"
+	case ReasonMasked:
+		return "masked"
+	case ReasonSaturated:
+		return "saturated"
+	case ReasonNarrow:
+		return "narrow"
+	case ReasonSharp:
+		return "sharp"
+	default:
+		return "none"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Params are the criteria used to reject spurious or unreliable stars before quad generation, where a zero value
+// disables the corresponding criterion.
+type Params struct {
+	MinimumFWHM      float64 // the minimum FWHM (in pixels), estimated as twice the half-flux radius, e.g., 1.0
+	MaximumSharpness float64 // the maximum sharpness of the peak pixel relative to its neighbours, e.g., 0.9
+	Saturation       float64 // the saturation level (in ADU), e.g., from DATAMAX or the ADU of the image
+	EdgeMargin       float64 // the margin (in pixels) from the edge of the image within which stars are rejected
+	Mask             *Mask   // the bad pixel mask of the detector, if any
+	// The pixel data checked against the saturation level, if it differs from the extraction data, e.g., the peak
+	// raw photosite values of a debayered one-shot-colour image:
+	SaturationData []float32
+}
+
+/*****************************************************************************************************************/
+
+// DefaultParams are sensible rejection criteria for most images, excluding the saturation level and the bad pixel
+// mask, which are specific to each detector.
+var DefaultParams = Params{
+	MinimumFWHM:      1.0,
+	MaximumSharpness: 0.9,
+	EdgeMargin:       4,
+}
+
+/*****************************************************************************************************************/
+
+// Summary is the number of stars rejected for each reason.
+type Summary struct {
+	Edge      int `json:"edge"`
+	Masked    int `json:"masked"`
+	Saturated int `json:"saturated"`
+	Narrow    int `json:"narrow"`
+	Sharp     int `json:"sharp"`
+}
+
+/*****************************************************************************************************************/
+
+// Total returns the total number of rejected stars.
+func (s Summary) Total() int {
+	return s.Edge + s.Masked + s.Saturated + s.Narrow + s.Sharp
+}
+
+/*****************************************************************************************************************/
+
+// add records a single rejection for the given reason.
+func (s *Summary) add(reason Reason) {
+	switch reason {
+	case ReasonEdge:
+		s.Edge++
+	case ReasonMasked:
+		s.Masked++
+	case ReasonSaturated:
+		s.Saturated++
+	case ReasonNarrow:
+		s.Narrow++
+	case ReasonSharp:
+		s.Sharp++
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Check determines whether the star should be rejected, returning the first failed criterion. The pixel data is
+// optional, e.g., for pre-extracted stars, in which case the sharpness is not checked and the saturation is checked
+// against the peak value of the star alone.
+func Check(s photometry.Star, data []float32, width int, height int, params Params) Reason {
+	x, y := float64(s.X), float64(s.Y)
+
+	// Reject stars within the edge margin, whose centroids are biased by the truncated PSF:
+	if params.EdgeMargin > 0 {
+		m := params.EdgeMargin
+
+		if x < m || y < m || x > float64(width-1)-m || y > float64(height-1)-m {
+			return ReasonEdge
+		}
+	}
+
+	// The radius (in pixels) over which the star is inspected:
+	radius := int(math.Ceil(math.Max(float64(s.HFR), 1)))
+
+	cx, cy := int(math.Round(x)), int(math.Round(y))
+
+	if params.Mask != nil && params.Mask.Overlaps(cx, cy, radius) {
+		return ReasonMasked
+	}
+
+	if params.Saturation > 0 {
+		peak := float64(s.Value)
+
+		if len(params.SaturationData) == width*height {
+			peak = maximum(params.SaturationData, width, height, cx, cy, 2*radius)
+		} else if len(data) == width*height {
+			peak = maximum(data, width, height, cx, cy, 2*radius)
+		}
+
+		if peak >= params.Saturation {
+			return ReasonSaturated
+		}
+	}
+
+	// The half-flux radius of a Gaussian PSF is exactly half of its FWHM:
+	if params.MinimumFWHM > 0 && s.HFR > 0 && 2*float64(s.HFR) < params.MinimumFWHM {
+		return ReasonNarrow
+	}
+
+	if params.MaximumSharpness > 0 && len(data) == width*height {
+		if sharpness, ok := Sharpness(data, width, height, cx, cy); ok && sharpness > params.MaximumSharpness {
+			return ReasonSharp
+		}
+	}
+
+	return ReasonNone
+}
+
+/*****************************************************************************************************************/
+
+// Filter removes the rejected stars, preserving the order of the accepted stars. The indices of the accepted stars
+// in the original slice are also returned, e.g., to filter any measurements held alongside the stars.
+func Filter(
+	stars []photometry.Star,
+	data []float32,
+	width int,
+	height int,
+	params Params,
+) ([]photometry.Star, []int, Summary) {
+	accepted := make([]photometry.Star, 0, len(stars))
+
+	indices := make([]int, 0, len(stars))
+
+	summary := Summary{}
+
+	for i, s := range stars {
+		if reason := Check(s, data, width, height, params); reason != ReasonNone {
+			summary.add(reason)
+			continue
+		}
+
+		accepted = append(accepted, s)
+
+		indices = append(indices, i)
+	}
+
+	return accepted, indices, summary
+}
+
+/*****************************************************************************************************************/
+
+// Sharpness measures how sharply peaked the brightest pixel near (x, y) is, as the excess of that pixel over the
+// mean of its eight neighbours, relative to its excess over the local background. A single hot pixel or cosmic ray
+// hit has a sharpness close to one, whereas a well sampled stellar PSF, e.g., with a FWHM of 2 pixels, has a
+// sharpness of around 0.6. The second return value is false when the sharpness cannot be measured.
+func Sharpness(data []float32, width int, height int, x int, y int) (float64, bool) {
+	// Locate the brightest pixel in the immediate neighbourhood of the centroid:
+	px, py, peak := x, y, math.Inf(-1)
+
+	for j := y - 1; j <= y+1; j++ {
+		for i := x - 1; i <= x+1; i++ {
+			if i < 1 || j < 1 || i >= width-1 || j >= height-1 {
+				continue
+			}
+
+			if v := float64(data[j*width+i]); v > peak {
+				px, py, peak = i, j, v
+			}
+		}
+	}
+
+	if math.IsInf(peak, -1) {
+		return 0, false
+	}
+
+	neighbours := 0.0
+
+	for j := py - 1; j <= py+1; j++ {
+		for i := px - 1; i <= px+1; i++ {
+			if i != px || j != py {
+				neighbours += float64(data[j*width+i])
+			}
+		}
+	}
+
+	neighbours /= 8
+
+	// Estimate the local background as the median of a square annulus around the peak:
+	annulus := []float64{}
+
+	for j := py - 4; j <= py+4; j++ {
+		for i := px - 4; i <= px+4; i++ {
+			if i < 0 || j < 0 || i >= width || j >= height {
+				continue
+			}
+
+			if max(abs(i-px), abs(j-py)) != 4 {
+				continue
+			}
+
+			if v := float64(data[j*width+i]); !math.IsNaN(v) {
+				annulus = append(annulus, v)
+			}
+		}
+	}
+
+	if len(annulus) == 0 {
+		return 0, false
+	}
+
+	sort.Float64s(annulus)
+
+	background := annulus[len(annulus)/2]
+
+	if peak <= background {
+		return 0, false
+	}
+
+	return (peak - neighbours) / (peak - background), true
+}
+
+/*****************************************************************************************************************/
+
+// maximum returns the maximum pixel value within the square of the given radius around (x, y).
+func maximum(data []float32, width int, height int, x int, y int, radius int) float64 {
+	peak := math.Inf(-1)
+
+	for j := max(y-radius, 0); j <= min(y+radius, height-1); j++ {
+		for i := max(x-radius, 0); i <= min(x+radius, width-1); i++ {
+			peak = math.Max(peak, float64(data[j*width+i]))
+		}
+	}
+
+	return peak
+}
+
+/*****************************************************************************************************************/
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+/*****************************************************************************************************************/
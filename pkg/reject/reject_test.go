@@ -0,0 +1,204 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package reject
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// newTestImage renders a flat background of 1000 ADU with Gaussian stars of the given FWHM and peak amplitudes.
+func newTestImage(width int, height int, stars [][3]float64, fwhm float64) []float32 {
+	data := make([]float32, width*height)
+
+	sigma := fwhm / (2 * math.Sqrt(2*math.Ln2))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := 1000.0
+
+			for _, s := range stars {
+				dx, dy := float64(x)-s[0], float64(y)-s[1]
+				v += s[2] * math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma))
+			}
+
+			data[y*width+x] = float32(v)
+		}
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+func TestCheck(t *testing.T) {
+	width, height := 128, 128
+
+	data := newTestImage(width, height, [][3]float64{
+		{40, 40, 5000},   // a well sampled star
+		{80, 80, 200000}, // a saturated star
+	}, 3)
+
+	// A single hot pixel, and a cosmic ray hit spanning two pixels:
+	data[60*width+20] = 9000
+	data[100*width+100] = 12000
+	data[100*width+101] = 4000
+
+	// Clip the saturated star at the saturation level of the detector:
+	for i, v := range data {
+		data[i] = float32(math.Min(float64(v), 65535))
+	}
+
+	params := DefaultParams
+	params.Saturation = 65535
+	params.Mask = NewMask(width, height)
+	params.Mask.Set(31, 90)
+
+	tests := []struct {
+		name string
+		star photometry.Star
+		want Reason
+	}{
+		{"star", photometry.Star{X: 40, Y: 40, HFR: 1.5}, ReasonNone},
+		{"saturated", photometry.Star{X: 80, Y: 80, HFR: 4}, ReasonSaturated},
+		{"hot pixel", photometry.Star{X: 20, Y: 60, HFR: 0.4}, ReasonNarrow},
+		{"cosmic ray", photometry.Star{X: 100.2, Y: 100, HFR: 0.8}, ReasonSharp},
+		{"edge", photometry.Star{X: 2, Y: 64, HFR: 1.5}, ReasonEdge},
+		{"masked", photometry.Star{X: 30, Y: 90, HFR: 1.5}, ReasonMasked},
+	}
+
+	for _, tt := range tests {
+		if got := Check(tt.star, data, width, height, params); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSharpness(t *testing.T) {
+	width, height := 64, 64
+
+	for _, tt := range []struct {
+		fwhm     float64
+		min, max float64
+	}{
+		{2, 0.5, 0.7},
+		{4, 0.1, 0.3},
+	} {
+		data := newTestImage(width, height, [][3]float64{{32, 32, 5000}}, tt.fwhm)
+
+		sharpness, ok := Sharpness(data, width, height, 32, 32)
+		if !ok {
+			t.Fatalf("FWHM %v: expected the sharpness to be measured", tt.fwhm)
+		}
+
+		if sharpness < tt.min || sharpness > tt.max {
+			t.Errorf("FWHM %v: expected a sharpness within [%v, %v], got %v", tt.fwhm, tt.min, tt.max, sharpness)
+		}
+	}
+
+	// A single hot pixel should have a sharpness of one:
+	data := newTestImage(width, height, nil, 1)
+	data[32*width+32] = 5000
+
+	if sharpness, _ := Sharpness(data, width, height, 32, 32); math.Abs(sharpness-1) > 1e-9 {
+		t.Errorf("expected a hot pixel to have a sharpness of 1, got %v", sharpness)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFilterWithoutPixelData(t *testing.T) {
+	stars := []photometry.Star{
+		{X: 50, Y: 50, HFR: 2, Value: 40000},
+		{X: 60, Y: 60, HFR: 2, Value: 65535},
+		{X: 70, Y: 70, HFR: 0.3, Value: 20000},
+		{X: 99, Y: 1, HFR: 2, Value: 20000},
+		{X: 30, Y: 30, HFR: 2, Value: 10000},
+	}
+
+	params := DefaultParams
+	params.Saturation = 65000
+
+	accepted, indices, summary := Filter(stars, nil, 100, 100, params)
+
+	if len(accepted) != 2 || indices[0] != 0 || indices[1] != 4 {
+		t.Fatalf("expected stars 0 and 4 to be accepted, got %v", indices)
+	}
+
+	if summary.Saturated != 1 || summary.Narrow != 1 || summary.Edge != 1 || summary.Total() != 3 {
+		t.Errorf("unexpected rejection summary: %+v", summary)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadTextMask(t *testing.T) {
+	mask, err := ReadTextMask(strings.NewReader("# bad pixels\n3 4\ncolumn 7\nrow 2\n"), 10, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, p := range [][2]int{{3, 4}, {7, 0}, {7, 9}, {0, 2}, {9, 2}} {
+		if !mask.IsBad(p[0], p[1]) {
+			t.Errorf("expected pixel %v to be bad", p)
+		}
+	}
+
+	if mask.IsBad(4, 4) {
+		t.Errorf("expected pixel (4, 4) to be good")
+	}
+
+	if _, err := ReadTextMask(strings.NewReader("3\n"), 10, 10); err == nil {
+		t.Errorf("expected an error for an invalid entry")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadFITSMask(t *testing.T) {
+	data := make([]float32, 6*4)
+	data[2*6+5] = 1
+
+	hdu, err := fitsio.NewImageHDU([]int{6, 4}, data, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := fitsio.WriteHDUs(&buf, []*fitsio.HDU{hdu}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mask, err := ReadFITSMask(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mask.Width != 6 || mask.Height != 4 {
+		t.Fatalf("expected a 6x4 mask, got %dx%d", mask.Width, mask.Height)
+	}
+
+	if !mask.IsBad(5, 2) || mask.IsBad(4, 2) {
+		t.Errorf("expected only pixel (5, 2) to be bad")
+	}
+}
+
+/*****************************************************************************************************************/
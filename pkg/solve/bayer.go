@@ -0,0 +1,81 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/skysolve/pkg/bayer"
+	"github.com/observerly/skysolve/pkg/extract"
+	"github.com/observerly/skysolve/pkg/reject"
+)
+
+/*****************************************************************************************************************/
+
+// getDebayeredRejectionParams expresses the rejection criteria, given in original pixels, in the pixels of the
+// debayered plane, and checks the saturation against the raw photosites rather than the interpolated luminance.
+func getDebayeredRejectionParams(params reject.Params, debayered *bayer.Image) reject.Params {
+	params.MinimumFWHM /= debayered.Scale
+	params.EdgeMargin /= debayered.Scale
+	params.SaturationData = debayered.Peak
+
+	if params.Mask != nil {
+		params.Mask = params.Mask.Downsample(int(debayered.Scale))
+	}
+
+	return params
+}
+
+/*****************************************************************************************************************/
+
+// mapDebayeredStars maps the centroids and sizes of the stars, and their detections, from the pixels of the
+// debayered plane back onto the pixels of the original image, in place.
+func mapDebayeredStars(debayered *bayer.Image, stars []photometry.Star, detections []extract.Detection) {
+	scale := debayered.Scale
+
+	for i := range stars {
+		x, y := debayered.ToOriginal(float64(stars[i].X), float64(stars[i].Y))
+
+		stars[i].X, stars[i].Y = float32(x), float32(y)
+		stars[i].HFR *= float32(scale)
+	}
+
+	for i := range detections {
+		d := &detections[i]
+
+		d.X, d.Y = debayered.ToOriginal(d.X, d.Y)
+		d.FWHM *= scale
+
+		for j := range d.Covariance {
+			for k := range d.Covariance[j] {
+				d.Covariance[j][k] *= scale * scale
+			}
+		}
+
+		if d.PSF != nil {
+			psf := *d.PSF
+
+			psf.X, psf.Y = debayered.ToOriginal(psf.X, psf.Y)
+			psf.FWHM *= scale
+			psf.FWHMMajor *= scale
+			psf.FWHMMinor *= scale
+
+			for j := range psf.Covariance {
+				for k := range psf.Covariance[j] {
+					psf.Covariance[j][k] *= scale * scale
+				}
+			}
+
+			d.PSF = &psf
+		}
+	}
+}
+
+/*****************************************************************************************************************/
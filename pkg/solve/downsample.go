@@ -0,0 +1,156 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/photometry"
+
+	"github.com/observerly/skysolve/pkg/bayer"
+	"github.com/observerly/skysolve/pkg/extract"
+)
+
+/*****************************************************************************************************************/
+
+// The maximum number of pixels stars are extracted from when the downsample factor is selected automatically, e.g.,
+// a 60 megapixel frame is binned 4×4, which still resolves the star positions well enough to match quads:
+const DOWNSAMPLE_MAXIMUM_PIXELS = 2048 * 2048
+
+/*****************************************************************************************************************/
+
+// The downsample factor which selects the binning factor from the size of the image, as GetDownsampleFactor:
+const DOWNSAMPLE_AUTO = -1
+
+/*****************************************************************************************************************/
+
+// GetDownsampleFactor returns the smallest power of two binning factor which reduces the image to at most
+// DOWNSAMPLE_MAXIMUM_PIXELS pixels, where images that are already small enough are not downsampled.
+func GetDownsampleFactor(width int, height int) int {
+	factor := 1
+
+	for (width/factor)*(height/factor) > DOWNSAMPLE_MAXIMUM_PIXELS {
+		factor *= 2
+	}
+
+	return factor
+}
+
+/*****************************************************************************************************************/
+
+// getDownsampleFactor resolves the downsample factor of the params, where DOWNSAMPLE_AUTO selects the factor from
+// the size of the plane the stars are extracted from, and 0 (the zero value) or 1 disables downsampling. A factor
+// larger than either dimension of the plane, which would bin it to an empty image, is an error.
+func getDownsampleFactor(downsample int, width int, height int) (int, error) {
+	switch {
+	case downsample == DOWNSAMPLE_AUTO:
+		return GetDownsampleFactor(width, height), nil
+	case downsample < 0:
+		return 0, fmt.Errorf("invalid downsample factor: %d", downsample)
+	case downsample == 0:
+		return 1, nil
+	case downsample > width || downsample > height:
+		return 0, fmt.Errorf("the downsample factor %d exceeds the %d × %d image", downsample, width, height)
+	default:
+		return downsample, nil
+	}
+}
+
+/*****************************************************************************************************************/
+
+// downsample bins the plane by the given factor, averaging the finite pixels of each bin and retaining the maximum
+// peak, such that saturated stars are still rejected. Partial bins at the right and bottom edges are discarded.
+func downsample(plane *bayer.Image, factor int) *bayer.Image {
+	width, height := plane.Width/factor, plane.Height/factor
+
+	binned := &bayer.Image{
+		Data:   make([]float32, width*height),
+		Peak:   make([]float32, width*height),
+		Width:  width,
+		Height: height,
+		Scale:  plane.Scale * float64(factor),
+		// The centre of the binned pixel (0, 0) is the centre of the first bin of the plane:
+		Offset: plane.Offset + plane.Scale*float64(factor-1)/2,
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sum, n := 0.0, 0
+
+			peak := float32(math.Inf(-1))
+
+			for j := y * factor; j < (y+1)*factor; j++ {
+				for i := x * factor; i < (x+1)*factor; i++ {
+					v := plane.Data[j*plane.Width+i]
+
+					if math.IsNaN(float64(v)) {
+						continue
+					}
+
+					sum += float64(v)
+					n++
+
+					peak = max(peak, plane.Peak[j*plane.Width+i])
+				}
+			}
+
+			if n == 0 {
+				binned.Data[y*width+x] = float32(math.NaN())
+				binned.Peak[y*width+x] = float32(math.NaN())
+				continue
+			}
+
+			binned.Data[y*width+x] = float32(sum / float64(n))
+			binned.Peak[y*width+x] = peak
+		}
+	}
+
+	return binned
+}
+
+/*****************************************************************************************************************/
+
+// refineDownsampledStars maps the stars, and their detections, extracted from the binned plane back onto the pixels
+// of the original image, in place, refining each centroid with the windowed centroid of the full resolution plane.
+func refineDownsampledStars(plane *bayer.Image, binned *bayer.Image, stars []photometry.Star, detections []extract.Detection) {
+	mapDebayeredStars(binned, stars, detections)
+
+	measured := len(detections) == len(stars)
+
+	for i := range stars {
+		// The FWHM of the star in the pixels of the full resolution plane:
+		fwhm := 2 * float64(stars[i].HFR) / plane.Scale
+
+		if measured {
+			fwhm = detections[i].FWHM / plane.Scale
+		}
+
+		// The approximate centroid in the pixels of the full resolution plane:
+		x := (float64(stars[i].X) - plane.Offset) / plane.Scale
+		y := (float64(stars[i].Y) - plane.Offset) / plane.Scale
+
+		x, y, ok := extract.RefineCentroid(plane.Data, plane.Width, plane.Height, x, y, fwhm)
+		if !ok {
+			continue
+		}
+
+		x, y = plane.ToOriginal(x, y)
+
+		stars[i].X, stars[i].Y = float32(x), float32(y)
+
+		if measured {
+			detections[i].X, detections[i].Y = x, y
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,77 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+
+	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// The minimum number of point correspondences per SIP coefficient before SIP distortions are fitted, such that the
+// polynomials are well constrained rather than fitting the centroid noise:
+const SIP_MINIMUM_PAIRS_PER_TERM = 3
+
+/*****************************************************************************************************************/
+
+// Refine refits a WCS solution against every extracted star with a projected catalog source within the pixel
+// tolerance, using weighted least squares where the stars carry positional uncertainties. When there are sufficient
+// correspondences, SIP distortion polynomials of the given order are fitted alongside the linear solution. When the
+// plate solver has a spherical projection, e.g., SIN, ARC, ZEA, STG or CAR, the solution is fitted in that projection.
+func (ps *PlateSolver) Refine(
+	w *wcs.WCS,
+	sources []catalog.Source,
+	tolerance float64,
+	sipOrder int,
+) (*wcs.WCS, []wcs.PointPair, error) {
+	if w == nil {
+		return nil, nil, fmt.Errorf("no WCS solution to refine")
+	}
+
+	stars := ps.GetStars()
+
+	pairs := MatchNearestNeighbours(stars, ProjectSources(w, sources), tolerance)
+
+	params, xr, yr, err := wcs.ComputeAffineTransformationFromPointPairs(pairs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	linear := ps.NewWorldCoordinateSystemFromAffine(params, xr, yr)
+
+	// The spherical projections, e.g., ZEA for all-sky cameras, are fitted on the sphere, without SIP distortions:
+	if ps.Projection.IsSpherical() {
+		refined, err := wcs.NewWorldCoordinateSystemFromPointPairs(ps.Projection, float64(ps.Width)/2, float64(ps.Height)/2, pairs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &refined, pairs, nil
+	}
+
+	// Only fit the SIP distortions when they are well constrained by the correspondences:
+	if sipOrder < 2 || len(pairs) < SIP_MINIMUM_PAIRS_PER_TERM*wcs.GetSIPTermCount(sipOrder) {
+		return linear, pairs, nil
+	}
+
+	// Jointly fit the linear solution and the SIP distortions, referenced to the center of the image:
+	refined, err := wcs.NewSIPWorldCoordinateSystemFromPointPairs(float64(ps.Width)/2, float64(ps.Height)/2, pairs, sipOrder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &refined, pairs, nil
+}
+
+/*****************************************************************************************************************/
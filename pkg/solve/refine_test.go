@@ -0,0 +1,161 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/transform"
+	"github.com/observerly/skysolve/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// newDistortedPlateSolver creates a PlateSolver whose extracted stars are scattered across the image, and whose
+// catalog sources are the equatorial coordinates of those stars through the (distorted) known WCS.
+func newDistortedPlateSolver(truth wcs.WCS, n int) *PlateSolver {
+	r := rand.New(rand.NewSource(7))
+
+	stars := make([]photometry.Star, 0, n)
+
+	sources := make([]catalog.Source, 0, n)
+
+	for i := 0; i < n; i++ {
+		x, y := r.Float64()*1200, r.Float64()*1200
+
+		eq := truth.PixelToEquatorialCoordinate(x, y)
+
+		stars = append(stars, photometry.Star{
+			X:         float32(x),
+			Y:         float32(y),
+			Intensity: float32(n - i),
+		})
+
+		sources = append(sources, catalog.Source{
+			Designation:          fmt.Sprintf("Synthetic %d", i),
+			RA:                   eq.RA,
+			Dec:                  eq.Dec,
+			PhotometricGMeanFlux: float64(n - i),
+		})
+	}
+
+	return &PlateSolver{
+		Stars:   stars,
+		Sources: sources,
+		RA:      truth.CRVAL1,
+		Dec:     truth.CRVAL2,
+		HasHint: true,
+		Width:   1200,
+		Height:  1200,
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRefineFitsSIPDistortion(t *testing.T) {
+	linear := newSyntheticWCS()
+
+	truth := linear
+	truth.FSIP = transform.SIP2DForwardParameters{
+		AOrder: 2,
+		APower: map[string]float64{"A_2_0": 2e-6, "A_0_2": -1e-6},
+		BOrder: 2,
+		BPower: map[string]float64{"B_1_1": 1.5e-6},
+	}
+
+	ps := newDistortedPlateSolver(truth, 80)
+
+	refined, pairs, err := ps.Refine(&linear, ps.Sources, 5, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pairs) != len(ps.Stars) {
+		t.Errorf("expected all %d stars to be paired, got %d", len(ps.Stars), len(pairs))
+	}
+
+	if refined.CTYPE1 != "RA---TAN-SIP" || refined.CTYPE2 != "DEC--TAN-SIP" {
+		t.Errorf("expected a TAN-SIP projection, got %s and %s", refined.CTYPE1, refined.CTYPE2)
+	}
+
+	// The refined solution should reproduce the distorted truth to well within a hundredth of a pixel:
+	for _, p := range [][2]float64{{0, 0}, {600, 600}, {1200, 1200}, {200, 900}, {1100, 100}} {
+		got := refined.PixelToEquatorialCoordinate(p[0], p[1])
+		want := truth.PixelToEquatorialCoordinate(p[0], p[1])
+
+		if math.Abs(got.RA-want.RA) > 5e-6 || math.Abs(got.Dec-want.Dec) > 5e-6 {
+			t.Errorf("pixel %v: got (%v, %v), want (%v, %v)", p, got.RA, got.Dec, want.RA, want.Dec)
+		}
+	}
+
+	if rms := ComputeResidualRMS(refined, pairs); rms > 0.01 {
+		t.Errorf("expected a residual RMS below 0.01 pixels, got %v", rms)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRefineWithoutSufficientPairsIsLinear(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	ps := newDistortedPlateSolver(truth, 12)
+
+	refined, _, err := ps.Refine(&truth, ps.Sources, 5, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if refined.CTYPE1 != "RA---TAN" || len(refined.FSIP.APower) != 0 {
+		t.Errorf("expected a linear solution when the SIP distortions are poorly constrained")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRefineFitsTheProjectionOfThePlateSolver(t *testing.T) {
+	// A 60 degree all-sky field, where the zenithal equal area projection departs from the linear model by degrees:
+	truth := wcs.NewWorldCoordinateSystem(600, 600, wcs.WCSParams{
+		Projection:   wcs.RADEC_ZEA,
+		AffineParams: transform.Affine2DParameters{A: -0.05, B: 0, C: 120, D: 0, E: 0.05, F: 35},
+	})
+
+	ps := newDistortedPlateSolver(truth, 80)
+
+	ps.Projection = wcs.RADEC_ZEA
+
+	// The initial solution is offset by a few pixels from the truth:
+	initial := truth
+	initial.CRVAL2 += 0.1
+
+	refined, pairs, err := ps.Refine(&initial, ps.Sources, 5, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if refined.CTYPE1 != "RA---ZEA" || refined.CTYPE2 != "DEC--ZEA" {
+		t.Errorf("expected a ZEA projection, got %s and %s", refined.CTYPE1, refined.CTYPE2)
+	}
+
+	if len(pairs) != len(ps.Stars) {
+		t.Errorf("expected all %d stars to be paired, got %d", len(ps.Stars), len(pairs))
+	}
+
+	if rms := ComputeResidualRMS(refined, pairs); rms > 1e-4 {
+		t.Errorf("expected a residual RMS below 1e-4 pixels, got %v", rms)
+	}
+}
+
+/*****************************************************************************************************************/
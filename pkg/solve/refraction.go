@@ -0,0 +1,108 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// RefractSources returns copies of the catalog sources displaced to their observed places, i.e., as refracted by the
+// atmosphere, such that a solution fitted against them absorbs the (differential) refraction across the field into
+// its reference point, rather than into its distortion polynomials.
+func RefractSources(sources []catalog.Source, refractor *astrometry.Refractor) []catalog.Source {
+	refracted := make([]catalog.Source, len(sources))
+
+	for i, source := range sources {
+		observed := refractor.ToObserved(astrometry.ICRSEquatorialCoordinate{RA: source.RA, Dec: source.Dec})
+
+		refracted[i] = source
+		refracted[i].RA = observed.RA
+		refracted[i].Dec = observed.Dec
+	}
+
+	return refracted
+}
+
+/*****************************************************************************************************************/
+
+// The number of pixels along each axis of the grid across which a WCS fitted against the observed places is refitted
+// against their astrometric places, and the minimum order of the SIP polynomials which absorb the differential
+// refraction across the field:
+const (
+	REFRACTION_GRID_SAMPLES  = 11
+	REFRACTION_MINIMUM_ORDER = 3
+)
+
+/*****************************************************************************************************************/
+
+// RemoveRefraction refits a WCS fitted against the observed places of the catalog sources to the astrometric (ICRS)
+// places of a grid of pixels across an image of the given dimensions, such that both its reference point and the
+// differential refraction across the field are removed. A TAN or TAN-SIP solution is refitted as TAN-SIP, and a TPV
+// solution as TPV, with polynomials of at least third order, which absorb the differential refraction, whereas the
+// other spherical projections are refitted with their linear model.
+func RemoveRefraction(w *wcs.WCS, refractor *astrometry.Refractor, width, height int) error {
+	if w == nil {
+		return nil
+	}
+
+	pairs := make([]wcs.PointPair, 0, REFRACTION_GRID_SAMPLES*REFRACTION_GRID_SAMPLES)
+
+	// The grid spans the full image, including its corners:
+	for j := 0; j < REFRACTION_GRID_SAMPLES; j++ {
+		for i := 0; i < REFRACTION_GRID_SAMPLES; i++ {
+			x := float64(i) * float64(width-1) / float64(REFRACTION_GRID_SAMPLES-1)
+			y := float64(j) * float64(height-1) / float64(REFRACTION_GRID_SAMPLES-1)
+
+			astrometric := refractor.ToAstrometric(w.PixelToEquatorialCoordinate(x, y))
+
+			pairs = append(pairs, wcs.PointPair{X: x, Y: y, RA: astrometric.RA, Dec: astrometric.Dec})
+		}
+	}
+
+	order := max(w.FSIP.AOrder, w.FSIP.BOrder, REFRACTION_MINIMUM_ORDER)
+
+	projection, err := w.GetProjection()
+	if err != nil {
+		return err
+	}
+
+	var refitted wcs.WCS
+
+	switch projection {
+	case wcs.RADEC_TAN, wcs.RADEC_TANSIP, wcs.RADEC_TPV:
+		refitted, err = wcs.NewSIPWorldCoordinateSystemFromPointPairs(w.CRPIX1, w.CRPIX2, pairs, order)
+		if err == nil && projection == wcs.RADEC_TPV {
+			refitted, _, err = refitted.ToTPV(width, height, order)
+		}
+	default:
+		refitted, err = wcs.NewWorldCoordinateSystemFromPointPairs(projection, w.CRPIX1, w.CRPIX2, pairs)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to refit the solution to the astrometric places: %w", err)
+	}
+
+	// Retain the reference frame and the observation date of the solution:
+	refitted.RADESYS = w.RADESYS
+	refitted.EQUINOX = w.EQUINOX
+	refitted.DATEOBS = w.DATEOBS
+	refitted.MJDOBS = w.MJDOBS
+
+	*w = refitted
+
+	return nil
+}
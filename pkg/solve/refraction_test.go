@@ -0,0 +1,109 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+)
+
+/*****************************************************************************************************************/
+
+func TestSolveAgainstRefractedSources(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	sources := newSyntheticPlateSolver(t, truth, 16, 60).Sources
+
+	datetime := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// An observer from whom the centre of the field is at an hour angle of 75°, i.e., at an altitude of ~10°:
+	longitude := math.Mod(truth.CRVAL1+75-astrometry.GetLocalApparentSiderealTime(datetime, 0)+540, 360) - 180
+
+	refractor, err := astrometry.NewRefractor(astrometry.RefractionParams{
+		Observer:    astrometry.Observer{Latitude: 52, Longitude: longitude},
+		Datetime:    datetime,
+		Temperature: astrometry.STANDARD_TEMPERATURE,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The stars are imaged at their observed places, through the purely optical truth WCS:
+	observed := RefractSources(sources, refractor)
+
+	ps := newSyntheticFrame(truth, observed, 16)
+
+	tolerance := ToleranceParams{
+		QuadTolerance:           0.02,
+		EuclidianPixelTolerance: 5,
+	}
+
+	solution, err := ps.SolveWithStrategies([]Strategy{&TriangleStrategy{}}, tolerance, 3, DefaultVerificationParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if solution.Verification.RMS > 0.01 {
+		t.Errorf("expected the observed places to be fitted exactly, got an RMS of %v pixels", solution.Verification.RMS)
+	}
+
+	// The refraction raises the centre of the field by several arcminutes:
+	centre := truth.PixelToEquatorialCoordinate(truth.CRPIX1, truth.CRPIX2)
+
+	astrometric := refractor.ToAstrometric(centre)
+
+	if shift := math.Hypot((centre.RA-astrometric.RA)*math.Cos(centre.Dec*math.Pi/180), centre.Dec-astrometric.Dec) * 60; shift < 4 {
+		t.Errorf("expected a refraction of more than 4' at an altitude of %v°, got %v'", refractor.GetAltitude(astrometric), shift)
+	}
+
+	// Before refitting, the observed places of the corners differ from their astrometric places by the refraction:
+	corner := solution.WCS.PixelToEquatorialCoordinate(0, 0)
+
+	if want := refractor.ToAstrometric(truth.PixelToEquatorialCoordinate(0, 0)); math.Abs(corner.Dec-want.Dec) < 1.0/60 {
+		t.Errorf("expected the observed place of the corner to differ from its astrometric place by more than 1'")
+	}
+
+	if err := RemoveRefraction(solution.WCS, refractor, ps.Width, ps.Height); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if solution.WCS.CTYPE1 != "RA---TAN-SIP" || solution.WCS.FSIP.AOrder < REFRACTION_MINIMUM_ORDER {
+		t.Errorf("expected the differential refraction to be absorbed by TAN-SIP polynomials, got %s of order %d", solution.WCS.CTYPE1, solution.WCS.FSIP.AOrder)
+	}
+
+	// The solution reports the astrometric places across the field, including the differential refraction across
+	// the 0.6° field, and its corners, to within a few milliarcseconds:
+	for _, p := range [][2]float64{{truth.CRPIX1, truth.CRPIX2}, {0, 0}, {1200, 1200}, {float64(ps.Width - 1), 0}, {300, 900}} {
+		got := solution.WCS.PixelToEquatorialCoordinate(p[0], p[1])
+		want := refractor.ToAstrometric(truth.PixelToEquatorialCoordinate(p[0], p[1]))
+
+		limit := 0.01 / 3600
+
+		if math.Abs(got.RA-want.RA)*math.Cos(want.Dec*math.Pi/180) > limit || math.Abs(got.Dec-want.Dec) > limit {
+			t.Errorf("pixel %v: got (%v, %v), want (%v, %v)", p, got.RA, got.Dec, want.RA, want.Dec)
+		}
+
+		// The pixel of the astrometric place is recovered by the inverse of the refitted solution:
+		if x, y := solution.WCS.EquatorialCoordinateToPixel(want.RA, want.Dec); math.Hypot(x-p[0], y-p[1]) > 0.01 {
+			t.Errorf("pixel %v: expected the inverse to recover the pixel, got (%v, %v)", p, x, y)
+		}
+	}
+
+	// The catalog sources themselves are left untouched:
+	if sources[0].RA == observed[0].RA && sources[0].Dec == observed[0].Dec {
+		t.Errorf("expected the observed place of the source to differ from its astrometric place")
+	}
+}
+
+/*****************************************************************************************************************/
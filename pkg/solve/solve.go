@@ -22,11 +22,15 @@ import (
 	stats "github.com/observerly/iris/pkg/statistics"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/observerly/skysolve/pkg/bayer"
 	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/extract"
 	"github.com/observerly/skysolve/pkg/geometry"
 	"github.com/observerly/skysolve/pkg/quad"
+	"github.com/observerly/skysolve/pkg/reject"
 	"github.com/observerly/skysolve/pkg/spatial"
 	"github.com/observerly/skysolve/pkg/star"
+	"github.com/observerly/skysolve/pkg/transform"
 	"github.com/observerly/skysolve/pkg/wcs"
 )
 
@@ -34,20 +38,33 @@ import (
 
 type PlateSolver struct {
 	Stars       []photometry.Star
+	Detections  []extract.Detection
+	Rejections  reject.Summary
 	Sources     []catalog.Source
 	Data        []float32
 	RA          float64
 	Dec         float64
+	HasHint     bool // whether RA and Dec are a pointing hint, where (0, 0) is a valid hint
 	Width       int
 	Height      int
 	PixelScaleX float64
 	PixelScaleY float64
+	Projection  wcs.CoordinateProjectionType
 }
 
 /*****************************************************************************************************************/
 
 type Params struct {
 	Data                []float32
+	Stars               []photometry.Star            // pre-extracted stars, e.g., from an xylist, which skip extraction entirely
+	Extraction          *extract.Params              // the native extraction parameters, used in place of the iris extractor
+	Rejection           *reject.Params               // the criteria for rejecting spurious stars before quad generation
+	Bayer               *bayer.Params                // the debayering of a one-shot-colour image, before star extraction
+	Downsample          int                          // the binning factor before star extraction (DOWNSAMPLE_AUTO selects it automatically, 0 or 1 disables it)
+	Projection          wcs.CoordinateProjectionType // the projection the refined solution is fitted with, e.g., ZEA for all-sky cameras
+	RA                  float64
+	Dec                 float64
+	HasHint             bool // whether RA and Dec are a pointing hint, rather than unset
 	Width               int
 	Height              int
 	PixelScaleX         float64
@@ -74,6 +91,7 @@ func NewPlateSolver(
 	radius := params.Radius
 	sigma := params.Sigma
 	stars := []photometry.Star{}
+	detections := []extract.Detection{}
 	sources := []catalog.Source{}
 
 	// Calculate the width of the image in pixels:
@@ -82,41 +100,156 @@ func NewPlateSolver(
 	// Calculate the height of the image in pixels:
 	ys := params.Height
 
+	// Without pixel data, the image dimensions cannot be inferred and must be provided:
+	if len(params.Data) == 0 && len(params.Stars) == 0 {
+		return nil, errors.New("either the image data or a list of pre-extracted stars is required")
+	}
+
+	if xs <= 0 || ys <= 0 {
+		return nil, fmt.Errorf("invalid image dimensions: %dx%d", xs, ys)
+	}
+
 	// Setup a wait group for the stars extractor:
 	var wg sync.WaitGroup
 	wg.Add(1)
 
+	var extractionErr error
+
+	rejections := reject.Summary{}
+
 	// Extract bright pixels (stars) from the image:
 	go func() {
 		defer wg.Done()
 
-		// Extract the image from the FITS file:
-		sexp := photometry.NewStarsExtractor(params.Data, xs, ys, float32(radius), params.ADU)
+		starsExtracted := make([]photometry.Star, len(params.Stars))
 
-		// Extract the bright pixels from the image:
-		starsExtracted := sexp.FindStars(stats.NewStats(params.Data, params.ADU, xs), float32(sigma), 2.2)
+		// The pixel data the stars are extracted from, which differs from the image for one-shot-colour images:
+		data, width, height := params.Data, xs, ys
+
+		var debayered *bayer.Image
+
+		// Extract the stars from a monochrome plane of a one-shot-colour image, rather than the raw Bayer mosaic:
+		if len(params.Stars) == 0 && params.Bayer != nil && params.Bayer.Mode != bayer.ModeNone {
+			debayered, extractionErr = bayer.Debayer(params.Data, xs, ys, *params.Bayer)
+			if extractionErr != nil {
+				return
+			}
+
+			data, width, height = debayered.Data, debayered.Width, debayered.Height
+		}
 
-		// Sort the stars by intensity, in descending order:
-		sort.Slice(starsExtracted, func(i, j int) bool {
+		// The full resolution plane, and its binned counterpart the stars are extracted from, when downsampling:
+		var plane, binned *bayer.Image
+
+		if len(params.Stars) == 0 {
+			var factor int
+
+			factor, extractionErr = getDownsampleFactor(params.Downsample, width, height)
+			if extractionErr != nil {
+				return
+			}
+
+			if factor > 1 {
+				plane = debayered
+
+				if plane == nil {
+					plane = &bayer.Image{Data: data, Peak: data, Width: width, Height: height, Scale: 1}
+				}
+
+				binned = downsample(plane, factor)
+
+				data, width, height = binned.Data, binned.Width, binned.Height
+			}
+		}
+
+		// If the stars have been pre-extracted, skip the extraction entirely:
+		if len(params.Stars) > 0 {
+			copy(starsExtracted, params.Stars)
+		} else if params.Extraction != nil {
+			// Extract the stars with the native background estimation and source extraction:
+			detections, _, extractionErr = extract.Extract(data, width, height, *params.Extraction)
+
+			starsExtracted = extract.Stars(detections)
+		} else {
+			// Extract the image from the FITS file:
+			sexp := photometry.NewStarsExtractor(data, width, height, float32(radius), params.ADU)
+
+			// Extract the bright pixels from the image:
+			starsExtracted = sexp.FindStars(stats.NewStats(data, params.ADU, width), float32(sigma), 2.2)
+		}
+
+		// Sort the stars by intensity, in descending order (the native detections are already sorted by flux, so a
+		// stable sort keeps them aligned with their stars):
+		sort.SliceStable(starsExtracted, func(i, j int) bool {
 			return starsExtracted[i].Intensity > starsExtracted[j].Intensity
 		})
 
+		// Reject hot pixels, cosmic rays, saturated and edge stars before selecting the brightest stars:
+		if params.Rejection != nil {
+			var accepted []int
+
+			rejection := *params.Rejection
+
+			// Express the rejection criteria in the pixels of the (binned) plane the stars were extracted from:
+			if binned != nil {
+				rejection = getDebayeredRejectionParams(rejection, binned)
+			} else if debayered != nil {
+				rejection = getDebayeredRejectionParams(rejection, debayered)
+			}
+
+			starsExtracted, accepted, rejections = reject.Filter(starsExtracted, data, width, height, rejection)
+
+			if len(detections) > 0 {
+				filtered := make([]extract.Detection, len(accepted))
+
+				for i, j := range accepted {
+					filtered[i] = detections[j]
+				}
+
+				detections = filtered
+			}
+		}
+
 		// Get a minimum of X stars from our list of stars, e.g., the brightest X stars:
-		k := math.Min(float64(len(starsExtracted)), params.ExtractionThreshold)
+		k := int(math.Min(float64(len(starsExtracted)), params.ExtractionThreshold))
+
+		stars = starsExtracted[:k]
 
-		stars = starsExtracted[:int(k)]
+		if len(detections) > k {
+			detections = detections[:k]
+		}
+
+		// Map the centroids of the binned or debayered plane back onto the pixels of the original image, such that the
+		// final fit, and the WCS, are in the original pixel coordinates:
+		if binned != nil {
+			refineDownsampledStars(plane, binned, stars, detections)
+		} else if debayered != nil {
+			mapDebayeredStars(debayered, stars, detections)
+		}
 	}()
 
 	// Wait for the stars extractor to finish
 	wg.Wait()
 
+	if extractionErr != nil {
+		return nil, extractionErr
+	}
+
 	// Return a new PlateSolver object with the catalog, stars, sources, RA, Dec, and pixel scale:
 	return &PlateSolver{
-		Stars:   stars,
-		Sources: sources,
-		Data:    params.Data,
-		Width:   xs,
-		Height:  ys,
+		Stars:       stars,
+		Detections:  detections,
+		Rejections:  rejections,
+		Sources:     sources,
+		Data:        params.Data,
+		RA:          params.RA,
+		Dec:         params.Dec,
+		HasHint:     params.HasHint,
+		Width:       xs,
+		Height:      ys,
+		PixelScaleX: params.PixelScaleX,
+		PixelScaleY: params.PixelScaleY,
+		Projection:  params.Projection,
 	}, nil
 }
 
@@ -312,7 +445,6 @@ func (ps *PlateSolver) ValidateAndConfirmMatches(candidateMatches []spatial.Quad
 			// Ensure the affine transformation is invertible by checking the determinant of the matrix:
 			determinant := params.A*params.E - params.B*params.D
 			if determinant == 0 {
-				fmt.Println("affine transformation is not invertible for match:", hash)
 				return nil // Skip this match as it cannot be inverted for WCS
 			}
 
@@ -386,10 +518,116 @@ func (ps *PlateSolver) ValidateAndConfirmMatches(candidateMatches []spatial.Quad
 
 /*****************************************************************************************************************/
 
-func (ps *PlateSolver) Solve(tolerance ToleranceParams, sipOrder int) (*wcs.WCS, []spatial.QuadMatch, error) {
+// GetStars converts the extracted stars of the PlateSolver into pixel-space stars ready for asterism generation.
+func (ps *PlateSolver) GetStars() []star.Star {
 	stars := make([]star.Star, len(ps.Stars))
 
-	sources := make([]star.Star, len(ps.Sources))
+	// The positional uncertainties are only known when the stars were measured by the native extractor:
+	measured := len(ps.Detections) == len(ps.Stars)
+
+	for i, s := range ps.Stars {
+		stars[i] = star.Star{
+			Designation: "Unknown",
+			X:           float64(s.X),
+			Y:           float64(s.Y),
+			RA:          math.Inf(1),
+			Dec:         math.Inf(1),
+			Intensity:   float64(s.Intensity),
+		}
+
+		if measured {
+			stars[i].Uncertainty = ps.Detections[i].Uncertainty()
+		}
+	}
+
+	return stars
+}
+
+/*****************************************************************************************************************/
+
+// GetSourceStars converts catalog sources into equatorial-space stars ready for asterism generation.
+func GetSourceStars(sources []catalog.Source) []star.Star {
+	stars := make([]star.Star, len(sources))
+
+	for i, source := range sources {
+		stars[i] = star.Star{
+			Designation: source.Designation,
+			X:           source.RA,
+			Y:           source.Dec,
+			RA:          source.RA,
+			Dec:         source.Dec,
+			Intensity:   source.PhotometricGMeanFlux,
+		}
+	}
+
+	return stars
+}
+
+/*****************************************************************************************************************/
+
+// NewWorldCoordinateSystemFromAffine creates a WCS from the fitted affine transformation, referenced to the
+// center of the image.
+func (ps *PlateSolver) NewWorldCoordinateSystemFromAffine(params transform.Affine2DParameters, xr, yr float64) *wcs.WCS {
+	// Calculate the x-coordinate of the center of the image:
+	xc := float64(ps.Width) / 2.0
+
+	// Calculate the y-coordinate of the center of the image:
+	yc := float64(ps.Height) / 2.0
+
+	// Create a new WCS object with the affine transformation matrix:
+	w := wcs.NewWorldCoordinateSystem(
+		xr,
+		yr,
+		wcs.WCSParams{
+			Projection:   wcs.RADEC_TAN,
+			AffineParams: params,
+			ReferenceX:   xc,
+			ReferenceY:   yc,
+		},
+	)
+
+	return &w
+}
+
+/*****************************************************************************************************************/
+
+// SolveForQuads matches the image quads against the catalog source quads, confirms the candidate matches and
+// computes the final WCS from the confirmed matches.
+func (ps *PlateSolver) SolveForQuads(quads []quad.Quad, sourceQuads []quad.Quad, tolerance ToleranceParams) (*wcs.WCS, []spatial.QuadMatch, error) {
+	// Create a new matcher with the generated quads:
+	matcher, err := spatial.NewQuadMatcher(quads)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Match the generated quads with the source quads for a given tolerance:
+	candidateMatches, err := matcher.MatchQuads(sourceQuads, tolerance.QuadTolerance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Now we have our candidate matches, we need to further verify them by comparing the stars within the quads.
+	// Validate and confirm matches by applying affine transformations and checking for alignment within the specified tolerance:
+	matches, err := ps.ValidateAndConfirmMatches(candidateMatches, tolerance.EuclidianPixelTolerance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Compute the final affine transformation matrix:
+	params, xr, yr, err := wcs.ComputeAffineTransformation(matches)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ps.NewWorldCoordinateSystemFromAffine(params, xr, yr), matches, nil
+}
+
+/*****************************************************************************************************************/
+
+func (ps *PlateSolver) Solve(tolerance ToleranceParams, sipOrder int) (*wcs.WCS, []spatial.QuadMatch, error) {
+	stars := []star.Star{}
+
+	sources := []star.Star{}
 
 	wg := sync.WaitGroup{}
 
@@ -397,32 +635,12 @@ func (ps *PlateSolver) Solve(tolerance ToleranceParams, sipOrder int) (*wcs.WCS,
 
 	go func() {
 		defer wg.Done()
-
-		for i, s := range ps.Stars {
-			stars[i] = star.Star{
-				Designation: "Unknown",
-				X:           float64(s.X),
-				Y:           float64(s.Y),
-				RA:          math.Inf(1),
-				Dec:         math.Inf(1),
-				Intensity:   float64(s.Intensity),
-			}
-		}
+		stars = ps.GetStars()
 	}()
 
 	go func() {
 		defer wg.Done()
-
-		for _, source := range ps.Sources {
-			sources = append(sources, star.Star{
-				Designation: source.Designation,
-				X:           source.RA,
-				Y:           source.Dec,
-				RA:          source.RA,
-				Dec:         source.Dec,
-				Intensity:   source.PhotometricGMeanFlux,
-			})
-		}
+		sources = GetSourceStars(ps.Sources)
 	}()
 
 	quads := []quad.Quad{}
@@ -461,50 +679,7 @@ func (ps *PlateSolver) Solve(tolerance ToleranceParams, sipOrder int) (*wcs.WCS,
 
 	wg.Wait()
 
-	// Create a new matcher with the generated quads:
-	matcher, err := spatial.NewQuadMatcher(quads)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Match the generated quads with the source quads for a given tolerance:
-	candidateMatches, err := matcher.MatchQuads(sourceQuads, tolerance.QuadTolerance)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Now we have our candidate matches, we need to further verify them by comparing the stars within the quads.
-	// Validate and confirm matches by applying affine transformations and checking for alignment within the specified tolerance:
-	matches, err := ps.ValidateAndConfirmMatches(candidateMatches, tolerance.EuclidianPixelTolerance)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Compute the final affine transformation matrix:
-	params, xr, yr, err := wcs.ComputeAffineTransformation(matches)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Calculate the x-coordinate of the center of the image:
-	xc := float64(ps.Width) / 2.0
-
-	// Calculate the y-coordinate of the center of the image:
-	yc := float64(ps.Height) / 2.0
-
-	// Create a new WCS object with the affine transformation matrix:
-	w := wcs.NewWorldCoordinateSystem(
-		xr,
-		yr,
-		wcs.WCSParams{
-			Projection:   wcs.RADEC_TAN,
-			AffineParams: params,
-			ReferenceX:   xc,
-			ReferenceY:   yc,
-		},
-	)
-
-	return &w, matches, nil
+	return ps.SolveForQuads(quads, sourceQuads, tolerance)
 }
 
 /*****************************************************************************************************************/
@@ -0,0 +1,231 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/skysolve/pkg/extract"
+	"github.com/observerly/skysolve/pkg/reject"
+)
+
+/*****************************************************************************************************************/
+
+func TestNewPlateSolverFromPreExtractedStars(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	frame := newSyntheticPlateSolver(t, truth, 16, 60)
+
+	// Solve from the pre-extracted stars alone, without any pixel data:
+	ps, err := NewPlateSolver(Params{
+		Stars:               frame.Stars,
+		RA:                  truth.CRVAL1,
+		Dec:                 truth.CRVAL2,
+		HasHint:             true,
+		Width:               frame.Width,
+		Height:              frame.Height,
+		ExtractionThreshold: 12,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ps.Stars) != 12 {
+		t.Fatalf("expected the brightest 12 stars to be kept, got %d", len(ps.Stars))
+	}
+
+	if ps.Stars[0].Intensity < ps.Stars[len(ps.Stars)-1].Intensity {
+		t.Errorf("expected the stars to be sorted by intensity, in descending order")
+	}
+
+	ps.Sources = frame.Sources
+
+	solution, err := ps.SolveWithStrategies(
+		[]Strategy{&TriangleStrategy{}},
+		ToleranceParams{QuadTolerance: 0.02, EuclidianPixelTolerance: 5},
+		3,
+		DefaultVerificationParams,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertSolutionMatchesTruth(t, solution, truth)
+}
+
+/*****************************************************************************************************************/
+
+func TestNewPlateSolverRequiresDataOrStars(t *testing.T) {
+	if _, err := NewPlateSolver(Params{Width: 100, Height: 100}); err == nil {
+		t.Errorf("expected an error when neither image data nor stars are provided")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewPlateSolverRejectsSpuriousStarsBeforeSelection(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	frame := newSyntheticPlateSolver(t, truth, 16, 60)
+
+	// Give each synthetic star a plausible half-flux radius:
+	stars := make([]photometry.Star, 0, len(frame.Stars)+2)
+
+	for _, s := range frame.Stars {
+		s.HFR = 1.5
+		stars = append(stars, s)
+	}
+
+	// Prepend a bright hot pixel and a bright star at the edge of the image, which would otherwise be selected:
+	stars = append([]photometry.Star{
+		{X: 400, Y: 400, Intensity: 1e9, HFR: 0.3},
+		{X: 1, Y: 600, Intensity: 1e9, HFR: 1.5},
+	}, stars...)
+
+	rejection := reject.DefaultParams
+
+	ps, err := NewPlateSolver(Params{
+		Stars:               stars,
+		Rejection:           &rejection,
+		RA:                  truth.CRVAL1,
+		Dec:                 truth.CRVAL2,
+		HasHint:             true,
+		Width:               frame.Width,
+		Height:              frame.Height,
+		ExtractionThreshold: 12,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if ps.Rejections.Narrow != 1 || ps.Rejections.Edge < 1 {
+		t.Errorf("expected the hot pixel and the edge star to be rejected, got %+v", ps.Rejections)
+	}
+
+	if len(ps.Stars) != 12 {
+		t.Fatalf("expected 12 stars to be kept, got %d", len(ps.Stars))
+	}
+
+	for _, s := range ps.Stars {
+		if s.Intensity == 1e9 {
+			t.Errorf("expected the spurious stars to be rejected before selecting the brightest stars")
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetDownsampleFactor(t *testing.T) {
+	tests := []struct {
+		width, height, factor int
+	}{
+		{1024, 768, 1},
+		{2048, 2048, 1},
+		{4096, 3072, 2},
+		{9576, 6388, 4}, // a 61 megapixel full frame sensor
+	}
+
+	for _, tt := range tests {
+		if factor := GetDownsampleFactor(tt.width, tt.height); factor != tt.factor {
+			t.Errorf("GetDownsampleFactor(%d, %d) = %d, want %d", tt.width, tt.height, factor, tt.factor)
+		}
+	}
+
+	// The zero value disables downsampling, which is only selected automatically when requested:
+	for downsample, want := range map[int]int{0: 1, 1: 1, 3: 3, DOWNSAMPLE_AUTO: 4} {
+		if factor, err := getDownsampleFactor(downsample, 9576, 6388); err != nil || factor != want {
+			t.Errorf("getDownsampleFactor(%d) = %d (%v), want %d", downsample, factor, err, want)
+		}
+	}
+
+	// A factor larger than the image would bin it to an empty image:
+	if _, err := getDownsampleFactor(64, 9576, 32); err == nil {
+		t.Errorf("expected an error for a downsample factor larger than the image")
+	}
+
+	if factor, err := getDownsampleFactor(32, 9576, 32); err != nil || factor != 32 {
+		t.Errorf("getDownsampleFactor(32) = %d (%v), want 32", factor, err)
+	}
+
+	if _, err := getDownsampleFactor(-2, 9576, 6388); err == nil {
+		t.Errorf("expected an error for a negative downsample factor")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewPlateSolverRefinesDownsampledStarsAtFullResolution(t *testing.T) {
+	width, height := 768, 640
+
+	// Stars at sub-pixel positions, which are not at the centres of the 4×4 bins:
+	positions := [][2]float64{
+		{120.3, 95.6}, {402.7, 130.2}, {610.1, 88.9}, {250.4, 330.8},
+		{530.9, 410.3}, {90.6, 520.1}, {360.2, 560.7}, {680.5, 300.4},
+	}
+
+	sigma := 6 / (2 * math.Sqrt(2*math.Ln2))
+
+	data := make([]float32, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := 1000.0
+
+			for i, p := range positions {
+				dx, dy := float64(x)-p[0], float64(y)-p[1]
+				v += float64(4000-300*i) * math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma))
+			}
+
+			data[y*width+x] = float32(v)
+		}
+	}
+
+	extraction := extract.DefaultParams
+
+	ps, err := NewPlateSolver(Params{
+		Data:                data,
+		Extraction:          &extraction,
+		Downsample:          4,
+		Width:               width,
+		Height:              height,
+		ADU:                 65535,
+		ExtractionThreshold: 16,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ps.Stars) != len(positions) || len(ps.Detections) != len(positions) {
+		t.Fatalf("expected %d stars, got %d", len(positions), len(ps.Stars))
+	}
+
+	// The stars are sorted by intensity, in the same order as the positions:
+	for i, p := range positions {
+		s, d := ps.Stars[i], ps.Detections[i]
+
+		if math.Hypot(float64(s.X)-p[0], float64(s.Y)-p[1]) > 0.05 {
+			t.Errorf("star %d: expected a centroid of (%v, %v), got (%v, %v)", i, p[0], p[1], s.X, s.Y)
+		}
+
+		if float32(d.X) != s.X || float32(d.Y) != s.Y {
+			t.Errorf("star %d: expected the detection to share the refined centroid", i)
+		}
+
+		// The FWHM is expressed in original pixels, albeit broadened by the binning:
+		if d.FWHM < 5 || d.FWHM > 8 {
+			t.Errorf("star %d: expected a FWHM of ~6 original pixels, got %v", i, d.FWHM)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,400 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/quad"
+	"github.com/observerly/skysolve/pkg/spatial"
+	"github.com/observerly/skysolve/pkg/star"
+	"github.com/observerly/skysolve/pkg/wcs"
+	"gonum.org/v1/gonum/spatial/kdtree"
+)
+
+/*****************************************************************************************************************/
+
+// Strategy is a single approach to plate solving, e.g., hinted quad matching, triangle matching, a blind
+// index search or tracking from a previously solved frame. Strategies are run in order by the PlateSolver
+// until one of them produces a solution that passes verification. The legacy PlateSolver of pkg/solver,
+// with its own catalog and matching loop, is out of scope and does not implement strategies.
+type Strategy interface {
+	// Name returns the unique name of the strategy, recorded in the solution when it succeeds:
+	Name() string
+	// Solve attempts to compute a WCS solution for the extracted stars of the PlateSolver:
+	Solve(ps *PlateSolver, tolerance ToleranceParams, sipOrder int) (*Solution, error)
+}
+
+/*****************************************************************************************************************/
+
+// Solution is the outcome of a single strategy, along with the verification of that outcome.
+type Solution struct {
+	WCS          *wcs.WCS            `json:"wcs"`          // The world coordinate system solution
+	Matches      []spatial.QuadMatch `json:"matches"`      // The confirmed quad matches (for quad-based strategies)
+	Pairs        []wcs.PointPair     `json:"pairs"`        // The star to source correspondences used in the fit
+	Sources      []catalog.Source    `json:"-"`            // The catalog sources the strategy was matched against
+	Strategy     string              `json:"strategy"`     // The name of the strategy that produced the solution
+	Verification Verification        `json:"verification"` // The verification of the solution against the catalog
+}
+
+/*****************************************************************************************************************/
+
+// Verification describes how well a WCS solution maps the catalog sources onto the extracted stars.
+type Verification struct {
+	Matched int     `json:"matched"` // The number of extracted stars with a catalog counterpart within tolerance
+	Total   int     `json:"total"`   // The total number of extracted stars considered
+	RMS     float64 `json:"rms"`     // The root mean square residual of the matched stars (in pixels)
+}
+
+/*****************************************************************************************************************/
+
+// VerificationParams are the acceptance criteria a solution must meet before it is returned.
+type VerificationParams struct {
+	MinimumMatches    int     // the minimum number of matched stars, e.g., 6
+	MinimumMatchRatio float64 // the minimum ratio of matched stars to extracted stars, e.g., 0.25
+	PixelTolerance    float64 // the maximum distance (in pixels) between a star and its projected source
+}
+
+/*****************************************************************************************************************/
+
+// DefaultVerificationParams are sensible acceptance criteria for most images.
+var DefaultVerificationParams = VerificationParams{
+	MinimumMatches:    6,
+	MinimumMatchRatio: 0.25,
+	PixelTolerance:    10,
+}
+
+/*****************************************************************************************************************/
+
+// Passes determines whether the verification satisfies the given acceptance criteria.
+func (v Verification) Passes(params VerificationParams) bool {
+	if v.Total == 0 || v.Matched < params.MinimumMatches {
+		return false
+	}
+
+	return float64(v.Matched)/float64(v.Total) >= params.MinimumMatchRatio
+}
+
+/*****************************************************************************************************************/
+
+// DefaultStrategies returns the default fallback chain: hinted quads, then triangles.
+func DefaultStrategies() []Strategy {
+	return []Strategy{
+		&QuadStrategy{},
+		&TriangleStrategy{},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// StrategyOptions are the additional data of the strategies resolved by name, where a strategy whose data is
+// missing, e.g., tracking the first frame of a sequence, fails and the next strategy in the chain is attempted.
+type StrategyOptions struct {
+	Index      []catalog.Source // the indexed catalog sources of the "blind" strategy
+	IndexQuads []quad.Quad      // the precomputed quads of the indexed catalog sources (optional)
+	Previous   *wcs.WCS         // the WCS solution of the previous frame for the "tracking" strategy
+}
+
+/*****************************************************************************************************************/
+
+// GetStrategiesByName resolves an ordered list of strategy names, i.e., "quad", "triangle", "blind" or
+// "tracking", into strategies, e.g., ["tracking", "quad", "triangle"] for a sequence of frames.
+func GetStrategiesByName(names []string, options StrategyOptions) ([]Strategy, error) {
+	strategies := make([]Strategy, 0, len(names))
+
+	for _, name := range names {
+		switch name {
+		case "quad":
+			strategies = append(strategies, &QuadStrategy{})
+		case "triangle":
+			strategies = append(strategies, &TriangleStrategy{})
+		case "blind":
+			strategies = append(strategies, &BlindIndexStrategy{Sources: options.Index, Quads: options.IndexQuads})
+		case "tracking":
+			strategies = append(strategies, &TrackingStrategy{Previous: options.Previous})
+		default:
+			return nil, fmt.Errorf("unknown solver strategy: %s", name)
+		}
+	}
+
+	return strategies, nil
+}
+
+/*****************************************************************************************************************/
+
+// SolveWithStrategies runs each strategy in the given order until one of them produces a solution that passes
+// verification. The verified solution is then refined against all of the matched stars, and the name of the
+// successful strategy is recorded in the returned solution.
+func (ps *PlateSolver) SolveWithStrategies(
+	strategies []Strategy,
+	tolerance ToleranceParams,
+	sipOrder int,
+	params VerificationParams,
+) (*Solution, error) {
+	if len(strategies) == 0 {
+		return nil, errors.New("no solver strategies provided")
+	}
+
+	errs := []error{}
+
+	for _, strategy := range strategies {
+		// Attempt to solve the image with the current strategy:
+		solution, err := strategy.Solve(ps, tolerance, sipOrder)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", strategy.Name(), err))
+			continue
+		}
+
+		if solution == nil || solution.WCS == nil {
+			errs = append(errs, fmt.Errorf("%s: no WCS solution found", strategy.Name()))
+			continue
+		}
+
+		// Verify the solution against the catalog sources the strategy was matched against:
+		solution.Verification = ps.Verify(solution.WCS, solution.Sources, params.PixelTolerance)
+
+		if !solution.Verification.Passes(params) {
+			errs = append(errs, fmt.Errorf(
+				"%s: verification failed with %d of %d stars matched",
+				strategy.Name(),
+				solution.Verification.Matched,
+				solution.Verification.Total,
+			))
+			continue
+		}
+
+		// Refine the verified solution against all of the matched stars, keeping it only if it verifies no worse:
+		if refined, pairs, err := ps.Refine(solution.WCS, solution.Sources, params.PixelTolerance, sipOrder); err == nil {
+			verification := ps.Verify(refined, solution.Sources, params.PixelTolerance)
+
+			if verification.Matched >= solution.Verification.Matched && verification.RMS <= solution.Verification.RMS {
+				solution.WCS = refined
+				solution.Pairs = pairs
+				solution.Verification = verification
+			}
+		}
+
+		// Record which strategy succeeded:
+		solution.Strategy = strategy.Name()
+
+		return solution, nil
+	}
+
+	return nil, fmt.Errorf("all solver strategies failed: %w", errors.Join(errs...))
+}
+
+/*****************************************************************************************************************/
+
+// GetPointingHint returns the approximate equatorial coordinate of the center of the image, falling back to the
+// mean position of the catalog sources when no hint has been provided, where the mean is of their unit vectors,
+// such that sources either side of 0h do not average to 12h.
+func (ps *PlateSolver) GetPointingHint() (ra float64, dec float64) {
+	if ps.HasHint || len(ps.Sources) == 0 {
+		return ps.RA, ps.Dec
+	}
+
+	var x, y, z float64
+
+	for _, source := range ps.Sources {
+		alpha, delta := source.RA*math.Pi/180, source.Dec*math.Pi/180
+
+		x += math.Cos(delta) * math.Cos(alpha)
+		y += math.Cos(delta) * math.Sin(alpha)
+		z += math.Sin(delta)
+	}
+
+	ra = math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+
+	dec = math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+
+	return ra, dec
+}
+
+/*****************************************************************************************************************/
+
+// Verify projects the catalog sources onto the image with the given WCS and counts how many extracted stars have
+// a projected source within the pixel tolerance.
+func (ps *PlateSolver) Verify(w *wcs.WCS, sources []catalog.Source, tolerance float64) Verification {
+	stars := ps.GetStars()
+
+	verification := Verification{
+		Total: len(stars),
+	}
+
+	if w == nil || len(sources) == 0 || len(stars) == 0 {
+		return verification
+	}
+
+	// Project each of the catalog sources onto the pixel grid of the image:
+	projected := ProjectSources(w, sources)
+
+	pairs := MatchNearestNeighbours(stars, projected, tolerance)
+
+	verification.Matched = len(pairs)
+
+	verification.RMS = ComputeResidualRMS(w, pairs)
+
+	return verification
+}
+
+/*****************************************************************************************************************/
+
+// MatchNearestNeighbours pairs each star with the nearest predicted star (in pixel space) within the given
+// tolerance, where each predicted star carries the equatorial coordinate of its catalog source. Each predicted
+// star is used at most once, with the closest star taking precedence.
+func MatchNearestNeighbours(stars []star.Star, predicted []star.Star, tolerance float64) []wcs.PointPair {
+	if len(stars) == 0 || len(predicted) == 0 {
+		return []wcs.PointPair{}
+	}
+
+	// Build a k-d tree over the predicted positions for fast nearest neighbour lookups, where each point carries the
+	// index of its predicted star, such that coincident predictions each recover their own source:
+	points := make(featurePoints, len(predicted))
+
+	for i, p := range predicted {
+		points[i] = featurePoint{p.X, p.Y, float64(i)}
+	}
+
+	tree := kdtree.New(points, false)
+
+	type candidate struct {
+		star      int
+		predicted int
+		distance  float64
+	}
+
+	candidates := make([]candidate, 0, len(stars))
+
+	for i, s := range stars {
+		nearest, d2 := tree.Nearest(featurePoint{s.X, s.Y, -1})
+
+		if nearest == nil || d2 > tolerance*tolerance {
+			continue
+		}
+
+		candidates = append(candidates, candidate{
+			star:      i,
+			predicted: int(nearest.(featurePoint)[2]),
+			distance:  d2,
+		})
+	}
+
+	// Resolve conflicts so that each predicted source is used by its closest star only:
+	best := make(map[int]candidate, len(candidates))
+
+	for _, c := range candidates {
+		if b, ok := best[c.predicted]; !ok || c.distance < b.distance {
+			best[c.predicted] = c
+		}
+	}
+
+	pairs := make([]wcs.PointPair, 0, len(best))
+
+	for _, c := range candidates {
+		if best[c.predicted] != c {
+			continue
+		}
+
+		pairs = append(pairs, wcs.PointPair{
+			X:      stars[c.star].X,
+			Y:      stars[c.star].Y,
+			RA:     predicted[c.predicted].RA,
+			Dec:    predicted[c.predicted].Dec,
+			Weight: wcs.GetWeightFromUncertainty(stars[c.star].Uncertainty),
+		})
+	}
+
+	return pairs
+}
+
+/*****************************************************************************************************************/
+
+// QuadStrategy is the hinted quad strategy: quads of the brightest extracted stars are matched against quads of
+// the catalog sources around the pointing hint.
+type QuadStrategy struct{}
+
+/*****************************************************************************************************************/
+
+func (s *QuadStrategy) Name() string {
+	return "quad"
+}
+
+/*****************************************************************************************************************/
+
+func (s *QuadStrategy) Solve(ps *PlateSolver, tolerance ToleranceParams, sipOrder int) (*Solution, error) {
+	w, matches, err := ps.Solve(tolerance, sipOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Solution{
+		WCS:     w,
+		Matches: matches,
+		Pairs:   wcs.GetPointPairsFromQuadMatches(matches),
+		Sources: ps.Sources,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// BlindIndexStrategy matches quads of the extracted stars against a precomputed index of catalog sources, e.g.,
+// the sources of a set of HEALPix pixels, without relying on the sources around the pointing hint.
+type BlindIndexStrategy struct {
+	Sources []catalog.Source // the indexed catalog sources
+	Quads   []quad.Quad      // the precomputed quads of the indexed catalog sources (optional)
+}
+
+/*****************************************************************************************************************/
+
+func (s *BlindIndexStrategy) Name() string {
+	return "blind"
+}
+
+/*****************************************************************************************************************/
+
+func (s *BlindIndexStrategy) Solve(ps *PlateSolver, tolerance ToleranceParams, sipOrder int) (*Solution, error) {
+	if len(s.Sources) == 0 {
+		return nil, errors.New("blind index contains no sources")
+	}
+
+	// Generate our quads from the extracted stars:
+	quads, err := GenerateEuclidianStarQuads(ps.GetStars(), 3)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceQuads := s.Quads
+
+	// If the index has not been precomputed, generate the quads from the indexed sources:
+	if len(sourceQuads) == 0 {
+		sourceQuads, err = GenerateEuclidianStarQuads(GetSourceStars(s.Sources), 3)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w, matches, err := ps.SolveForQuads(quads, sourceQuads, tolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Solution{
+		WCS:     w,
+		Matches: matches,
+		Pairs:   wcs.GetPointPairsFromQuadMatches(matches),
+		Sources: s.Sources,
+	}, nil
+}
+
+/*****************************************************************************************************************/
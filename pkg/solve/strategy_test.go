@@ -0,0 +1,300 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/transform"
+	"github.com/observerly/skysolve/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// newSyntheticPlateSolver creates a PlateSolver whose extracted stars are the catalog sources projected through a
+// known WCS, so that each strategy can be tested without pixel data or network access.
+func newSyntheticPlateSolver(t testing.TB, truth wcs.WCS, n int, m int) *PlateSolver {
+	t.Helper()
+
+	r := rand.New(rand.NewSource(42))
+
+	sources := make([]catalog.Source, 0, m)
+
+	for i := 0; i < m; i++ {
+		sources = append(sources, catalog.Source{
+			Designation:          fmt.Sprintf("Synthetic %d", i),
+			RA:                   truth.CRVAL1 + (r.Float64()-0.5)*0.6,
+			Dec:                  truth.CRVAL2 + (r.Float64()-0.5)*0.6,
+			PhotometricGMeanFlux: r.Float64() * 1e6,
+		})
+	}
+
+	return newSyntheticFrame(truth, sources, n)
+}
+
+/*****************************************************************************************************************/
+
+// newSyntheticFrame creates a PlateSolver for a single frame, whose extracted stars are the brightest n of the given
+// sources projected through the known WCS of the frame.
+func newSyntheticFrame(truth wcs.WCS, sources []catalog.Source, n int) *PlateSolver {
+	width, height := 1200.0, 1200.0
+
+	stars := []photometry.Star{}
+
+	for _, source := range sources {
+		x, y := truth.EquatorialCoordinateToPixel(source.RA, source.Dec)
+
+		if x < 0 || y < 0 || x > width || y > height {
+			continue
+		}
+
+		stars = append(stars, photometry.Star{
+			X:         float32(x),
+			Y:         float32(y),
+			Intensity: float32(source.PhotometricGMeanFlux),
+		})
+	}
+
+	sort.Slice(stars, func(i, j int) bool {
+		return stars[i].Intensity > stars[j].Intensity
+	})
+
+	if len(stars) > n {
+		stars = stars[:n]
+	}
+
+	return &PlateSolver{
+		Stars:   stars,
+		Sources: sources,
+		RA:      truth.CRVAL1,
+		Dec:     truth.CRVAL2,
+		HasHint: true,
+		Width:   int(width),
+		Height:  int(height),
+	}
+}
+
+/*****************************************************************************************************************/
+
+func newSyntheticWCS() wcs.WCS {
+	scale := 0.0005
+
+	theta := 30 * math.Pi / 180
+
+	return wcs.NewWorldCoordinateSystem(600, 600, wcs.WCSParams{
+		Projection: wcs.RADEC_TAN,
+		AffineParams: transform.Affine2DParameters{
+			A: scale * math.Cos(theta),
+			B: -scale * math.Sin(theta),
+			C: 100,
+			D: scale * math.Sin(theta),
+			E: scale * math.Cos(theta),
+			F: 0.5,
+		},
+	})
+}
+
+/*****************************************************************************************************************/
+
+type failingStrategy struct{}
+
+func (s *failingStrategy) Name() string {
+	return "failing"
+}
+
+func (s *failingStrategy) Solve(ps *PlateSolver, tolerance ToleranceParams, sipOrder int) (*Solution, error) {
+	return nil, errors.New("always fails")
+}
+
+/*****************************************************************************************************************/
+
+func assertSolutionMatchesTruth(t *testing.T, solution *Solution, truth wcs.WCS) {
+	t.Helper()
+
+	for _, p := range [][2]float64{{0, 0}, {600, 600}, {1200, 1200}, {200, 900}} {
+		got := solution.WCS.PixelToEquatorialCoordinate(p[0], p[1])
+		want := truth.PixelToEquatorialCoordinate(p[0], p[1])
+
+		if math.Abs(got.RA-want.RA) > 1e-6 || math.Abs(got.Dec-want.Dec) > 1e-6 {
+			t.Errorf("pixel %v: got (%v, %v), want (%v, %v)", p, got.RA, got.Dec, want.RA, want.Dec)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSolveWithStrategiesFallsBackToTriangles(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	ps := newSyntheticPlateSolver(t, truth, 16, 60)
+
+	tolerance := ToleranceParams{
+		QuadTolerance:           0.02,
+		EuclidianPixelTolerance: 5,
+	}
+
+	solution, err := ps.SolveWithStrategies(
+		[]Strategy{&failingStrategy{}, &TriangleStrategy{}},
+		tolerance,
+		3,
+		DefaultVerificationParams,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if solution.Strategy != "triangle" {
+		t.Errorf("expected the triangle strategy to succeed, got %q", solution.Strategy)
+	}
+
+	if solution.Verification.Matched != len(ps.Stars) {
+		t.Errorf("expected all %d stars to be verified, got %d", len(ps.Stars), solution.Verification.Matched)
+	}
+
+	assertSolutionMatchesTruth(t, solution, truth)
+}
+
+/*****************************************************************************************************************/
+
+func TestSolveWithStrategiesQuad(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	ps := newSyntheticPlateSolver(t, truth, 12, 30)
+
+	tolerance := ToleranceParams{
+		QuadTolerance:           0.02,
+		EuclidianPixelTolerance: 5,
+	}
+
+	solution, err := ps.SolveWithStrategies(DefaultStrategies(), tolerance, 3, DefaultVerificationParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if solution.Strategy != "quad" {
+		t.Errorf("expected the quad strategy to succeed, got %q", solution.Strategy)
+	}
+
+	assertSolutionMatchesTruth(t, solution, truth)
+}
+
+/*****************************************************************************************************************/
+
+func TestSolveWithStrategiesTracking(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	ps := newSyntheticPlateSolver(t, truth, 16, 60)
+
+	// Offset the previous solution by a few pixels to simulate drift between frames:
+	previous := truth
+	previous.CRPIX1 += 3
+	previous.CRPIX2 -= 2
+
+	solution, err := ps.SolveWithStrategies(
+		[]Strategy{&TrackingStrategy{Previous: &previous}},
+		ToleranceParams{EuclidianPixelTolerance: 10},
+		3,
+		DefaultVerificationParams,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if solution.Strategy != "tracking" {
+		t.Errorf("expected the tracking strategy to succeed, got %q", solution.Strategy)
+	}
+
+	assertSolutionMatchesTruth(t, solution, truth)
+}
+
+/*****************************************************************************************************************/
+
+func TestSolveWithStrategiesAllFail(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	ps := newSyntheticPlateSolver(t, truth, 16, 60)
+
+	_, err := ps.SolveWithStrategies(
+		[]Strategy{&failingStrategy{}, &TrackingStrategy{}},
+		ToleranceParams{},
+		3,
+		DefaultVerificationParams,
+	)
+	if err == nil {
+		t.Fatalf("expected an error when all strategies fail")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetStrategiesByName(t *testing.T) {
+	previous := newSyntheticWCS()
+
+	strategies, err := GetStrategiesByName([]string{"tracking", "quad", "triangle", "blind"}, StrategyOptions{
+		Index:    []catalog.Source{{RA: 180, Dec: 45}},
+		Previous: &previous,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, name := range []string{"tracking", "quad", "triangle", "blind"} {
+		if strategies[i].Name() != name {
+			t.Errorf("strategy %d = %q, want %q", i, strategies[i].Name(), name)
+		}
+	}
+
+	if tracking := strategies[0].(*TrackingStrategy); tracking.Previous != &previous {
+		t.Errorf("expected the tracking strategy to track from the previous solution")
+	}
+
+	if blind := strategies[3].(*BlindIndexStrategy); len(blind.Sources) != 1 {
+		t.Errorf("expected the blind strategy to search the index, got %d sources", len(blind.Sources))
+	}
+
+	if _, err := GetStrategiesByName([]string{"quad", "unknown"}, StrategyOptions{}); err == nil {
+		t.Errorf("expected an error for an unknown strategy")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetPointingHint(t *testing.T) {
+	// A hint at (0, 0) is a valid hint, rather than an unset one:
+	ps := &PlateSolver{
+		Sources: []catalog.Source{{RA: 120, Dec: 30}},
+		HasHint: true,
+	}
+
+	if ra, dec := ps.GetPointingHint(); ra != 0 || dec != 0 {
+		t.Errorf("expected the hint (0, 0), got (%v, %v)", ra, dec)
+	}
+
+	// Without a hint, the mean of the sources either side of 0h is near 0h, rather than 12h:
+	ps = &PlateSolver{
+		Sources: []catalog.Source{{RA: 359.5, Dec: 10}, {RA: 0.5, Dec: 10}, {RA: 359.9, Dec: 11}, {RA: 0.1, Dec: 11}},
+	}
+
+	ra, dec := ps.GetPointingHint()
+
+	if math.Abs(math.Mod(ra+180, 360)-180) > 1e-9 || math.Abs(dec-10.5) > 0.01 {
+		t.Errorf("expected a mean near (0, 10.5), got (%v, %v)", ra, dec)
+	}
+}
+
+/*****************************************************************************************************************/
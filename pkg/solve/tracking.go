@@ -0,0 +1,259 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/observerly/skysolve/pkg/catalog"
+	"github.com/observerly/skysolve/pkg/star"
+	"github.com/observerly/skysolve/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// ProjectSources projects the catalog sources onto the pixel grid of an image with the given WCS, returning stars
+// with both the predicted pixel coordinates and the equatorial coordinates of each source.
+func ProjectSources(w *wcs.WCS, sources []catalog.Source) []star.Star {
+	ra, dec := make([]float64, len(sources)), make([]float64, len(sources))
+
+	for i, source := range sources {
+		ra[i], dec[i] = source.RA, source.Dec
+	}
+
+	// The equatorial coordinates are of equal length, so the batch transformation cannot fail:
+	x, y, _ := w.EquatorialCoordinatesToPixels(ra, dec, wcs.BatchParams{})
+
+	projected := make([]star.Star, len(sources))
+
+	for i, source := range sources {
+		projected[i] = star.Star{
+			Designation: source.Designation,
+			X:           x[i],
+			Y:           y[i],
+			RA:          source.RA,
+			Dec:         source.Dec,
+			Intensity:   source.PhotometricGMeanFlux,
+		}
+	}
+
+	return projected
+}
+
+/*****************************************************************************************************************/
+
+// ComputeResidualRMS computes the root mean square distance (in pixels) between the pixel coordinates of each point
+// pair and the pixel coordinates predicted by the WCS for its equatorial coordinate.
+func ComputeResidualRMS(w *wcs.WCS, pairs []wcs.PointPair) float64 {
+	if len(pairs) == 0 {
+		return math.Inf(1)
+	}
+
+	ra, dec := make([]float64, len(pairs)), make([]float64, len(pairs))
+
+	for i, pair := range pairs {
+		ra[i], dec[i] = pair.RA, pair.Dec
+	}
+
+	// The equatorial coordinates are of equal length, so the batch transformation cannot fail:
+	x, y, _ := w.EquatorialCoordinatesToPixels(ra, dec, wcs.BatchParams{})
+
+	sum := 0.0
+
+	for i, pair := range pairs {
+		sum += (pair.X-x[i])*(pair.X-x[i]) + (pair.Y-y[i])*(pair.Y-y[i])
+	}
+
+	return math.Sqrt(sum / float64(len(pairs)))
+}
+
+/*****************************************************************************************************************/
+
+// TrackingStrategy reuses the WCS of a previously solved frame to predict the pixel positions of the catalog
+// sources, pairs them with the extracted stars by nearest neighbour and refits the WCS.
+type TrackingStrategy struct {
+	Previous   *wcs.WCS // the WCS solution of the previous frame
+	Iterations int      // the number of predict, match and refit iterations (default 2)
+}
+
+/*****************************************************************************************************************/
+
+func (s *TrackingStrategy) Name() string {
+	return "tracking"
+}
+
+/*****************************************************************************************************************/
+
+func (s *TrackingStrategy) Solve(ps *PlateSolver, tolerance ToleranceParams, sipOrder int) (*Solution, error) {
+	if s.Previous == nil {
+		return nil, errors.New("no previous WCS solution to track from")
+	}
+
+	iterations := s.Iterations
+	if iterations <= 0 {
+		iterations = 2
+	}
+
+	stars := ps.GetStars()
+
+	w := s.Previous
+
+	pairs := []wcs.PointPair{}
+
+	// Iteratively predict the source positions, match them to the stars and refit, such that later iterations
+	// benefit from the drift already absorbed by the earlier refits:
+	for i := 0; i < iterations; i++ {
+		// Project the catalog sources with the current best WCS solution:
+		predicted := ProjectSources(w, ps.Sources)
+
+		pairs = MatchNearestNeighbours(stars, predicted, tolerance.EuclidianPixelTolerance)
+
+		if len(pairs) < 3 {
+			return nil, fmt.Errorf("insufficient nearest neighbour matches to refit: %d", len(pairs))
+		}
+
+		params, xr, yr, err := wcs.ComputeAffineTransformationFromPointPairs(pairs)
+		if err != nil {
+			return nil, err
+		}
+
+		w = ps.NewWorldCoordinateSystemFromAffine(params, xr, yr)
+	}
+
+	return &Solution{
+		WCS:     w,
+		Pairs:   pairs,
+		Sources: ps.Sources,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// The default minimum number of tracked matches before a Tracker falls back to a full solve, where a handful of
+// pairs would otherwise be accepted for a refit of the six affine parameters:
+const TRACKER_MINIMUM_MATCHES = 8
+
+/*****************************************************************************************************************/
+
+// TrackerParams are the parameters of a Tracker.
+type TrackerParams struct {
+	Tolerance          ToleranceParams    // the tolerances used for both tracking and the full solve fallback
+	Verification       VerificationParams // the acceptance criteria of a full solve
+	MinimumMatches     int                // the minimum number of tracked matches before falling back (default 8)
+	MaximumRMS         float64            // the maximum tracked residual (in pixels) before falling back, e.g., 1.5
+	Fallback           []Strategy         // the strategies of the full solve, used when tracking degrades
+	SIPOrder           int                // the SIP order of the full solve fallback
+	TrackingIterations int                // the number of predict, match and refit iterations per frame
+}
+
+/*****************************************************************************************************************/
+
+// Tracker solves consecutive frames of a sequence, e.g., video-rate imaging for SSA or guiding, by reusing the WCS
+// of the previous frame. A full solve is only performed for the first frame, or when the residual or the number
+// of matches of the tracked solution degrades beyond the configured limits.
+type Tracker struct {
+	Params   TrackerParams
+	Previous *wcs.WCS
+	mu       sync.Mutex
+}
+
+/*****************************************************************************************************************/
+
+// NewTracker creates a new Tracker, optionally seeded with a known WCS solution.
+func NewTracker(previous *wcs.WCS, params TrackerParams) *Tracker {
+	if len(params.Fallback) == 0 {
+		params.Fallback = DefaultStrategies()
+	}
+
+	if params.MinimumMatches <= 0 {
+		params.MinimumMatches = TRACKER_MINIMUM_MATCHES
+	}
+
+	return &Tracker{
+		Params:   params,
+		Previous: previous,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Solve computes the WCS solution of the next frame in the sequence, where the PlateSolver holds the extracted
+// stars of the frame and the catalog sources of the tracked field.
+func (t *Tracker) Solve(ps *PlateSolver) (*Solution, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.Previous != nil {
+		tracking := &TrackingStrategy{
+			Previous:   t.Previous,
+			Iterations: t.Params.TrackingIterations,
+		}
+
+		solution, err := tracking.Solve(ps, t.Params.Tolerance, t.Params.SIPOrder)
+
+		// Accept the tracked solution only whilst it remains healthy:
+		if err == nil && t.isHealthy(solution) {
+			solution.Strategy = tracking.Name()
+			solution.Verification = Verification{
+				Matched: len(solution.Pairs),
+				Total:   len(ps.Stars),
+				RMS:     ComputeResidualRMS(solution.WCS, solution.Pairs),
+			}
+
+			t.Previous = solution.WCS
+
+			return solution, nil
+		}
+	}
+
+	// Otherwise, fall back to a full solve of the frame:
+	solution, err := ps.SolveWithStrategies(t.Params.Fallback, t.Params.Tolerance, t.Params.SIPOrder, t.Params.Verification)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Previous = solution.WCS
+
+	return solution, nil
+}
+
+/*****************************************************************************************************************/
+
+// Reset discards the previous WCS solution, such that the next frame is fully solved.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Previous = nil
+}
+
+/*****************************************************************************************************************/
+
+func (t *Tracker) isHealthy(solution *Solution) bool {
+	if solution == nil || solution.WCS == nil {
+		return false
+	}
+
+	if len(solution.Pairs) < t.Params.MinimumMatches {
+		return false
+	}
+
+	if t.Params.MaximumRMS > 0 && ComputeResidualRMS(solution.WCS, solution.Pairs) > t.Params.MaximumRMS {
+		return false
+	}
+
+	return true
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,174 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/star"
+	"github.com/observerly/skysolve/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// newDriftingWCS returns the truth WCS of the nth frame of a sequence drifting by (dx, dy) pixels per frame.
+func newDriftingWCS(truth wcs.WCS, n int, dx, dy float64) wcs.WCS {
+	frame := truth
+	eq := truth.PixelToEquatorialCoordinate(truth.CRPIX1-float64(n)*dx, truth.CRPIX2-float64(n)*dy)
+	frame.CRVAL1 = eq.RA
+	frame.CRVAL2 = eq.Dec
+	return frame
+}
+
+/*****************************************************************************************************************/
+
+func newTestTracker() *Tracker {
+	return NewTracker(nil, TrackerParams{
+		Tolerance: ToleranceParams{
+			QuadTolerance:           0.02,
+			EuclidianPixelTolerance: 10,
+		},
+		Verification:   DefaultVerificationParams,
+		MinimumMatches: 8,
+		MaximumRMS:     1.5,
+		Fallback:       []Strategy{&TriangleStrategy{}},
+		SIPOrder:       3,
+	})
+}
+
+/*****************************************************************************************************************/
+
+func TestTrackerTracksConsecutiveFrames(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	sources := newSyntheticPlateSolver(t, truth, 16, 60).Sources
+
+	tracker := newTestTracker()
+
+	for n := 0; n < 5; n++ {
+		frame := newDriftingWCS(truth, n, 2.5, -1.5)
+
+		solution, err := tracker.Solve(newSyntheticFrame(frame, sources, 16))
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", n, err)
+		}
+
+		// The first frame has no previous solution, so must be fully solved:
+		if n == 0 && solution.Strategy == "tracking" {
+			t.Errorf("frame %d: expected a full solve, got %q", n, solution.Strategy)
+		}
+
+		if n > 0 && solution.Strategy != "tracking" {
+			t.Errorf("frame %d: expected the frame to be tracked, got %q", n, solution.Strategy)
+		}
+
+		assertSolutionMatchesTruth(t, solution, frame)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestTrackerFallsBackToFullSolveOnLargeJump(t *testing.T) {
+	truth := newSyntheticWCS()
+
+	sources := newSyntheticPlateSolver(t, truth, 16, 60).Sources
+
+	tracker := newTestTracker()
+
+	if _, err := tracker.Solve(newSyntheticFrame(truth, sources, 16)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Slew the field by a few hundred pixels, such that the prediction from the previous frame is useless:
+	frame := newDriftingWCS(truth, 1, 150, 120)
+
+	solution, err := tracker.Solve(newSyntheticFrame(frame, sources, 16))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if solution.Strategy != "triangle" {
+		t.Errorf("expected a fallback to the triangle strategy, got %q", solution.Strategy)
+	}
+
+	assertSolutionMatchesTruth(t, solution, frame)
+}
+
+/*****************************************************************************************************************/
+
+func TestNewTrackerDefaultsMinimumMatches(t *testing.T) {
+	tracker := NewTracker(nil, TrackerParams{})
+
+	if tracker.Params.MinimumMatches != TRACKER_MINIMUM_MATCHES {
+		t.Errorf("expected a default of %d minimum matches, got %d", TRACKER_MINIMUM_MATCHES, tracker.Params.MinimumMatches)
+	}
+
+	// A tracked solution of only three pairs is not healthy:
+	truth := newSyntheticWCS()
+
+	pairs := []wcs.PointPair{{X: 10, Y: 10}, {X: 500, Y: 20}, {X: 30, Y: 700}}
+
+	if tracker.isHealthy(&Solution{WCS: &truth, Pairs: pairs}) {
+		t.Errorf("expected a tracked solution of %d pairs to be unhealthy", len(pairs))
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMatchNearestNeighboursRecoversCoincidentPredictions(t *testing.T) {
+	// The second and third predictions share the same pixel coordinates, but not the same source:
+	predicted := []star.Star{
+		{X: 100, Y: 100, RA: 10, Dec: 1},
+		{X: 400, Y: 300, RA: 20, Dec: 2},
+		{X: 400, Y: 300, RA: 30, Dec: 3},
+		{X: 700, Y: 900, RA: 40, Dec: 4},
+	}
+
+	stars := []star.Star{{X: 100.5, Y: 99.5}, {X: 400.2, Y: 300.1}, {X: 699, Y: 901}}
+
+	pairs := MatchNearestNeighbours(stars, predicted, 5)
+
+	if len(pairs) != 3 {
+		t.Fatalf("expected 3 pairs, got %d", len(pairs))
+	}
+
+	for _, pair := range pairs {
+		switch {
+		case pair.X == 100.5 && pair.RA == 10:
+		case pair.X == 400.2 && (pair.RA == 20 || pair.RA == 30):
+		case pair.X == 699 && pair.RA == 40:
+		default:
+			t.Errorf("expected the star at (%v, %v) to be paired with its own source, got %v", pair.X, pair.Y, pair.RA)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func BenchmarkTrackerSolve(b *testing.B) {
+	truth := newSyntheticWCS()
+
+	ps := newSyntheticFrame(truth, newSyntheticPlateSolver(b, truth, 16, 60).Sources, 32)
+
+	tracker := newTestTracker()
+
+	tracker.Previous = &truth
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := tracker.Solve(ps); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
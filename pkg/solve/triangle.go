@@ -0,0 +1,336 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package solve
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/observerly/skysolve/pkg/projection"
+	"github.com/observerly/skysolve/pkg/star"
+	"github.com/observerly/skysolve/pkg/wcs"
+	"gonum.org/v1/gonum/spatial/kdtree"
+)
+
+/*****************************************************************************************************************/
+
+// TriangleStrategy matches triangles of the brightest extracted stars against triangles of the catalog sources
+// projected onto the tangent plane at the pointing hint. Triangles need fewer stars than quads, which makes the
+// strategy a useful fallback for sparse fields.
+type TriangleStrategy struct {
+	MaximumStars   int // the maximum number of extracted stars used to form triangles (default 12)
+	MaximumSources int // the maximum number of catalog sources used to form triangles (default 40)
+}
+
+/*****************************************************************************************************************/
+
+func (s *TriangleStrategy) Name() string {
+	return "triangle"
+}
+
+/*****************************************************************************************************************/
+
+// triangle is a set of three stars, ordered canonically by ascending length of the side opposite each vertex, with
+// the similarity invariant features of the shortest and middle side relative to the longest side.
+type triangle struct {
+	Vertices [3]star.Star
+	Features kdtree.Point
+}
+
+/*****************************************************************************************************************/
+
+// newTriangle creates a canonically ordered triangle from three points, where (xs[i], ys[i]) are the cartesian
+// coordinates used to compute the invariant features of the star s[i].
+func newTriangle(s [3]star.Star, xs, ys [3]float64) (triangle, bool) {
+	// The length of the side opposite each vertex:
+	sides := [3]float64{
+		math.Hypot(xs[1]-xs[2], ys[1]-ys[2]),
+		math.Hypot(xs[0]-xs[2], ys[0]-ys[2]),
+		math.Hypot(xs[0]-xs[1], ys[0]-ys[1]),
+	}
+
+	order := []int{0, 1, 2}
+
+	sort.Slice(order, func(i, j int) bool {
+		return sides[order[i]] < sides[order[j]]
+	})
+
+	longest := sides[order[2]]
+
+	// Reject degenerate, e.g., collinear or coincident, triangles:
+	if longest == 0 || sides[order[0]]/longest < 0.1 {
+		return triangle{}, false
+	}
+
+	return triangle{
+		Vertices: [3]star.Star{s[order[0]], s[order[1]], s[order[2]]},
+		Features: kdtree.Point{sides[order[0]] / longest, sides[order[1]] / longest},
+	}, true
+}
+
+/*****************************************************************************************************************/
+
+func (s *TriangleStrategy) Solve(ps *PlateSolver, tolerance ToleranceParams, sipOrder int) (*Solution, error) {
+	maximumStars := s.MaximumStars
+	if maximumStars <= 0 {
+		maximumStars = 12
+	}
+
+	maximumSources := s.MaximumSources
+	if maximumSources <= 0 {
+		maximumSources = 40
+	}
+
+	stars := ps.GetStars()
+
+	// Extracted stars are sorted by intensity, so the brightest stars come first:
+	if len(stars) > maximumStars {
+		stars = stars[:maximumStars]
+	}
+
+	sources := GetSourceStars(ps.Sources)
+
+	// Sort the sources by flux, in descending order, so that the brightest sources come first:
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Intensity > sources[j].Intensity
+	})
+
+	if len(sources) > maximumSources {
+		sources = sources[:maximumSources]
+	}
+
+	if len(stars) < 3 || len(sources) < 3 {
+		return nil, errors.New("not enough stars or sources to form a triangle")
+	}
+
+	ra0, dec0 := ps.GetPointingHint()
+
+	// Project the sources onto the tangent plane so that the triangles are similar to those in the image:
+	xi := make([]float64, len(sources))
+	eta := make([]float64, len(sources))
+
+	for i, source := range sources {
+		xi[i], eta[i] = projection.ConvertEquatorialToGnomic(source.RA, source.Dec, ra0, dec0)
+	}
+
+	sourceTriangles := []triangle{}
+
+	points := featurePoints{}
+
+	for i := 0; i < len(sources)-2; i++ {
+		for j := i + 1; j < len(sources)-1; j++ {
+			for k := j + 1; k < len(sources); k++ {
+				t, ok := newTriangle(
+					[3]star.Star{sources[i], sources[j], sources[k]},
+					[3]float64{xi[i], xi[j], xi[k]},
+					[3]float64{eta[i], eta[j], eta[k]},
+				)
+				if !ok {
+					continue
+				}
+
+				// Store the index of the triangle as the third dimension, excluded from the distance:
+				points = append(points, featurePoint{t.Features[0], t.Features[1], float64(len(sourceTriangles))})
+
+				sourceTriangles = append(sourceTriangles, t)
+			}
+		}
+	}
+
+	if len(sourceTriangles) == 0 {
+		return nil, errors.New("no valid source triangles could be formed")
+	}
+
+	tree := kdtree.New(points, false)
+
+	// All of the extracted stars are used to confirm each hypothesis:
+	all := ps.GetStars()
+
+	best := []wcs.PointPair{}
+
+search:
+	for i := 0; i < len(stars)-2; i++ {
+		for j := i + 1; j < len(stars)-1; j++ {
+			for k := j + 1; k < len(stars); k++ {
+				t, ok := newTriangle(
+					[3]star.Star{stars[i], stars[j], stars[k]},
+					[3]float64{stars[i].X, stars[j].X, stars[k].X},
+					[3]float64{stars[i].Y, stars[j].Y, stars[k].Y},
+				)
+				if !ok {
+					continue
+				}
+
+				// Find all of the source triangles with similar invariant features:
+				keeper := kdtree.NewDistKeeper(tolerance.QuadTolerance * tolerance.QuadTolerance)
+
+				tree.NearestSet(keeper, featurePoint{t.Features[0], t.Features[1], -1})
+
+				for _, candidate := range keeper.Heap {
+					if candidate.Comparable == nil {
+						continue
+					}
+
+					st := sourceTriangles[int(candidate.Comparable.(featurePoint)[2])]
+
+					pairs := make([]wcs.PointPair, 3)
+
+					for v := 0; v < 3; v++ {
+						pairs[v] = wcs.PointPair{
+							X:   t.Vertices[v].X,
+							Y:   t.Vertices[v].Y,
+							RA:  st.Vertices[v].RA,
+							Dec: st.Vertices[v].Dec,
+						}
+					}
+
+					// Compute the hypothesised affine transformation from the three correspondences:
+					params, xr, yr, err := wcs.ComputeAffineTransformationFromPointPairs(pairs)
+					if err != nil {
+						continue
+					}
+
+					// Ensure the affine transformation is invertible:
+					if params.A*params.E-params.B*params.D == 0 {
+						continue
+					}
+
+					w := wcs.NewWorldCoordinateSystem(xr, yr, wcs.WCSParams{
+						Projection:   wcs.RADEC_TAN,
+						AffineParams: params,
+					})
+
+					// Confirm the hypothesis by projecting all of the sources into the image:
+					predicted := ProjectSources(&w, ps.Sources)
+
+					confirmed := MatchNearestNeighbours(all, predicted, tolerance.EuclidianPixelTolerance)
+
+					if len(confirmed) > len(best) {
+						best = confirmed
+					}
+
+					// If every extracted star has been confirmed, there is no better hypothesis to be found:
+					if len(best) == len(all) {
+						break search
+					}
+				}
+			}
+		}
+	}
+
+	if len(best) < 3 {
+		return nil, errors.New("no triangle match could be confirmed")
+	}
+
+	// Refit the affine transformation with all of the confirmed correspondences:
+	params, xr, yr, err := wcs.ComputeAffineTransformationFromPointPairs(best)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Solution{
+		WCS:     ps.NewWorldCoordinateSystemFromAffine(params, xr, yr),
+		Pairs:   best,
+		Sources: ps.Sources,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// featurePoint is a point in a two dimensional space, e.g., the invariant feature space of a triangle or the pixel
+// coordinates of a predicted star, carrying the index of the triangle (or star) as a third component which is
+// excluded from all distance calculations.
+type featurePoint [3]float64
+
+/*****************************************************************************************************************/
+
+func (p featurePoint) Compare(c kdtree.Comparable, d kdtree.Dim) float64 {
+	q := c.(featurePoint)
+	return p[d] - q[d]
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePoint) Dims() int {
+	return 2
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePoint) Distance(c kdtree.Comparable) float64 {
+	q := c.(featurePoint)
+	return (p[0]-q[0])*(p[0]-q[0]) + (p[1]-q[1])*(p[1]-q[1])
+}
+
+/*****************************************************************************************************************/
+
+// featurePoints is a collection of feature points satisfying the kdtree.Interface.
+type featurePoints []featurePoint
+
+/*****************************************************************************************************************/
+
+func (p featurePoints) Index(i int) kdtree.Comparable {
+	return p[i]
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePoints) Len() int {
+	return len(p)
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePoints) Pivot(d kdtree.Dim) int {
+	return featurePlane{featurePoints: p, Dim: d}.Pivot()
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePoints) Slice(start, end int) kdtree.Interface {
+	return p[start:end]
+}
+
+/*****************************************************************************************************************/
+
+// featurePlane is a wrapping type that allows feature points to be partitioned along a given dimension.
+type featurePlane struct {
+	kdtree.Dim
+	featurePoints
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePlane) Less(i, j int) bool {
+	return p.featurePoints[i][p.Dim] < p.featurePoints[j][p.Dim]
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePlane) Pivot() int {
+	return kdtree.Partition(p, kdtree.MedianOfMedians(p))
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePlane) Slice(start, end int) kdtree.SortSlicer {
+	p.featurePoints = p.featurePoints[start:end]
+	return p
+}
+
+/*****************************************************************************************************************/
+
+func (p featurePlane) Swap(i, j int) {
+	p.featurePoints[i], p.featurePoints[j] = p.featurePoints[j], p.featurePoints[i]
+}
+
+/*****************************************************************************************************************/
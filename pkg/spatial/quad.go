@@ -13,6 +13,7 @@ package spatial
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"github.com/observerly/skysolve/pkg/quad"
 	"golang.org/x/sync/errgroup"
@@ -109,6 +110,9 @@ func (m *QuadMatcher) MatchQuads(quads []quad.Quad, tolerance float64) ([]QuadMa
 	// Preallocate slice for matches to avoid reallocation:
 	matches := make([]QuadMatch, 0, len(quads))
 
+	// Mutex to guard the matches slice against concurrent appends:
+	var mu sync.Mutex
+
 	// Use errgroup to run each quad concurrently and handle errors:
 	g, _ := errgroup.WithContext(context.Background())
 
@@ -123,7 +127,9 @@ func (m *QuadMatcher) MatchQuads(quads []quad.Quad, tolerance float64) ([]QuadMa
 				return nil
 			}
 
+			mu.Lock()
 			matches = append(matches, *match)
+			mu.Unlock()
 			return nil
 		})
 	}
@@ -21,6 +21,7 @@ type Star struct {
 	RA          float64 `json:"ra"`          // Sky coordinates in the azimuthal plane (in degrees)
 	Dec         float64 `json:"dec"`         // Sky coordinates in the polar plane (in degrees)
 	Intensity   float64 `json:"intensity"`   // Intensity of the star at the central pixel, X and Y
+	Uncertainty float64 `json:"uncertainty"` // 1σ positional uncertainty of X and Y (in pixels), or zero if unknown
 }
 
 /*****************************************************************************************************************/
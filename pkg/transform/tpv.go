@@ -0,0 +1,26 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package transform
+
+/*****************************************************************************************************************/
+
+// TPV is the distortion convention of SCAMP, and many survey pipelines, which extends the gnomonic (TAN) projection
+// with polynomials, of up to seventh order, of the intermediate world coordinates (x, y), in degrees. The PV1_k
+// coefficients describe ξ(x, y, r), and the PV2_k coefficients describe η(y, x, r), with the roles of x and y swapped.
+// @see https://fits.gsfc.nasa.gov/registry/tpvwcs/tpv.html
+
+/*****************************************************************************************************************/
+
+// The TPV parameters are the PVi_k polynomial coefficients, keyed by their FITS names, e.g., "PV1_7".
+type TPV2DParameters struct {
+	PV1 map[string]float64
+	PV2 map[string]float64
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,200 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// The minimum number of coordinates transformed by each goroutine of a batch, below which the overhead of the
+// goroutine outweighs the work:
+const BATCH_MINIMUM_CHUNK = 256
+
+/*****************************************************************************************************************/
+
+// BatchParams configures the batch transformations between pixels and equatorial coordinates.
+type BatchParams struct {
+	Workers int // The number of goroutines to fan the batch out across, where zero (or one) transforms serially
+}
+
+/*****************************************************************************************************************/
+
+// distortions are the SIP and TPV polynomials of the WCS, parsed once from their FITS keys, such that a batch of
+// coordinates shares the parsing rather than repeating it for each coordinate:
+type distortions struct {
+	a, b     sipPolynomial // The forward SIP polynomials
+	ap, bp   sipPolynomial // The inverse SIP polynomials
+	pv1, pv2 []float64     // The TPV coefficients, for the TPV projection only
+}
+
+/*****************************************************************************************************************/
+
+// cachedDistortions are parsed distortions, along with a copy of the coefficients they were parsed from, such that
+// a change to the coefficients of the WCS, e.g., by a refit, is detected rather than returning stale polynomials:
+type cachedDistortions struct {
+	tpv         bool
+	fsip        transform.SIP2DForwardParameters
+	isip        transform.SIP2DInverseParameters
+	pv          transform.TPV2DParameters
+	distortions *distortions
+}
+
+/*****************************************************************************************************************/
+
+// distortionsCache holds the most recently parsed distortions, such that the single coordinate transformations of
+// the same WCS, e.g., PixelToEquatorialCoordinate called in a loop, share the parsing of the polynomials:
+var distortionsCache atomic.Pointer[cachedDistortions]
+
+/*****************************************************************************************************************/
+
+// matches determines whether the cached distortions were parsed from the coefficients of the WCS.
+func (c *cachedDistortions) matches(wcs *WCS, tpv bool) bool {
+	return c.tpv == tpv &&
+		maps.Equal(c.fsip.APower, wcs.FSIP.APower) &&
+		maps.Equal(c.fsip.BPower, wcs.FSIP.BPower) &&
+		maps.Equal(c.isip.APPower, wcs.ISIP.APPower) &&
+		maps.Equal(c.isip.BPPower, wcs.ISIP.BPPower) &&
+		(!tpv || maps.Equal(c.pv.PV1, wcs.TPV.PV1) && maps.Equal(c.pv.PV2, wcs.TPV.PV2))
+}
+
+/*****************************************************************************************************************/
+
+// getDistortions returns the SIP and TPV distortion polynomials of the WCS, parsing them only when they differ
+// from the most recently parsed distortions.
+func (wcs *WCS) getDistortions() *distortions {
+	tpv := getProjectionCode(wcs.CTYPE1) == "TPV"
+
+	if cached := distortionsCache.Load(); cached != nil && cached.matches(wcs, tpv) {
+		return cached.distortions
+	}
+
+	d := &distortions{
+		a:  newSIPPolynomial(wcs.FSIP.APower, "A"),
+		b:  newSIPPolynomial(wcs.FSIP.BPower, "B"),
+		ap: newSIPPolynomial(wcs.ISIP.APPower, "AP"),
+		bp: newSIPPolynomial(wcs.ISIP.BPPower, "BP"),
+	}
+
+	if tpv {
+		d.pv1 = getTPVCoefficients(wcs.TPV.PV1, 1)
+		d.pv2 = getTPVCoefficients(wcs.TPV.PV2, 2)
+	}
+
+	distortionsCache.Store(&cachedDistortions{
+		tpv: tpv,
+		fsip: transform.SIP2DForwardParameters{
+			APower: maps.Clone(wcs.FSIP.APower),
+			BPower: maps.Clone(wcs.FSIP.BPower),
+		},
+		isip: transform.SIP2DInverseParameters{
+			APPower: maps.Clone(wcs.ISIP.APPower),
+			BPPower: maps.Clone(wcs.ISIP.BPPower),
+		},
+		pv: transform.TPV2DParameters{
+			PV1: maps.Clone(wcs.TPV.PV1),
+			PV2: maps.Clone(wcs.TPV.PV2),
+		},
+		distortions: d,
+	})
+
+	return d
+}
+
+/*****************************************************************************************************************/
+
+// PixelsToEquatorialCoordinates converts the pixels (x[i], y[i]) to their equatorial coordinates, parsing the
+// distortions of the WCS once for the batch, and fanning out across the goroutines of the params.
+func (wcs *WCS) PixelsToEquatorialCoordinates(
+	x, y []float64,
+	params BatchParams,
+) ([]astrometry.ICRSEquatorialCoordinate, error) {
+	if len(x) != len(y) {
+		return nil, fmt.Errorf("mismatched pixel coordinates: %d x and %d y", len(x), len(y))
+	}
+
+	d := wcs.getDistortions()
+
+	coordinates := make([]astrometry.ICRSEquatorialCoordinate, len(x))
+
+	forEachChunk(len(x), params.Workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			coordinates[i] = wcs.pixelToEquatorialCoordinate(x[i], y[i], d)
+		}
+	})
+
+	return coordinates, nil
+}
+
+/*****************************************************************************************************************/
+
+// EquatorialCoordinatesToPixels converts the equatorial coordinates (ra[i], dec[i]) to their pixels, parsing the
+// distortions of the WCS once for the batch, and fanning out across the goroutines of the params.
+func (wcs *WCS) EquatorialCoordinatesToPixels(
+	ra, dec []float64,
+	params BatchParams,
+) (x, y []float64, err error) {
+	if len(ra) != len(dec) {
+		return nil, nil, fmt.Errorf("mismatched equatorial coordinates: %d ra and %d dec", len(ra), len(dec))
+	}
+
+	d := wcs.getDistortions()
+
+	x = make([]float64, len(ra))
+	y = make([]float64, len(ra))
+
+	forEachChunk(len(ra), params.Workers, func(start, end int) {
+		for i := start; i < end; i++ {
+			x[i], y[i] = wcs.equatorialCoordinateToPixel(ra[i], dec[i], d)
+		}
+	})
+
+	return x, y, nil
+}
+
+/*****************************************************************************************************************/
+
+// forEachChunk splits the indices [0, n) into contiguous chunks of at least BATCH_MINIMUM_CHUNK indices, and calls
+// fn for each chunk on its own goroutine, up to the number of workers, waiting for every chunk to complete.
+func forEachChunk(n, workers int, fn func(start, end int)) {
+	workers = min(workers, (n+BATCH_MINIMUM_CHUNK-1)/BATCH_MINIMUM_CHUNK)
+
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+
+	size := (n + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	for start := 0; start < n; start += size {
+		end := min(start+size, n)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			fn(start, end)
+		}()
+	}
+
+	wg.Wait()
+}
+
+/*****************************************************************************************************************/
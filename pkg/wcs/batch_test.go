@@ -0,0 +1,234 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// newBatchTestPixels returns a grid of n × n pixels spanning a 2048 × 1536 image:
+func newBatchTestPixels(n int) (x, y []float64) {
+	x = make([]float64, 0, n*n)
+	y = make([]float64, 0, n*n)
+
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			x = append(x, float64(i)*2048/float64(n-1))
+			y = append(y, float64(j)*1536/float64(n-1))
+		}
+	}
+
+	return x, y
+}
+
+/*****************************************************************************************************************/
+
+func TestSIPPolynomialEvaluate(t *testing.T) {
+	terms := map[string]float64{"A_0_0": 0.5, "A_2_0": 4e-6, "A_0_2": -2e-6, "A_1_1": 1e-6, "A_3_0": 1e-9, "A_1_2": 2e-9, "B_1_1": 1}
+
+	polynomial := newSIPPolynomial(terms, "A")
+
+	u, v := 312.5, -207.25
+
+	value, du, dv := polynomial.evaluate(u, v)
+
+	want := 0.5 + 4e-6*u*u - 2e-6*v*v + 1e-6*u*v + 1e-9*u*u*u + 2e-9*u*v*v
+	wantU := 8e-6*u + 1e-6*v + 3e-9*u*u + 2e-9*v*v
+	wantV := -4e-6*v + 1e-6*u + 4e-9*u*v
+
+	if math.Abs(value-want) > 1e-12 || math.Abs(du-wantU) > 1e-15 || math.Abs(dv-wantV) > 1e-15 {
+		t.Errorf("expected (%v, %v, %v), got (%v, %v, %v)", want, wantU, wantV, value, du, dv)
+	}
+
+	if value, du, dv := newSIPPolynomial(nil, "A").evaluate(u, v); value != 0 || du != 0 || dv != 0 {
+		t.Errorf("expected an empty polynomial to evaluate to zero")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestBatchTransformationsMatchThePerPointTransformations(t *testing.T) {
+	x, y := newBatchTestPixels(40)
+
+	for _, w := range []WCS{newPixelGridTestWCS(t), newTPVWCS(), newProjectedWCS(RADEC_ZEA, 10, 60)} {
+		for _, workers := range []int{0, 1, 4} {
+			coordinates, err := w.PixelsToEquatorialCoordinates(x, y, BatchParams{Workers: workers})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ra := make([]float64, len(coordinates))
+			dec := make([]float64, len(coordinates))
+
+			for i, eq := range coordinates {
+				if want := w.PixelToEquatorialCoordinate(x[i], y[i]); eq != want {
+					t.Fatalf("%s: expected %v at (%v, %v), got %v", w.CTYPE1, want, x[i], y[i], eq)
+				}
+
+				ra[i], dec[i] = eq.RA, eq.Dec
+			}
+
+			px, py, err := w.EquatorialCoordinatesToPixels(ra, dec, BatchParams{Workers: workers})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i := range px {
+				wantX, wantY := w.EquatorialCoordinateToPixel(ra[i], dec[i])
+
+				if px[i] != wantX || py[i] != wantY {
+					t.Fatalf("%s: expected (%v, %v), got (%v, %v)", w.CTYPE1, wantX, wantY, px[i], py[i])
+				}
+
+				if math.Hypot(px[i]-x[i], py[i]-y[i]) > 1e-6 {
+					t.Errorf("%s: expected (%v, %v) to round trip, got (%v, %v)", w.CTYPE1, x[i], y[i], px[i], py[i])
+				}
+			}
+		}
+	}
+
+	w := newTPVWCS()
+
+	if _, err := w.PixelsToEquatorialCoordinates([]float64{1, 2}, []float64{1}, BatchParams{}); err == nil {
+		t.Errorf("expected an error for mismatched pixel coordinates")
+	}
+
+	if _, _, err := w.EquatorialCoordinatesToPixels([]float64{1}, nil, BatchParams{}); err == nil {
+		t.Errorf("expected an error for mismatched equatorial coordinates")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDistortionsAreCachedUntilTheCoefficientsChange(t *testing.T) {
+	w := newPixelGridTestWCS(t)
+
+	before := w.PixelToEquatorialCoordinate(100, 200)
+
+	d := w.getDistortions()
+
+	if w.getDistortions() != d {
+		t.Errorf("expected the distortions of an unchanged WCS to be cached")
+	}
+
+	// Modify the coefficients in place, as a refit might, which must invalidate the cached distortions:
+	w.FSIP.APower["A_0_2"] += 1e-5
+
+	if w.getDistortions() == d {
+		t.Fatalf("expected the distortions to be parsed again after the coefficients changed")
+	}
+
+	if after := w.PixelToEquatorialCoordinate(100, 200); after == before {
+		t.Errorf("expected the modified distortion to move the coordinate at (100, 200)")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func BenchmarkPixelToEquatorialCoordinate(b *testing.B) {
+	w := newPixelGridTestWCS(b)
+
+	x, y := newBatchTestPixels(100)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for i := range x {
+			w.PixelToEquatorialCoordinate(x[i], y[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func BenchmarkPixelsToEquatorialCoordinates(b *testing.B) {
+	w := newPixelGridTestWCS(b)
+
+	x, y := newBatchTestPixels(100)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := w.PixelsToEquatorialCoordinates(x, y, BatchParams{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func BenchmarkPixelsToEquatorialCoordinatesConcurrently(b *testing.B) {
+	w := newPixelGridTestWCS(b)
+
+	x, y := newBatchTestPixels(100)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, err := w.PixelsToEquatorialCoordinates(x, y, BatchParams{Workers: 8}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func BenchmarkEquatorialCoordinateToPixel(b *testing.B) {
+	w := newPixelGridTestWCS(b)
+
+	ra, dec := getBenchmarkEquatorialCoordinates(w)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		for i := range ra {
+			w.EquatorialCoordinateToPixel(ra[i], dec[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func BenchmarkEquatorialCoordinatesToPixels(b *testing.B) {
+	w := newPixelGridTestWCS(b)
+
+	ra, dec := getBenchmarkEquatorialCoordinates(w)
+
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		if _, _, err := w.EquatorialCoordinatesToPixels(ra, dec, BatchParams{Workers: 8}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// getBenchmarkEquatorialCoordinates returns the equatorial coordinates of a grid of pixels on the image:
+func getBenchmarkEquatorialCoordinates(w WCS) (ra, dec []float64) {
+	x, y := newBatchTestPixels(100)
+
+	ra = make([]float64, len(x))
+	dec = make([]float64, len(x))
+
+	for i := range x {
+		eq := w.PixelToEquatorialCoordinate(x[i], y[i])
+		ra[i], dec[i] = eq.RA, eq.Dec
+	}
+
+	return ra, dec
+}
+
+/*****************************************************************************************************************/
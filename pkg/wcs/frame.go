@@ -0,0 +1,94 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"strings"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+)
+
+/*****************************************************************************************************************/
+
+// The longitude and latitude axes of the CTYPE keywords of each celestial coordinate frame, e.g., "GLON-TAN":
+var frameAxes = map[astrometry.CoordinateFrame][2]string{
+	astrometry.FRAME_ICRS:     {"RA", "DEC"},
+	astrometry.FRAME_GALACTIC: {"GLON", "GLAT"},
+	astrometry.FRAME_ECLIPTIC: {"ELON", "ELAT"},
+}
+
+/*****************************************************************************************************************/
+
+// ToFrame returns the CTYPE keywords of the projection with the axes of the celestial coordinate frame, e.g.,
+// "GLON-TAN" and "GLAT-TAN" for galactic coordinates, where an unknown frame is the ICRS.
+func (c CTypeP) ToFrame(frame astrometry.CoordinateFrame) CTypeP {
+	axes, ok := frameAxes[frame]
+	if !ok || len(c.CType1) < 4 || len(c.CType2) < 4 {
+		return c
+	}
+
+	// The axis occupies the first four characters of the CTYPE, padded with hyphens:
+	pad := func(axis string) string {
+		return axis + strings.Repeat("-", 4-len(axis))
+	}
+
+	return CTypeP{
+		CType1: pad(axes[0]) + c.CType1[4:],
+		CType2: pad(axes[1]) + c.CType2[4:],
+	}
+}
+
+/*****************************************************************************************************************/
+
+// GetFrame returns the celestial coordinate frame of the axes of the WCS, i.e., galactic coordinates for GLON-/GLAT-
+// axes, the mean ecliptic of J2000.0 for ELON-/ELAT- axes, and otherwise the ICRS.
+func (wcs *WCS) GetFrame() astrometry.CoordinateFrame {
+	if len(wcs.CTYPE1) < 4 {
+		return astrometry.FRAME_ICRS
+	}
+
+	switch strings.TrimRight(wcs.CTYPE1[:4], "-") {
+	case "GLON":
+		return astrometry.FRAME_GALACTIC
+	case "ELON":
+		return astrometry.FRAME_ECLIPTIC
+	default:
+		return astrometry.FRAME_ICRS
+	}
+}
+
+/*****************************************************************************************************************/
+
+// PixelToWorldCoordinate converts the pixel (x, y) to the longitude and latitude, in degrees, of the celestial frame
+// of the WCS, e.g., the galactic longitude and latitude of a GLON-/GLAT- solution.
+func (wcs *WCS) PixelToWorldCoordinate(x, y float64) (lon float64, lat float64) {
+	return wcs.pixelToWorldCoordinate(x, y, wcs.getDistortions())
+}
+
+/*****************************************************************************************************************/
+
+// WorldCoordinateToPixel converts the longitude and latitude, in degrees, of the celestial frame of the WCS to its
+// pixel.
+func (wcs *WCS) WorldCoordinateToPixel(lon, lat float64) (x float64, y float64) {
+	eq := astrometry.NewICRSEquatorialCoordinateFromFrame(wcs.GetFrame(), lon, lat)
+
+	return wcs.EquatorialCoordinateToPixel(eq.RA, eq.Dec)
+}
+
+/*****************************************************************************************************************/
+
+// getWorldCoordinate converts the equatorial coordinate to the longitude and latitude of the celestial frame of the
+// WCS, which is the identity for the ICRS.
+func (wcs *WCS) getWorldCoordinate(ra, dec float64) (lon float64, lat float64) {
+	return astrometry.ICRSEquatorialCoordinate{RA: ra, Dec: dec}.ToFrame(wcs.GetFrame())
+}
+
+/*****************************************************************************************************************/
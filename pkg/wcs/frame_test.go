@@ -0,0 +1,144 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/fitsio"
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// newGalacticTestWCS creates a 2000 × 1000 pixel solution of 0.01 degrees per pixel, centred on the galactic centre
+// with galactic north up, in the given projection:
+func newGalacticTestWCS(projectionType CoordinateProjectionType) WCS {
+	return NewWorldCoordinateSystem(1000, 500, WCSParams{
+		Projection: projectionType,
+		Frame:      astrometry.FRAME_GALACTIC,
+		AffineParams: transform.Affine2DParameters{
+			A: -0.01, C: 0,
+			E: 0.01, F: 0,
+		},
+	})
+}
+
+/*****************************************************************************************************************/
+
+func TestCTypesToFrame(t *testing.T) {
+	tests := []struct {
+		projectionType CoordinateProjectionType
+		frame          astrometry.CoordinateFrame
+		want           CTypeP
+	}{
+		{RADEC_TAN, astrometry.FRAME_ICRS, CTypeP{CType1: "RA---TAN", CType2: "DEC--TAN"}},
+		{RADEC_TANSIP, astrometry.FRAME_GALACTIC, CTypeP{CType1: "GLON-TAN-SIP", CType2: "GLAT-TAN-SIP"}},
+		{RADEC_ZEA, astrometry.FRAME_ECLIPTIC, CTypeP{CType1: "ELON-ZEA", CType2: "ELAT-ZEA"}},
+		{RADEC_CAR, "", CTypeP{CType1: "RA---CAR", CType2: "DEC--CAR"}},
+	}
+
+	for _, tt := range tests {
+		ctypes := tt.projectionType.ToCTypes().ToFrame(tt.frame)
+
+		if ctypes != tt.want {
+			t.Errorf("expected %+v, got %+v", tt.want, ctypes)
+		}
+
+		w := WCS{CTYPE1: ctypes.CType1, CTYPE2: ctypes.CType2}
+
+		if frame := w.GetFrame(); frame != tt.frame && !(tt.frame == "" && frame == astrometry.FRAME_ICRS) {
+			t.Errorf("expected the frame of %s to be %s, got %s", ctypes.CType1, tt.frame, frame)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGalacticWorldCoordinateSystem(t *testing.T) {
+	for _, projectionType := range []CoordinateProjectionType{RADEC_TAN, RADEC_ZEA, RADEC_CAR} {
+		w := newGalacticTestWCS(projectionType)
+
+		if w.RADESYS != "" {
+			t.Errorf("%s: expected no RADESYS for galactic coordinates, got %s", w.CTYPE1, w.RADESYS)
+		}
+
+		// The reference pixel is the galactic centre:
+		if l, b := w.PixelToWorldCoordinate(1000, 500); math.Abs(math.Mod(l+180, 360)-180) > 1e-9 || math.Abs(b) > 1e-9 {
+			t.Errorf("%s: expected the galactic centre at the reference pixel, got (%v, %v)", w.CTYPE1, l, b)
+		}
+
+		centre := w.PixelToEquatorialCoordinate(1000, 500)
+
+		if math.Abs(centre.RA-266.40499) > 1e-5 || math.Abs(centre.Dec+28.93617) > 1e-5 {
+			t.Errorf("%s: expected the galactic centre at (266.40499, -28.93617), got (%v, %v)", w.CTYPE1, centre.RA, centre.Dec)
+		}
+
+		for _, pixel := range [][2]float64{{5, 5}, {1500, 200}, {1995, 995}, {10, 990}} {
+			l, b := w.PixelToWorldCoordinate(pixel[0], pixel[1])
+
+			// Galactic longitudes either side of l = 0 map onto either side of the image:
+			eq := w.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+			if g := eq.ToGalactic(); math.Abs(math.Mod(g.L-l+540, 360)-180) > 1e-9 || math.Abs(g.B-b) > 1e-9 {
+				t.Errorf("%s: expected (%v, %v) at %v, got (%v, %v)", w.CTYPE1, l, b, pixel, g.L, g.B)
+			}
+
+			if x, y := w.EquatorialCoordinateToPixel(eq.RA, eq.Dec); math.Hypot(x-pixel[0], y-pixel[1]) > 1e-6 {
+				t.Errorf("%s: expected %v to round trip, got (%v, %v)", w.CTYPE1, pixel, x, y)
+			}
+
+			if x, y := w.WorldCoordinateToPixel(l, b); math.Hypot(x-pixel[0], y-pixel[1]) > 1e-6 {
+				t.Errorf("%s: expected (%v, %v) at %v, got (%v, %v)", w.CTYPE1, l, b, pixel, x, y)
+			}
+
+			if !w.Contains(eq.RA, eq.Dec, 2000, 1000) {
+				t.Errorf("%s: expected the coordinate of %v to be on the image", w.CTYPE1, pixel)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGalacticFITSHeaderRoundTrip(t *testing.T) {
+	w := newGalacticTestWCS(RADEC_TAN)
+
+	header := w.ToFITSHeader()
+
+	if header.Has("RADESYS") || header.Has("EQUINOX") {
+		t.Errorf("expected a galactic header without RADESYS or EQUINOX")
+	}
+
+	parsed, _, err := fitsio.ReadHeader(strings.NewReader(string(header.Bytes())))
+	if err != nil {
+		t.Fatalf("failed to read the serialized header: %v", err)
+	}
+
+	roundtrip, err := FromFITSHeader(parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(w, roundtrip) {
+		t.Errorf("expected the WCS to round trip, got %+v, want %+v", roundtrip, w)
+	}
+
+	if roundtrip.GetFrame() != astrometry.FRAME_GALACTIC {
+		t.Errorf("expected galactic coordinates, got %s", roundtrip.GetFrame())
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,215 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/projection"
+)
+
+/*****************************************************************************************************************/
+
+// The step (in pixels) of the central differences of the local Jacobian of the WCS:
+const JACOBIAN_STEP = 0.5
+
+/*****************************************************************************************************************/
+
+// The maximum distance (in pixels) between an equatorial coordinate and the equatorial coordinate of its pixel, for
+// the coordinate to be considered on the image, which rejects the aliased pixels of the far side of the sky:
+const CONTAINMENT_TOLERANCE = 0.01
+
+/*****************************************************************************************************************/
+
+// Jacobian is the local linear transformation from a pixel offset (dx, dy) to the offset on the tangent plane of the
+// sky (dξ, dη), in degrees, where ξ increases to the east and η increases to the north:
+//
+//	| dξ |   | XiX   XiY  | | dx |
+//	| dη | = | EtaX  EtaY | | dy |
+type Jacobian struct {
+	XiX, XiY   float64
+	EtaX, EtaY float64
+}
+
+/*****************************************************************************************************************/
+
+// Determinant returns the determinant of the Jacobian, i.e., the solid angle of a pixel in square degrees, which is
+// negative when the image has the (usual) parity of east to the left when north is up.
+func (j Jacobian) Determinant() float64 {
+	return j.XiX*j.EtaY - j.XiY*j.EtaX
+}
+
+/*****************************************************************************************************************/
+
+// getImageBounds returns the outer edges of an image of the given dimensions, where (as for the transformations of the
+// pixel grid) the centres of the pixels are at the integer coordinates 0 to width - 1 and 0 to height - 1, so the
+// edges of the image are half a pixel beyond the centres of the outermost pixels:
+func getImageBounds(width, height int) (x0, y0, x1, y1 float64) {
+	return -0.5, -0.5, float64(width) - 0.5, float64(height) - 0.5
+}
+
+/*****************************************************************************************************************/
+
+// GetCorners returns the equatorial coordinates of the four outer corners of an image of the given dimensions, in the
+// order (-0.5, -0.5), (width - 0.5, -0.5), (width - 0.5, height - 0.5) and (-0.5, height - 0.5).
+func (wcs *WCS) GetCorners(width, height int) [4]astrometry.ICRSEquatorialCoordinate {
+	x0, y0, x1, y1 := getImageBounds(width, height)
+
+	return [4]astrometry.ICRSEquatorialCoordinate{
+		wcs.PixelToEquatorialCoordinate(x0, y0),
+		wcs.PixelToEquatorialCoordinate(x1, y0),
+		wcs.PixelToEquatorialCoordinate(x1, y1),
+		wcs.PixelToEquatorialCoordinate(x0, y1),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// GetFootprint returns the sky polygon of an image of the given dimensions, starting at the corner (-0.5, -0.5), with
+// the given number of segments along each edge, such that the curved edges of distorted, or wide-field, solutions are
+// followed. A single segment per edge returns the corners.
+func (wcs *WCS) GetFootprint(width, height int, segments int) []astrometry.ICRSEquatorialCoordinate {
+	segments = max(segments, 1)
+
+	x0, y0, x1, y1 := getImageBounds(width, height)
+
+	// The corners, in order, of the edges of the image:
+	corners := [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+
+	footprint := make([]astrometry.ICRSEquatorialCoordinate, 0, 4*segments)
+
+	for i, start := range corners {
+		end := corners[(i+1)%len(corners)]
+
+		for s := 0; s < segments; s++ {
+			t := float64(s) / float64(segments)
+
+			x := start[0] + t*(end[0]-start[0])
+			y := start[1] + t*(end[1]-start[1])
+
+			footprint = append(footprint, wcs.PixelToEquatorialCoordinate(x, y))
+		}
+	}
+
+	return footprint
+}
+
+/*****************************************************************************************************************/
+
+// GetJacobian returns the local Jacobian of the WCS at the pixel (x, y), from the central differences of the
+// equatorial coordinates of the neighbouring pixels on the tangent plane of the sky at (x, y).
+func (wcs *WCS) GetJacobian(x, y float64) Jacobian {
+	origin := wcs.PixelToEquatorialCoordinate(x, y)
+
+	// The offset, in degrees, on the tangent plane at the origin of the equatorial coordinate of the pixel:
+	offset := func(px, py float64) (xi, eta float64) {
+		eq := wcs.PixelToEquatorialCoordinate(px, py)
+
+		xi, eta = projection.ConvertEquatorialToGnomic(eq.RA, eq.Dec, origin.RA, origin.Dec)
+
+		return projection.Degrees(xi), projection.Degrees(eta)
+	}
+
+	xiPositiveX, etaPositiveX := offset(x+JACOBIAN_STEP, y)
+	xiNegativeX, etaNegativeX := offset(x-JACOBIAN_STEP, y)
+	xiPositiveY, etaPositiveY := offset(x, y+JACOBIAN_STEP)
+	xiNegativeY, etaNegativeY := offset(x, y-JACOBIAN_STEP)
+
+	return Jacobian{
+		XiX:  (xiPositiveX - xiNegativeX) / (2 * JACOBIAN_STEP),
+		XiY:  (xiPositiveY - xiNegativeY) / (2 * JACOBIAN_STEP),
+		EtaX: (etaPositiveX - etaNegativeX) / (2 * JACOBIAN_STEP),
+		EtaY: (etaPositiveY - etaNegativeY) / (2 * JACOBIAN_STEP),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// GetPixelScale returns the local pixel scale of the WCS at the pixel (x, y), in degrees per pixel, i.e., the square
+// root of the solid angle of the pixel.
+func (wcs *WCS) GetPixelScale(x, y float64) float64 {
+	return math.Sqrt(math.Abs(wcs.GetJacobian(x, y).Determinant()))
+}
+
+/*****************************************************************************************************************/
+
+// GetPositionAngles returns the directions of north and east on the image at the pixel (x, y), in degrees, measured
+// anticlockwise from the +x axis of the pixel grid towards the +y axis, e.g., north is 90° and east is 180° for an
+// image with north up and east to the left.
+func (wcs *WCS) GetPositionAngles(x, y float64) (north float64, east float64) {
+	j := wcs.GetJacobian(x, y)
+
+	det := j.Determinant()
+
+	// The columns of the inverse Jacobian are the pixel offsets of a unit step to the east and to the north:
+	eastX, eastY := j.EtaY/det, -j.EtaX/det
+	northX, northY := -j.XiY/det, j.XiX/det
+
+	north = math.Mod(projection.Degrees(math.Atan2(northY, northX))+360, 360)
+	east = math.Mod(projection.Degrees(math.Atan2(eastY, eastX))+360, 360)
+
+	return north, east
+}
+
+/*****************************************************************************************************************/
+
+// toPixel converts the equatorial coordinate to its pixel, wrapping the right ascension about the reference point,
+// and reports whether the pixel maps back onto the equatorial coordinate, i.e., the coordinate is not on the far side
+// of the sky, or beyond the boundary of the projection.
+func (wcs *WCS) toPixel(ra, dec float64) (x float64, y float64, ok bool) {
+	ra = wcs.CRVAL1 + math.Mod(ra-wcs.CRVAL1+540, 360) - 180
+
+	x, y = wcs.EquatorialCoordinateToPixel(ra, dec)
+
+	if math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return x, y, false
+	}
+
+	eq := wcs.PixelToEquatorialCoordinate(x, y)
+
+	scale := math.Sqrt(math.Abs(wcs.CD1_1*wcs.CD2_2 - wcs.CD1_2*wcs.CD2_1))
+
+	return x, y, getAngularSeparation(ra, dec, eq.RA, eq.Dec) <= CONTAINMENT_TOLERANCE*scale
+}
+
+/*****************************************************************************************************************/
+
+// Contains reports whether the equatorial coordinate lies on an image of the given dimensions.
+func (wcs *WCS) Contains(ra, dec float64, width, height int) bool {
+	return wcs.GetEdgeDistance(ra, dec, width, height) >= 0
+}
+
+/*****************************************************************************************************************/
+
+// GetEdgeDistance returns the distance, in pixels, from the equatorial coordinate to the nearest edge of an image of
+// the given dimensions, which is positive on the image and negative off the image. Coordinates which cannot be
+// mapped onto the pixel grid, e.g., on the far side of the sky, are an infinite distance from the image.
+func (wcs *WCS) GetEdgeDistance(ra, dec float64, width, height int) float64 {
+	x, y, ok := wcs.toPixel(ra, dec)
+	if !ok {
+		return math.Inf(-1)
+	}
+
+	x0, y0, x1, y1 := getImageBounds(width, height)
+
+	if x >= x0 && x <= x1 && y >= y0 && y <= y1 {
+		return math.Min(math.Min(x-x0, x1-x), math.Min(y-y0, y1-y))
+	}
+
+	// The distance to the nearest point of the image, outside of the image:
+	dx := math.Max(math.Max(x0-x, x-x1), 0)
+	dy := math.Max(math.Max(y0-y, y-y1), 0)
+
+	return -math.Hypot(dx, dy)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,207 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/projection"
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// newRotatedTestWCS creates a 2000 × 1000 pixel gnomonic solution (a TPV projection without distortions, i.e., on the
+// sphere) of 2 arcseconds per pixel, with north rotated by 30 degrees anticlockwise from the +y axis, and east to the
+// left of north:
+func newRotatedTestWCS() WCS {
+	rho := projection.Radians(30)
+
+	s := 2.0 / 3600
+
+	return NewWorldCoordinateSystem(1000, 500, WCSParams{
+		Projection: RADEC_TPV,
+		AffineParams: transform.Affine2DParameters{
+			A: -s * math.Cos(rho), B: -s * math.Sin(rho), C: 250,
+			D: -s * math.Sin(rho), E: s * math.Cos(rho), F: -40,
+		},
+	})
+}
+
+/*****************************************************************************************************************/
+
+func TestGetCornersAndFootprint(t *testing.T) {
+	w := newRotatedTestWCS()
+
+	corners := w.GetCorners(2000, 1000)
+
+	// The outer corners of the image are half a pixel beyond the centres of the corner pixels:
+	for i, pixel := range [][2]float64{{-0.5, -0.5}, {1999.5, -0.5}, {1999.5, 999.5}, {-0.5, 999.5}} {
+		want := w.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		if corners[i] != want {
+			t.Errorf("expected corner %d at (%v, %v), got (%v, %v)", i, want.RA, want.Dec, corners[i].RA, corners[i].Dec)
+		}
+	}
+
+	footprint := w.GetFootprint(2000, 1000, 4)
+
+	if len(footprint) != 16 {
+		t.Fatalf("expected 16 vertices, got %d", len(footprint))
+	}
+
+	// Every fourth vertex is a corner, and the vertices between are on the edges of the image:
+	for i, vertex := range footprint {
+		if i%4 == 0 && vertex != corners[i/4] {
+			t.Errorf("expected vertex %d to be corner %d", i, i/4)
+		}
+
+		if d := w.GetEdgeDistance(vertex.RA, vertex.Dec, 2000, 1000); math.Abs(d) > 1e-6 {
+			t.Errorf("expected vertex %d on the edge of the image, got a distance of %v pixels", i, d)
+		}
+	}
+
+	if len(w.GetFootprint(2000, 1000, 0)) != 4 {
+		t.Errorf("expected the corners for a single segment per edge")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetCornersOfTransformedPixelGrids(t *testing.T) {
+	w := newRotatedTestWCS()
+
+	corners := w.GetCorners(2000, 1000)
+
+	// The outer corner of a crop is the outer corner of the pixel of the original image at its origin:
+	cropped := w.Crop(300, 200)
+
+	if got, want := cropped.GetCorners(400, 300)[0], w.PixelToEquatorialCoordinate(299.5, 199.5); getAngularSeparation(got.RA, got.Dec, want.RA, want.Dec) > 1e-9 {
+		t.Errorf("expected the corner of the crop at (%v, %v), got (%v, %v)", want.RA, want.Dec, got.RA, got.Dec)
+	}
+
+	// The crop of the whole image has the same corners as the image:
+	whole := w.Crop(0, 0)
+
+	for i, got := range whole.GetCorners(2000, 1000) {
+		if getAngularSeparation(got.RA, got.Dec, corners[i].RA, corners[i].Dec) > 1e-9 {
+			t.Errorf("expected corner %d of the uncropped image at (%v, %v), got (%v, %v)", i, corners[i].RA, corners[i].Dec, got.RA, got.Dec)
+		}
+	}
+
+	// Each quarter turn of the image moves each of its corners onto the preceding corner of the original image:
+	for turns := 0; turns < 4; turns++ {
+		rotated := w.Rotate90(2000, 1000, turns)
+
+		width, height := 2000, 1000
+
+		if turns%2 == 1 {
+			width, height = height, width
+		}
+
+		for i, got := range rotated.GetCorners(width, height) {
+			want := corners[(i-turns+4)%4]
+
+			if getAngularSeparation(got.RA, got.Dec, want.RA, want.Dec) > 1e-9 {
+				t.Errorf("%d turns: expected corner %d at (%v, %v), got (%v, %v)", turns, i, want.RA, want.Dec, got.RA, got.Dec)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetJacobianPixelScaleAndPositionAngles(t *testing.T) {
+	w := newRotatedTestWCS()
+
+	for _, pixel := range [][2]float64{{1000, 500}, {0, 0}, {1800, 900}} {
+		if scale := w.GetPixelScale(pixel[0], pixel[1]) * 3600; math.Abs(scale-2) > 1e-3 {
+			t.Errorf("expected a pixel scale of 2 arcseconds at %v, got %v", pixel, scale)
+		}
+	}
+
+	north, east := w.GetPositionAngles(1000, 500)
+
+	if math.Abs(north-120) > 1e-4 || math.Abs(east-210) > 1e-4 {
+		t.Errorf("expected north at 120° and east at 210°, got %v and %v", north, east)
+	}
+
+	// The meridians converge towards the south celestial pole, so north turns by ΔRA·sin(δ) across the image:
+	if north, _ := w.GetPositionAngles(0, 0); north <= 120 || north > 121 {
+		t.Errorf("expected north to turn anticlockwise by less than a degree at (0, 0), got %v", north)
+	}
+
+	// East to the left of north is the negative parity of the sky as seen from within the celestial sphere:
+	if det := w.GetJacobian(1000, 500).Determinant(); det >= 0 {
+		t.Errorf("expected a negative determinant, got %v", det)
+	}
+
+	// The zenithal equidistant projection preserves the radial scale, but compresses the tangential scale by sin(r)/r
+	// at a distance r from the reference point, so the local pixel scale decreases towards the corners:
+	arc := newProjectedWCS(RADEC_ARC, 10, 20)
+
+	r := projection.Radians(math.Hypot(2048, 1536) * 0.01)
+
+	if scale := arc.GetPixelScale(arc.CRPIX1, arc.CRPIX2); math.Abs(scale-0.01) > 1e-8 {
+		t.Errorf("expected a scale of 0.01 degrees at the reference pixel, got %v", scale)
+	}
+
+	if scale, want := arc.GetPixelScale(0, 0), 0.01*math.Sqrt(math.Sin(r)/r); math.Abs(scale-want) > 1e-7 {
+		t.Errorf("expected a scale of %v degrees at the corner, got %v", want, scale)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestContainsAndGetEdgeDistance(t *testing.T) {
+	w := newRotatedTestWCS()
+
+	tests := []struct {
+		x, y     float64
+		distance float64
+	}{
+		{999.5, 499.5, 500},
+		{9.5, 500, 10},
+		{1989.5, 20, 10},
+		{2009.5, 500, -10},
+		{-30.5, -40.5, -50},
+		{0, 0, 0.5},
+	}
+
+	for _, tt := range tests {
+		eq := w.PixelToEquatorialCoordinate(tt.x, tt.y)
+
+		if d := w.GetEdgeDistance(eq.RA, eq.Dec, 2000, 1000); math.Abs(d-tt.distance) > 1e-6 {
+			t.Errorf("expected a distance of %v pixels at (%v, %v), got %v", tt.distance, tt.x, tt.y, d)
+		}
+
+		if contains := w.Contains(eq.RA, eq.Dec, 2000, 1000); contains != (tt.distance >= 0) {
+			t.Errorf("expected Contains at (%v, %v) to be %v", tt.x, tt.y, tt.distance >= 0)
+		}
+	}
+
+	// The antipode of the reference point is on the far side of the sky:
+	if w.Contains(w.CRVAL1-180, -w.CRVAL2, 2000, 1000) {
+		t.Errorf("expected the antipode not to be on the image")
+	}
+
+	// The right ascension wraps about the reference point:
+	linear := NewWorldCoordinateSystem(500, 500, WCSParams{
+		AffineParams: transform.Affine2DParameters{A: -0.001, C: 359.9, E: 0.001, F: 10},
+	})
+
+	if !linear.Contains(0.05, 10, 1000, 1000) || !linear.Contains(359.8, 10, 1000, 1000) {
+		t.Errorf("expected coordinates either side of 0h to be on the image")
+	}
+}
+
+/*****************************************************************************************************************/
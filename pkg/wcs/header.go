@@ -0,0 +1,539 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/observerly/sidera/pkg/epoch"
+
+	"github.com/observerly/skysolve/pkg/astrometry"
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// The Julian date of the modified Julian date epoch, i.e., 1858 November 17 00:00:00 UTC:
+const MJD_EPOCH = 2400000.5
+
+/*****************************************************************************************************************/
+
+// The descriptions of the coordinate axes and projections of a CTYPE, as written by astropy (wcslib):
+var (
+	ctypeAxes = map[string]string{
+		"RA":   "Right ascension",
+		"DEC":  "Declination",
+		"GLON": "Galactic longitude",
+		"GLAT": "Galactic latitude",
+		"ELON": "Ecliptic longitude",
+		"ELAT": "Ecliptic latitude",
+	}
+	ctypeProjections = map[string]string{
+		"TAN": "gnomonic",
+		"TPV": "gnomonic",
+		"SIN": "orthographic/synthesis",
+		"ARC": "zenithal/azimuthal equidistant",
+		"ZEA": "zenithal/azimuthal equal area",
+		"STG": "stereographic",
+		"CAR": "plate caree",
+	}
+)
+
+/*****************************************************************************************************************/
+
+// The keywords of the celestial WCS of a FITS header, i.e., the linear transformation in any of its forms (a CD matrix,
+// a PC matrix with CDELT, or CDELT with CROTA), the reference frame and the SIP and TPV distortion polynomials:
+var wcsKeywords = regexp.MustCompile(
+	`^(WCSAXES|WCAXES|WCSNAME|LONPOLE|LATPOLE|RADESYS|RADECSYS|EQUINOX|EPOCH|` +
+		`(CRPIX|CRVAL|CTYPE|CUNIT|CDELT|CROTA)[0-9]+|(CD|PC|PV)[0-9]+_[0-9]+|(A|B|AP|BP)_([0-9]+_[0-9]+|ORDER|DMAX))$`,
+)
+
+/*****************************************************************************************************************/
+
+// The layouts of the ISO-8601 DATE-OBS keyword, in order of precedence:
+var dateLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+/*****************************************************************************************************************/
+
+// GetModifiedJulianDate returns the modified Julian date (MJD) of the given date and time, i.e., JD - 2400000.5.
+func GetModifiedJulianDate(datetime time.Time) float64 {
+	return epoch.GetJulianDate(datetime) - MJD_EPOCH
+}
+
+/*****************************************************************************************************************/
+
+// ParseDateObs parses an ISO-8601 DATE-OBS value, e.g., "2024-11-26T17:20:00.000", which is assumed to be UTC
+// unless a time zone is given.
+func ParseDateObs(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid DATE-OBS: %q", value)
+}
+
+/*****************************************************************************************************************/
+
+// FromFITSHeader reads the celestial WCS of a FITS header, i.e., the CRPIX, CRVAL, CTYPE and CUNIT keywords, the
+// linear transformation as a CD matrix, a PC matrix with CDELT, or the CDELT and (deprecated) CROTA2 keywords, the
+// RADESYS, EQUINOX, LONPOLE, LATPOLE, DATE-OBS and MJD-OBS keywords, and any SIP or TPV distortion polynomials.
+// Keywords absent from the header take their default values from the FITS WCS standard. The 1-based FITS reference
+// pixel is converted to the 0-based pixel coordinates of the WCS.
+//
+// @see https://fits.gsfc.nasa.gov/fits_wcs.html
+func FromFITSHeader(header *fitsio.Header) (WCS, error) {
+	if header == nil {
+		return WCS{}, errors.New("no FITS header to read the WCS from")
+	}
+
+	ctype1, ok1 := header.String("CTYPE1")
+	ctype2, ok2 := header.String("CTYPE2")
+
+	if !ok1 || !ok2 {
+		return WCS{}, errors.New("the FITS header does not contain a celestial WCS: CTYPE1 and CTYPE2 are required")
+	}
+
+	wcs := WCS{
+		WCAXES: 2,
+		CTYPE1: strings.TrimSpace(ctype1),
+		CTYPE2: strings.TrimSpace(ctype2),
+		CUNIT1: "deg",
+		CUNIT2: "deg",
+	}
+
+	// The number of WCS axes, where the WCAXES spelling has historically been written by skysolve:
+	if n, ok := header.Int("WCSAXES"); ok {
+		wcs.WCAXES = int(n)
+	} else if n, ok := header.Int("WCAXES"); ok {
+		wcs.WCAXES = int(n)
+	}
+
+	for _, keyword := range []struct {
+		key   string
+		value *float64
+	}{
+		{"CRPIX1", &wcs.CRPIX1},
+		{"CRPIX2", &wcs.CRPIX2},
+		{"CRVAL1", &wcs.CRVAL1},
+		{"CRVAL2", &wcs.CRVAL2},
+	} {
+		v, ok := header.Float(keyword.key)
+		if !ok {
+			return WCS{}, fmt.Errorf("the FITS header does not contain a celestial WCS: %s is required", keyword.key)
+		}
+
+		*keyword.value = v
+	}
+
+	// The FITS reference pixel is 1-based, i.e., the centre of the first pixel is (1, 1), whereas the pixel
+	// coordinates of the WCS are 0-based:
+	wcs.CRPIX1 -= 1
+	wcs.CRPIX2 -= 1
+
+	if unit, ok := header.String("CUNIT1"); ok && strings.TrimSpace(unit) != "" {
+		wcs.CUNIT1 = strings.TrimSpace(unit)
+	}
+
+	if unit, ok := header.String("CUNIT2"); ok && strings.TrimSpace(unit) != "" {
+		wcs.CUNIT2 = strings.TrimSpace(unit)
+	}
+
+	readLinearTransformation(header, &wcs)
+
+	readReferenceFrame(header, &wcs)
+
+	if err := readObservationDate(header, &wcs); err != nil {
+		return WCS{}, err
+	}
+
+	wcs.FSIP.AOrder, wcs.FSIP.APower = readSIPPolynomial(header, "A")
+	wcs.FSIP.BOrder, wcs.FSIP.BPower = readSIPPolynomial(header, "B")
+	wcs.ISIP.APOrder, wcs.ISIP.APPower = readSIPPolynomial(header, "AP")
+	wcs.ISIP.BPOrder, wcs.ISIP.BPPower = readSIPPolynomial(header, "BP")
+
+	// The PVi_k keywords are only the distortion coefficients of a TPV projection, e.g., as written by SCAMP:
+	if getProjectionCode(wcs.CTYPE1) == "TPV" {
+		wcs.TPV.PV1 = readTPVPolynomial(header, 1)
+		wcs.TPV.PV2 = readTPVPolynomial(header, 2)
+	}
+
+	return wcs, nil
+}
+
+/*****************************************************************************************************************/
+
+// readLinearTransformation reads the CD matrix of the WCS from the CDi_j keywords or, failing that, from the
+// PCi_j matrix (default identity) scaled by CDELTi or, failing that, the CDELTi and CROTA2 keywords.
+func readLinearTransformation(header *fitsio.Header, wcs *WCS) {
+	cdelt1, hasCDELT1 := header.Float("CDELT1")
+	cdelt2, hasCDELT2 := header.Float("CDELT2")
+
+	hasCD := false
+
+	for _, key := range []string{"CD1_1", "CD1_2", "CD2_1", "CD2_2"} {
+		hasCD = hasCD || header.Has(key)
+	}
+
+	switch {
+	case hasCD:
+		// Any missing elements of the CD matrix are zero:
+		wcs.CD1_1, _ = header.Float("CD1_1")
+		wcs.CD1_2, _ = header.Float("CD1_2")
+		wcs.CD2_1, _ = header.Float("CD2_1")
+		wcs.CD2_2, _ = header.Float("CD2_2")
+	default:
+		if !hasCDELT1 {
+			cdelt1 = 1
+		}
+
+		if !hasCDELT2 {
+			cdelt2 = 1
+		}
+
+		pc11, pc12, pc21, pc22 := 1.0, 0.0, 0.0, 1.0
+
+		hasPC := header.Has("PC1_1") || header.Has("PC1_2") || header.Has("PC2_1") || header.Has("PC2_2")
+
+		if crota, ok := header.Float("CROTA2"); ok && !hasPC {
+			// The AIPS convention of a rotation of the second (latitude) axis:
+			rho := crota * math.Pi / 180
+
+			pc11, pc12, pc21, pc22 = math.Cos(rho), -math.Sin(rho)*cdelt2/cdelt1, math.Sin(rho)*cdelt1/cdelt2, math.Cos(rho)
+		}
+
+		if hasPC {
+			pc11, pc12, pc21, pc22 = getFloatOrDefault(header, "PC1_1", 1), getFloatOrDefault(header, "PC1_2", 0),
+				getFloatOrDefault(header, "PC2_1", 0), getFloatOrDefault(header, "PC2_2", 1)
+		}
+
+		wcs.CD1_1, wcs.CD1_2 = cdelt1*pc11, cdelt1*pc12
+		wcs.CD2_1, wcs.CD2_2 = cdelt2*pc21, cdelt2*pc22
+	}
+
+	// The coordinate increments are informative alongside a CD matrix, so are derived from it when absent:
+	wcs.CDELT1, wcs.CDELT2 = cdelt1, cdelt2
+
+	if !hasCDELT1 {
+		wcs.CDELT1 = -math.Sqrt(wcs.CD1_1*wcs.CD1_1 + wcs.CD2_1*wcs.CD2_1)
+	}
+
+	if !hasCDELT2 {
+		wcs.CDELT2 = math.Sqrt(wcs.CD1_2*wcs.CD1_2 + wcs.CD2_2*wcs.CD2_2)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// readReferenceFrame reads the RADESYS, EQUINOX, LONPOLE and LATPOLE keywords, defaulting the reference frame from
+// the equinox (FK4 before 1984, FK5 thereafter, and ICRS without an equinox) and vice versa.
+func readReferenceFrame(header *fitsio.Header, wcs *WCS) {
+	radesys, hasRADESYS := header.String("RADESYS")
+
+	// The EPOCH keyword is the deprecated predecessor of EQUINOX:
+	equinox, hasEQUINOX := header.Float("EQUINOX")
+
+	if !hasEQUINOX {
+		equinox, hasEQUINOX = header.Float("EPOCH")
+	}
+
+	wcs.RADESYS = strings.ToUpper(strings.TrimSpace(radesys))
+
+	switch {
+	case hasRADESYS && wcs.RADESYS != "":
+	case wcs.GetFrame() == astrometry.FRAME_GALACTIC:
+		// Galactic coordinates are independent of the equatorial reference frame and its equinox:
+	case hasEQUINOX && equinox < 1984:
+		wcs.RADESYS = "FK4"
+	case hasEQUINOX:
+		wcs.RADESYS = "FK5"
+	default:
+		wcs.RADESYS = "ICRS"
+	}
+
+	switch {
+	case hasEQUINOX:
+		wcs.EQUINOX = equinox
+	case wcs.RADESYS == "FK4" || wcs.RADESYS == "FK4-NO-E":
+		wcs.EQUINOX = 1950
+	case wcs.RADESYS == "FK5":
+		wcs.EQUINOX = 2000
+	}
+
+	// The celestial pole is at a native longitude of 180 degrees for zenithal projections, unless the reference point
+	// is at the pole itself, and for zenithal projections its native latitude is the declination of the reference point:
+	lonpole, latpole := getDefaultCelestialPole(getProjectionCode(wcs.CTYPE1), wcs.CRVAL2)
+
+	wcs.LONPOLE = getFloatOrDefault(header, "LONPOLE", lonpole)
+
+	wcs.LATPOLE = getFloatOrDefault(header, "LATPOLE", latpole)
+}
+
+/*****************************************************************************************************************/
+
+// readObservationDate reads the DATE-OBS and MJD-OBS keywords, deriving the modified Julian date from the date of
+// the observation when only the latter is present.
+func readObservationDate(header *fitsio.Header, wcs *WCS) error {
+	if date, ok := header.String("DATE-OBS"); ok {
+		wcs.DATEOBS = strings.TrimSpace(date)
+	}
+
+	if mjd, ok := header.Float("MJD-OBS"); ok {
+		wcs.MJDOBS = mjd
+		return nil
+	}
+
+	if wcs.DATEOBS == "" {
+		return nil
+	}
+
+	t, err := ParseDateObs(wcs.DATEOBS)
+	if err != nil {
+		return err
+	}
+
+	wcs.MJDOBS = GetModifiedJulianDate(t)
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// readSIPPolynomial reads the order, e.g., A_ORDER, and the coefficients, e.g., A_p_q, of a SIP polynomial, where
+// the coefficients are keyed by their FITS SIP names.
+func readSIPPolynomial(header *fitsio.Header, prefix string) (int, map[string]float64) {
+	order, ok := header.Int(prefix + "_ORDER")
+	if !ok || order < 0 {
+		return 0, nil
+	}
+
+	terms := map[string]float64{}
+
+	for p := 0; p <= int(order); p++ {
+		for q := 0; q <= int(order)-p; q++ {
+			key := fmt.Sprintf("%s_%d_%d", prefix, p, q)
+
+			if v, ok := header.Float(key); ok {
+				terms[key] = v
+			}
+		}
+	}
+
+	return int(order), terms
+}
+
+/*****************************************************************************************************************/
+
+// readTPVPolynomial reads the PVi_k coefficients of a TPV polynomial, where the coefficients are keyed by their FITS
+// names, or returns nil when the axis has no coefficients.
+func readTPVPolynomial(header *fitsio.Header, axis int) map[string]float64 {
+	var terms map[string]float64
+
+	for k := range tpvTerms {
+		key := fmt.Sprintf("PV%d_%d", axis, k)
+
+		if v, ok := header.Float(key); ok {
+			if terms == nil {
+				terms = map[string]float64{}
+			}
+
+			terms[key] = v
+		}
+	}
+
+	return terms
+}
+
+/*****************************************************************************************************************/
+
+// getFloatOrDefault returns the floating point value of the keyword, or the default value if it is absent.
+func getFloatOrDefault(header *fitsio.Header, key string, value float64) float64 {
+	if v, ok := header.Float(key); ok {
+		return v
+	}
+
+	return value
+}
+
+/*****************************************************************************************************************/
+
+// ToFITSHeader writes the WCS as the standard FITS WCS keywords, in the order written by astropy, including the
+// SIP distortion polynomials of a TAN-SIP projection and the PVi_k coefficients of a TPV projection. The optional
+// keywords, e.g., EQUINOX for ICRS coordinates, are omitted when they are unset. The 0-based reference pixel of the
+// WCS is written as the 1-based FITS reference pixel.
+func (wcs *WCS) ToFITSHeader() *fitsio.Header {
+	header := fitsio.NewHeader()
+
+	axes := wcs.WCAXES
+
+	if axes == 0 {
+		axes = 2
+	}
+
+	header.Set("WCSAXES", axes, "Number of coordinate axes")
+	// The 0-based reference pixel of the WCS is written as the 1-based FITS reference pixel:
+	header.Set("CRPIX1", wcs.CRPIX1+1, "Pixel coordinate of reference point")
+	header.Set("CRPIX2", wcs.CRPIX2+1, "Pixel coordinate of reference point")
+	header.Set("CD1_1", wcs.CD1_1, "Coordinate transformation matrix element")
+	header.Set("CD1_2", wcs.CD1_2, "Coordinate transformation matrix element")
+	header.Set("CD2_1", wcs.CD2_1, "Coordinate transformation matrix element")
+	header.Set("CD2_2", wcs.CD2_2, "Coordinate transformation matrix element")
+	header.Set("CDELT1", wcs.CDELT1, "[deg] Coordinate increment at reference point")
+	header.Set("CDELT2", wcs.CDELT2, "[deg] Coordinate increment at reference point")
+	header.Set("CUNIT1", getStringOrDefault(wcs.CUNIT1, "deg"), "Units of coordinate increment and value")
+	header.Set("CUNIT2", getStringOrDefault(wcs.CUNIT2, "deg"), "Units of coordinate increment and value")
+	header.Set("CTYPE1", wcs.CTYPE1, getCTypeComment(wcs.CTYPE1))
+	header.Set("CTYPE2", wcs.CTYPE2, getCTypeComment(wcs.CTYPE2))
+	header.Set("CRVAL1", wcs.CRVAL1, "[deg] Coordinate value at reference point")
+	header.Set("CRVAL2", wcs.CRVAL2, "[deg] Coordinate value at reference point")
+
+	if wcs.LONPOLE != 0 {
+		header.Set("LONPOLE", wcs.LONPOLE, "[deg] Native longitude of celestial pole")
+	}
+
+	if wcs.LATPOLE != 0 {
+		header.Set("LATPOLE", wcs.LATPOLE, "[deg] Native latitude of celestial pole")
+	}
+
+	if wcs.DATEOBS != "" {
+		header.Set("DATE-OBS", wcs.DATEOBS, "ISO-8601 time of observation")
+	}
+
+	if wcs.MJDOBS != 0 {
+		header.Set("MJD-OBS", wcs.MJDOBS, "[d] MJD of observation")
+	}
+
+	if wcs.RADESYS != "" {
+		header.Set("RADESYS", wcs.RADESYS, "Equatorial coordinate system")
+	}
+
+	if wcs.EQUINOX != 0 {
+		header.Set("EQUINOX", wcs.EQUINOX, "[yr] Equinox of equatorial coordinates")
+	}
+
+	writeSIPPolynomial(header, "A", wcs.FSIP.AOrder, wcs.FSIP.APower, "detector to sky")
+	writeSIPPolynomial(header, "B", wcs.FSIP.BOrder, wcs.FSIP.BPower, "detector to sky")
+	writeSIPPolynomial(header, "AP", wcs.ISIP.APOrder, wcs.ISIP.APPower, "sky to detector")
+	writeSIPPolynomial(header, "BP", wcs.ISIP.BPOrder, wcs.ISIP.BPPower, "sky to detector")
+
+	writeTPVPolynomial(header, 1, wcs.TPV.PV1)
+	writeTPVPolynomial(header, 2, wcs.TPV.PV2)
+
+	return header
+}
+
+/*****************************************************************************************************************/
+
+// UpdateFITSHeader replaces the WCS of the FITS header with the WCS, removing every existing WCS keyword, e.g., a PC
+// matrix, CROTA2, or SIP and TPV distortion polynomials of a previous solution, before writing the keywords of
+// ToFITSHeader, such that the header describes the WCS alone. Keywords other than those of the WCS are retained.
+func (wcs *WCS) UpdateFITSHeader(header *fitsio.Header) {
+	cards := header.Cards[:0]
+
+	for _, card := range header.Cards {
+		if !wcsKeywords.MatchString(card.Key) {
+			cards = append(cards, card)
+		}
+	}
+
+	header.Cards = cards
+
+	for _, card := range wcs.ToFITSHeader().Cards {
+		header.Set(card.Key, card.Value, card.Comment)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// writeSIPPolynomial writes the order and coefficients of a SIP polynomial, ordered by the power of u and then v.
+func writeSIPPolynomial(header *fitsio.Header, prefix string, order int, terms map[string]float64, direction string) {
+	if order <= 0 || len(terms) == 0 {
+		return
+	}
+
+	axis := 0
+
+	if strings.HasPrefix(prefix, "B") {
+		axis = 1
+	}
+
+	header.Set(prefix+"_ORDER", order, fmt.Sprintf("SIP polynomial order, axis %d, %s", axis, direction))
+
+	for p := 0; p <= order; p++ {
+		for q := 0; q <= order-p; q++ {
+			key := fmt.Sprintf("%s_%d_%d", prefix, p, q)
+
+			if v, ok := terms[key]; ok {
+				header.Set(key, v, "SIP distortion coefficient")
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// writeTPVPolynomial writes the PVi_k coefficients of a TPV polynomial, ordered by k.
+func writeTPVPolynomial(header *fitsio.Header, axis int, terms map[string]float64) {
+	for k := range tpvTerms {
+		key := fmt.Sprintf("PV%d_%d", axis, k)
+
+		if v, ok := terms[key]; ok {
+			header.Set(key, v, "TPV distortion coefficient")
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// getCTypeComment describes the coordinate axis and projection of a CTYPE, e.g., "Right ascension, gnomonic
+// projection" for "RA---TAN-SIP".
+func getCTypeComment(ctype string) string {
+	if len(ctype) < 8 {
+		return "Coordinate type code"
+	}
+
+	axis, ok := ctypeAxes[strings.TrimRight(ctype[:4], "-")]
+	if !ok {
+		return "Coordinate type code"
+	}
+
+	projection, ok := ctypeProjections[ctype[5:8]]
+	if !ok {
+		return axis
+	}
+
+	return fmt.Sprintf("%s, %s projection", axis, projection)
+}
+
+/*****************************************************************************************************************/
+
+// getStringOrDefault returns the value, or the default value if it is empty.
+func getStringOrDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+
+	return value
+}
+
+/*****************************************************************************************************************/
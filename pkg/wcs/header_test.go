@@ -0,0 +1,413 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// readHeaderFile reads a text header of newline separated cards, as written by astropy's Header.totextfile:
+func readHeaderFile(t *testing.T, name string) *fitsio.Header {
+	t.Helper()
+
+	text, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+
+	header := fitsio.NewHeader()
+
+	for _, line := range strings.Split(strings.TrimRight(string(text), "\n"), "\n") {
+		card, err := fitsio.ParseCard(line)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", line, err)
+		}
+
+		header.Cards = append(header.Cards, card)
+	}
+
+	return header
+}
+
+/*****************************************************************************************************************/
+
+func TestFromFITSHeaderReadsAstropyReferenceHeaders(t *testing.T) {
+	tests := []struct {
+		name            string
+		wcsaxes         int
+		ctype1          string
+		cunit1, cunit2  string
+		crpix1, crpix2  float64
+		crval1, crval2  float64
+		cd              [4]float64
+		cdelt1, cdelt2  float64
+		radesys         string
+		equinox         float64
+		lonpole         float64
+		latpole         float64
+		dateobs         string
+		mjd             float64
+		aOrder, apOrder int
+		bOrder, bpOrder int
+		aTerms, apTerms int
+		bTerms, bpTerms int
+	}{
+		{
+			name: "tan.hdr",
+			// The 1-based FITS reference pixel (2048.5, 1536.5) is the 0-based reference pixel (2047.5, 1535.5):
+			crpix1: 2047.5, crpix2: 1535.5,
+			cd:      [4]float64{-0.0003045, 0.0000262, 0.0000262, 0.0003045},
+			radesys: "ICRS",
+			latpole: -5.391111111111111,
+			mjd:     60640.72222222222,
+			ctype1:  "RA---TAN",
+			cdelt1:  -math.Hypot(0.0003045, 0.0000262), cdelt2: math.Hypot(0.0000262, 0.0003045),
+			dateobs: "2024-11-26T17:20:00.000",
+			lonpole: 180,
+			crval1:  83.82208333333333, crval2: -5.391111111111111,
+			cunit1: "deg", cunit2: "deg",
+			wcsaxes: 2,
+		},
+		{
+			name:   "tan-sip.hdr",
+			crpix1: 1023, crpix2: 767,
+			cd:      [4]float64{-0.000277, -0.0000041, -0.0000041, 0.000277},
+			radesys: "FK5",
+			equinox: 2000,
+			latpole: 47.19527777777778,
+			mjd:     60052.94529513889,
+			aTerms:  5, apTerms: 5, aOrder: 3, apOrder: 3,
+			bTerms: 5, bpTerms: 5, bOrder: 3, bpOrder: 3,
+			ctype1: "RA---TAN-SIP",
+			cdelt1: -math.Hypot(0.000277, 0.0000041), cdelt2: math.Hypot(0.0000041, 0.000277),
+			dateobs: "2023-04-18T22:41:13.500",
+			lonpole: 180,
+			crval1:  202.4695833333333, crval2: 47.19527777777778,
+			cunit1: "deg", cunit2: "deg",
+			wcsaxes: 2,
+		},
+		{
+			name:   "fk4-pc.hdr",
+			crpix1: 511, crpix2: 511,
+			// The CD matrix is the PC matrix scaled by CDELT, i.e., a rotation of 10 degrees:
+			cd: [4]float64{
+				-0.000555555555556 * 0.9848077530122, -0.000555555555556 * -0.1736481776669,
+				0.000555555555556 * 0.1736481776669, 0.000555555555556 * 0.9848077530122,
+			},
+			radesys: "FK4",
+			equinox: 1950,
+			latpole: 41.26875,
+			ctype1:  "RA---TAN",
+			cdelt1:  -0.000555555555556, cdelt2: 0.000555555555556,
+			lonpole: 180,
+			crval1:  10.684708333333333, crval2: 41.26875,
+			cunit1: "deg", cunit2: "deg",
+			wcsaxes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		w, err := FromFITSHeader(readHeaderFile(t, tt.name))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		floats := []struct {
+			key       string
+			got, want float64
+		}{
+			{"CRPIX1", w.CRPIX1, tt.crpix1},
+			{"CRPIX2", w.CRPIX2, tt.crpix2},
+			{"CRVAL1", w.CRVAL1, tt.crval1},
+			{"CRVAL2", w.CRVAL2, tt.crval2},
+			{"CD1_1", w.CD1_1, tt.cd[0]},
+			{"CD1_2", w.CD1_2, tt.cd[1]},
+			{"CD2_1", w.CD2_1, tt.cd[2]},
+			{"CD2_2", w.CD2_2, tt.cd[3]},
+			{"CDELT1", w.CDELT1, tt.cdelt1},
+			{"CDELT2", w.CDELT2, tt.cdelt2},
+			{"EQUINOX", w.EQUINOX, tt.equinox},
+			{"LONPOLE", w.LONPOLE, tt.lonpole},
+			{"LATPOLE", w.LATPOLE, tt.latpole},
+			{"MJD-OBS", w.MJDOBS, tt.mjd},
+		}
+
+		for _, f := range floats {
+			if math.Abs(f.got-f.want) > 1e-12*math.Max(1, math.Abs(f.want)) {
+				t.Errorf("%s: expected %s = %v, got %v", tt.name, f.key, f.want, f.got)
+			}
+		}
+
+		strs := []struct {
+			key       string
+			got, want string
+		}{
+			{"CTYPE1", w.CTYPE1, tt.ctype1},
+			{"CUNIT1", w.CUNIT1, tt.cunit1},
+			{"CUNIT2", w.CUNIT2, tt.cunit2},
+			{"RADESYS", w.RADESYS, tt.radesys},
+			{"DATE-OBS", w.DATEOBS, tt.dateobs},
+		}
+
+		for _, s := range strs {
+			if s.got != s.want {
+				t.Errorf("%s: expected %s = %q, got %q", tt.name, s.key, s.want, s.got)
+			}
+		}
+
+		if w.WCAXES != tt.wcsaxes {
+			t.Errorf("%s: expected WCSAXES = %d, got %d", tt.name, tt.wcsaxes, w.WCAXES)
+		}
+
+		if w.FSIP.AOrder != tt.aOrder || len(w.FSIP.APower) != tt.aTerms || w.FSIP.BOrder != tt.bOrder || len(w.FSIP.BPower) != tt.bTerms {
+			t.Errorf("%s: expected %d A and %d B forward SIP terms, got %+v", tt.name, tt.aTerms, tt.bTerms, w.FSIP)
+		}
+
+		if w.ISIP.APOrder != tt.apOrder || len(w.ISIP.APPower) != tt.apTerms || w.ISIP.BPOrder != tt.bpOrder || len(w.ISIP.BPPower) != tt.bpTerms {
+			t.Errorf("%s: expected %d AP and %d BP inverse SIP terms, got %+v", tt.name, tt.apTerms, tt.bpTerms, w.ISIP)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestToFITSHeaderRoundTripsAstropyReferenceHeaders(t *testing.T) {
+	for _, name := range []string{"tan.hdr", "tan-sip.hdr", "fk4-pc.hdr"} {
+		reference := readHeaderFile(t, name)
+
+		w, err := FromFITSHeader(reference)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		header := w.ToFITSHeader()
+
+		// Serialize the header to its 2880 byte blocks, and read it back:
+		parsed, _, err := fitsio.ReadHeader(strings.NewReader(string(header.Bytes())))
+		if err != nil {
+			t.Fatalf("%s: failed to read the serialized header: %v", name, err)
+		}
+
+		roundtrip, err := FromFITSHeader(parsed)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		if !reflect.DeepEqual(w, roundtrip) {
+			t.Errorf("%s: expected the WCS to round trip, got %+v, want %+v", name, roundtrip, w)
+		}
+
+		// Every keyword that astropy wrote, which the WCS also writes, has the same value:
+		for _, card := range reference.Cards {
+			value, ok := parsed.Get(card.Key)
+			if !ok {
+				// The PC matrix is written as the equivalent CD matrix, and the MJDREF of wcslib is implied:
+				if !strings.HasPrefix(card.Key, "PC") && card.Key != "MJDREF" {
+					t.Errorf("%s: expected %s to be written", name, card.Key)
+				}
+				continue
+			}
+
+			switch want := card.Value.(type) {
+			case float64:
+				got, _ := parsed.Float(card.Key)
+
+				if math.Abs(got-want) > 1e-12*math.Max(1, math.Abs(want)) {
+					t.Errorf("%s: expected %s = %v, got %v", name, card.Key, want, got)
+				}
+			default:
+				if value != want {
+					t.Errorf("%s: expected %s = %v, got %v", name, card.Key, want, value)
+				}
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFromFITSHeaderDefaults(t *testing.T) {
+	header := fitsio.NewHeader()
+
+	header.Set("CTYPE1", "RA---TAN", "")
+	header.Set("CTYPE2", "DEC--TAN", "")
+	header.Set("CRPIX1", 100.0, "")
+	header.Set("CRPIX2", 200.0, "")
+	header.Set("CRVAL1", 150.0, "")
+	header.Set("CRVAL2", 2.0, "")
+	header.Set("CDELT1", -0.001, "")
+	header.Set("CDELT2", 0.001, "")
+	header.Set("CROTA2", 30.0, "")
+	header.Set("EQUINOX", 1950.0, "")
+	header.Set("DATE-OBS", "2000-01-01T12:00:00", "")
+
+	w, err := FromFITSHeader(header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The AIPS convention of CDELT and CROTA2 is converted to the equivalent CD matrix:
+	rho := 30 * math.Pi / 180
+
+	cd := [4]float64{-0.001 * math.Cos(rho), -0.001 * math.Sin(rho), -0.001 * math.Sin(rho), 0.001 * math.Cos(rho)}
+
+	for i, got := range []float64{w.CD1_1, w.CD1_2, w.CD2_1, w.CD2_2} {
+		if math.Abs(got-cd[i]) > 1e-15 {
+			t.Errorf("expected CD element %d = %v, got %v", i, cd[i], got)
+		}
+	}
+
+	// An equinox before 1984 implies the FK4 reference frame:
+	if w.RADESYS != "FK4" || w.EQUINOX != 1950 {
+		t.Errorf("expected RADESYS = FK4 and EQUINOX = 1950, got %s and %v", w.RADESYS, w.EQUINOX)
+	}
+
+	if w.LONPOLE != 180 || w.LATPOLE != 2 {
+		t.Errorf("expected LONPOLE = 180 and LATPOLE = 2, got %v and %v", w.LONPOLE, w.LATPOLE)
+	}
+
+	// The modified Julian date is derived from DATE-OBS, where J2000.0 is MJD 51544.5:
+	if math.Abs(w.MJDOBS-51544.5) > 1e-8 {
+		t.Errorf("expected MJD-OBS = 51544.5, got %v", w.MJDOBS)
+	}
+
+	if mjd := GetModifiedJulianDate(time.Date(2024, 11, 26, 17, 20, 0, 0, time.UTC)); math.Abs(mjd-60640.72222222222) > 1e-8 {
+		t.Errorf("expected an MJD of 60640.72222222222, got %v", mjd)
+	}
+
+	header.Delete("CRVAL2")
+
+	if _, err := FromFITSHeader(header); err == nil {
+		t.Errorf("expected an error when CRVAL2 is missing")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFromFITSHeaderMatchesAstropyPixelToWorldCoordinates(t *testing.T) {
+	// The reference values of astropy's all_pix2world(x, y, 0), i.e., for 0-based pixels, at the reference pixel of
+	// each header, which is at CRPIX - 1, as the TAN projection retains the linear model away from the reference pixel:
+	tests := []struct {
+		name    string
+		x, y    float64
+		ra, dec float64
+	}{
+		{"tan.hdr", 2047.5, 1535.5, 83.82208333333333, -5.391111111111111},
+		{"tan-sip.hdr", 1023, 767, 202.4695833333333, 47.19527777777778},
+		{"fk4-pc.hdr", 511, 511, 10.684708333333333, 41.26875},
+	}
+
+	for _, tt := range tests {
+		w, err := FromFITSHeader(readHeaderFile(t, tt.name))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		ra, dec := w.PixelToWorldCoordinate(tt.x, tt.y)
+
+		if math.Abs(ra-tt.ra) > 1e-9 || math.Abs(dec-tt.dec) > 1e-9 {
+			t.Errorf("%s: expected (%v, %v) at (%v, %v), got (%v, %v)", tt.name, tt.ra, tt.dec, tt.x, tt.y, ra, dec)
+		}
+
+		// The pixels across the image round trip through the world coordinates, inverting any SIP distortions:
+		for _, pixel := range [][2]float64{{0, 0}, {tt.x, tt.y}, {2 * tt.x, 2 * tt.y}, {tt.x / 4, 1.8 * tt.y}} {
+			ra, dec := w.PixelToWorldCoordinate(pixel[0], pixel[1])
+
+			if x, y := w.WorldCoordinateToPixel(ra, dec); math.Abs(x-pixel[0]) > 1e-6 || math.Abs(y-pixel[1]) > 1e-6 {
+				t.Errorf("%s: expected %v to round trip, got (%v, %v)", tt.name, pixel, x, y)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestUpdateFITSHeaderReplacesAnExistingWCS(t *testing.T) {
+	// A previously solved header, with a PC matrix and CDELT, rather than a CD matrix, third order forward and inverse
+	// SIP polynomials, a deprecated CROTA2 and the FK5 reference frame:
+	header := readHeaderFile(t, "tan-sip.hdr")
+
+	for _, key := range []string{"CD1_1", "CD1_2", "CD2_1", "CD2_2"} {
+		header.Delete(key)
+	}
+
+	header.Set("CDELT1", -0.000277, "")
+	header.Set("CDELT2", 0.000277, "")
+	header.Set("PC1_1", 1.0, "")
+	header.Set("PC1_2", 0.0148, "")
+	header.Set("PC2_1", -0.0148, "")
+	header.Set("PC2_2", 1.0, "")
+	header.Set("CROTA2", 0.85, "")
+	header.Set("OBJECT", "M 51", "")
+
+	// The re-solved WCS has a CD matrix and only second order forward SIP polynomials:
+	w := NewWorldCoordinateSystem(1000, 750, WCSParams{
+		Projection: RADEC_TANSIP,
+		AffineParams: transform.Affine2DParameters{
+			A: -0.000275, B: -0.0000039, C: 202.47,
+			D: -0.0000039, E: 0.000275, F: 47.2,
+		},
+		SIPForwardParams: transform.SIP2DForwardParameters{
+			AOrder: 2,
+			APower: map[string]float64{"A_2_0": 2.4e-6, "A_1_1": -1.1e-6},
+			BOrder: 2,
+			BPower: map[string]float64{"B_0_2": 2.1e-6},
+		},
+	})
+
+	w.UpdateFITSHeader(header)
+
+	// No keyword of the previous WCS remains, which a reader might otherwise combine with the new solution:
+	for _, key := range []string{"PC1_1", "PC1_2", "PC2_1", "PC2_2", "CROTA2", "EQUINOX", "A_3_0", "A_1_2", "B_0_3", "B_2_1", "AP_ORDER", "AP_1_0", "BP_ORDER", "BP_0_1"} {
+		if header.Has(key) {
+			t.Errorf("expected the stale %s keyword to be removed", key)
+		}
+	}
+
+	// The keywords other than those of the WCS are retained:
+	if object, ok := header.String("OBJECT"); !ok || object != "M 51" {
+		t.Errorf("expected OBJECT = 'M 51' to be retained, got %q", object)
+	}
+
+	if !header.Has("DATE-OBS") {
+		t.Errorf("expected DATE-OBS to be retained")
+	}
+
+	got, err := FromFITSHeader(header)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.CD1_1 != w.CD1_1 || got.CD1_2 != w.CD1_2 || got.CD2_1 != w.CD2_1 || got.CD2_2 != w.CD2_2 {
+		t.Errorf("expected the CD matrix of the new solution, got %v %v %v %v", got.CD1_1, got.CD1_2, got.CD2_1, got.CD2_2)
+	}
+
+	if got.RADESYS != "ICRS" || got.EQUINOX != 0 {
+		t.Errorf("expected the ICRS reference frame without an equinox, got %s and %v", got.RADESYS, got.EQUINOX)
+	}
+
+	if !reflect.DeepEqual(got.FSIP, w.FSIP) || len(got.ISIP.APPower) != 0 || len(got.ISIP.BPPower) != 0 {
+		t.Errorf("expected only the SIP polynomials of the new solution, got %+v and %+v", got.FSIP, got.ISIP)
+	}
+}
+
+/*****************************************************************************************************************/
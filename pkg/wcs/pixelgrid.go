@@ -0,0 +1,262 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// The transformations of the pixel grid follow the convention of the extracted stars, where the centres of the pixels
+// are at the integer coordinates 0 to width - 1 and 0 to height - 1. Each transformation maps a pixel (x', y') of the
+// transformed image onto the pixel (x, y) = M·(x', y') + t of the original image, so the transformed WCS is found by
+// substitution without re-solving: the reference pixel is moved to M⁻¹·(CRPIX - t), the CD matrix becomes CD·M, and
+// a (forward or inverse) SIP polynomial S becomes M⁻¹·S(M·d).
+
+/*****************************************************************************************************************/
+
+// pixelTransformation is the affine map (x, y) = M·(x', y') + t from the transformed to the original pixels.
+type pixelTransformation struct {
+	m11, m12 float64
+	m21, m22 float64
+	tx, ty   float64
+}
+
+/*****************************************************************************************************************/
+
+// Crop returns the WCS of the image cropped such that the pixel (x0, y0) of the original image is its pixel (0, 0).
+func (wcs *WCS) Crop(x0, y0 int) WCS {
+	w, _ := wcs.applyPixelTransformation(pixelTransformation{m11: 1, m22: 1, tx: float64(x0), ty: float64(y0)})
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+// Bin returns the WCS of the image binned by the given factor, where the binned pixel (0, 0) is the mean of the
+// original pixels (0, 0) to (factor - 1, factor - 1), and partial bins at the right and bottom edges are discarded.
+func (wcs *WCS) Bin(factor int) (WCS, error) {
+	if factor < 1 {
+		return WCS{}, fmt.Errorf("invalid binning factor: %d", factor)
+	}
+
+	f := float64(factor)
+
+	return wcs.applyPixelTransformation(pixelTransformation{m11: f, m22: f, tx: (f - 1) / 2, ty: (f - 1) / 2})
+}
+
+/*****************************************************************************************************************/
+
+// Rotate90 returns the WCS of an image of the given dimensions rotated by the given number of quarter turns, where a
+// positive quarter turn rotates the +x axis of the original image onto the +y axis of the rotated image.
+func (wcs *WCS) Rotate90(width, height int, turns int) WCS {
+	w, h := float64(width), float64(height)
+
+	var t pixelTransformation
+
+	switch ((turns % 4) + 4) % 4 {
+	case 0:
+		t = pixelTransformation{m11: 1, m22: 1}
+	case 1:
+		// The rotated pixel (x', y') is the original pixel (y', h - 1 - x'):
+		t = pixelTransformation{m12: 1, m21: -1, ty: h - 1}
+	case 2:
+		// The rotated pixel (x', y') is the original pixel (w - 1 - x', h - 1 - y'):
+		t = pixelTransformation{m11: -1, m22: -1, tx: w - 1, ty: h - 1}
+	case 3:
+		// The rotated pixel (x', y') is the original pixel (w - 1 - y', x'):
+		t = pixelTransformation{m12: -1, m21: 1, tx: w - 1}
+	}
+
+	rotated, _ := wcs.applyPixelTransformation(t)
+
+	return rotated
+}
+
+/*****************************************************************************************************************/
+
+// FlipHorizontal returns the WCS of an image of the given width mirrored left to right, i.e., about its y axis.
+func (wcs *WCS) FlipHorizontal(width int) WCS {
+	w, _ := wcs.applyPixelTransformation(pixelTransformation{m11: -1, m22: 1, tx: float64(width) - 1})
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+// FlipVertical returns the WCS of an image of the given height mirrored top to bottom, i.e., about its x axis.
+func (wcs *WCS) FlipVertical(height int) WCS {
+	w, _ := wcs.applyPixelTransformation(pixelTransformation{m11: 1, m22: -1, ty: float64(height) - 1})
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+// applyPixelTransformation returns the WCS of the transformed pixel grid, where (x, y) = M·(x', y') + t.
+func (wcs *WCS) applyPixelTransformation(t pixelTransformation) (WCS, error) {
+	det := t.m11*t.m22 - t.m12*t.m21
+
+	if det == 0 {
+		return WCS{}, errors.New("the pixel transformation is singular")
+	}
+
+	// The inverse of M:
+	i11, i12 := t.m22/det, -t.m12/det
+	i21, i22 := -t.m21/det, t.m11/det
+
+	w := *wcs
+
+	// The reference pixel of the transformed image, M⁻¹·(CRPIX - t):
+	dx, dy := wcs.CRPIX1-t.tx, wcs.CRPIX2-t.ty
+
+	w.CRPIX1 = i11*dx + i12*dy
+	w.CRPIX2 = i21*dx + i22*dy
+
+	// The CD matrix of the transformed image, CD·M:
+	w.CD1_1 = wcs.CD1_1*t.m11 + wcs.CD1_2*t.m21
+	w.CD1_2 = wcs.CD1_1*t.m12 + wcs.CD1_2*t.m22
+	w.CD2_1 = wcs.CD2_1*t.m11 + wcs.CD2_2*t.m21
+	w.CD2_2 = wcs.CD2_1*t.m12 + wcs.CD2_2*t.m22
+
+	w.CDELT1 = -math.Sqrt(w.CD1_1*w.CD1_1 + w.CD2_1*w.CD2_1)
+	w.CDELT2 = math.Sqrt(w.CD1_2*w.CD1_2 + w.CD2_2*w.CD2_2)
+
+	// The SIP polynomials of the transformed image, M⁻¹·S(M·d), where the TPV polynomials are unchanged, as they are
+	// functions of the intermediate world coordinates, CD·d, which are preserved:
+	a, b := transformSIPPolynomials(wcs.FSIP.APower, wcs.FSIP.BPower, "A", "B", t)
+
+	w.FSIP = transform.SIP2DForwardParameters{
+		AOrder: wcs.FSIP.AOrder,
+		APower: a,
+		BOrder: wcs.FSIP.BOrder,
+		BPower: b,
+	}
+
+	ap, bp := transformSIPPolynomials(wcs.ISIP.APPower, wcs.ISIP.BPPower, "AP", "BP", t)
+
+	w.ISIP = transform.SIP2DInverseParameters{
+		APOrder: wcs.ISIP.APOrder,
+		APPower: ap,
+		BPOrder: wcs.ISIP.BPOrder,
+		BPPower: bp,
+	}
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
+
+// transformSIPPolynomials substitutes d = M·d' into the pair of SIP polynomials (U, V), and applies M⁻¹ to their
+// values, returning the coefficients of the transformed pair keyed by their FITS SIP names.
+func transformSIPPolynomials(
+	u, v map[string]float64,
+	prefixU, prefixV string,
+	t pixelTransformation,
+) (map[string]float64, map[string]float64) {
+	if len(u) == 0 && len(v) == 0 {
+		return u, v
+	}
+
+	det := t.m11*t.m22 - t.m12*t.m21
+
+	// Expand each polynomial in the transformed pixel offsets (x', y'):
+	su := substituteSIPPolynomial(u, prefixU, t)
+	sv := substituteSIPPolynomial(v, prefixV, t)
+
+	terms := map[sipTerm]bool{}
+
+	for term := range su {
+		terms[term] = true
+	}
+
+	for term := range sv {
+		terms[term] = true
+	}
+
+	tu := make(map[string]float64, len(terms))
+	tv := make(map[string]float64, len(terms))
+
+	for term := range terms {
+		// Apply the inverse of M to the values of the pair of polynomials:
+		cu := (t.m22*su[term] - t.m12*sv[term]) / det
+		cv := (-t.m21*su[term] + t.m11*sv[term]) / det
+
+		if u != nil {
+			tu[fmt.Sprintf("%s_%d_%d", prefixU, term.p, term.q)] = cu
+		}
+
+		if v != nil {
+			tv[fmt.Sprintf("%s_%d_%d", prefixV, term.p, term.q)] = cv
+		}
+	}
+
+	return tu, tv
+}
+
+/*****************************************************************************************************************/
+
+// substituteSIPPolynomial expands the SIP polynomial, Σ c·x^p·y^q, at (x, y) = M·(x', y'), i.e., with
+// x = m11·x' + m12·y' and y = m21·x' + m22·y', into its coefficients in (x', y').
+func substituteSIPPolynomial(terms map[string]float64, prefix string, t pixelTransformation) map[sipTerm]float64 {
+	expanded := map[sipTerm]float64{}
+
+	for key, coeff := range terms {
+		p, q, err := parseSIPTerm(key, prefix)
+		if err != nil {
+			continue
+		}
+
+		// Ensure that zero coefficients retain their terms, as written in the FITS header:
+		expanded[sipTerm{p: p, q: q}] += 0
+
+		// (m11·x' + m12·y')^p = Σ_i C(p, i) m11^(p-i) m12^i x'^(p-i) y'^i, and likewise for y^q:
+		for i := 0; i <= p; i++ {
+			ci := coeff * binomial(p, i) * math.Pow(t.m11, float64(p-i)) * math.Pow(t.m12, float64(i))
+
+			if ci == 0 {
+				continue
+			}
+
+			for j := 0; j <= q; j++ {
+				cj := binomial(q, j) * math.Pow(t.m21, float64(q-j)) * math.Pow(t.m22, float64(j))
+
+				if cj == 0 {
+					continue
+				}
+
+				expanded[sipTerm{p: p - i + q - j, q: i + j}] += ci * cj
+			}
+		}
+	}
+
+	return expanded
+}
+
+/*****************************************************************************************************************/
+
+// binomial returns the binomial coefficient C(n, k):
+func binomial(n, k int) float64 {
+	c := 1.0
+
+	for i := 1; i <= k; i++ {
+		c = c * float64(n-k+i) / float64(i)
+	}
+
+	return c
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,136 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// newPixelGridTestWCS creates a 2048 × 1536 pixel TAN-SIP solution with both forward and inverse distortions:
+func newPixelGridTestWCS(t testing.TB) WCS {
+	w := NewWorldCoordinateSystem(1024, 768, WCSParams{
+		Projection: RADEC_TANSIP,
+		AffineParams: transform.Affine2DParameters{
+			A: -0.000263, B: 0.0000031, C: 150.12,
+			D: 0.0000029, E: 0.000263, F: 2.21,
+		},
+		SIPForwardParams: transform.SIP2DForwardParameters{
+			AOrder: 3,
+			APower: map[string]float64{"A_2_0": 4e-6, "A_0_2": -2e-6, "A_1_1": 1e-6, "A_3_0": 1e-9, "A_1_2": 2e-9},
+			BOrder: 3,
+			BPower: map[string]float64{"B_2_0": -1e-6, "B_1_1": 3e-6, "B_0_3": -1.5e-9, "B_2_1": 1e-9},
+		},
+	})
+
+	inverse, _, err := w.FitInverseSIP(2048, 1536, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	w.ISIP = inverse
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+func TestPixelGridTransformations(t *testing.T) {
+	w := newPixelGridTestWCS(t)
+
+	binned, err := w.Bin(2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		wcs  WCS
+		// The pixel of the original image of the pixel (x', y') of the transformed image:
+		original func(x, y float64) (float64, float64)
+	}{
+		{"crop", w.Crop(100, 200), func(x, y float64) (float64, float64) { return x + 100, y + 200 }},
+		{"bin", binned, func(x, y float64) (float64, float64) { return 2*x + 0.5, 2*y + 0.5 }},
+		{"rotate 0", w.Rotate90(2048, 1536, 4), func(x, y float64) (float64, float64) { return x, y }},
+		{"rotate 90", w.Rotate90(2048, 1536, 1), func(x, y float64) (float64, float64) { return y, 1535 - x }},
+		{"rotate 180", w.Rotate90(2048, 1536, 2), func(x, y float64) (float64, float64) { return 2047 - x, 1535 - y }},
+		{"rotate 270", w.Rotate90(2048, 1536, -1), func(x, y float64) (float64, float64) { return 2047 - y, x }},
+		{"flip horizontal", w.FlipHorizontal(2048), func(x, y float64) (float64, float64) { return 2047 - x, y }},
+		{"flip vertical", w.FlipVertical(1536), func(x, y float64) (float64, float64) { return x, 1535 - y }},
+	}
+
+	for _, tt := range tests {
+		for _, pixel := range [][2]float64{{0, 0}, {700, 300}, {1000, 1000}, {50, 1400}, {1500, 20}} {
+			x, y := tt.original(pixel[0], pixel[1])
+
+			want := w.PixelToEquatorialCoordinate(x, y)
+			got := tt.wcs.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+			if s := getAngularSeparation(want.RA, want.Dec, got.RA, got.Dec); s > 1e-9 {
+				t.Errorf("%s: expected %v to map to (%v, %v), got (%v, %v)", tt.name, pixel, want.RA, want.Dec, got.RA, got.Dec)
+			}
+
+			// The transformed inverse SIP polynomials retain the accuracy of the original inverse polynomials:
+			px, py := tt.wcs.EquatorialCoordinateToPixel(want.RA, want.Dec)
+
+			if math.Hypot(px-pixel[0], py-pixel[1]) > 1e-6 {
+				t.Errorf("%s: expected the pixel %v, got (%v, %v)", tt.name, pixel, px, py)
+			}
+		}
+
+		if math.Abs(tt.wcs.CDELT1) != math.Hypot(tt.wcs.CD1_1, tt.wcs.CD2_1) {
+			t.Errorf("%s: expected CDELT1 to be consistent with the CD matrix", tt.name)
+		}
+	}
+
+	// Binning scales the pixels, and each SIP coefficient of order n by factor^(n-1):
+	if math.Abs(binned.CD1_1-2*w.CD1_1) > 1e-15 || math.Abs(binned.FSIP.APower["A_2_0"]-2*4e-6) > 1e-18 {
+		t.Errorf("expected the binned CD matrix and SIP coefficients to be scaled, got %v and %v", binned.CD1_1, binned.FSIP.APower["A_2_0"])
+	}
+
+	if math.Abs(binned.FSIP.APower["A_3_0"]-4e-9) > 1e-21 {
+		t.Errorf("expected A_3_0 to be scaled by four, got %v", binned.FSIP.APower["A_3_0"])
+	}
+
+	if _, err := w.Bin(0); err == nil {
+		t.Errorf("expected an error for a binning factor of zero")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestPixelGridTransformationsOfSphericalProjections(t *testing.T) {
+	for _, w := range []WCS{newTPVWCS(), newProjectedWCS(RADEC_ZEA, 10, 60)} {
+		rotated := w.Rotate90(2048, 1536, 1)
+
+		flipped := rotated.FlipHorizontal(1536)
+
+		// Rotating by a quarter turn, then flipping horizontally, transposes the image:
+		for _, pixel := range [][2]float64{{0, 0}, {300, 1200}, {1800, 900}} {
+			want := w.PixelToEquatorialCoordinate(pixel[1], pixel[0])
+			got := flipped.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+			if s := getAngularSeparation(want.RA, want.Dec, got.RA, got.Dec); s > 1e-9 {
+				t.Errorf("%s: expected %v to map to (%v, %v), got (%v, %v)", w.CTYPE1, pixel, want.RA, want.Dec, got.RA, got.Dec)
+			}
+		}
+
+		if flipped.TPV.PV1["PV1_7"] != w.TPV.PV1["PV1_7"] {
+			t.Errorf("%s: expected the TPV coefficients to be unchanged", w.CTYPE1)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
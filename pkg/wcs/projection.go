@@ -0,0 +1,530 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/observerly/skysolve/pkg/projection"
+)
+
+/*****************************************************************************************************************/
+
+// The maximum number of iterations, and the convergence threshold (in degrees) of the offset of the reference point,
+// when fitting a spherical projection to point correspondences:
+const (
+	PROJECTION_MAXIMUM_ITERATIONS = 20
+	PROJECTION_CONVERGENCE        = 1e-10
+)
+
+/*****************************************************************************************************************/
+
+// The projection codes whose pixel to equatorial transforms are computed on the sphere, following Calabretta &
+// Greisen (2002), "Representations of celestial coordinates in FITS". The TAN projection retains the linear model,
+// whereas TPV is the gnomonic projection on the sphere, of the distorted intermediate world coordinates:
+var sphericalProjections = map[string]bool{
+	"TPV": true,
+	"SIN": true,
+	"ARC": true,
+	"ZEA": true,
+	"STG": true,
+	"CAR": true,
+}
+
+/*****************************************************************************************************************/
+
+// The projection types of each CLI or configuration name:
+var projectionNames = map[string]CoordinateProjectionType{
+	"tan":     RADEC_TAN,
+	"tan-sip": RADEC_TANSIP,
+	"sin":     RADEC_SIN,
+	"arc":     RADEC_ARC,
+	"zea":     RADEC_ZEA,
+	"stg":     RADEC_STG,
+	"car":     RADEC_CAR,
+}
+
+/*****************************************************************************************************************/
+
+// ParseProjection returns the projection type of the given name, e.g., "tan", "sin", "arc", "zea", "stg" or "car".
+func ParseProjection(name string) (CoordinateProjectionType, error) {
+	projectionType, ok := projectionNames[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return RADEC_TAN, fmt.Errorf("unknown projection: %q", name)
+	}
+
+	return projectionType, nil
+}
+
+/*****************************************************************************************************************/
+
+// IsSpherical reports whether the projection is computed on the sphere, rather than by the linear TAN model.
+func (c CoordinateProjectionType) IsSpherical() bool {
+	return sphericalProjections[getProjectionCode(c.ToCTypes().CType1)]
+}
+
+/*****************************************************************************************************************/
+
+// getProjectionCode returns the three letter projection code of a CTYPE, e.g., "SIN" for "RA---SIN", or "TAN" when
+// the CTYPE has no projection code.
+func getProjectionCode(ctype string) string {
+	if len(ctype) < 8 {
+		return "TAN"
+	}
+
+	return ctype[5:8]
+}
+
+/*****************************************************************************************************************/
+
+// GetProjection returns the projection type of the WCS from its CTYPE1, e.g., RADEC_ZEA for "RA---ZEA", or
+// RADEC_TANSIP for "RA---TAN-SIP".
+func (wcs *WCS) GetProjection() (CoordinateProjectionType, error) {
+	switch code := getProjectionCode(wcs.CTYPE1); {
+	case code == "TPV":
+		return RADEC_TPV, nil
+	case code == "TAN" && strings.HasSuffix(wcs.CTYPE1, "-SIP"):
+		return RADEC_TANSIP, nil
+	default:
+		return ParseProjection(code)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// getNativeReferenceLatitude returns the native latitude θ0 (in degrees) of the reference point of the projection,
+// i.e., the native pole for zenithal projections and the native equator for cylindrical projections, e.g., CAR.
+func getNativeReferenceLatitude(code string) float64 {
+	if code == "CAR" {
+		return 0
+	}
+
+	return 90
+}
+
+/*****************************************************************************************************************/
+
+// getDefaultCelestialPole returns the default LONPOLE and LATPOLE of the projection for a reference declination,
+// where the celestial pole is at a native longitude of 180 degrees unless the reference point is at (or above) θ0:
+func getDefaultCelestialPole(code string, crval2 float64) (lonpole float64, latpole float64) {
+	theta0 := getNativeReferenceLatitude(code)
+
+	lonpole = 180
+
+	if crval2 >= theta0 {
+		lonpole = 0
+	}
+
+	// For zenithal projections, the native latitude of the celestial pole is the declination of the reference point,
+	// otherwise LATPOLE selects the northern of the two possible poles:
+	if theta0 == 90 {
+		return lonpole, crval2
+	}
+
+	return lonpole, 90
+}
+
+/*****************************************************************************************************************/
+
+// isSpherical reports whether the pixel to equatorial transforms of the WCS are computed on the sphere.
+func (wcs *WCS) isSpherical() bool {
+	return sphericalProjections[getProjectionCode(wcs.CTYPE1)]
+}
+
+/*****************************************************************************************************************/
+
+// getNativePole returns the LONPOLE and LATPOLE of the WCS, or the defaults of its projection when both are unset,
+// e.g., for a WCS without the LONPOLE and LATPOLE keywords, as (0, 0) is never the default of any projection:
+func (wcs *WCS) getNativePole() (lonpole float64, latpole float64) {
+	if wcs.LONPOLE == 0 && wcs.LATPOLE == 0 {
+		return getDefaultCelestialPole(getProjectionCode(wcs.CTYPE1), wcs.CRVAL2)
+	}
+
+	return wcs.LONPOLE, wcs.LATPOLE
+}
+
+/*****************************************************************************************************************/
+
+// getCelestialPole returns the celestial coordinates (αp, δp) of the native pole, in degrees, from the reference
+// point, LONPOLE and LATPOLE (Calabretta & Greisen 2002, §2.4).
+func (wcs *WCS) getCelestialPole() (alphap float64, deltap float64) {
+	theta0 := getNativeReferenceLatitude(getProjectionCode(wcs.CTYPE1))
+
+	// For zenithal projections, the native pole is the reference point itself:
+	if theta0 == 90 {
+		return wcs.CRVAL1, wcs.CRVAL2
+	}
+
+	lonpole, latpole := wcs.getNativePole()
+
+	alpha0, delta0 := projection.Radians(wcs.CRVAL1), projection.Radians(wcs.CRVAL2)
+
+	// The native longitude of the reference point, φ0, is zero for every supported projection:
+	dphi := projection.Radians(lonpole)
+
+	sinTheta0, cosTheta0 := math.Sin(projection.Radians(theta0)), math.Cos(projection.Radians(theta0))
+
+	t := math.Atan2(sinTheta0, cosTheta0*math.Cos(dphi))
+
+	u := math.Acos(clamp(math.Sin(delta0)/math.Sqrt(1-cosTheta0*cosTheta0*math.Pow(math.Sin(dphi), 2)), -1, 1))
+
+	// Of the two solutions, select the valid latitude closest to LATPOLE:
+	deltap = math.NaN()
+
+	for _, candidate := range []float64{t + u, t - u} {
+		candidate = projection.Degrees(math.Remainder(candidate, 2*math.Pi))
+
+		if candidate < -90-1e-9 || candidate > 90+1e-9 {
+			continue
+		}
+
+		if math.IsNaN(deltap) || math.Abs(candidate-latpole) < math.Abs(deltap-latpole) {
+			deltap = clamp(candidate, -90, 90)
+		}
+	}
+
+	switch {
+	case math.Abs(deltap-90) < 1e-9:
+		return wcs.CRVAL1 + lonpole - 180, 90
+	case math.Abs(deltap+90) < 1e-9:
+		return wcs.CRVAL1 - lonpole, -90
+	}
+
+	sinDeltap, cosDeltap := math.Sin(projection.Radians(deltap)), math.Cos(projection.Radians(deltap))
+
+	alphap = alpha0 - math.Atan2(
+		math.Sin(dphi)*cosTheta0/math.Cos(delta0),
+		(sinTheta0-sinDeltap*math.Sin(delta0))/(cosDeltap*math.Cos(delta0)),
+	)
+
+	return projection.Degrees(alphap), deltap
+}
+
+/*****************************************************************************************************************/
+
+// nativeToCelestial rotates the native spherical coordinate (φ, θ) to the equatorial coordinate (α, δ), in degrees.
+func (wcs *WCS) nativeToCelestial(phi, theta float64) (ra float64, dec float64) {
+	alphap, deltap := wcs.getCelestialPole()
+
+	lonpole, _ := wcs.getNativePole()
+
+	dphi := projection.Radians(phi - lonpole)
+
+	sinTheta, cosTheta := math.Sin(projection.Radians(theta)), math.Cos(projection.Radians(theta))
+
+	sinDeltap, cosDeltap := math.Sin(projection.Radians(deltap)), math.Cos(projection.Radians(deltap))
+
+	// The components of the unit vector in the celestial frame, where the latitude is found from atan2 rather than
+	// asin, which loses precision for the sub-arcsecond offsets near the reference point of zenithal projections:
+	x := -cosTheta * math.Sin(dphi)
+	y := sinTheta*cosDeltap - cosTheta*sinDeltap*math.Cos(dphi)
+	z := sinTheta*sinDeltap + cosTheta*cosDeltap*math.Cos(dphi)
+
+	ra = alphap + projection.Degrees(math.Atan2(x, y))
+
+	dec = projection.Degrees(math.Atan2(z, math.Hypot(x, y)))
+
+	// Correct the right ascension into the range [0, 360) degrees:
+	ra = math.Mod(ra, 360)
+
+	if ra < 0 {
+		ra += 360
+	}
+
+	return ra, dec
+}
+
+/*****************************************************************************************************************/
+
+// celestialToNative rotates the equatorial coordinate (α, δ) to the native spherical coordinate (φ, θ), in degrees.
+func (wcs *WCS) celestialToNative(ra, dec float64) (phi float64, theta float64) {
+	alphap, deltap := wcs.getCelestialPole()
+
+	dalpha := projection.Radians(ra - alphap)
+
+	sinDelta, cosDelta := math.Sin(projection.Radians(dec)), math.Cos(projection.Radians(dec))
+
+	sinDeltap, cosDeltap := math.Sin(projection.Radians(deltap)), math.Cos(projection.Radians(deltap))
+
+	// The components of the unit vector in the native frame, where the latitude is found from atan2, as above:
+	x := -cosDelta * math.Sin(dalpha)
+	y := sinDelta*cosDeltap - cosDelta*sinDeltap*math.Cos(dalpha)
+	z := sinDelta*sinDeltap + cosDelta*cosDeltap*math.Cos(dalpha)
+
+	lonpole, _ := wcs.getNativePole()
+
+	phi = lonpole + projection.Degrees(math.Atan2(x, y))
+
+	theta = projection.Degrees(math.Atan2(z, math.Hypot(x, y)))
+
+	// Wrap the native longitude into the range [-180, 180) degrees:
+	phi = math.Mod(phi+540, 360) - 180
+
+	return phi, theta
+}
+
+/*****************************************************************************************************************/
+
+// deproject converts the intermediate world coordinate (x, y), in degrees, to the native spherical coordinate
+// (φ, θ) of the projection, returning false when (x, y) lies outside of the projection's boundary.
+func deproject(code string, x, y float64) (phi float64, theta float64, ok bool) {
+	if code == "CAR" {
+		return x, y, y >= -90 && y <= 90
+	}
+
+	r := math.Hypot(x, y)
+
+	// The native longitude of the zenithal projections, where φ = arg(-y, x):
+	phi = 0
+
+	if r != 0 {
+		phi = projection.Degrees(math.Atan2(x, -y))
+	}
+
+	// The radius in radians, i.e., Rθ scaled by π/180:
+	rho := projection.Radians(r)
+
+	switch code {
+	case "SIN":
+		if rho > 1 {
+			return 0, 0, false
+		}
+		theta = projection.Degrees(math.Acos(rho))
+	case "ARC":
+		theta = 90 - r
+	case "ZEA":
+		if rho > 2 {
+			return 0, 0, false
+		}
+		theta = 90 - 2*projection.Degrees(math.Asin(rho/2))
+	case "STG":
+		theta = 90 - 2*projection.Degrees(math.Atan(rho/2))
+	default:
+		theta = projection.Degrees(math.Atan2(1, rho))
+	}
+
+	return phi, theta, theta >= -90
+}
+
+/*****************************************************************************************************************/
+
+// project converts the native spherical coordinate (φ, θ), in degrees, to the intermediate world coordinate (x, y)
+// of the projection, returning false when (φ, θ) cannot be projected, e.g., the far hemisphere of SIN.
+func project(code string, phi, theta float64) (x float64, y float64, ok bool) {
+	if code == "CAR" {
+		return phi, theta, true
+	}
+
+	colatitude := projection.Radians(90 - theta)
+
+	var r float64
+
+	switch code {
+	case "SIN":
+		if theta < 0 {
+			return 0, 0, false
+		}
+		r = projection.Degrees(math.Cos(projection.Radians(theta)))
+	case "ARC":
+		r = 90 - theta
+	case "ZEA":
+		r = projection.Degrees(2 * math.Sin(colatitude/2))
+	case "STG":
+		if theta <= -90 {
+			return 0, 0, false
+		}
+		r = projection.Degrees(2 * math.Tan(colatitude/2))
+	default:
+		if theta <= 0 {
+			return 0, 0, false
+		}
+		r = projection.Degrees(1 / math.Tan(projection.Radians(theta)))
+	}
+
+	x = r * math.Sin(projection.Radians(phi))
+	y = -r * math.Cos(projection.Radians(phi))
+
+	return x, y, true
+}
+
+/*****************************************************************************************************************/
+
+// pixelToSphericalWorldCoordinate converts the (SIP corrected) pixel offset from the reference pixel to the celestial
+// coordinate, via the intermediate world coordinate and the native spherical coordinate:
+func (wcs *WCS) pixelToSphericalWorldCoordinate(deltaX, deltaY float64, d *distortions) (lon float64, lat float64) {
+	code := getProjectionCode(wcs.CTYPE1)
+
+	x := wcs.CD1_1*deltaX + wcs.CD1_2*deltaY
+	y := wcs.CD2_1*deltaX + wcs.CD2_2*deltaY
+
+	if code == "TPV" {
+		x, y = applyTPVPolynomials(d.pv1, d.pv2, x, y)
+	}
+
+	phi, theta, ok := deproject(code, x, y)
+	if !ok {
+		return math.NaN(), math.NaN()
+	}
+
+	return wcs.nativeToCelestial(phi, theta)
+}
+
+/*****************************************************************************************************************/
+
+// getSphericalIntermediatePixelOffset projects the equatorial coordinate, and inverts the CD matrix, to find the
+// undistorted pixel offset (u, v) from the reference pixel.
+func (wcs *WCS) getSphericalIntermediatePixelOffset(ra, dec float64, d *distortions) (u, v float64, err error) {
+	det := wcs.CD1_1*wcs.CD2_2 - wcs.CD1_2*wcs.CD2_1
+
+	if det == 0 {
+		return 0, 0, errors.New("the CD matrix of the WCS is singular")
+	}
+
+	phi, theta := wcs.celestialToNative(ra, dec)
+
+	code := getProjectionCode(wcs.CTYPE1)
+
+	x, y, ok := project(code, phi, theta)
+	if !ok {
+		return math.NaN(), math.NaN(), fmt.Errorf("the coordinate (%v, %v) cannot be projected", ra, dec)
+	}
+
+	if code == "TPV" {
+		if x, y, err = removeTPVPolynomials(d.pv1, d.pv2, x, y); err != nil {
+			return math.NaN(), math.NaN(), err
+		}
+	}
+
+	u = (wcs.CD2_2*x - wcs.CD1_2*y) / det
+	v = (-wcs.CD2_1*x + wcs.CD1_1*y) / det
+
+	return u, v, nil
+}
+
+/*****************************************************************************************************************/
+
+// NewWorldCoordinateSystemFromPointPairs fits a WCS of the given spherical projection, referenced to the pixel
+// (xc, yc), to the pixel to equatorial point correspondences. The correspondences are projected about the current
+// reference point, the CD matrix is fitted by weighted least squares, and the reference point is moved to the fitted
+// intermediate coordinate of the reference pixel, until it converges.
+func NewWorldCoordinateSystemFromPointPairs(
+	projectionType CoordinateProjectionType,
+	xc, yc float64,
+	pairs []PointPair,
+) (WCS, error) {
+	if len(pairs) == 0 {
+		return WCS{}, errors.New("no point correspondences to fit")
+	}
+
+	// The linear model provides the initial reference point and CD matrix, where the right ascension is fitted
+	// relative to the first correspondence, to avoid wrapping at 0h:
+	ra0 := pairs[0].RA
+
+	unwrapped := make([]PointPair, len(pairs))
+
+	for i, pair := range pairs {
+		unwrapped[i] = pair
+		unwrapped[i].RA = ra0 + math.Mod(pair.RA-ra0+540, 360) - 180
+	}
+
+	params, _, _, err := ComputeAffineTransformationFromPointPairs(unwrapped)
+	if err != nil {
+		return WCS{}, err
+	}
+
+	// The linear model is evaluated at the reference pixel, as the affine parameters are referenced to the origin:
+	params.C = math.Mod(params.C+params.A*xc+params.B*yc+360, 360)
+	params.F += params.D*xc + params.E*yc
+
+	w := NewWorldCoordinateSystem(xc, yc, WCSParams{
+		Projection:   projectionType,
+		AffineParams: params,
+	})
+
+	if !projectionType.IsSpherical() {
+		return w, nil
+	}
+
+	code := getProjectionCode(w.CTYPE1)
+
+	for iteration := 0; iteration < PROJECTION_MAXIMUM_ITERATIONS; iteration++ {
+		w.LONPOLE, w.LATPOLE = getDefaultCelestialPole(code, w.CRVAL2)
+
+		projected := make([]PointPair, 0, len(pairs))
+
+		// Fit the intermediate world coordinates of each correspondence against the pixel offsets:
+		for _, pair := range pairs {
+			phi, theta := w.celestialToNative(pair.RA, pair.Dec)
+
+			x, y, ok := project(code, phi, theta)
+			if !ok {
+				continue
+			}
+
+			projected = append(projected, PointPair{
+				X:      pair.X - xc,
+				Y:      pair.Y - yc,
+				RA:     x,
+				Dec:    y,
+				Weight: pair.Weight,
+			})
+		}
+
+		params, _, _, err = ComputeAffineTransformationFromPointPairs(projected)
+		if err != nil {
+			return WCS{}, err
+		}
+
+		w.CD1_1, w.CD1_2, w.CD2_1, w.CD2_2 = params.A, params.B, params.D, params.E
+
+		// The offset is the intermediate world coordinate of the reference pixel, i.e., the new reference point:
+		phi, theta, ok := deproject(code, params.C, params.F)
+		if !ok {
+			return WCS{}, errors.New("the reference pixel lies outside of the projection")
+		}
+
+		w.CRVAL1, w.CRVAL2 = w.nativeToCelestial(phi, theta)
+
+		if math.Hypot(params.C, params.F) < PROJECTION_CONVERGENCE {
+			break
+		}
+	}
+
+	w.LONPOLE, w.LATPOLE = getDefaultCelestialPole(code, w.CRVAL2)
+
+	w.CDELT1 = -math.Sqrt(w.CD1_1*w.CD1_1 + w.CD2_1*w.CD2_1)
+	w.CDELT2 = math.Sqrt(w.CD1_2*w.CD1_2 + w.CD2_2*w.CD2_2)
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
+
+// getAngularSeparation returns the great circle distance between two equatorial coordinates, in degrees, using the
+// haversine formula, which is well conditioned for small distances:
+func getAngularSeparation(ra1, dec1, ra2, dec2 float64) float64 {
+	h := math.Pow(math.Sin(projection.Radians(dec2-dec1)/2), 2) +
+		math.Cos(projection.Radians(dec1))*math.Cos(projection.Radians(dec2))*math.Pow(math.Sin(projection.Radians(ra2-ra1)/2), 2)
+
+	return projection.Degrees(2 * math.Asin(math.Sqrt(clamp(h, 0, 1))))
+}
+
+/*****************************************************************************************************************/
+
+// clamp limits the value to the range [minimum, maximum]:
+func clamp(value, minimum, maximum float64) float64 {
+	return math.Max(minimum, math.Min(maximum, value))
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,208 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/projection"
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+var sphericalProjectionTypes = []CoordinateProjectionType{RADEC_SIN, RADEC_ARC, RADEC_ZEA, RADEC_STG, RADEC_CAR}
+
+/*****************************************************************************************************************/
+
+// newProjectedWCS creates a wide field WCS of the given projection, with a scale of 0.01 degrees per pixel and a
+// rotation of 15 degrees:
+func newProjectedWCS(projectionType CoordinateProjectionType, ra, dec float64) WCS {
+	rho := projection.Radians(15)
+
+	return NewWorldCoordinateSystem(2048, 1536, WCSParams{
+		Projection: projectionType,
+		AffineParams: transform.Affine2DParameters{
+			A: -0.01 * math.Cos(rho), B: 0.01 * math.Sin(rho), C: ra,
+			D: 0.01 * math.Sin(rho), E: 0.01 * math.Cos(rho), F: dec,
+		},
+	})
+}
+
+/*****************************************************************************************************************/
+
+func TestParseProjection(t *testing.T) {
+	for name, want := range map[string]CoordinateProjectionType{"TAN": RADEC_TAN, "sin": RADEC_SIN, " zea ": RADEC_ZEA, "car": RADEC_CAR} {
+		got, err := ParseProjection(name)
+		if err != nil || got != want {
+			t.Errorf("ParseProjection(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseProjection("mercator"); err == nil {
+		t.Errorf("expected an error for an unknown projection")
+	}
+
+	if RADEC_TAN.IsSpherical() || RADEC_TANSIP.IsSpherical() || !RADEC_STG.IsSpherical() {
+		t.Errorf("expected only the non-TAN projections to be spherical")
+	}
+
+	for _, projectionType := range []CoordinateProjectionType{RADEC_TAN, RADEC_TANSIP, RADEC_TPV, RADEC_SIN, RADEC_STG, RADEC_CAR} {
+		// The projection type of a WCS is recovered from its CTYPE:
+		w := WCS{CTYPE1: projectionType.ToCTypes().CType1}
+
+		if got, err := w.GetProjection(); err != nil || got != projectionType {
+			t.Errorf("expected the projection of %s to be %v, got %v (%v)", w.CTYPE1, projectionType, got, err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestProjectDeprojectRoundTrip(t *testing.T) {
+	// The radius Rθ of each zenithal projection at θ = 60 degrees (Calabretta & Greisen 2002, §5.1):
+	radii := map[string]float64{
+		"SIN": projection.Degrees(math.Cos(projection.Radians(60))),
+		"ARC": 30,
+		"ZEA": projection.Degrees(2 * math.Sin(projection.Radians(15))),
+		"STG": projection.Degrees(2 * math.Tan(projection.Radians(15))),
+	}
+
+	for code, r := range radii {
+		x, y, ok := project(code, 90, 60)
+		if !ok || math.Abs(x-r) > 1e-12 || math.Abs(y) > 1e-12 {
+			t.Errorf("%s: expected (φ, θ) = (90, 60) to project to (%v, 0), got (%v, %v)", code, r, x, y)
+		}
+	}
+
+	for _, code := range []string{"SIN", "ARC", "ZEA", "STG", "CAR"} {
+		for _, phi := range []float64{-170, -90, -30, 0, 45, 120} {
+			for _, theta := range []float64{5, 30, 60, 89} {
+				x, y, ok := project(code, phi, theta)
+				if !ok {
+					t.Fatalf("%s: failed to project (%v, %v)", code, phi, theta)
+				}
+
+				p, q, ok := deproject(code, x, y)
+				if !ok || math.Abs(p-phi) > 1e-9 || math.Abs(q-theta) > 1e-9 {
+					t.Errorf("%s: expected (%v, %v) to round trip, got (%v, %v)", code, phi, theta, p, q)
+				}
+			}
+		}
+	}
+
+	// The far hemisphere of the orthographic projection cannot be projected:
+	if _, _, ok := project("SIN", 0, -10); ok {
+		t.Errorf("expected the far hemisphere of SIN to be rejected")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSphericalPixelToEquatorialCoordinateRoundTrip(t *testing.T) {
+	for _, projectionType := range sphericalProjectionTypes {
+		for _, dec := range []float64{-62.5, -5.4, 0, 41.3, 88} {
+			w := newProjectedWCS(projectionType, 83.8, dec)
+
+			centre := w.PixelToEquatorialCoordinate(w.CRPIX1, w.CRPIX2)
+
+			if getAngularSeparation(centre.RA, centre.Dec, w.CRVAL1, w.CRVAL2) > 1e-9 {
+				t.Errorf("%s: expected the reference pixel at (%v, %v), got (%v, %v)", w.CTYPE1, w.CRVAL1, w.CRVAL2, centre.RA, centre.Dec)
+			}
+
+			// The corners of the 40 × 30 degree field:
+			for _, pixel := range [][2]float64{{0, 0}, {4096, 0}, {0, 3072}, {4096, 3072}, {1000, 2500}} {
+				eq := w.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+				x, y := w.EquatorialCoordinateToPixel(eq.RA, eq.Dec)
+
+				if math.Hypot(x-pixel[0], y-pixel[1]) > 1e-6 {
+					t.Errorf("%s at %v: expected %v to round trip, got (%v, %v)", w.CTYPE1, dec, pixel, x, y)
+				}
+			}
+
+			// North is up, i.e., the declination increases along the rotated y-axis near the reference pixel:
+			north := w.PixelToEquatorialCoordinate(w.CRPIX1+10*math.Sin(projection.Radians(15)), w.CRPIX2+10*math.Cos(projection.Radians(15)))
+
+			if dec < 80 && math.Abs(north.Dec-(dec+0.1)) > 1e-4 {
+				t.Errorf("%s at %v: expected the declination to increase by 0.1 degrees, got %v", w.CTYPE1, dec, north.Dec)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestZenithalEquidistantProjectionPreservesDistances(t *testing.T) {
+	w := newProjectedWCS(RADEC_ARC, 210, 54)
+
+	// The radial distance in the ARC projection is the angular distance from the reference point, so 3000 pixels
+	// at 0.01 degrees per pixel is 30 degrees in any direction:
+	for _, angle := range []float64{0, 45, 90, 200, 300} {
+		x := w.CRPIX1 + 3000*math.Cos(projection.Radians(angle))
+		y := w.CRPIX2 + 3000*math.Sin(projection.Radians(angle))
+
+		eq := w.PixelToEquatorialCoordinate(x, y)
+
+		if separation := getAngularSeparation(eq.RA, eq.Dec, w.CRVAL1, w.CRVAL2); math.Abs(separation-30) > 1e-9 {
+			t.Errorf("expected an angular distance of 30 degrees at %v degrees, got %v", angle, separation)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewWorldCoordinateSystemFromPointPairs(t *testing.T) {
+	for _, projectionType := range sphericalProjectionTypes {
+		want := newProjectedWCS(projectionType, 300.5, 38.2)
+
+		pairs := []PointPair{}
+
+		for y := 0.0; y <= 3072; y += 256 {
+			for x := 0.0; x <= 4096; x += 256 {
+				eq := want.PixelToEquatorialCoordinate(x, y)
+
+				pairs = append(pairs, PointPair{X: x, Y: y, RA: eq.RA, Dec: eq.Dec})
+			}
+		}
+
+		got, err := NewWorldCoordinateSystemFromPointPairs(projectionType, 2048, 1536, pairs)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", want.CTYPE1, err)
+		}
+
+		if got.CTYPE1 != want.CTYPE1 || got.CTYPE2 != want.CTYPE2 {
+			t.Errorf("expected CTYPEs %s and %s, got %s and %s", want.CTYPE1, want.CTYPE2, got.CTYPE1, got.CTYPE2)
+		}
+
+		if getAngularSeparation(got.CRVAL1, got.CRVAL2, want.CRVAL1, want.CRVAL2) > 1e-9 {
+			t.Errorf("%s: expected CRVAL (%v, %v), got (%v, %v)", want.CTYPE1, want.CRVAL1, want.CRVAL2, got.CRVAL1, got.CRVAL2)
+		}
+
+		for i, cd := range [][2]float64{{got.CD1_1, want.CD1_1}, {got.CD1_2, want.CD1_2}, {got.CD2_1, want.CD2_1}, {got.CD2_2, want.CD2_2}} {
+			if math.Abs(cd[0]-cd[1]) > 1e-12 {
+				t.Errorf("%s: expected CD element %d = %v, got %v", want.CTYPE1, i, cd[1], cd[0])
+			}
+		}
+
+		// Every correspondence is reprojected onto its pixel:
+		for _, pair := range pairs {
+			x, y := got.EquatorialCoordinateToPixel(pair.RA, pair.Dec)
+
+			if math.Hypot(x-pair.X, y-pair.Y) > 1e-6 {
+				t.Fatalf("%s: expected (%v, %v), got (%v, %v)", want.CTYPE1, pair.X, pair.Y, x, y)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
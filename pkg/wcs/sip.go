@@ -0,0 +1,569 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/skysolve/pkg/transform"
+	"gonum.org/v1/gonum/mat"
+)
+
+/*****************************************************************************************************************/
+
+// The maximum number of Newton iterations, and the convergence threshold (in pixels), when inverting the forward SIP
+// distortion polynomials:
+const (
+	SIP_INVERSE_MAXIMUM_ITERATIONS = 20
+	SIP_INVERSE_CONVERGENCE        = 1e-10
+)
+
+/*****************************************************************************************************************/
+
+// The number of samples along each axis of the image when fitting, or converting between, distortion polynomials,
+// e.g., the inverse SIP polynomials or the TPV equivalent of a SIP solution:
+const DISTORTION_FIT_SAMPLES = 25
+
+// The number of samples along each axis of the image when evaluating the error of a fitted distortion polynomial,
+// such that the samples (other than the corners of the image) are between, rather than on, the fitted samples:
+const DISTORTION_VALIDATION_SAMPLES = 2 * DISTORTION_FIT_SAMPLES
+
+/*****************************************************************************************************************/
+
+// sipTerm is a single (p, q) power of a SIP polynomial, i.e., the coefficient of u^p * v^q.
+type sipTerm struct {
+	p, q int
+}
+
+/*****************************************************************************************************************/
+
+// getSIPTerms returns the polynomial powers with minimum <= p + q <= order.
+func getSIPTerms(minimum, order int) []sipTerm {
+	terms := []sipTerm{}
+
+	for n := minimum; n <= order; n++ {
+		for q := 0; q <= n; q++ {
+			terms = append(terms, sipTerm{p: n - q, q: q})
+		}
+	}
+
+	return terms
+}
+
+/*****************************************************************************************************************/
+
+// GetSIPTermCount returns the number of coefficients of a single forward SIP polynomial of the given order, i.e.,
+// the terms where 2 <= p + q <= order, as the linear terms are described by the CD matrix.
+func GetSIPTermCount(order int) int {
+	return len(getSIPTerms(2, order))
+}
+
+/*****************************************************************************************************************/
+
+// getIntermediatePixelOffset inverts the CD matrix of the WCS to find the undistorted pixel offset (u, v) from the
+// reference pixel that corresponds to the given equatorial coordinate.
+func (wcs *WCS) getIntermediatePixelOffset(ra, dec float64) (u, v float64, err error) {
+	// Convert the equatorial coordinate to the frame of the WCS, e.g., galactic coordinates:
+	ra, dec = wcs.getWorldCoordinate(ra, dec)
+
+	if wcs.isSpherical() {
+		return wcs.getSphericalIntermediatePixelOffset(ra, dec, wcs.getDistortions())
+	}
+
+	det := wcs.CD1_1*wcs.CD2_2 - wcs.CD1_2*wcs.CD2_1
+
+	if det == 0 {
+		return 0, 0, errors.New("the CD matrix of the WCS is singular")
+	}
+
+	// Wrap the right ascension offset into the range [-180, 180) degrees:
+	dra := math.Mod(ra-wcs.CRVAL1+540, 360) - 180
+
+	ddec := dec - wcs.CRVAL2
+
+	u = (wcs.CD2_2*dra - wcs.CD1_2*ddec) / det
+	v = (-wcs.CD2_1*dra + wcs.CD1_1*ddec) / det
+
+	return u, v, nil
+}
+
+/*****************************************************************************************************************/
+
+// fitSIPPolynomial solves the weighted least squares problem for the two polynomials, in (s, t), that best describe
+// the target offsets (du, dv), returning the coefficients keyed by their FITS SIP names.
+func fitSIPPolynomial(
+	s, t, du, dv, weights []float64,
+	terms []sipTerm,
+	prefixU, prefixV string,
+) (map[string]float64, map[string]float64, error) {
+	n := len(s)
+
+	// Normalise the polynomial arguments to improve the conditioning of the design matrix:
+	scale := 0.0
+
+	for i := 0; i < n; i++ {
+		scale = math.Max(scale, math.Max(math.Abs(s[i]), math.Abs(t[i])))
+	}
+
+	if scale == 0 {
+		return nil, nil, errors.New("degenerate point correspondences for SIP fit")
+	}
+
+	A := mat.NewDense(n, len(terms), nil)
+	bu := mat.NewVecDense(n, nil)
+	bv := mat.NewVecDense(n, nil)
+
+	for i := 0; i < n; i++ {
+		w := math.Sqrt(weights[i])
+
+		for k, term := range terms {
+			A.Set(i, k, w*math.Pow(s[i]/scale, float64(term.p))*math.Pow(t[i]/scale, float64(term.q)))
+		}
+
+		bu.SetVec(i, w*du[i])
+		bv.SetVec(i, w*dv[i])
+	}
+
+	var qr mat.QR
+	qr.Factorize(A)
+
+	var cu, cv mat.VecDense
+
+	if err := qr.SolveVecTo(&cu, false, bu); err != nil {
+		return nil, nil, fmt.Errorf("failed to solve SIP transformation: %v", err)
+	}
+
+	if err := qr.SolveVecTo(&cv, false, bv); err != nil {
+		return nil, nil, fmt.Errorf("failed to solve SIP transformation: %v", err)
+	}
+
+	u := make(map[string]float64, len(terms))
+	v := make(map[string]float64, len(terms))
+
+	for k, term := range terms {
+		// Undo the normalisation of the polynomial arguments:
+		norm := math.Pow(scale, float64(term.p+term.q))
+
+		u[fmt.Sprintf("%s_%d_%d", prefixU, term.p, term.q)] = cu.AtVec(k) / norm
+		v[fmt.Sprintf("%s_%d_%d", prefixV, term.p, term.q)] = cv.AtVec(k) / norm
+	}
+
+	return u, v, nil
+}
+
+/*****************************************************************************************************************/
+
+// ComputeSIPTransformationFromPointPairs fits the forward (A, B) and inverse (AP, BP) SIP distortion polynomials of
+// the given order to the residuals of the point correspondences with respect to the linear part of the WCS, i.e.,
+// its CD matrix, CRPIX and CRVAL. Each correspondence is weighted by its Weight, as for the affine transformation.
+func ComputeSIPTransformationFromPointPairs(
+	w WCS,
+	pairs []PointPair,
+	order int,
+) (transform.SIP2DForwardParameters, transform.SIP2DInverseParameters, error) {
+	if err := validateSIPPointPairs(pairs, order); err != nil {
+		return transform.SIP2DForwardParameters{}, transform.SIP2DInverseParameters{}, err
+	}
+
+	n := len(pairs)
+
+	// The distorted pixel offsets from the reference pixel:
+	x := make([]float64, n)
+	y := make([]float64, n)
+
+	// The forward distortions, from the distorted to the undistorted offsets:
+	du := make([]float64, n)
+	dv := make([]float64, n)
+
+	weights := make([]float64, n)
+
+	for i, pair := range pairs {
+		u, v, err := w.getIntermediatePixelOffset(pair.RA, pair.Dec)
+		if err != nil {
+			return transform.SIP2DForwardParameters{}, transform.SIP2DInverseParameters{}, err
+		}
+
+		x[i] = pair.X - w.CRPIX1
+		y[i] = pair.Y - w.CRPIX2
+		du[i] = u - x[i]
+		dv[i] = v - y[i]
+		weights[i] = pair.weight()
+	}
+
+	// Fit the forward polynomials, A(x, y) and B(x, y):
+	a, b, err := fitSIPPolynomial(x, y, du, dv, weights, getSIPTerms(2, order), "A", "B")
+	if err != nil {
+		return transform.SIP2DForwardParameters{}, transform.SIP2DInverseParameters{}, err
+	}
+
+	forward := transform.SIP2DForwardParameters{
+		AOrder: order,
+		APower: a,
+		BOrder: order,
+		BPower: b,
+	}
+
+	inverse, err := computeInverseSIPTransformation(w, pairs, order)
+	if err != nil {
+		return transform.SIP2DForwardParameters{}, transform.SIP2DInverseParameters{}, err
+	}
+
+	return forward, inverse, nil
+}
+
+/*****************************************************************************************************************/
+
+// NewSIPWorldCoordinateSystemFromPointPairs jointly fits the linear solution (CRVAL and the CD matrix) and the SIP
+// distortion polynomials of the given order to the point correspondences, referenced to the pixel (xc, yc). As the
+// SIP model is sky = CRVAL + CD·(d + A(d)) for a pixel offset d, fitting a full polynomial in d to each equatorial
+// axis recovers CRVAL from the constant terms, CD from the linear terms and A, B from CD⁻¹ applied to the higher
+// order terms, without having to alternate between the strongly correlated linear and distortion fits.
+func NewSIPWorldCoordinateSystemFromPointPairs(xc, yc float64, pairs []PointPair, order int) (WCS, error) {
+	if err := validateSIPPointPairs(pairs, order); err != nil {
+		return WCS{}, err
+	}
+
+	n := len(pairs)
+
+	x := make([]float64, n)
+	y := make([]float64, n)
+
+	ra := make([]float64, n)
+	dec := make([]float64, n)
+
+	weights := make([]float64, n)
+
+	// Fit the right ascension relative to the first correspondence, to avoid wrapping at 0h:
+	ra0 := pairs[0].RA
+
+	for i, pair := range pairs {
+		x[i] = pair.X - xc
+		y[i] = pair.Y - yc
+		ra[i] = math.Mod(pair.RA-ra0+540, 360) - 180
+		dec[i] = pair.Dec
+		weights[i] = pair.weight()
+	}
+
+	terms := getSIPTerms(0, order)
+
+	p, q, err := fitSIPPolynomial(x, y, ra, dec, weights, terms, "P", "Q")
+	if err != nil {
+		return WCS{}, err
+	}
+
+	key := func(prefix string, term sipTerm) string {
+		return fmt.Sprintf("%s_%d_%d", prefix, term.p, term.q)
+	}
+
+	w := NewWorldCoordinateSystem(xc, yc, WCSParams{
+		Projection: RADEC_TANSIP,
+		AffineParams: transform.Affine2DParameters{
+			A: p["P_1_0"],
+			B: p["P_0_1"],
+			C: math.Mod(p["P_0_0"]+ra0+360, 360),
+			D: q["Q_1_0"],
+			E: q["Q_0_1"],
+			F: q["Q_0_0"],
+		},
+	})
+
+	det := w.CD1_1*w.CD2_2 - w.CD1_2*w.CD2_1
+
+	if det == 0 {
+		return WCS{}, errors.New("the fitted CD matrix is singular")
+	}
+
+	w.FSIP = transform.SIP2DForwardParameters{
+		AOrder: order,
+		APower: map[string]float64{},
+		BOrder: order,
+		BPower: map[string]float64{},
+	}
+
+	// Apply the inverse CD matrix to the higher order terms to recover the SIP distortions (in pixels):
+	for _, term := range getSIPTerms(2, order) {
+		pra, pdec := p[key("P", term)], q[key("Q", term)]
+
+		w.FSIP.APower[key("A", term)] = (w.CD2_2*pra - w.CD1_2*pdec) / det
+		w.FSIP.BPower[key("B", term)] = (-w.CD2_1*pra + w.CD1_1*pdec) / det
+	}
+
+	w.ISIP, err = computeInverseSIPTransformation(w, pairs, order)
+	if err != nil {
+		return WCS{}, err
+	}
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
+
+// validateSIPPointPairs checks that the order is valid, and that there are enough correspondences to constrain the
+// SIP polynomials of that order.
+func validateSIPPointPairs(pairs []PointPair, order int) error {
+	if order < 2 {
+		return fmt.Errorf("invalid SIP order: %d", order)
+	}
+
+	if len(pairs) < len(getSIPTerms(0, order)) {
+		return fmt.Errorf("not enough point correspondences to compute SIP transformation of order %d: %d", order, len(pairs))
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// computeInverseSIPTransformation fits the inverse (AP, BP) SIP polynomials, from the undistorted (intermediate)
+// pixel offsets of the equatorial coordinates to the distorted pixel offsets of the point correspondences.
+func computeInverseSIPTransformation(w WCS, pairs []PointPair, order int) (transform.SIP2DInverseParameters, error) {
+	n := len(pairs)
+
+	u := make([]float64, n)
+	v := make([]float64, n)
+
+	dx := make([]float64, n)
+	dy := make([]float64, n)
+
+	weights := make([]float64, n)
+
+	for i, pair := range pairs {
+		ui, vi, err := w.getIntermediatePixelOffset(pair.RA, pair.Dec)
+		if err != nil {
+			return transform.SIP2DInverseParameters{}, err
+		}
+
+		u[i] = ui
+		v[i] = vi
+		dx[i] = pair.X - w.CRPIX1 - ui
+		dy[i] = pair.Y - w.CRPIX2 - vi
+		weights[i] = pair.weight()
+	}
+
+	// The inverse polynomials also absorb the small linear terms of the inverse of the forward distortion:
+	ap, bp, err := fitSIPPolynomial(u, v, dx, dy, weights, getSIPTerms(1, order), "AP", "BP")
+	if err != nil {
+		return transform.SIP2DInverseParameters{}, err
+	}
+
+	return transform.SIP2DInverseParameters{
+		APOrder: order,
+		APPower: ap,
+		BPOrder: order,
+		BPPower: bp,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// getSamplePixels returns a regular grid of the given number of pixels along each axis, spanning the image from edge
+// to edge, including its corners:
+func getSamplePixels(width, height int, samples int) [][2]float64 {
+	x0, y0, x1, y1 := getImageBounds(width, height)
+
+	pixels := make([][2]float64, 0, samples*samples)
+
+	for j := 0; j < samples; j++ {
+		for i := 0; i < samples; i++ {
+			pixels = append(pixels, [2]float64{
+				x0 + float64(i)*(x1-x0)/float64(samples-1),
+				y0 + float64(j)*(y1-y0)/float64(samples-1),
+			})
+		}
+	}
+
+	return pixels
+}
+
+/*****************************************************************************************************************/
+
+// sipPolynomial is a SIP polynomial with its coefficients parsed once into an array, indexed by [p][q], such that it
+// is evaluated by Horner's method without parsing the FITS SIP keys, or calling math.Pow, for each coordinate.
+type sipPolynomial [][]float64
+
+/*****************************************************************************************************************/
+
+// newSIPPolynomial parses the coefficients of the SIP polynomial, keyed by their FITS SIP names with the prefix:
+func newSIPPolynomial(terms map[string]float64, prefix string) sipPolynomial {
+	parsed := make(map[sipTerm]float64, len(terms))
+
+	maximumP, maximumQ := -1, -1
+
+	for term, coeff := range terms {
+		p, q, err := parseSIPTerm(term, prefix)
+		if err != nil || p < 0 || q < 0 {
+			continue
+		}
+
+		parsed[sipTerm{p: p, q: q}] += coeff
+
+		maximumP = max(maximumP, p)
+		maximumQ = max(maximumQ, q)
+	}
+
+	if maximumP < 0 {
+		return nil
+	}
+
+	polynomial := make(sipPolynomial, maximumP+1)
+
+	for p := range polynomial {
+		polynomial[p] = make([]float64, maximumQ+1)
+	}
+
+	for term, coeff := range parsed {
+		polynomial[term.p][term.q] = coeff
+	}
+
+	return polynomial
+}
+
+/*****************************************************************************************************************/
+
+// evaluate returns the value, and the partial derivatives, of the SIP polynomial at (u, v).
+func (s sipPolynomial) evaluate(u, v float64) (value, du, dv float64) {
+	for p := len(s) - 1; p >= 0; p-- {
+		// The polynomial in v of the coefficients of u^p, and its derivative, by Horner's method:
+		r, dr := 0.0, 0.0
+
+		for q := len(s[p]) - 1; q >= 0; q-- {
+			dr = dr*v + r
+			r = r*v + s[p][q]
+		}
+
+		du = du*u + value
+		value = value*u + r
+		dv = dv*u + dr
+	}
+
+	return value, du, dv
+}
+
+/*****************************************************************************************************************/
+
+// hasForwardSIP reports whether the WCS has forward (A, B) SIP distortion polynomials.
+func (wcs *WCS) hasForwardSIP() bool {
+	return len(wcs.FSIP.APower) > 0 || len(wcs.FSIP.BPower) > 0
+}
+
+/*****************************************************************************************************************/
+
+// removeForwardSIPDistortion inverts the forward SIP polynomials by Newton's method, finding the distorted pixel
+// offset d for which d + (A(d), B(d)) is the undistorted pixel offset (u, v), starting from the estimate (x, y),
+// e.g., that of the inverse (AP, BP) polynomials.
+func (d *distortions) removeForwardSIPDistortion(u, v, x, y float64) (float64, float64, error) {
+	for iteration := 0; iteration < SIP_INVERSE_MAXIMUM_ITERATIONS; iteration++ {
+		a, ax, ay := d.a.evaluate(x, y)
+		b, bx, by := d.b.evaluate(x, y)
+
+		// The residual, and the Jacobian, of d + (A(d), B(d)) - (u, v):
+		f := x + a - u
+		g := y + b - v
+
+		fx, fy := 1+ax, ay
+		gx, gy := bx, 1+by
+
+		det := fx*gy - fy*gx
+
+		if det == 0 {
+			return x, y, errors.New("the SIP distortion is singular")
+		}
+
+		dx := (gy*f - fy*g) / det
+		dy := (-gx*f + fx*g) / det
+
+		x -= dx
+		y -= dy
+
+		if math.Hypot(dx, dy) < SIP_INVERSE_CONVERGENCE {
+			return x, y, nil
+		}
+	}
+
+	return x, y, errors.New("the inverse of the SIP distortion did not converge")
+}
+
+/*****************************************************************************************************************/
+
+// FitInverseSIP fits the inverse (AP, BP) SIP polynomials of the given order to the forward (A, B) polynomials of
+// the WCS, across an image of the given dimensions, e.g., for headers from tools which only write the forward terms.
+// It returns the inverse polynomials and the maximum inversion error, in pixels, over a denser grid of the image than
+// that of the fit, i.e., the error between the fitted samples and at the corners of the image.
+func (wcs *WCS) FitInverseSIP(width, height int, order int) (transform.SIP2DInverseParameters, float64, error) {
+	if order < 1 {
+		return transform.SIP2DInverseParameters{}, math.Inf(1), fmt.Errorf("invalid SIP order: %d", order)
+	}
+
+	if !wcs.hasForwardSIP() {
+		return transform.SIP2DInverseParameters{}, math.Inf(1), errors.New("the WCS has no forward SIP polynomials to invert")
+	}
+
+	pixels := getSamplePixels(width, height, DISTORTION_FIT_SAMPLES)
+
+	n := len(pixels)
+
+	forwardA, forwardB := newSIPPolynomial(wcs.FSIP.APower, "A"), newSIPPolynomial(wcs.FSIP.BPower, "B")
+
+	// The undistorted pixel offsets, u = d + A(d), and the inverse distortions, from the undistorted to the distorted
+	// offsets:
+	u := make([]float64, n)
+	v := make([]float64, n)
+
+	dx := make([]float64, n)
+	dy := make([]float64, n)
+
+	weights := make([]float64, n)
+
+	for i, pixel := range pixels {
+		x, y := pixel[0]-wcs.CRPIX1, pixel[1]-wcs.CRPIX2
+
+		a, _, _ := forwardA.evaluate(x, y)
+		b, _, _ := forwardB.evaluate(x, y)
+
+		u[i], v[i] = x+a, y+b
+		dx[i], dy[i] = -a, -b
+		weights[i] = 1
+	}
+
+	ap, bp, err := fitSIPPolynomial(u, v, dx, dy, weights, getSIPTerms(1, order), "AP", "BP")
+	if err != nil {
+		return transform.SIP2DInverseParameters{}, math.Inf(1), err
+	}
+
+	// The maximum distance between the distorted pixel offsets and those recovered by the inverse polynomials, on
+	// the samples between those of the fit:
+	maximum := 0.0
+
+	inverseA, inverseB := newSIPPolynomial(ap, "AP"), newSIPPolynomial(bp, "BP")
+
+	for _, pixel := range getSamplePixels(width, height, DISTORTION_VALIDATION_SAMPLES) {
+		x, y := pixel[0]-wcs.CRPIX1, pixel[1]-wcs.CRPIX2
+
+		a, _, _ := forwardA.evaluate(x, y)
+		b, _, _ := forwardB.evaluate(x, y)
+
+		ia, _, _ := inverseA.evaluate(x+a, y+b)
+		ib, _, _ := inverseB.evaluate(x+a, y+b)
+
+		maximum = math.Max(maximum, math.Hypot(ia+a, ib+b))
+	}
+
+	return transform.SIP2DInverseParameters{
+		APOrder: order,
+		APPower: ap,
+		BPOrder: order,
+		BPPower: bp,
+	}, maximum, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,313 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+func newLinearTestWCS() WCS {
+	return NewWorldCoordinateSystem(600, 600, WCSParams{
+		Projection: RADEC_TAN,
+		AffineParams: transform.Affine2DParameters{
+			A: -0.0005,
+			B: 0.00001,
+			C: 120,
+			D: 0.00001,
+			E: 0.0005,
+			F: 35,
+		},
+	})
+}
+
+/*****************************************************************************************************************/
+
+func TestComputeAffineTransformationFromPointPairsIsWeighted(t *testing.T) {
+	truth := newLinearTestWCS()
+
+	pairs := []PointPair{}
+
+	for x := 0.0; x <= 1200; x += 300 {
+		for y := 0.0; y <= 1200; y += 300 {
+			eq := truth.PixelToEquatorialCoordinate(x, y)
+
+			pairs = append(pairs, PointPair{X: x, Y: y, RA: eq.RA, Dec: eq.Dec, Weight: GetWeightFromUncertainty(0.01)})
+		}
+	}
+
+	// Add a poorly measured star, whose position is offset by several pixels:
+	eq := truth.PixelToEquatorialCoordinate(450, 450)
+
+	pairs = append(pairs, PointPair{X: 455, Y: 447, RA: eq.RA, Dec: eq.Dec, Weight: GetWeightFromUncertainty(50)})
+
+	params, _, _, err := ComputeAffineTransformationFromPointPairs(pairs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ra := params.A*450 + params.B*450 + params.C
+	dec := params.D*450 + params.E*450 + params.F
+
+	want := truth.PixelToEquatorialCoordinate(450, 450)
+
+	// The poorly measured star should have a negligible influence on the weighted fit:
+	if math.Abs(ra-want.RA) > 1e-7 || math.Abs(dec-want.Dec) > 1e-7 {
+		t.Errorf("expected (%v, %v), got (%v, %v)", want.RA, want.Dec, ra, dec)
+	}
+
+	// Whereas with unit weights, it should noticeably bias the fit:
+	for i := range pairs {
+		pairs[i].Weight = 0
+	}
+
+	params, _, _, err = ComputeAffineTransformationFromPointPairs(pairs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ra = params.A*450 + params.B*450 + params.C
+
+	if math.Abs(ra-want.RA) < 1e-5 {
+		t.Errorf("expected the unweighted fit to be biased by the offset star")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestComputeSIPTransformationFromPointPairs(t *testing.T) {
+	linear := newLinearTestWCS()
+
+	truth := linear
+	truth.FSIP = transform.SIP2DForwardParameters{
+		AOrder: 2,
+		APower: map[string]float64{"A_2_0": 2e-6, "A_1_1": -1e-6},
+		BOrder: 2,
+		BPower: map[string]float64{"B_0_2": 1.5e-6, "B_2_0": 5e-7},
+	}
+
+	pairs := []PointPair{}
+
+	for x := 0.0; x <= 1200; x += 100 {
+		for y := 0.0; y <= 1200; y += 100 {
+			eq := truth.PixelToEquatorialCoordinate(x, y)
+
+			pairs = append(pairs, PointPair{X: x, Y: y, RA: eq.RA, Dec: eq.Dec})
+		}
+	}
+
+	forward, inverse, err := ComputeSIPTransformationFromPointPairs(linear, pairs, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, want := range truth.FSIP.APower {
+		if math.Abs(forward.APower[key]-want) > 1e-10 {
+			t.Errorf("%s: expected %v, got %v", key, want, forward.APower[key])
+		}
+	}
+
+	for key, want := range truth.FSIP.BPower {
+		if math.Abs(forward.BPower[key]-want) > 1e-10 {
+			t.Errorf("%s: expected %v, got %v", key, want, forward.BPower[key])
+		}
+	}
+
+	if len(forward.APower) != GetSIPTermCount(3) {
+		t.Errorf("expected %d forward terms, got %d", GetSIPTermCount(3), len(forward.APower))
+	}
+
+	fitted := linear
+	fitted.FSIP = forward
+	fitted.ISIP = inverse
+
+	// The fitted inverse polynomials should round trip the pixel coordinates to a small fraction of a pixel:
+	for _, pair := range pairs {
+		eq := fitted.PixelToEquatorialCoordinate(pair.X, pair.Y)
+
+		x, y := fitted.EquatorialCoordinateToPixel(eq.RA, eq.Dec)
+
+		if math.Hypot(x-pair.X, y-pair.Y) > 0.01 {
+			t.Errorf("pixel (%v, %v): round trip to (%v, %v)", pair.X, pair.Y, x, y)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestComputeSIPTransformationFromPointPairsRequiresSufficientPairs(t *testing.T) {
+	_, _, err := ComputeSIPTransformationFromPointPairs(newLinearTestWCS(), []PointPair{{X: 1, Y: 1, RA: 120, Dec: 35}}, 3)
+	if err == nil {
+		t.Fatalf("expected an error for insufficient point correspondences")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewSIPWorldCoordinateSystemFromPointPairs(t *testing.T) {
+	truth := newLinearTestWCS()
+	truth.FSIP = transform.SIP2DForwardParameters{
+		AOrder: 3,
+		APower: map[string]float64{"A_2_0": 2e-6, "A_0_3": 1e-9},
+		BOrder: 3,
+		BPower: map[string]float64{"B_1_1": -1e-6},
+	}
+
+	pairs := []PointPair{}
+
+	for x := 0.0; x <= 1200; x += 100 {
+		for y := 0.0; y <= 1200; y += 100 {
+			eq := truth.PixelToEquatorialCoordinate(x, y)
+
+			pairs = append(pairs, PointPair{X: x, Y: y, RA: eq.RA, Dec: eq.Dec})
+		}
+	}
+
+	w, err := NewSIPWorldCoordinateSystemFromPointPairs(600, 600, pairs, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.CTYPE1 != "RA---TAN-SIP" || w.CTYPE2 != "DEC--TAN-SIP" {
+		t.Errorf("expected a TAN-SIP projection, got %s and %s", w.CTYPE1, w.CTYPE2)
+	}
+
+	if math.Abs(w.CRVAL1-truth.CRVAL1) > 1e-9 || math.Abs(w.CRVAL2-truth.CRVAL2) > 1e-9 {
+		t.Errorf("expected CRVAL (%v, %v), got (%v, %v)", truth.CRVAL1, truth.CRVAL2, w.CRVAL1, w.CRVAL2)
+	}
+
+	if math.Abs(w.CD1_1-truth.CD1_1) > 1e-12 || math.Abs(w.CD2_2-truth.CD2_2) > 1e-12 {
+		t.Errorf("expected the CD matrix to be recovered, got %v and %v", w.CD1_1, w.CD2_2)
+	}
+
+	for key, want := range truth.FSIP.APower {
+		if math.Abs(w.FSIP.APower[key]-want) > 1e-12 {
+			t.Errorf("%s: expected %v, got %v", key, want, w.FSIP.APower[key])
+		}
+	}
+
+	for key, want := range truth.FSIP.BPower {
+		if math.Abs(w.FSIP.BPower[key]-want) > 1e-12 {
+			t.Errorf("%s: expected %v, got %v", key, want, w.FSIP.BPower[key])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// newForwardOnlySIPTestWCS creates a 1200 × 1200 pixel TAN-SIP solution with only the forward (A, B) polynomials,
+// as written by many other tools:
+func newForwardOnlySIPTestWCS() WCS {
+	w := newLinearTestWCS()
+
+	w.CTYPE1, w.CTYPE2 = "RA---TAN-SIP", "DEC--TAN-SIP"
+
+	w.FSIP = transform.SIP2DForwardParameters{
+		AOrder: 3,
+		APower: map[string]float64{"A_2_0": 2e-5, "A_1_1": -1.2e-5, "A_0_2": 8e-6, "A_3_0": -3e-8, "A_1_2": 2e-8},
+		BOrder: 3,
+		BPower: map[string]float64{"B_2_0": -6e-6, "B_1_1": 1.5e-5, "B_0_2": 2.4e-5, "B_0_3": -2.5e-8, "B_2_1": 1.8e-8},
+	}
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+func TestEquatorialCoordinateToPixelInvertsForwardOnlySIP(t *testing.T) {
+	w := newForwardOnlySIPTestWCS()
+
+	// The distortions are tens of pixels at the corners of the image:
+	for _, pixel := range [][2]float64{{0, 0}, {1200, 0}, {0, 1200}, {1200, 1200}, {600, 600}, {150, 950}} {
+		eq := w.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		x, y := w.EquatorialCoordinateToPixel(eq.RA, eq.Dec)
+
+		if math.Hypot(x-pixel[0], y-pixel[1]) > 1e-8 {
+			t.Errorf("expected %v to round trip, got (%v, %v)", pixel, x, y)
+		}
+	}
+
+	// Inaccurate inverse polynomials are only the initial estimate of the Newton iteration:
+	w.ISIP = transform.SIP2DInverseParameters{
+		APOrder: 2,
+		APPower: map[string]float64{"AP_2_0": -1.5e-5},
+		BPOrder: 2,
+		BPPower: map[string]float64{"BP_0_2": -2e-5},
+	}
+
+	eq := w.PixelToEquatorialCoordinate(1100, 80)
+
+	if x, y := w.EquatorialCoordinateToPixel(eq.RA, eq.Dec); math.Hypot(x-1100, y-80) > 1e-8 {
+		t.Errorf("expected (1100, 80) to round trip, got (%v, %v)", x, y)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitInverseSIP(t *testing.T) {
+	w := newForwardOnlySIPTestWCS()
+
+	isip, maximum, err := w.FitInverseSIP(1200, 1200, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if isip.APOrder != 5 || isip.BPOrder != 5 || len(isip.APPower) != len(getSIPTerms(1, 5)) {
+		t.Errorf("expected fifth order inverse polynomials, got %+v", isip)
+	}
+
+	if maximum > 0.01 {
+		t.Errorf("expected a maximum inversion error below 0.01 pixels, got %v", maximum)
+	}
+
+	// The fitted inverse polynomials alone recover the pixel to within the reported error, i.e., without the forward
+	// polynomials to refine them:
+	inverse := w
+	inverse.ISIP = isip
+
+	eq := w.PixelToEquatorialCoordinate(1150, 40)
+
+	inverse.FSIP = transform.SIP2DForwardParameters{}
+
+	if x, y := inverse.EquatorialCoordinateToPixel(eq.RA, eq.Dec); math.Hypot(x-1150, y-40) > maximum+1e-9 {
+		t.Errorf("expected (1150, 40) within %v pixels, got (%v, %v)", maximum, x, y)
+	}
+
+	// The reported error bounds the inversion error across the image, i.e., between the fitted samples and at the
+	// outer corners of the image, rather than only at the fitted samples:
+	for y := -0.5; y <= 1199.5; y += 15.625 {
+		for x := -0.5; x <= 1199.5; x += 15.625 {
+			eq := w.PixelToEquatorialCoordinate(x, y)
+
+			if px, py := inverse.EquatorialCoordinateToPixel(eq.RA, eq.Dec); math.Hypot(px-x, py-y) > maximum+1e-9 {
+				t.Fatalf("expected (%v, %v) within %v pixels, got (%v, %v)", x, y, maximum, px, py)
+			}
+		}
+	}
+
+	// A lower order inverse is a poorer approximation, which is reported:
+	if _, coarse, _ := w.FitInverseSIP(1200, 1200, 2); coarse <= maximum {
+		t.Errorf("expected a second order inverse to be less accurate than %v, got %v", maximum, coarse)
+	}
+
+	linear := newLinearTestWCS()
+
+	if _, _, err := linear.FitInverseSIP(1200, 1200, 3); err == nil {
+		t.Errorf("expected an error without forward SIP polynomials")
+	}
+}
+
+/*****************************************************************************************************************/
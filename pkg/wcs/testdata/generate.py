@@ -0,0 +1,72 @@
+"""
+Generates the astropy reference WCS headers used by the Go tests of the wcs package.
+
+Usage: python generate.py (requires astropy)
+"""
+
+from astropy.wcs import WCS
+
+# A TAN projection with a rotated CD matrix, in the ICRS reference frame:
+tan = WCS(naxis=2)
+tan.wcs.ctype = ["RA---TAN", "DEC--TAN"]
+tan.wcs.crpix = [2048.5, 1536.5]
+tan.wcs.crval = [83.82208333333333, -5.391111111111111]
+tan.wcs.cd = [[-0.0003045, 0.0000262], [0.0000262, 0.0003045]]
+tan.wcs.radesys = "ICRS"
+tan.wcs.dateobs = "2024-11-26T17:20:00.000"
+tan.wcs.mjdobs = 60640.72222222222
+tan.wcs.set()
+tan.to_header().totextfile("tan.hdr", overwrite=True)
+
+# A TAN-SIP projection with third order forward and inverse distortion polynomials:
+sip = WCS(naxis=2)
+sip.wcs.ctype = ["RA---TAN-SIP", "DEC--TAN-SIP"]
+sip.wcs.crpix = [1024.0, 768.0]
+sip.wcs.crval = [202.4695833333333, 47.19527777777778]
+sip.wcs.cd = [[-0.000277, -0.0000041], [-0.0000041, 0.000277]]
+sip.wcs.radesys = "FK5"
+sip.wcs.equinox = 2000.0
+sip.wcs.dateobs = "2023-04-18T22:41:13.500"
+sip.wcs.mjdobs = 60052.94529513889
+sip.wcs.set()
+
+from astropy.wcs import Sip
+import numpy as np
+
+a = np.zeros((4, 4))
+b = np.zeros((4, 4))
+ap = np.zeros((4, 4))
+bp = np.zeros((4, 4))
+
+a[2, 0], a[1, 1], a[0, 2], a[3, 0], a[1, 2] = 2.5e-6, -1.2e-6, 4.1e-7, 3.2e-10, -1.1e-10
+b[2, 0], b[1, 1], b[0, 2], b[2, 1], b[0, 3] = -7.5e-7, 1.9e-6, 2.2e-6, 1.4e-10, 2.7e-10
+ap[1, 0], ap[0, 1], ap[2, 0], ap[1, 1], ap[0, 2] = 1.2e-5, -3.4e-6, -2.5e-6, 1.2e-6, -4.1e-7
+bp[1, 0], bp[0, 1], bp[2, 0], bp[1, 1], bp[0, 2] = 2.1e-6, 8.7e-6, 7.5e-7, -1.9e-6, -2.2e-6
+
+sip.sip = Sip(a, b, ap, bp, sip.wcs.crpix)
+sip.to_header(relax=True).totextfile("tan-sip.hdr", overwrite=True)
+
+# A TAN projection with a PC matrix and CDELT, in the FK4 reference frame:
+pc = WCS(naxis=2)
+pc.wcs.ctype = ["RA---TAN", "DEC--TAN"]
+pc.wcs.crpix = [512.0, 512.0]
+pc.wcs.crval = [10.684708333333333, 41.26875]
+pc.wcs.cdelt = [-0.000555555555556, 0.000555555555556]
+pc.wcs.pc = [[0.9848077530122, -0.1736481776669], [0.1736481776669, 0.9848077530122]]
+pc.wcs.radesys = "FK4"
+pc.wcs.equinox = 1950.0
+pc.wcs.set()
+pc.to_header().totextfile("fk4-pc.hdr", overwrite=True)
+
+# The reference world coordinates of the 0-based reference pixels, asserted by
+# TestFromFITSHeaderMatchesAstropyPixelToWorldCoordinates:
+pixels = {
+    "tan.hdr": (tan, [(2047.5, 1535.5)]),
+    "tan-sip.hdr": (sip, [(1023, 767)]),
+    "fk4-pc.hdr": (pc, [(511, 511)]),
+}
+
+for name, (w, points) in pixels.items():
+    for x, y in points:
+        ra, dec = w.all_pix2world(x, y, 0)
+        print(f'{{"{name}", {x}, {y}, {float(ra)!r}, {float(dec)!r}}},')
@@ -0,0 +1,323 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// The maximum polynomial order of the TPV convention, i.e., the PVi_0 to PVi_39 coefficients:
+const TPV_MAXIMUM_ORDER = 7
+
+/*****************************************************************************************************************/
+
+// tpvTerm is a single PVi_k term of the TPV polynomial, i.e., x^p * y^q, or r^n for the odd radial terms.
+type tpvTerm struct {
+	p, q, r int
+}
+
+/*****************************************************************************************************************/
+
+// tpvTerms are the 40 terms of the TPV polynomial, in the order of their PVi_k index, where each order lists the
+// monomials from x^n to y^n, followed by r^n for odd orders:
+var tpvTerms = getTPVTerms()
+
+/*****************************************************************************************************************/
+
+// getTPVTerms returns the terms of the TPV polynomial, in the order of their PVi_k index.
+func getTPVTerms() []tpvTerm {
+	terms := []tpvTerm{}
+
+	for n := 0; n <= TPV_MAXIMUM_ORDER; n++ {
+		for q := 0; q <= n; q++ {
+			terms = append(terms, tpvTerm{p: n - q, q: q})
+		}
+
+		if n%2 == 1 {
+			terms = append(terms, tpvTerm{r: n})
+		}
+	}
+
+	return terms
+}
+
+/*****************************************************************************************************************/
+
+// evaluate returns the value of the term, and its partial derivatives, at (x, y).
+func (t tpvTerm) evaluate(x, y float64) (value, dx, dy float64) {
+	if t.r > 0 {
+		r := math.Hypot(x, y)
+
+		value = math.Pow(r, float64(t.r))
+
+		// d(r^n)/dx = n r^(n-2) x, where r^(n-2) is well defined for the odd orders n >= 1 away from the origin:
+		if r == 0 {
+			if t.r == 1 {
+				return 0, 1, 0
+			}
+			return 0, 0, 0
+		}
+
+		k := float64(t.r) * math.Pow(r, float64(t.r-2))
+
+		return value, k * x, k * y
+	}
+
+	value = math.Pow(x, float64(t.p)) * math.Pow(y, float64(t.q))
+
+	if t.p > 0 {
+		dx = float64(t.p) * math.Pow(x, float64(t.p-1)) * math.Pow(y, float64(t.q))
+	}
+
+	if t.q > 0 {
+		dy = float64(t.q) * math.Pow(x, float64(t.p)) * math.Pow(y, float64(t.q-1))
+	}
+
+	return value, dx, dy
+}
+
+/*****************************************************************************************************************/
+
+// getTPVCoefficients returns the PVi_k coefficients of the axis, where PVi_1 defaults to one, such that a TPV
+// projection without any PV keywords is the gnomonic (TAN) projection:
+func getTPVCoefficients(terms map[string]float64, axis int) []float64 {
+	coefficients := make([]float64, len(tpvTerms))
+
+	coefficients[1] = 1
+
+	for k := range tpvTerms {
+		if v, ok := terms[fmt.Sprintf("PV%d_%d", axis, k)]; ok {
+			coefficients[k] = v
+		}
+	}
+
+	return coefficients
+}
+
+/*****************************************************************************************************************/
+
+// evaluateTPVPolynomial returns the value, and the partial derivatives, of the TPV polynomial at (x, y).
+func evaluateTPVPolynomial(coefficients []float64, x, y float64) (value, dx, dy float64) {
+	for k, c := range coefficients {
+		if c == 0 {
+			continue
+		}
+
+		v, tx, ty := tpvTerms[k].evaluate(x, y)
+
+		value += c * v
+		dx += c * tx
+		dy += c * ty
+	}
+
+	return value, dx, dy
+}
+
+/*****************************************************************************************************************/
+
+// applyTPVDistortion maps the intermediate world coordinate (x, y) to the distorted coordinate (ξ, η), in degrees,
+// where the polynomial of the second axis is evaluated with the roles of x and y swapped.
+func (wcs *WCS) applyTPVDistortion(x, y float64) (xi, eta float64) {
+	return applyTPVPolynomials(getTPVCoefficients(wcs.TPV.PV1, 1), getTPVCoefficients(wcs.TPV.PV2, 2), x, y)
+}
+
+/*****************************************************************************************************************/
+
+// applyTPVPolynomials maps the intermediate world coordinate (x, y) to the distorted coordinate (ξ, η) with the
+// parsed PV1 and PV2 coefficients.
+func applyTPVPolynomials(pv1, pv2 []float64, x, y float64) (xi, eta float64) {
+	xi, _, _ = evaluateTPVPolynomial(pv1, x, y)
+
+	eta, _, _ = evaluateTPVPolynomial(pv2, y, x)
+
+	return xi, eta
+}
+
+/*****************************************************************************************************************/
+
+// removeTPVDistortion inverts the TPV polynomials by Newton's method, from the distorted coordinate (ξ, η) to the
+// intermediate world coordinate (x, y), in degrees, starting from the undistorted guess (x, y) = (ξ, η).
+func (wcs *WCS) removeTPVDistortion(xi, eta float64) (x, y float64, err error) {
+	return removeTPVPolynomials(getTPVCoefficients(wcs.TPV.PV1, 1), getTPVCoefficients(wcs.TPV.PV2, 2), xi, eta)
+}
+
+/*****************************************************************************************************************/
+
+// removeTPVPolynomials inverts the TPV polynomials, with the parsed PV1 and PV2 coefficients, by Newton's method.
+func removeTPVPolynomials(pv1, pv2 []float64, xi, eta float64) (x, y float64, err error) {
+	x, y = xi, eta
+
+	for iteration := 0; iteration < PROJECTION_MAXIMUM_ITERATIONS; iteration++ {
+		f, fx, fy := evaluateTPVPolynomial(pv1, x, y)
+
+		// The second axis is evaluated at (y, x), so its partial derivatives are swapped:
+		g, gy, gx := evaluateTPVPolynomial(pv2, y, x)
+
+		f -= xi
+		g -= eta
+
+		det := fx*gy - fy*gx
+
+		if det == 0 {
+			return x, y, errors.New("the TPV distortion is singular")
+		}
+
+		dx := (gy*f - fy*g) / det
+		dy := (-gx*f + fx*g) / det
+
+		x -= dx
+		y -= dy
+
+		if math.Hypot(dx, dy) < PROJECTION_CONVERGENCE*1e-2 {
+			return x, y, nil
+		}
+	}
+
+	return x, y, errors.New("the inverse of the TPV distortion did not converge")
+}
+
+/*****************************************************************************************************************/
+
+// GetApproximationError returns the maximum angular distance, in pixels of the source WCS, between the equatorial
+// coordinates of the two WCS solutions across an image of the given dimensions, sampled between the samples of a fit.
+func GetApproximationError(source WCS, target WCS, width, height int) float64 {
+	scale := math.Sqrt(math.Abs(source.CD1_1*source.CD2_2 - source.CD1_2*source.CD2_1))
+
+	maximum := 0.0
+
+	for _, pixel := range getSamplePixels(width, height, DISTORTION_VALIDATION_SAMPLES) {
+		a := source.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+		b := target.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		separation := getAngularSeparation(a.RA, a.Dec, b.RA, b.Dec)
+
+		if math.IsNaN(separation) {
+			return math.Inf(1)
+		}
+
+		maximum = math.Max(maximum, separation/scale)
+	}
+
+	return maximum
+}
+
+/*****************************************************************************************************************/
+
+// ToTPV converts the WCS, e.g., a TAN-SIP solution, to the equivalent TPV projection with polynomials of the given
+// order (at most seven), fitted across an image of the given dimensions. It returns the TPV solution and the maximum
+// approximation error, in pixels.
+func (wcs *WCS) ToTPV(width, height int, order int) (WCS, float64, error) {
+	if order < 1 || order > TPV_MAXIMUM_ORDER {
+		return WCS{}, math.Inf(1), fmt.Errorf("invalid TPV order: %d", order)
+	}
+
+	ctypes := RADEC_TPV.ToCTypes().ToFrame(wcs.GetFrame())
+
+	tpv := *wcs
+	tpv.CTYPE1 = ctypes.CType1
+	tpv.CTYPE2 = ctypes.CType2
+	tpv.FSIP = transform.SIP2DForwardParameters{}
+	tpv.ISIP = transform.SIP2DInverseParameters{}
+	tpv.TPV = transform.TPV2DParameters{}
+	tpv.LONPOLE, tpv.LATPOLE = getDefaultCelestialPole("TPV", tpv.CRVAL2)
+
+	pixels := getSamplePixels(width, height, DISTORTION_FIT_SAMPLES)
+
+	n := len(pixels)
+
+	x := make([]float64, n)
+	y := make([]float64, n)
+
+	xi := make([]float64, n)
+	eta := make([]float64, n)
+
+	weights := make([]float64, n)
+
+	for i, pixel := range pixels {
+		eq := wcs.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		phi, theta := tpv.celestialToNative(tpv.getWorldCoordinate(eq.RA, eq.Dec))
+
+		var ok bool
+
+		xi[i], eta[i], ok = project("TAN", phi, theta)
+		if !ok {
+			return WCS{}, math.Inf(1), fmt.Errorf("the pixel (%v, %v) cannot be projected", pixel[0], pixel[1])
+		}
+
+		dx, dy := pixel[0]-tpv.CRPIX1, pixel[1]-tpv.CRPIX2
+
+		x[i] = tpv.CD1_1*dx + tpv.CD1_2*dy
+		y[i] = tpv.CD2_1*dx + tpv.CD2_2*dy
+
+		weights[i] = 1
+	}
+
+	// Fit ξ and η as polynomials of (x, y), where the coefficient of x^p y^q of η is the PV2 term of y^q x^p:
+	p, q, err := fitSIPPolynomial(x, y, xi, eta, weights, getSIPTerms(0, order), "P", "Q")
+	if err != nil {
+		return WCS{}, math.Inf(1), err
+	}
+
+	tpv.TPV = transform.TPV2DParameters{
+		PV1: map[string]float64{},
+		PV2: map[string]float64{},
+	}
+
+	for k, term := range tpvTerms {
+		if term.r > 0 || term.p+term.q > order {
+			continue
+		}
+
+		tpv.TPV.PV1[fmt.Sprintf("PV1_%d", k)] = p[fmt.Sprintf("P_%d_%d", term.p, term.q)]
+		tpv.TPV.PV2[fmt.Sprintf("PV2_%d", k)] = q[fmt.Sprintf("Q_%d_%d", term.q, term.p)]
+	}
+
+	return tpv, GetApproximationError(*wcs, tpv, width, height), nil
+}
+
+/*****************************************************************************************************************/
+
+// ToSIP converts the WCS, e.g., a TPV solution, to the equivalent TAN-SIP solution with forward and inverse
+// polynomials of the given order, fitted across an image of the given dimensions. It returns the TAN-SIP solution
+// and the maximum approximation error, in pixels.
+func (wcs *WCS) ToSIP(width, height int, order int) (WCS, float64, error) {
+	pixels := getSamplePixels(width, height, DISTORTION_FIT_SAMPLES)
+
+	pairs := make([]PointPair, 0, len(pixels))
+
+	for _, pixel := range pixels {
+		eq := wcs.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		pairs = append(pairs, PointPair{X: pixel[0], Y: pixel[1], RA: eq.RA, Dec: eq.Dec})
+	}
+
+	sip, err := NewSIPWorldCoordinateSystemFromPointPairs(wcs.CRPIX1, wcs.CRPIX2, pairs, order)
+	if err != nil {
+		return WCS{}, math.Inf(1), err
+	}
+
+	// Retain the reference frame and the observation date of the source WCS:
+	sip.RADESYS = wcs.RADESYS
+	sip.EQUINOX = wcs.EQUINOX
+	sip.DATEOBS = wcs.DATEOBS
+	sip.MJDOBS = wcs.MJDOBS
+
+	return sip, GetApproximationError(*wcs, sip, width, height), nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,320 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+	"github.com/observerly/skysolve/pkg/transform"
+)
+
+/*****************************************************************************************************************/
+
+// newTPVWCS creates a TPV solution with SCAMP-like distortion coefficients, for a 0.5 degree field:
+func newTPVWCS() WCS {
+	return WCS{
+		WCAXES: 2,
+		CRPIX1: 1024, CRPIX2: 768,
+		CRVAL1: 150.12, CRVAL2: 2.21,
+		CTYPE1: "RA---TPV", CTYPE2: "DEC--TPV",
+		CUNIT1: "deg", CUNIT2: "deg",
+		CD1_1: -0.000263, CD1_2: 0.0000031,
+		CD2_1: 0.0000029, CD2_2: 0.000263,
+		CDELT1: -0.000263, CDELT2: 0.000263,
+		RADESYS: "ICRS",
+		LONPOLE: 180, LATPOLE: 2.21,
+		TPV: transform.TPV2DParameters{
+			PV1: map[string]float64{"PV1_0": 1.2e-5, "PV1_1": 1.0002, "PV1_2": -3.1e-4, "PV1_4": 2.1e-3, "PV1_7": -0.012, "PV1_9": 0.004},
+			PV2: map[string]float64{"PV2_0": -8.0e-6, "PV2_1": 0.9997, "PV2_2": 2.4e-4, "PV2_5": -1.7e-3, "PV2_7": -0.011, "PV2_11": 0.003},
+		},
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestTPVTerms(t *testing.T) {
+	if len(tpvTerms) != 40 {
+		t.Fatalf("expected 40 TPV terms, got %d", len(tpvTerms))
+	}
+
+	tests := map[int]tpvTerm{
+		0:  {},
+		1:  {p: 1},
+		2:  {q: 1},
+		3:  {r: 1},
+		5:  {p: 1, q: 1},
+		8:  {p: 2, q: 1},
+		11: {r: 3},
+		12: {p: 4},
+		23: {r: 5},
+		30: {q: 6},
+		39: {r: 7},
+	}
+
+	for k, want := range tests {
+		if tpvTerms[k] != want {
+			t.Errorf("expected PVi_%d to be %+v, got %+v", k, want, tpvTerms[k])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestTPVDistortionRoundTrip(t *testing.T) {
+	w := newTPVWCS()
+
+	// The second axis swaps the roles of x and y, i.e., PV2_2 is the coefficient of x and PV2_5 of xy:
+	xi, eta := w.applyTPVDistortion(0.1, 0.2)
+
+	wantXi := 1.2e-5 + 1.0002*0.1 - 3.1e-4*0.2 + 2.1e-3*0.01 - 0.012*0.001 + 0.004*0.1*0.04
+	wantEta := -8.0e-6 + 0.9997*0.2 + 2.4e-4*0.1 - 1.7e-3*0.02 - 0.011*0.008 + 0.003*math.Pow(math.Hypot(0.1, 0.2), 3)
+
+	if math.Abs(xi-wantXi) > 1e-15 || math.Abs(eta-wantEta) > 1e-15 {
+		t.Errorf("expected (ξ, η) = (%v, %v), got (%v, %v)", wantXi, wantEta, xi, eta)
+	}
+
+	x, y, err := w.removeTPVDistortion(xi, eta)
+	if err != nil || math.Abs(x-0.1) > 1e-12 || math.Abs(y-0.2) > 1e-12 {
+		t.Errorf("expected the distortion to invert to (0.1, 0.2), got (%v, %v, %v)", x, y, err)
+	}
+
+	for _, pixel := range [][2]float64{{0, 0}, {2048, 0}, {0, 1536}, {2048, 1536}, {1024, 768}, {300, 1200}} {
+		eq := w.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		x, y := w.EquatorialCoordinateToPixel(eq.RA, eq.Dec)
+
+		if math.Hypot(x-pixel[0], y-pixel[1]) > 1e-6 {
+			t.Errorf("expected %v to round trip, got (%v, %v)", pixel, x, y)
+		}
+	}
+
+	// A TPV projection without PV keywords is the gnomonic projection, i.e., PVi_1 defaults to one:
+	tan := newTPVWCS()
+	tan.TPV = transform.TPV2DParameters{}
+
+	if xi, eta := tan.applyTPVDistortion(0.1, 0.2); xi != 0.1 || eta != 0.2 {
+		t.Errorf("expected the identity distortion, got (%v, %v)", xi, eta)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestTPVFITSHeaderRoundTrip(t *testing.T) {
+	w := newTPVWCS()
+
+	header := w.ToFITSHeader()
+
+	if header.Has("A_ORDER") || !header.Has("PV1_7") || !header.Has("PV2_11") || header.Has("PV1_3") {
+		t.Errorf("expected only the TPV coefficients to be written")
+	}
+
+	parsed, _, err := fitsio.ReadHeader(strings.NewReader(string(header.Bytes())))
+	if err != nil {
+		t.Fatalf("failed to read the serialized header: %v", err)
+	}
+
+	roundtrip, err := FromFITSHeader(parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(w, roundtrip) {
+		t.Errorf("expected the WCS to round trip, got %+v, want %+v", roundtrip, w)
+	}
+
+	// The PVi_k keywords of non-TPV projections are not distortion coefficients:
+	parsed.Set("CTYPE1", "RA---ZEA", "")
+	parsed.Set("CTYPE2", "DEC--ZEA", "")
+
+	zea, err := FromFITSHeader(parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if zea.TPV.PV1 != nil || zea.TPV.PV2 != nil {
+		t.Errorf("expected no TPV coefficients for a ZEA projection, got %+v", zea.TPV)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// The monomials x^p y^q of the first twelve PVi_k terms, i.e., to third order, where the radial terms PVi_3 and
+// PVi_11 are r and r^3:
+var tpvTestMonomials = [12][2]int{{0, 0}, {1, 0}, {0, 1}, {-1, 1}, {2, 0}, {1, 1}, {0, 2}, {3, 0}, {2, 1}, {1, 2}, {0, 3}, {-1, 3}}
+
+/*****************************************************************************************************************/
+
+// evaluateGnomonic independently evaluates the gnomonic (TAN) projection of the WCS, from the standard equations of
+// the tangent plane, where distort maps the pixel offsets from the reference pixel to the standard coordinates (ξ,
+// η) in degrees:
+func evaluateGnomonic(w WCS, x, y float64, distort func(u, v float64) (xi, eta float64)) (ra, dec float64) {
+	xi, eta := distort(x-w.CRPIX1, y-w.CRPIX2)
+
+	xi, eta = xi*math.Pi/180, eta*math.Pi/180
+
+	ra0, dec0 := w.CRVAL1*math.Pi/180, w.CRVAL2*math.Pi/180
+
+	d := math.Cos(dec0) - eta*math.Sin(dec0)
+
+	ra = math.Mod(ra0+math.Atan2(xi, d)+2*math.Pi, 2*math.Pi)
+
+	dec = math.Atan2(math.Sin(dec0)+eta*math.Cos(dec0), math.Hypot(xi, d))
+
+	return ra * 180 / math.Pi, dec * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
+
+// evaluateSIPLinear independently evaluates a TAN-SIP solution by the linear model, applying the forward SIP
+// polynomials to the pixel offsets before the CD matrix, whose intermediate world coordinates are added to CRVAL:
+func evaluateSIPLinear(w WCS, x, y float64) (ra, dec float64) {
+	u, v := x-w.CRPIX1, y-w.CRPIX2
+
+	f, g := u, v
+
+	for p := 0; p <= w.FSIP.AOrder; p++ {
+		for q := 0; q <= w.FSIP.AOrder-p; q++ {
+			f += w.FSIP.APower[fmt.Sprintf("A_%d_%d", p, q)] * math.Pow(u, float64(p)) * math.Pow(v, float64(q))
+		}
+	}
+
+	for p := 0; p <= w.FSIP.BOrder; p++ {
+		for q := 0; q <= w.FSIP.BOrder-p; q++ {
+			g += w.FSIP.BPower[fmt.Sprintf("B_%d_%d", p, q)] * math.Pow(u, float64(p)) * math.Pow(v, float64(q))
+		}
+	}
+
+	return w.CRVAL1 + w.CD1_1*f + w.CD1_2*g, w.CRVAL2 + w.CD2_1*f + w.CD2_2*g
+}
+
+/*****************************************************************************************************************/
+
+// evaluateTPVGnomonic independently evaluates a third order TPV solution, applying the PVi_k polynomials to the
+// intermediate world coordinates of the CD matrix, where the second axis swaps the roles of x and y:
+func evaluateTPVGnomonic(w WCS, x, y float64) (ra, dec float64) {
+	polynomial := func(pv map[string]float64, axis int, x, y float64) (value float64) {
+		for k, m := range tpvTestMonomials {
+			c := pv[fmt.Sprintf("PV%d_%d", axis, k)]
+
+			if m[0] < 0 {
+				value += c * math.Pow(math.Hypot(x, y), float64(m[1]))
+				continue
+			}
+
+			value += c * math.Pow(x, float64(m[0])) * math.Pow(y, float64(m[1]))
+		}
+
+		return value
+	}
+
+	return evaluateGnomonic(w, x, y, func(u, v float64) (float64, float64) {
+		x, y := w.CD1_1*u+w.CD1_2*v, w.CD2_1*u+w.CD2_2*v
+
+		return polynomial(w.TPV.PV1, 1, x, y), polynomial(w.TPV.PV2, 2, y, x)
+	})
+}
+
+/*****************************************************************************************************************/
+
+func TestSIPToTPVConversion(t *testing.T) {
+	sip := NewWorldCoordinateSystem(1024, 768, WCSParams{
+		Projection: RADEC_TANSIP,
+		AffineParams: transform.Affine2DParameters{
+			A: -0.000263, B: 0.0000031, C: 150.12,
+			D: 0.0000029, E: 0.000263, F: 2.21,
+		},
+		SIPForwardParams: transform.SIP2DForwardParameters{
+			AOrder: 3,
+			APower: map[string]float64{"A_2_0": 4e-6, "A_0_2": -2e-6, "A_3_0": 1e-9, "A_1_2": 2e-9},
+			BOrder: 3,
+			BPower: map[string]float64{"B_1_1": 3e-6, "B_0_3": -1.5e-9, "B_2_1": 1e-9},
+		},
+	})
+
+	tpv, maximum, err := sip.ToTPV(2048, 1536, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tpv.CTYPE1 != "RA---TPV" || tpv.CTYPE2 != "DEC--TPV" || len(tpv.FSIP.APower) != 0 {
+		t.Errorf("expected a TPV projection without SIP distortions, got %s and %+v", tpv.CTYPE1, tpv.FSIP)
+	}
+
+	// The third order SIP polynomials, of the linear model, are described by third order TPV polynomials to well
+	// within a hundredth of a pixel:
+	if maximum > 0.01 {
+		t.Errorf("expected a maximum approximation error below 0.01 pixels, got %v", maximum)
+	}
+
+	// The TPV solution is compared with independent evaluations of the gnomonic projection of the TPV solution, and
+	// of the linear model of the TAN-SIP solution, across the image and its corners:
+	scale := 0.000263
+
+	for _, pixel := range [][2]float64{{0, 0}, {2047, 0}, {0, 1535}, {2047, 1535}, {1024, 768}, {300, 1200}, {1900, 100}} {
+		ra, dec := evaluateSIPLinear(sip, pixel[0], pixel[1])
+
+		tra, tdec := evaluateTPVGnomonic(tpv, pixel[0], pixel[1])
+
+		if separation := getAngularSeparation(ra, dec, tra, tdec); separation/scale > maximum+1e-9 {
+			t.Errorf("expected the TPV solution at %v within %v pixels of the TAN-SIP solution, got %v pixels", pixel, maximum, separation/scale)
+		}
+
+		eq := tpv.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		if separation := getAngularSeparation(eq.RA, eq.Dec, tra, tdec); separation/scale > 1e-9 {
+			t.Errorf("expected the TPV evaluation at %v to match the gnomonic projection, got %v pixels", pixel, separation/scale)
+		}
+
+		eq = sip.PixelToEquatorialCoordinate(pixel[0], pixel[1])
+
+		if separation := getAngularSeparation(eq.RA, eq.Dec, ra, dec); separation/scale > 1e-9 {
+			t.Errorf("expected the TAN-SIP evaluation at %v to match the linear model, got %v pixels", pixel, separation/scale)
+		}
+	}
+
+	if got := GetApproximationError(sip, tpv, 2048, 1536); got != maximum {
+		t.Errorf("expected the reported approximation error %v, got %v", maximum, got)
+	}
+
+	back, maximum, err := tpv.ToSIP(2048, 1536, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if back.CTYPE1 != "RA---TAN-SIP" || back.FSIP.AOrder != 3 || back.RADESYS != "ICRS" {
+		t.Errorf("expected a third order TAN-SIP projection, got %s of order %d", back.CTYPE1, back.FSIP.AOrder)
+	}
+
+	if maximum > 0.01 {
+		t.Errorf("expected a maximum approximation error below 0.01 pixels, got %v", maximum)
+	}
+
+	for _, pixel := range [][2]float64{{0, 0}, {2047, 0}, {0, 1535}, {2047, 1535}, {300, 1200}} {
+		ra, dec := evaluateSIPLinear(sip, pixel[0], pixel[1])
+
+		bra, bdec := evaluateSIPLinear(back, pixel[0], pixel[1])
+
+		if separation := getAngularSeparation(ra, dec, bra, bdec); separation/scale > 0.02 {
+			t.Errorf("expected the TAN-SIP solution at %v within 0.02 pixels of the source, got %v pixels", pixel, separation/scale)
+		}
+	}
+
+	if _, _, err := sip.ToTPV(2048, 1536, 8); err == nil {
+		t.Errorf("expected an error for a TPV order above seven")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -18,6 +18,7 @@ import (
 
 	"github.com/observerly/skysolve/pkg/astrometry"
 	"github.com/observerly/skysolve/pkg/spatial"
+	"github.com/observerly/skysolve/pkg/star"
 	"github.com/observerly/skysolve/pkg/transform"
 	"gonum.org/v1/gonum/mat"
 )
@@ -29,6 +30,12 @@ type CoordinateProjectionType int
 const (
 	RADEC_TAN CoordinateProjectionType = iota
 	RADEC_TANSIP
+	RADEC_SIN
+	RADEC_ARC
+	RADEC_ZEA
+	RADEC_STG
+	RADEC_CAR
+	RADEC_TPV
 )
 
 /*****************************************************************************************************************/
@@ -52,6 +59,36 @@ func (c CoordinateProjectionType) ToCTypes() CTypeP {
 			CType1: "RA---TAN-SIP",
 			CType2: "DEC--TAN-SIP",
 		}
+	case RADEC_SIN:
+		return CTypeP{
+			CType1: "RA---SIN",
+			CType2: "DEC--SIN",
+		}
+	case RADEC_ARC:
+		return CTypeP{
+			CType1: "RA---ARC",
+			CType2: "DEC--ARC",
+		}
+	case RADEC_ZEA:
+		return CTypeP{
+			CType1: "RA---ZEA",
+			CType2: "DEC--ZEA",
+		}
+	case RADEC_STG:
+		return CTypeP{
+			CType1: "RA---STG",
+			CType2: "DEC--STG",
+		}
+	case RADEC_CAR:
+		return CTypeP{
+			CType1: "RA---CAR",
+			CType2: "DEC--CAR",
+		}
+	case RADEC_TPV:
+		return CTypeP{
+			CType1: "RA---TPV",
+			CType2: "DEC--TPV",
+		}
 	default:
 		return CTypeP{
 			CType1: "RA---TAN",
@@ -63,44 +100,52 @@ func (c CoordinateProjectionType) ToCTypes() CTypeP {
 /*****************************************************************************************************************/
 
 type WCSParams struct {
-	Projection       CoordinateProjectionType         // Projection type e.g., "TAN", or "TAN-SIP"
+	Projection       CoordinateProjectionType         // Projection type e.g., "TAN", "TAN-SIP", "TPV", "SIN", "ARC", "ZEA", "STG" or "CAR"
 	AffineParams     transform.Affine2DParameters     // Affine transformation parameters
 	ReferenceX       float64                          // Reference X coordinate
 	ReferenceY       float64                          // Reference Y coordinate
 	SIPForwardParams transform.SIP2DForwardParameters // SIP forward transformation (distortion) coefficients, x, y to RA, Dec
 	SIPInverseParams transform.SIP2DInverseParameters // SIP inverse transformation (distortion) coefficients RA, Dec to x, y
+	Frame            astrometry.CoordinateFrame       // The celestial frame of the affine parameters, e.g., galactic (the ICRS if empty)
 }
 
 /*****************************************************************************************************************/
 
 type WCS struct {
-	WCAXES int                              `json:"wcaxes" hdu:"WCAXES" default:"2"`        // Number of world coordinate axes
-	CRPIX1 float64                          `json:"crpix1" hdu:"CRPIX1"`                    // Reference pixel X
-	CRPIX2 float64                          `json:"crpix2" hdu:"CRPIX2"`                    // Reference pixel Y
-	CRVAL1 float64                          `json:"crval1" hdu:"CRVAL1" default:"0.0"`      // Reference RA (example default, often specific to image)
-	CRVAL2 float64                          `json:"crval2" hdu:"CRVAL2" default:"0.0"`      // Reference Dec (example default, often specific to image)
-	CTYPE1 string                           `json:"ctype1" hdu:"CTYPE1" default:"RA---TAN"` // Coordinate type for axis 1, typically RA with TAN projection
-	CTYPE2 string                           `json:"ctype2" hdu:"CTYPE2" default:"DEC--TAN"` // Coordinate type for axis 2, typically DEC with TAN projection
-	CDELT1 float64                          `json:"cdelt1" hdu:"CDELT1"`                    // Coordinate increment for axis 1 (no default)
-	CDELT2 float64                          `json:"cdelt2" hdu:"CDELT2"`                    // Coordinate increment for axis 2 (no default)
-	CUNIT1 string                           `json:"cunit1" hdu:"CUNIT1" default:"deg"`      // Coordinate unit for axis 1, defaulted to degrees
-	CUNIT2 string                           `json:"cunit2" hdu:"CUNIT2" default:"deg"`      // Coordinate unit for axis 2, defaulted to degrees
-	CD1_1  float64                          `json:"cd1_1" hdu:"CD1_1"`                      // Affine transform parameter A (no default)
-	CD1_2  float64                          `json:"cd1_2" hdu:"CD1_2"`                      // Affine transform parameter B (no default)
-	CD2_1  float64                          `json:"cd2_1" hdu:"CD2_1"`                      // Affine transform parameter C (no default)
-	CD2_2  float64                          `json:"cd2_2" hdu:"CD2_2"`                      // Affine transform parameter D (no default)
-	E      float64                          `json:"e" hdu:"E"`                              // Affine translation parameter e (optional, no default)
-	F      float64                          `json:"f" hdu:"F"`                              // Affine translation parameter f (optional, no default)
-	FSIP   transform.SIP2DForwardParameters `json:"fsip" hdu:"FSIP"`                        // SIP forward transformation (distortion) coefficients
-	ISIP   transform.SIP2DInverseParameters `json:"isip" hdu:"ISIP"`                        // SIP inverse transformation (distortion) coefficients
+	WCAXES  int                              `json:"wcaxes" hdu:"WCAXES" default:"2"`        // Number of world coordinate axes
+	CRPIX1  float64                          `json:"crpix1" hdu:"CRPIX1"`                    // Reference pixel X
+	CRPIX2  float64                          `json:"crpix2" hdu:"CRPIX2"`                    // Reference pixel Y
+	CRVAL1  float64                          `json:"crval1" hdu:"CRVAL1" default:"0.0"`      // Reference RA (example default, often specific to image)
+	CRVAL2  float64                          `json:"crval2" hdu:"CRVAL2" default:"0.0"`      // Reference Dec (example default, often specific to image)
+	CTYPE1  string                           `json:"ctype1" hdu:"CTYPE1" default:"RA---TAN"` // Coordinate type for axis 1, typically RA with TAN projection
+	CTYPE2  string                           `json:"ctype2" hdu:"CTYPE2" default:"DEC--TAN"` // Coordinate type for axis 2, typically DEC with TAN projection
+	CDELT1  float64                          `json:"cdelt1" hdu:"CDELT1"`                    // Coordinate increment for axis 1 (no default)
+	CDELT2  float64                          `json:"cdelt2" hdu:"CDELT2"`                    // Coordinate increment for axis 2 (no default)
+	CUNIT1  string                           `json:"cunit1" hdu:"CUNIT1" default:"deg"`      // Coordinate unit for axis 1, defaulted to degrees
+	CUNIT2  string                           `json:"cunit2" hdu:"CUNIT2" default:"deg"`      // Coordinate unit for axis 2, defaulted to degrees
+	CD1_1   float64                          `json:"cd1_1" hdu:"CD1_1"`                      // Affine transform parameter A (no default)
+	CD1_2   float64                          `json:"cd1_2" hdu:"CD1_2"`                      // Affine transform parameter B (no default)
+	CD2_1   float64                          `json:"cd2_1" hdu:"CD2_1"`                      // Affine transform parameter C (no default)
+	CD2_2   float64                          `json:"cd2_2" hdu:"CD2_2"`                      // Affine transform parameter D (no default)
+	E       float64                          `json:"e" hdu:"E"`                              // Affine translation parameter e (optional, no default)
+	F       float64                          `json:"f" hdu:"F"`                              // Affine translation parameter f (optional, no default)
+	FSIP    transform.SIP2DForwardParameters `json:"fsip" hdu:"FSIP"`                        // SIP forward transformation (distortion) coefficients
+	ISIP    transform.SIP2DInverseParameters `json:"isip" hdu:"ISIP"`                        // SIP inverse transformation (distortion) coefficients
+	TPV     transform.TPV2DParameters        `json:"tpv" hdu:"TPV"`                          // TPV (PVi_k) distortion coefficients of a TPV projection
+	RADESYS string                           `json:"radesys" hdu:"RADESYS" default:"ICRS"`   // The reference frame of the equatorial coordinates
+	EQUINOX float64                          `json:"equinox" hdu:"EQUINOX"`                  // The equinox of the equatorial coordinates (FK4 and FK5 only)
+	LONPOLE float64                          `json:"lonpole" hdu:"LONPOLE" default:"180.0"`  // The native longitude of the celestial pole
+	LATPOLE float64                          `json:"latpole" hdu:"LATPOLE"`                  // The native latitude of the celestial pole
+	DATEOBS string                           `json:"dateobs" hdu:"DATE-OBS"`                 // The ISO-8601 date and time of the observation
+	MJDOBS  float64                          `json:"mjdobs" hdu:"MJD-OBS"`                   // The modified Julian date of the observation
 }
 
 /*****************************************************************************************************************/
 
 // NewWorldCoordinateSystem creates a new WCS object with correctly mapped affine parameters.
 func NewWorldCoordinateSystem(xc float64, yc float64, params WCSParams) WCS {
-	// Get the coordinate projection types, e.g., "RA---TAN", or "RA---TAN-SIP":
-	ctypes := params.Projection.ToCTypes()
+	// Get the coordinate projection types, e.g., "RA---TAN", "RA---TAN-SIP" or "GLON-TAN" for galactic coordinates:
+	ctypes := params.Projection.ToCTypes().ToFrame(params.Frame)
 
 	// Set CD matrix elements
 	CD1_1 := params.AffineParams.A
@@ -129,8 +174,18 @@ func NewWorldCoordinateSystem(xc float64, yc float64, params WCSParams) WCS {
 		CD2_2:  CD2_2,
 		FSIP:   params.SIPForwardParams,
 		ISIP:   params.SIPInverseParams,
+		// The equatorial coordinates of the Gaia catalog are in the ICRS reference frame:
+		RADESYS: "ICRS",
 	}
 
+	// The RADESYS keyword only describes equatorial and ecliptic coordinates:
+	if wcs.GetFrame() == astrometry.FRAME_GALACTIC {
+		wcs.RADESYS = ""
+	}
+
+	// The celestial pole is at a native longitude of 180 degrees for zenithal projections, e.g., TAN:
+	wcs.LONPOLE, wcs.LATPOLE = getDefaultCelestialPole(getProjectionCode(wcs.CTYPE1), wcs.CRVAL2)
+
 	// Calculate the coordinate increment for axis 1 (CDELT1)
 	wcs.CDELT1 = -math.Sqrt(wcs.CD1_1*wcs.CD1_1 + wcs.CD2_1*wcs.CD2_1)
 
@@ -139,15 +194,15 @@ func NewWorldCoordinateSystem(xc float64, yc float64, params WCSParams) WCS {
 
 	// If a reference pixel is provided, then re-calibrate the WCS object to the reference pixel
 	if params.ReferenceX != 0 && params.ReferenceY != 0 {
-		eq := wcs.PixelToEquatorialCoordinate(params.ReferenceX, params.ReferenceY)
-
-		wcs.CRVAL1 = eq.RA
-		wcs.CRVAL2 = eq.Dec
+		wcs.CRVAL1, wcs.CRVAL2 = wcs.PixelToWorldCoordinate(params.ReferenceX, params.ReferenceY)
 
 		wcs.CRPIX1 = params.ReferenceX
 		wcs.CRPIX2 = params.ReferenceY
 	}
 
+	// The default celestial pole depends upon the (re-calibrated) declination of the reference point:
+	wcs.LONPOLE, wcs.LATPOLE = getDefaultCelestialPole(getProjectionCode(wcs.CTYPE1), wcs.CRVAL2)
+
 	return wcs
 }
 
@@ -177,39 +232,52 @@ func parseSIPTerm(term, prefix string) (i int, j int, err error) {
 
 /*****************************************************************************************************************/
 
+// PixelToEquatorialCoordinate converts the pixel (x, y) to its equatorial coordinate, applying the forward SIP (or
+// TPV) distortions of the WCS.
 func (wcs *WCS) PixelToEquatorialCoordinate(
 	x, y float64,
 ) (coordinate astrometry.ICRSEquatorialCoordinate) {
+	return wcs.pixelToEquatorialCoordinate(x, y, wcs.getDistortions())
+}
+
+/*****************************************************************************************************************/
+
+// pixelToEquatorialCoordinate converts the pixel (x, y) to its equatorial coordinate with the parsed distortions.
+func (wcs *WCS) pixelToEquatorialCoordinate(
+	x, y float64,
+	d *distortions,
+) (coordinate astrometry.ICRSEquatorialCoordinate) {
+	lon, lat := wcs.pixelToWorldCoordinate(x, y, d)
+
+	// Convert the world coordinate from the frame of the WCS, e.g., galactic coordinates, to the ICRS:
+	return astrometry.NewICRSEquatorialCoordinateFromFrame(wcs.GetFrame(), lon, lat)
+}
+
+/*****************************************************************************************************************/
+
+// pixelToWorldCoordinate converts the pixel (x, y) to the longitude and latitude of the celestial frame of the WCS,
+// e.g., the right ascension and declination, with the parsed distortions.
+func (wcs *WCS) pixelToWorldCoordinate(
+	x, y float64,
+	d *distortions,
+) (lon float64, lat float64) {
 	// Compute the offsets from the reference pixel
 	deltaX := x - wcs.CRPIX1 // Offset in X
 	deltaY := y - wcs.CRPIX2 // Offset in Y
 
 	// Compute non-linear SIP distortion corrections A and B:
-	A := 0.0
-	B := 0.0
-
-	// Apply A polynomial corrections:
-	for term, coeff := range wcs.FSIP.APower {
-		i, j, err := parseSIPTerm(term, "A")
-		if err != nil {
-			continue
-		}
-		A += coeff * math.Pow(deltaX, float64(i)) * math.Pow(deltaY, float64(j))
-	}
-
-	// Apply B polynomial corrections:
-	for term, coeff := range wcs.FSIP.BPower {
-		i, j, err := parseSIPTerm(term, "B")
-		if err != nil {
-			continue
-		}
-		B += coeff * math.Pow(deltaX, float64(i)) * math.Pow(deltaY, float64(j))
-	}
+	A, _, _ := d.a.evaluate(deltaX, deltaY)
+	B, _, _ := d.b.evaluate(deltaX, deltaY)
 
 	// Apply forward SIP transformation to correct for non-linear distortions:
 	deltaX += A
 	deltaY += B
 
+	// The spherical projections, e.g., SIN or ZEA, are deprojected and rotated onto the celestial sphere:
+	if wcs.isSpherical() {
+		return wcs.pixelToSphericalWorldCoordinate(deltaX, deltaY, d)
+	}
+
 	// Calculate the reference equatorial coordinate for the right ascension:
 	ra := wcs.CD1_1*deltaX + wcs.CD1_2*deltaY + wcs.CRVAL1
 
@@ -227,17 +295,49 @@ func (wcs *WCS) PixelToEquatorialCoordinate(
 	// Correct for large values of declination:
 	dec = math.Mod(dec, 90)
 
-	return astrometry.ICRSEquatorialCoordinate{
-		RA:  ra,
-		Dec: dec,
-	}
+	return ra, dec
 }
 
 /*****************************************************************************************************************/
 
-// without iterative removal of SIP distortions.
+// EquatorialCoordinateToPixel converts the equatorial coordinate to its pixel, where the forward SIP distortions are
+// removed by Newton's method, starting from the estimate of the inverse (AP, BP) polynomials, if any, such that the
+// result is accurate even when the inverse polynomials are absent or inaccurate.
 func (wcs *WCS) EquatorialCoordinateToPixel(
 	ra, dec float64,
+) (x, y float64) {
+	return wcs.equatorialCoordinateToPixel(ra, dec, wcs.getDistortions())
+}
+
+/*****************************************************************************************************************/
+
+// equatorialCoordinateToPixel converts the equatorial coordinate to its pixel with the parsed distortions.
+func (wcs *WCS) equatorialCoordinateToPixel(
+	ra, dec float64,
+	d *distortions,
+) (x, y float64) {
+	frame := wcs.GetFrame()
+
+	if frame == astrometry.FRAME_ICRS {
+		return wcs.worldCoordinateToPixel(ra, dec, d)
+	}
+
+	// Convert the equatorial coordinate to the frame of the WCS, e.g., galactic coordinates, where the longitude is
+	// wrapped about the reference point:
+	lon, lat := astrometry.ICRSEquatorialCoordinate{RA: ra, Dec: dec}.ToFrame(frame)
+
+	lon = wcs.CRVAL1 + math.Mod(lon-wcs.CRVAL1+540, 360) - 180
+
+	return wcs.worldCoordinateToPixel(lon, lat, d)
+}
+
+/*****************************************************************************************************************/
+
+// worldCoordinateToPixel converts the longitude and latitude of the celestial frame of the WCS, e.g., the right
+// ascension and declination, to its pixel with the parsed distortions.
+func (wcs *WCS) worldCoordinateToPixel(
+	ra, dec float64,
+	d *distortions,
 ) (x, y float64) {
 	// Find the determinant of the CD matrix, for the inverse CD matrix:
 	det := wcs.CD1_1*wcs.CD2_2 - wcs.CD1_2*wcs.CD2_1
@@ -264,32 +364,32 @@ func (wcs *WCS) EquatorialCoordinateToPixel(
 	deltaX := invCD1_1*ra + invCD1_2*dec + invC
 	deltaY := invCD2_1*ra + invCD2_2*dec + invF
 
-	// Compute non-linear SIP distortion corrections A and B:
-	A := 0.0
-	B := 0.0
-
-	// Apply inverse A polynomial corrections
-	for term, coeff := range wcs.ISIP.APPower {
-		i, j, err := parseSIPTerm(term, "AP")
-		if err != nil {
-			continue
-		}
-		A += coeff * math.Pow(deltaX, float64(i)) * math.Pow(deltaY, float64(j))
-	}
+	// The spherical projections, e.g., SIN or ZEA, are projected from the celestial sphere before the inverse CD matrix:
+	if wcs.isSpherical() {
+		var err error
 
-	// Apply inverse B polynomial corrections
-	for term, coeff := range wcs.ISIP.BPPower {
-		i, j, err := parseSIPTerm(term, "BP")
-		if err != nil {
-			continue
+		if deltaX, deltaY, err = wcs.getSphericalIntermediatePixelOffset(ra, dec, d); err != nil {
+			return math.NaN(), math.NaN()
 		}
-		B += coeff * math.Pow(deltaX, float64(i)) * math.Pow(deltaY, float64(j))
 	}
 
+	// Compute non-linear SIP distortion corrections A and B:
+	A, _, _ := d.ap.evaluate(deltaX, deltaY)
+	B, _, _ := d.bp.evaluate(deltaX, deltaY)
+
 	// Apply backward SIP transformation to correct for non-linear distortions:
+	u, v := deltaX, deltaY
+
 	deltaX += A
 	deltaY += B
 
+	// Refine the inverse of the forward SIP distortions, retaining the inverse polynomial estimate on failure:
+	if wcs.hasForwardSIP() {
+		if dx, dy, err := d.removeForwardSIPDistortion(u, v, deltaX, deltaY); err == nil {
+			deltaX, deltaY = dx, dy
+		}
+	}
+
 	// Add the reference pixel coordinates to obtain final pixel positions
 	x = deltaX + wcs.CRPIX1
 	y = deltaY + wcs.CRPIX2
@@ -303,52 +403,83 @@ func (wcs *WCS) EquatorialCoordinateToPixel(
 type PointPair struct {
 	X, Y    float64 // Generated Quad NormalisedC and NormalisedD
 	RA, Dec float64 // Source Quad SourceRA and SourceDec
+	Weight  float64 // The least squares weight, e.g., 1/σ² of the pixel position, where zero denotes unit weight
 }
 
 /*****************************************************************************************************************/
 
-// ComputeAffineTransformation computes the affine transformation parameters based on matched quads.
-// It returns the affine parameters and an error if the computation fails.
-func ComputeAffineTransformation(matches []spatial.QuadMatch) (transform.Affine2DParameters, float64, float64, error) {
-	var pairs []PointPair
+// GetWeightFromUncertainty returns the inverse variance least squares weight for a 1σ positional uncertainty (in
+// pixels), or zero (i.e., unit weight) when the uncertainty is unknown.
+func GetWeightFromUncertainty(sigma float64) float64 {
+	if sigma <= 0 || math.IsNaN(sigma) || math.IsInf(sigma, 0) {
+		return 0
+	}
+
+	return 1 / (sigma * sigma)
+}
+
+/*****************************************************************************************************************/
+
+// weight returns the effective least squares weight of the point pair.
+func (p PointPair) weight() float64 {
+	if p.Weight <= 0 {
+		return 1
+	}
+
+	return p.Weight
+}
+
+/*****************************************************************************************************************/
+
+// GetPointPairsFromQuadMatches returns the unique point correspondences (A, B, C and D) of the matched quads,
+// weighted by the positional uncertainties of the stars, such that a star shared by several overlapping quads is
+// not counted more than once in the fit.
+func GetPointPairsFromQuadMatches(matches []spatial.QuadMatch) []PointPair {
+	seen := make(map[PointPair]bool, 4*len(matches))
+
+	pairs := make([]PointPair, 0, 4*len(matches))
 
 	// Iterate over each match to extract all four point correspondences:
 	for _, match := range matches {
-		// Extract Point A:
-		pairs = append(pairs, PointPair{
-			X:   match.Quad.A.X,   // Generated Quad X
-			Y:   match.Quad.A.Y,   // Generated Quad Y
-			RA:  match.Quad.A.RA,  // Source Quad RA
-			Dec: match.Quad.A.Dec, // Source Quad Dec
-		})
-
-		// Extract Point B:
-		pairs = append(pairs, PointPair{
-			X:   match.Quad.B.X,
-			Y:   match.Quad.B.Y,
-			RA:  match.Quad.B.RA,
-			Dec: match.Quad.B.Dec,
-		})
-
-		// Extract Point C:
-		pairs = append(pairs, PointPair{
-			X:   match.Quad.C.X,
-			Y:   match.Quad.C.Y,
-			RA:  match.Quad.C.RA,
-			Dec: match.Quad.C.Dec,
-		})
-
-		// Extract Point D:
-		pairs = append(pairs, PointPair{
-			X:   match.Quad.D.X,
-			Y:   match.Quad.D.Y,
-			RA:  match.Quad.D.RA,
-			Dec: match.Quad.D.Dec,
-		})
+		for _, point := range []star.Star{match.Quad.A, match.Quad.B, match.Quad.C, match.Quad.D} {
+			pair := PointPair{
+				X:      point.X,   // Generated Quad X
+				Y:      point.Y,   // Generated Quad Y
+				RA:     point.RA,  // Source Quad RA
+				Dec:    point.Dec, // Source Quad Dec
+				Weight: GetWeightFromUncertainty(point.Uncertainty),
+			}
+
+			// Skip the correspondences already contributed by an overlapping quad:
+			if seen[pair] {
+				continue
+			}
+
+			seen[pair] = true
+
+			pairs = append(pairs, pair)
+		}
 	}
 
+	return pairs
+}
+
+/*****************************************************************************************************************/
+
+// ComputeAffineTransformation computes the affine transformation parameters based on matched quads.
+// It returns the affine parameters and an error if the computation fails.
+func ComputeAffineTransformation(matches []spatial.QuadMatch) (transform.Affine2DParameters, float64, float64, error) {
+	return ComputeAffineTransformationFromPointPairs(GetPointPairsFromQuadMatches(matches))
+}
+
+/*****************************************************************************************************************/
+
+// ComputeAffineTransformationFromPointPairs computes the affine transformation parameters from any set of
+// pixel to equatorial point correspondences, e.g., those found by a triangle or nearest neighbour matcher. Each
+// correspondence is weighted by its Weight, such that precisely measured stars dominate the least squares fit.
+func ComputeAffineTransformationFromPointPairs(pairs []PointPair) (transform.Affine2DParameters, float64, float64, error) {
 	n := len(pairs)
-	if n < 2 { // Need at least three point correspondences for affine transformation:
+	if n < 3 { // Need at least three point correspondences for affine transformation:
 		return transform.Affine2DParameters{}, math.Inf(1), math.Inf(1), errors.New("not enough point correspondences to compute affine transformation")
 	}
 
@@ -357,23 +488,26 @@ func ComputeAffineTransformation(matches []spatial.QuadMatch) (transform.Affine2
 	bVec := mat.NewVecDense(2*n, nil)
 
 	for i, pair := range pairs {
+		// Scale both equations by the square root of the weight, for weighted least squares:
+		w := math.Sqrt(pair.weight())
+
 		// First equation: RA = a*X + b*Y + c:
-		A.Set(2*i, 0, pair.X) // a
-		A.Set(2*i, 1, pair.Y) // b
-		A.Set(2*i, 2, 1.0)    // c
-		A.Set(2*i, 3, 0.0)    // d
-		A.Set(2*i, 4, 0.0)    // e
-		A.Set(2*i, 5, 0.0)    // f
-		bVec.SetVec(2*i, pair.RA)
+		A.Set(2*i, 0, w*pair.X) // a
+		A.Set(2*i, 1, w*pair.Y) // b
+		A.Set(2*i, 2, w)        // c
+		A.Set(2*i, 3, 0.0)      // d
+		A.Set(2*i, 4, 0.0)      // e
+		A.Set(2*i, 5, 0.0)      // f
+		bVec.SetVec(2*i, w*pair.RA)
 
 		// Second equation: Dec = d*X + e*Y + f:
-		A.Set(2*i+1, 0, 0.0)    // a
-		A.Set(2*i+1, 1, 0.0)    // b
-		A.Set(2*i+1, 2, 0.0)    // c
-		A.Set(2*i+1, 3, pair.X) // d
-		A.Set(2*i+1, 4, pair.Y) // e
-		A.Set(2*i+1, 5, 1.0)    // f
-		bVec.SetVec(2*i+1, pair.Dec)
+		A.Set(2*i+1, 0, 0.0)      // a
+		A.Set(2*i+1, 1, 0.0)      // b
+		A.Set(2*i+1, 2, 0.0)      // c
+		A.Set(2*i+1, 3, w*pair.X) // d
+		A.Set(2*i+1, 4, w*pair.Y) // e
+		A.Set(2*i+1, 5, w)        // f
+		bVec.SetVec(2*i+1, w*pair.Dec)
 	}
 
 	// Solve the least squares problem: A * params = b:
@@ -14,6 +14,9 @@ import (
 	"math"
 	"testing"
 
+	"github.com/observerly/skysolve/pkg/quad"
+	"github.com/observerly/skysolve/pkg/spatial"
+	"github.com/observerly/skysolve/pkg/star"
 	"github.com/observerly/skysolve/pkg/transform"
 )
 
@@ -472,3 +475,31 @@ func TestEquatorialCoordinateToPixelWithSIPDistortion(t *testing.T) {
 }
 
 /*****************************************************************************************************************/
+
+func TestGetPointPairsFromQuadMatchesRemovesDuplicates(t *testing.T) {
+	a := star.Star{X: 10, Y: 10, RA: 180.0, Dec: 45.0}
+	b := star.Star{X: 50, Y: 20, RA: 179.99, Dec: 45.01}
+	c := star.Star{X: 30, Y: 60, RA: 180.01, Dec: 45.02}
+	d := star.Star{X: 70, Y: 80, RA: 179.98, Dec: 45.03}
+	e := star.Star{X: 90, Y: 15, RA: 179.97, Dec: 45.04}
+
+	// Two overlapping quads, which share the stars A, B and C:
+	matches := []spatial.QuadMatch{
+		{Quad: quad.Quad{A: a, B: b, C: c, D: d}},
+		{Quad: quad.Quad{A: a, B: b, C: c, D: e}},
+	}
+
+	pairs := GetPointPairsFromQuadMatches(matches)
+
+	if len(pairs) != 5 {
+		t.Fatalf("expected 5 unique point pairs, got %d", len(pairs))
+	}
+
+	for i, s := range []star.Star{a, b, c, d, e} {
+		if pairs[i].X != s.X || pairs[i].Y != s.Y || pairs[i].RA != s.RA || pairs[i].Dec != s.Dec {
+			t.Errorf("pair %d = %+v, want the star %+v", i, pairs[i], s)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
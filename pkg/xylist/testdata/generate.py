@@ -0,0 +1,81 @@
+"""
+Generates the astrometry.net xylist used by the Go tests of the xylist package, in the layout written by image2xy,
+i.e., an empty primary HDU followed by a binary table of single precision X, Y, FLUX and BACKGROUND columns, where
+X and Y are 1-based FITS pixel coordinates (the centre of the first pixel is (1, 1)).
+
+Usage: python generate.py (requires only the standard library)
+"""
+
+import struct
+
+
+def card(key, value=None, comment=""):
+    if value is None:
+        return f"{key:<80}"
+
+    if isinstance(value, bool):
+        value = "T" if value else "F"
+        text = f"{key:<8}= {value:>20}"
+    elif isinstance(value, str):
+        text = f"{key:<8}= '{value:<8}'"
+    else:
+        text = f"{key:<8}= {value:>20}"
+
+    if comment:
+        text += f" / {comment}"
+
+    return f"{text:<80}"[:80]
+
+
+def block(cards):
+    header = "".join(cards + [card("END")])
+    return (header + " " * (-len(header) % 2880)).encode("ascii")
+
+
+rows = [
+    # (X, Y, FLUX, BACKGROUND), brightest first as written by image2xy:
+    (1.0, 1.0, 15234.5, 812.25),
+    (1024.5, 768.5, 9876.0, 805.5),
+    (2047.25, 12.75, 4321.125, 799.0),
+    (300.0, 1500.5, 1234.5, 801.75),
+]
+
+primary = block([
+    card("SIMPLE", True, "file does conform to FITS standard"),
+    card("BITPIX", 8, "number of bits per data pixel"),
+    card("NAXIS", 0, "number of data axes"),
+    card("EXTEND", True, "FITS dataset may contain extensions"),
+    card("AN_FILE", "XYLS", "Astrometry.net file type"),
+    card("IMAGEW", 2048, "image width"),
+    card("IMAGEH", 1536, "image height"),
+])
+
+table = block([
+    card("XTENSION", "BINTABLE", "binary table extension"),
+    card("BITPIX", 8, "8-bit bytes"),
+    card("NAXIS", 2, "2-dimensional binary table"),
+    card("NAXIS1", 16, "width of table in bytes"),
+    card("NAXIS2", len(rows), "number of rows in table"),
+    card("PCOUNT", 0, "size of special data area"),
+    card("GCOUNT", 1, "one data group (required keyword)"),
+    card("TFIELDS", 4, "number of fields in each row"),
+    card("TTYPE1", "X", "label for field   1"),
+    card("TFORM1", "E", "data format of field: 4-byte REAL"),
+    card("TUNIT1", "pix", "physical unit of field"),
+    card("TTYPE2", "Y", "label for field   2"),
+    card("TFORM2", "E", "data format of field: 4-byte REAL"),
+    card("TUNIT2", "pix", "physical unit of field"),
+    card("TTYPE3", "FLUX", "label for field   3"),
+    card("TFORM3", "E", "data format of field: 4-byte REAL"),
+    card("TTYPE4", "BACKGROUND", "label for field   4"),
+    card("TFORM4", "E", "data format of field: 4-byte REAL"),
+    card("IMAGEW", 2048, "image width"),
+    card("IMAGEH", 1536, "image height"),
+    card("ESTSIGMA", 12.5, "Estimated source image variance"),
+])
+
+data = b"".join(struct.pack(">4f", *row) for row in rows)
+data += b"\0" * (-len(data) % 2880)
+
+with open("image2xy.xyls", "wb") as f:
+    f.write(primary + table + data)
@@ -0,0 +1,278 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package xylist
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/iris/pkg/photometry"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+// Centroid is a single pre-extracted star, in (0-based) pixel coordinates of the original image.
+type Centroid struct {
+	X    float64 `json:"x"`    // X pixel coordinate
+	Y    float64 `json:"y"`    // Y pixel coordinate
+	Flux float64 `json:"flux"` // The (relative) flux of the star, used to select the brightest stars
+}
+
+/*****************************************************************************************************************/
+
+// XYList is a list of pre-extracted star centroids, e.g., from SExtractor, photutils or an upstream pipeline,
+// along with the dimensions of the image the centroids were extracted from.
+type XYList struct {
+	Centroids []Centroid `json:"stars"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+}
+
+/*****************************************************************************************************************/
+
+// Stars converts the centroids into photometry stars, sorted by flux in descending order, such that they can be
+// passed directly to the plate solver in place of extracted stars.
+func (l *XYList) Stars() []photometry.Star {
+	stars := make([]photometry.Star, len(l.Centroids))
+
+	for i, c := range l.Centroids {
+		stars[i] = photometry.Star{
+			X:         float32(c.X),
+			Y:         float32(c.Y),
+			Intensity: float32(c.Flux),
+		}
+	}
+
+	sort.SliceStable(stars, func(i, j int) bool {
+		return stars[i].Intensity > stars[j].Intensity
+	})
+
+	return stars
+}
+
+/*****************************************************************************************************************/
+
+// Read reads an xylist from the file at the given path, where the format is determined by the file extension,
+// e.g., ".csv", ".json" or ".fits" (a FITS binary table, including astrometry.net ".xyls" and ".axy" files).
+func Read(path string) (*XYList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ReadFile(f)
+}
+
+/*****************************************************************************************************************/
+
+// ReadFile reads an xylist from an open file, where the format is determined by the extension of the file name.
+func ReadFile(f *os.File) (*XYList, error) {
+	path := f.Name()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".txt":
+		return ReadCSV(f)
+	case ".json":
+		return ReadJSON(f)
+	case ".fits", ".fit", ".fts", ".xyls", ".axy":
+		return ReadFITS(f)
+	default:
+		return nil, fmt.Errorf("unsupported xylist file extension: %s", filepath.Ext(path))
+	}
+}
+
+/*****************************************************************************************************************/
+
+// ReadCSV reads an xylist from comma separated values. If the first row is a header, the "x", "y" and (optional)
+// "flux" columns are located by name, otherwise the columns are assumed to be x, y and (optionally) flux.
+func ReadCSV(r io.Reader) (*XYList, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read xylist CSV: %w", err)
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("xylist CSV contains no rows")
+	}
+
+	x, y, flux := 0, 1, 2
+
+	// If the first row cannot be parsed as a number, it is a header row:
+	if _, err := strconv.ParseFloat(strings.TrimSpace(records[0][0]), 64); err != nil {
+		x, y, flux = -1, -1, -1
+
+		for i, name := range records[0] {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "x":
+				x = i
+			case "y":
+				y = i
+			case "flux", "intensity":
+				flux = i
+			}
+		}
+
+		if x < 0 || y < 0 {
+			return nil, errors.New("xylist CSV header must contain both x and y columns")
+		}
+
+		records = records[1:]
+	}
+
+	list := &XYList{
+		Centroids: make([]Centroid, 0, len(records)),
+	}
+
+	for row, record := range records {
+		c := Centroid{}
+
+		if c.X, err = parseCSVField(record, x); err != nil {
+			return nil, fmt.Errorf("xylist CSV row %d: %w", row+1, err)
+		}
+
+		if c.Y, err = parseCSVField(record, y); err != nil {
+			return nil, fmt.Errorf("xylist CSV row %d: %w", row+1, err)
+		}
+
+		// The flux is optional, so we default to zero when the column is absent:
+		if flux >= 0 && flux < len(record) {
+			if c.Flux, err = parseCSVField(record, flux); err != nil {
+				return nil, fmt.Errorf("xylist CSV row %d: %w", row+1, err)
+			}
+		}
+
+		list.Centroids = append(list.Centroids, c)
+	}
+
+	return list, nil
+}
+
+/*****************************************************************************************************************/
+
+func parseCSVField(record []string, i int) (float64, error) {
+	if i >= len(record) {
+		return 0, fmt.Errorf("missing column %d", i+1)
+	}
+
+	return strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+}
+
+/*****************************************************************************************************************/
+
+// ReadJSON reads an xylist from JSON, either as an object with "width", "height" and "stars" fields, or as a
+// bare array of {"x", "y", "flux"} objects.
+func ReadJSON(r io.Reader) (*XYList, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+
+	list := &XYList{}
+
+	if strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &list.Centroids)
+	} else {
+		err = json.Unmarshal(data, list)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read xylist JSON: %w", err)
+	}
+
+	return list, nil
+}
+
+/*****************************************************************************************************************/
+
+// ReadFITS reads an xylist from the first binary table extension of a FITS file, with "X", "Y" and (optional)
+// "FLUX" columns, e.g., an astrometry.net ".xyls" or ".axy" file, where the 1-based FITS pixel coordinates of the
+// table are converted to 0-based pixel coordinates. The image dimensions are read from the IMAGEW and IMAGEH
+// keywords where present.
+func ReadFITS(r io.Reader) (*XYList, error) {
+	hdus, err := fitsio.ReadHDUs(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read xylist FITS: %w", err)
+	}
+
+	list := &XYList{}
+
+	for _, hdu := range hdus {
+		// The image dimensions may be recorded in either the primary or the table header:
+		if w, ok := hdu.Header.Int("IMAGEW"); ok {
+			list.Width = int(w)
+		}
+
+		if h, ok := hdu.Header.Int("IMAGEH"); ok {
+			list.Height = int(h)
+		}
+
+		if hdu.Extension() != "BINTABLE" {
+			continue
+		}
+
+		table, err := fitsio.NewBinaryTableFromHDU(hdu)
+		if err != nil {
+			return nil, err
+		}
+
+		xs, err := table.Float64Column("X")
+		if err != nil {
+			return nil, err
+		}
+
+		ys, err := table.Float64Column("Y")
+		if err != nil {
+			return nil, err
+		}
+
+		// The flux is optional, so we default to zero when the column is absent:
+		fluxes, err := table.Float64Column("FLUX")
+		if err != nil {
+			fluxes = make([]float64, table.Rows)
+		}
+
+		list.Centroids = make([]Centroid, table.Rows)
+
+		// The centre of the first pixel is (1, 1) in FITS tables, but (0, 0) for the centroids:
+		for i := range list.Centroids {
+			list.Centroids[i] = Centroid{
+				X:    xs[i] - 1,
+				Y:    ys[i] - 1,
+				Flux: fluxes[i],
+			}
+		}
+
+		return list, nil
+	}
+
+	return nil, errors.New("xylist FITS contains no binary table extension")
+}
+
+/*****************************************************************************************************************/
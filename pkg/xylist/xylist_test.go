@@ -0,0 +1,151 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/skysolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package xylist
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/observerly/skysolve/pkg/fitsio"
+)
+
+/*****************************************************************************************************************/
+
+func assertCentroids(t *testing.T, list *XYList, want []Centroid) {
+	t.Helper()
+
+	if len(list.Centroids) != len(want) {
+		t.Fatalf("expected %d centroids, got %d", len(want), len(list.Centroids))
+	}
+
+	for i := range want {
+		if list.Centroids[i] != want[i] {
+			t.Errorf("centroid %d: expected %+v, got %+v", i, want[i], list.Centroids[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadCSVWithHeader(t *testing.T) {
+	list, err := ReadCSV(strings.NewReader("# extracted stars\nflux,x,y\n1000,10.5,20.5\n500,30,40\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertCentroids(t, list, []Centroid{{10.5, 20.5, 1000}, {30, 40, 500}})
+}
+
+/*****************************************************************************************************************/
+
+func TestReadCSVWithoutHeader(t *testing.T) {
+	list, err := ReadCSV(strings.NewReader("10.5, 20.5\n30, 40\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertCentroids(t, list, []Centroid{{10.5, 20.5, 0}, {30, 40, 0}})
+}
+
+/*****************************************************************************************************************/
+
+func TestReadJSON(t *testing.T) {
+	list, err := ReadJSON(strings.NewReader(`{"width": 1024, "height": 768, "stars": [{"x": 1, "y": 2, "flux": 3}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if list.Width != 1024 || list.Height != 768 {
+		t.Errorf("expected dimensions of 1024x768, got %dx%d", list.Width, list.Height)
+	}
+
+	assertCentroids(t, list, []Centroid{{1, 2, 3}})
+
+	list, err = ReadJSON(strings.NewReader(`[{"x": 4, "y": 5, "flux": 6}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assertCentroids(t, list, []Centroid{{4, 5, 6}})
+}
+
+/*****************************************************************************************************************/
+
+func TestReadFITS(t *testing.T) {
+	table, err := fitsio.NewBinaryTableHDU(
+		[]string{"X", "Y", "FLUX"},
+		[][]float64{{10.5, 30}, {20.5, 40}, {1000, 500}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	primary := fitsio.NewPrimaryHDU()
+	primary.Header.Set("IMAGEW", 4096, "image width")
+	primary.Header.Set("IMAGEH", 2048, "image height")
+
+	var buf bytes.Buffer
+
+	if err := fitsio.WriteHDUs(&buf, []*fitsio.HDU{primary, table}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list, err := ReadFITS(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if list.Width != 4096 || list.Height != 2048 {
+		t.Errorf("expected dimensions of 4096x2048, got %dx%d", list.Width, list.Height)
+	}
+
+	// The 1-based FITS pixel coordinates of the table are read as 0-based pixel coordinates:
+	assertCentroids(t, list, []Centroid{{9.5, 19.5, 1000}, {29, 39, 500}})
+
+	stars := list.Stars()
+
+	if stars[0].Intensity != 1000 {
+		t.Errorf("expected the brightest star first, got %v", stars[0].Intensity)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReadFITSAstrometryNetXYList(t *testing.T) {
+	// An xylist in the layout written by astrometry.net's image2xy, i.e., single precision X, Y, FLUX and
+	// BACKGROUND columns, where the first star is centred on the first (1, 1) pixel:
+	f, err := os.Open(filepath.Join("testdata", "image2xy.xyls"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	list, err := ReadFITS(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if list.Width != 2048 || list.Height != 1536 {
+		t.Errorf("expected dimensions of 2048x1536, got %dx%d", list.Width, list.Height)
+	}
+
+	assertCentroids(t, list, []Centroid{
+		{0, 0, 15234.5},
+		{1023.5, 767.5, 9876},
+		{2046.25, 11.75, 4321.125},
+		{299, 1499.5, 1234.5},
+	})
+}
+
+/*****************************************************************************************************************/